@@ -20,3 +20,21 @@ func Require(funcName string, count int, args []object.Object) *object.Error {
 	}
 	return nil
 }
+
+// Kwargs returns an error if any of the given keyword arguments is not one
+// of the allowed names.
+func Kwargs(funcName string, kwargs *object.Map, allowed ...string) *object.Error {
+	for _, name := range kwargs.StringKeys() {
+		found := false
+		for _, a := range allowed {
+			if a == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return object.NewKwargsError(funcName, name)
+		}
+	}
+	return nil
+}
@@ -33,3 +33,17 @@ func TestRequire(t *testing.T) {
 	require.Equal(t, "type error: bar() takes exactly 2 arguments (1 given)",
 		err.Message().Value())
 }
+
+func TestKwargs(t *testing.T) {
+	kwargs := object.NewMap(map[string]object.Object{
+		"key":     object.Nil,
+		"reverse": object.True,
+	})
+	require.Nil(t, arg.Kwargs("sorted", kwargs, "key", "reverse"))
+	require.Nil(t, arg.Kwargs("sorted", object.NewMap(nil)))
+
+	err := arg.Kwargs("sorted", kwargs, "key")
+	require.NotNil(t, err)
+	require.Equal(t, `type error: sorted() got an unexpected keyword argument "reverse"`,
+		err.Message().Value())
+}
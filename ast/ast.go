@@ -5,6 +5,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/cloudcmds/tamarin/tmpl"
@@ -91,14 +92,17 @@ type Var struct {
 
 	// isWalrus is true if this is a ":=" statement.
 	isWalrus bool
+
+	// typeAnnotation is the optional type of the variable, as in "x: int := 1"
+	typeAnnotation *Ident
 }
 
-func NewVar(token token.Token, name *Ident, value Expression) *Var {
-	return &Var{token: token, name: name, value: value}
+func NewVar(token token.Token, name *Ident, value Expression, typeAnnotation *Ident) *Var {
+	return &Var{token: token, name: name, value: value, typeAnnotation: typeAnnotation}
 }
 
-func NewDeclaration(token token.Token, name *Ident, value Expression) *Var {
-	return &Var{token: token, name: name, value: value, isWalrus: true}
+func NewDeclaration(token token.Token, name *Ident, value Expression, typeAnnotation *Ident) *Var {
+	return &Var{token: token, name: name, value: value, isWalrus: true, typeAnnotation: typeAnnotation}
 }
 
 func (s *Var) StatementNode() {}
@@ -111,15 +115,23 @@ func (s *Var) Value() (string, Expression) { return s.name.value, s.value }
 
 func (s *Var) IsWalrus() bool { return s.isWalrus }
 
+// TypeAnnotation returns the declared type of the variable, or nil if it
+// has none.
+func (s *Var) TypeAnnotation() *Ident { return s.typeAnnotation }
+
 func (s *Var) String() string {
 	var out bytes.Buffer
+	name := s.name.Literal()
+	if s.typeAnnotation != nil {
+		name += ": " + s.typeAnnotation.Literal()
+	}
 	if s.isWalrus {
-		out.WriteString(s.name.Literal() + " := ")
+		out.WriteString(name + " := ")
 		out.WriteString(s.value.String())
 		return out.String()
 	}
 	out.WriteString(s.Literal() + " ")
-	out.WriteString(s.name.Literal())
+	out.WriteString(name)
 	out.WriteString(" = ")
 	if s.value != nil {
 		out.WriteString(s.value.String())
@@ -176,10 +188,13 @@ type Const struct {
 	token token.Token // the "const" token
 	name  *Ident      // name of the constant
 	value Expression  // value of the constant
+
+	// typeAnnotation is the optional type of the constant
+	typeAnnotation *Ident
 }
 
-func NewConst(token token.Token, name *Ident, value Expression) *Const {
-	return &Const{token: token, name: name, value: value}
+func NewConst(token token.Token, name *Ident, value Expression, typeAnnotation *Ident) *Const {
+	return &Const{token: token, name: name, value: value, typeAnnotation: typeAnnotation}
 }
 
 func (c *Const) StatementNode() {}
@@ -190,10 +205,17 @@ func (c *Const) Literal() string { return c.token.Literal }
 
 func (c *Const) Value() (string, Expression) { return c.name.value, c.value }
 
+// TypeAnnotation returns the declared type of the constant, or nil if it
+// has none.
+func (c *Const) TypeAnnotation() *Ident { return c.typeAnnotation }
+
 func (c *Const) String() string {
 	var out bytes.Buffer
 	out.WriteString(c.Literal() + " ")
 	out.WriteString(c.name.Literal())
+	if c.typeAnnotation != nil {
+		out.WriteString(": " + c.typeAnnotation.Literal())
+	}
 	out.WriteString(" = ")
 	if c.value != nil {
 		out.WriteString(c.value.String())
@@ -267,6 +289,26 @@ func (i *Int) Value() int64 { return i.value }
 
 func (i *Int) String() string { return i.token.Literal }
 
+// BigInt holds an integer literal that is too large for an int64
+type BigInt struct {
+	token token.Token // the token containing the number
+	value *big.Int    // the value of the integer
+}
+
+func NewBigInt(token token.Token, value *big.Int) *BigInt {
+	return &BigInt{token: token, value: value}
+}
+
+func (i *BigInt) ExpressionNode() {}
+
+func (i *BigInt) Token() token.Token { return i.token }
+
+func (i *BigInt) Literal() string { return i.token.Literal }
+
+func (i *BigInt) Value() *big.Int { return i.value }
+
+func (i *BigInt) String() string { return i.token.Literal }
+
 // Float holds a floating point number
 type Float struct {
 	token token.Token // the token containing the number
@@ -582,7 +624,11 @@ func (f *For) IsIteratorLoop() bool {
 	if f.condition == nil {
 		return false
 	}
-	switch f.condition.(type) {
+	switch condition := f.condition.(type) {
+	case *Destructure:
+		// Destructuring assignments are supported in the same way:
+		//   for i, [a, b] := range x {}
+		return condition.IsWalrus()
 	case *Var, *MultiVar:
 		// The only case where var and multi-var assignments are supported are
 		// when an iterator is being used to define the loop. The assignment AST
@@ -638,15 +684,68 @@ type Func struct {
 
 	// body contains the set of statements within the function.
 	body *Block
+
+	// variadic is true if the last parameter collects any extra arguments
+	// into a list.
+	variadic bool
+
+	// generator is true if the body contains a yield statement.
+	generator bool
+
+	// types holds any type annotations, and may be nil.
+	types *FuncTypes
+}
+
+// FuncOpts configures a new Func.
+type FuncOpts struct {
+	// Token is the "func" token.
+	Token token.Token
+
+	// Name of the function, or nil for anonymous functions.
+	Name *Ident
+
+	// Parameters is the list of parameters the function receives.
+	Parameters []*Ident
+
+	// Defaults holds any default values for arguments which aren't specified.
+	Defaults map[string]Expression
+
+	// Body contains the set of statements within the function.
+	Body *Block
+
+	// Variadic is true if the last parameter collects any extra arguments
+	// into a list.
+	Variadic bool
+
+	// Generator is true if the body contains a yield statement.
+	Generator bool
+
+	// Types holds any type annotations, and may be nil.
+	Types *FuncTypes
 }
 
 func NewFunc(token token.Token, name *Ident, parameters []*Ident, defaults map[string]Expression, body *Block) *Func {
+	return NewFuncWithOpts(FuncOpts{
+		Token:      token,
+		Name:       name,
+		Parameters: parameters,
+		Defaults:   defaults,
+		Body:       body,
+	})
+}
+
+// NewFuncWithOpts returns a Func that may also be variadic, a generator or
+// have type annotations.
+func NewFuncWithOpts(opts FuncOpts) *Func {
 	return &Func{
-		token:      token,
-		name:       name,
-		parameters: parameters,
-		defaults:   defaults,
-		body:       body,
+		token:      opts.Token,
+		name:       opts.Name,
+		parameters: opts.Parameters,
+		defaults:   opts.Defaults,
+		body:       opts.Body,
+		variadic:   opts.Variadic,
+		generator:  opts.Generator,
+		types:      opts.Types,
 	}
 }
 
@@ -664,11 +763,28 @@ func (f *Func) Defaults() map[string]Expression { return f.defaults }
 
 func (f *Func) Body() *Block { return f.body }
 
+func (f *Func) Variadic() bool { return f.variadic }
+
+// Generator returns true if the function contains a yield statement, which
+// means calling it returns an iterator over the yielded values.
+func (f *Func) Generator() bool { return f.generator }
+
+// Types returns the type annotations of the function. The result may be nil
+// if there are none, and its methods may be called on nil.
+func (f *Func) Types() *FuncTypes { return f.types }
+
 func (f *Func) String() string {
 	var out bytes.Buffer
 	params := make([]string, 0)
 	for _, p := range f.parameters {
-		params = append(params, p.value)
+		param := p.value
+		if typ := f.types.Param(param); typ != nil {
+			param += ": " + typ.value
+		}
+		params = append(params, param)
+	}
+	if f.variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
 	}
 	out.WriteString(f.Literal())
 	if f.name != nil {
@@ -677,10 +793,49 @@ func (f *Func) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if result := f.types.Result(); result != nil {
+		out.WriteString("-> " + result.value + " ")
+	}
 	out.WriteString(f.body.String())
 	return out.String()
 }
 
+// FuncTypes holds the type annotations of a function, as in
+// "func f(x: int) -> string". Annotations are optional, so any parameter
+// may be missing and the result may be nil.
+type FuncTypes struct {
+	params map[string]*Ident
+	result *Ident
+}
+
+func NewFuncTypes(params map[string]*Ident, result *Ident) *FuncTypes {
+	return &FuncTypes{params: params, result: result}
+}
+
+// Param returns the type of the named parameter, or nil if it has none.
+func (t *FuncTypes) Param(name string) *Ident {
+	if t == nil {
+		return nil
+	}
+	return t.params[name]
+}
+
+// Params returns the annotated parameters, keyed by name.
+func (t *FuncTypes) Params() map[string]*Ident {
+	if t == nil {
+		return nil
+	}
+	return t.params
+}
+
+// Result returns the type of the function result, or nil if it has none.
+func (t *FuncTypes) Result() *Ident {
+	if t == nil {
+		return nil
+	}
+	return t.result
+}
+
 // Call holds the invocation of a method.
 type Call struct {
 	token     token.Token  // the '(' token
@@ -715,6 +870,69 @@ func (c *Call) String() string {
 	return out.String()
 }
 
+// Spread holds an argument that is spread into multiple arguments of a call.
+type Spread struct {
+	token token.Token // the "..." token
+	value Expression  // the list to spread
+}
+
+func NewSpread(token token.Token, value Expression) *Spread {
+	return &Spread{token: token, value: value}
+}
+
+func (s *Spread) ExpressionNode() {}
+
+func (s *Spread) Token() token.Token { return s.token }
+
+func (s *Spread) Literal() string { return s.token.Literal }
+
+func (s *Spread) Value() Expression { return s.value }
+
+func (s *Spread) String() string { return s.Literal() + s.value.String() }
+
+// KeywordArg holds an argument that is passed to a call by name.
+type KeywordArg struct {
+	token token.Token // the parameter name
+	value Expression  // the argument value
+}
+
+func NewKeywordArg(token token.Token, value Expression) *KeywordArg {
+	return &KeywordArg{token: token, value: value}
+}
+
+func (k *KeywordArg) ExpressionNode() {}
+
+func (k *KeywordArg) Token() token.Token { return k.token }
+
+func (k *KeywordArg) Literal() string { return k.token.Literal }
+
+func (k *KeywordArg) Name() string { return k.token.Literal }
+
+func (k *KeywordArg) Value() Expression { return k.value }
+
+func (k *KeywordArg) String() string { return k.Name() + "=" + k.value.String() }
+
+// Propagate is the postfix "?" operator. It evaluates to the value of an ok
+// result, or returns an err result from the enclosing function.
+type Propagate struct {
+	token token.Token // the "?" token
+	value Expression  // the result being unwrapped
+}
+
+func NewPropagate(token token.Token, value Expression) *Propagate {
+	return &Propagate{token: token, value: value}
+}
+
+func (p *Propagate) ExpressionNode() {}
+
+func (p *Propagate) Token() token.Token { return p.token }
+
+func (p *Propagate) Literal() string { return p.token.Literal }
+
+func (p *Propagate) Value() Expression { return p.value }
+
+func (p *Propagate) String() string { return "(" + p.value.String() + "?)" }
+
 // GetAttr
 type GetAttr struct {
 	token token.Token
@@ -925,10 +1143,13 @@ type Slice struct {
 
 	// Optional "to" index for [from:to] style expressions
 	toIndex Expression
+
+	// Optional step for [from:to:step] style expressions
+	step Expression
 }
 
-func NewSlice(token token.Token, left Expression, fromIndex Expression, toIndex Expression) *Slice {
-	return &Slice{token: token, left: left, fromIndex: fromIndex, toIndex: toIndex}
+func NewSlice(token token.Token, left Expression, fromIndex Expression, toIndex Expression, step Expression) *Slice {
+	return &Slice{token: token, left: left, fromIndex: fromIndex, toIndex: toIndex, step: step}
 }
 
 func (i *Slice) ExpressionNode() {}
@@ -943,28 +1164,73 @@ func (i *Slice) FromIndex() Expression { return i.fromIndex }
 
 func (i *Slice) ToIndex() Expression { return i.toIndex }
 
+func (i *Slice) Step() Expression { return i.step }
+
 func (i *Slice) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(i.left.String())
 	out.WriteString("[")
-	if i.fromIndex != nil {
-		out.WriteString(i.fromIndex.String())
+	out.WriteString(sliceString(i.fromIndex, i.toIndex, i.step))
+	out.WriteString("])")
+	return out.String()
+}
+
+func sliceString(from, to, step Expression) string {
+	var out bytes.Buffer
+	if from != nil {
+		out.WriteString(from.String())
+	}
+	out.WriteString(":")
+	if to != nil {
+		out.WriteString(to.String())
 	}
-	if i.toIndex != nil {
+	if step != nil {
 		out.WriteString(":")
-		out.WriteString(i.toIndex.String())
+		out.WriteString(step.String())
 	}
-	out.WriteString("])")
 	return out.String()
 }
 
+// SliceLiteral holds a slice given outside of an index expression, as in
+// the call delete(x, 1:3). It evaluates to an object.Slice.
+type SliceLiteral struct {
+	token token.Token
+
+	// Optional start, stop and step of the slice
+	fromIndex Expression
+	toIndex   Expression
+	step      Expression
+}
+
+func NewSliceLiteral(token token.Token, fromIndex, toIndex, step Expression) *SliceLiteral {
+	return &SliceLiteral{token: token, fromIndex: fromIndex, toIndex: toIndex, step: step}
+}
+
+func (s *SliceLiteral) ExpressionNode() {}
+
+func (s *SliceLiteral) Token() token.Token { return s.token }
+
+func (s *SliceLiteral) Literal() string { return s.token.Literal }
+
+func (s *SliceLiteral) FromIndex() Expression { return s.fromIndex }
+
+func (s *SliceLiteral) ToIndex() Expression { return s.toIndex }
+
+func (s *SliceLiteral) Step() Expression { return s.step }
+
+func (s *SliceLiteral) String() string {
+	return sliceString(s.fromIndex, s.toIndex, s.step)
+}
+
 // Assign is generally used for a simple assignment like "x = y". We also
 // support other operators like "+=", "-=", "*=", and "/=".
 type Assign struct {
 	token    token.Token
 	name     *Ident
 	index    *Index
+	slice    *Slice
+	attr     *GetAttr
 	operator string
 	value    Expression
 }
@@ -977,6 +1243,14 @@ func NewAssignIndex(operator token.Token, index *Index, value Expression) *Assig
 	return &Assign{token: operator, index: index, operator: operator.Literal, value: value}
 }
 
+func NewAssignSlice(operator token.Token, slice *Slice, value Expression) *Assign {
+	return &Assign{token: operator, slice: slice, operator: operator.Literal, value: value}
+}
+
+func NewAssignAttr(operator token.Token, attr *GetAttr, value Expression) *Assign {
+	return &Assign{token: operator, attr: attr, operator: operator.Literal, value: value}
+}
+
 func (a *Assign) ExpressionNode() {}
 
 func (a *Assign) Token() token.Token { return a.token }
@@ -987,6 +1261,10 @@ func (a *Assign) Name() string { return a.name.value }
 
 func (a *Assign) Index() *Index { return a.index }
 
+func (a *Assign) Slice() *Slice { return a.slice }
+
+func (a *Assign) Attr() *GetAttr { return a.attr }
+
 func (a *Assign) Operator() string { return a.operator }
 
 func (a *Assign) Value() Expression { return a.value }
@@ -995,6 +1273,10 @@ func (a *Assign) String() string {
 	var out bytes.Buffer
 	if a.index != nil {
 		out.WriteString(a.index.String())
+	} else if a.slice != nil {
+		out.WriteString(a.slice.String())
+	} else if a.attr != nil {
+		out.WriteString(a.attr.String())
 	} else {
 		out.WriteString(a.name.value)
 	}
@@ -1095,6 +1377,633 @@ func (s *Switch) String() string {
 	return out.String()
 }
 
+// Go holds a go statement, which runs a function call concurrently.
+type Go struct {
+	token token.Token // the "go" token
+	call  Expression  // the call to run, either a *Call or an *ObjectCall
+}
+
+func NewGo(token token.Token, call Expression) *Go {
+	return &Go{token: token, call: call}
+}
+
+func (g *Go) StatementNode() {}
+
+func (g *Go) Token() token.Token { return g.token }
+
+func (g *Go) Literal() string { return g.token.Literal }
+
+func (g *Go) Call() Expression { return g.call }
+
+func (g *Go) String() string {
+	var out bytes.Buffer
+	out.WriteString(g.Literal() + " ")
+	out.WriteString(g.call.String())
+	return out.String()
+}
+
+// Yield holds a yield statement, which produces the next value of the
+// iterator returned by a generator function.
+type Yield struct {
+	token token.Token // the "yield" token
+	value Expression  // the value to produce, or nil for a bare yield
+}
+
+func NewYield(token token.Token, value Expression) *Yield {
+	return &Yield{token: token, value: value}
+}
+
+func (y *Yield) StatementNode() {}
+
+func (y *Yield) Token() token.Token { return y.token }
+
+func (y *Yield) Literal() string { return y.token.Literal }
+
+func (y *Yield) Value() Expression { return y.value }
+
+func (y *Yield) String() string {
+	if y.value == nil {
+		return y.Literal()
+	}
+	return y.Literal() + " " + y.value.String()
+}
+
+// Defer holds a defer statement, which schedules a function call to run when
+// the surrounding function returns.
+type Defer struct {
+	token token.Token // the "defer" token
+	call  Expression  // the call to run, either a *Call or an *ObjectCall
+}
+
+func NewDefer(token token.Token, call Expression) *Defer {
+	return &Defer{token: token, call: call}
+}
+
+func (d *Defer) StatementNode() {}
+
+func (d *Defer) Token() token.Token { return d.token }
+
+func (d *Defer) Literal() string { return d.token.Literal }
+
+func (d *Defer) Call() Expression { return d.call }
+
+func (d *Defer) String() string {
+	var out bytes.Buffer
+	out.WriteString(d.Literal() + " ")
+	out.WriteString(d.call.String())
+	return out.String()
+}
+
+// SelectCase is one case within a select statement. Each case other than
+// the default either sends to or receives from a channel.
+type SelectCase struct {
+	token token.Token
+
+	// Default branch?
+	isDefault bool
+
+	// Variables declared to hold the received value and an ok flag
+	names []*Ident
+
+	// The send or recv method call on a channel
+	comm *ObjectCall
+
+	// The code to execute if this case is chosen
+	block *Block
+}
+
+func NewSelectCase(token token.Token, names []*Ident, comm *ObjectCall, block *Block) *SelectCase {
+	return &SelectCase{token: token, names: names, comm: comm, block: block}
+}
+
+func NewDefaultSelectCase(token token.Token, block *Block) *SelectCase {
+	return &SelectCase{token: token, isDefault: true, block: block}
+}
+
+func (c *SelectCase) ExpressionNode() {}
+
+func (c *SelectCase) Token() token.Token { return c.token }
+
+func (c *SelectCase) Literal() string { return c.token.Literal }
+
+func (c *SelectCase) IsDefault() bool { return c.isDefault }
+
+func (c *SelectCase) Names() []*Ident { return c.names }
+
+// Channel returns the expression that evaluates to the channel.
+func (c *SelectCase) Channel() Expression { return c.comm.Object() }
+
+// IsSend returns true if this case sends a value rather than receiving one.
+func (c *SelectCase) IsSend() bool { return c.comm.Call().(*Call).Function().String() == "send" }
+
+// Value returns the expression for the value being sent, if this is a send.
+func (c *SelectCase) Value() Expression {
+	args := c.comm.Call().(*Call).Arguments()
+	if len(args) == 0 {
+		return nil
+	}
+	return args[0]
+}
+
+func (c *SelectCase) Block() *Block { return c.block }
+
+func (c *SelectCase) String() string {
+	var out bytes.Buffer
+	if c.isDefault {
+		out.WriteString("default")
+	} else {
+		out.WriteString("case ")
+		if len(c.names) > 0 {
+			tmp := []string{}
+			for _, name := range c.names {
+				tmp = append(tmp, name.String())
+			}
+			out.WriteString(strings.Join(tmp, ", "))
+			out.WriteString(" := ")
+		}
+		out.WriteString(c.comm.String())
+	}
+	out.WriteString(":\n")
+	for i, exp := range c.block.statements {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("\t" + exp.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Select waits on multiple channel operations and runs the block of the
+// first one that is ready.
+type Select struct {
+	token token.Token   // token containing "select"
+	cases []*SelectCase // select cases
+}
+
+func NewSelect(token token.Token, cases []*SelectCase) *Select {
+	return &Select{token: token, cases: cases}
+}
+
+func (s *Select) ExpressionNode() {}
+
+func (s *Select) Token() token.Token { return s.token }
+
+func (s *Select) Literal() string { return s.token.Literal }
+
+func (s *Select) Cases() []*SelectCase { return s.cases }
+
+func (s *Select) String() string {
+	var out bytes.Buffer
+	out.WriteString("\nselect {\n")
+	for _, tmp := range s.cases {
+		out.WriteString(tmp.String())
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// Pattern is implemented by the patterns that may appear in the cases of a
+// match expression.
+type Pattern interface {
+	Node
+
+	// Bindings returns the names of the variables the pattern declares when
+	// it matches, in the order they appear in the pattern.
+	Bindings() []string
+}
+
+// WildcardPattern is the "_" pattern, which matches any value.
+type WildcardPattern struct {
+	token token.Token
+}
+
+func NewWildcardPattern(token token.Token) *WildcardPattern {
+	return &WildcardPattern{token: token}
+}
+
+func (p *WildcardPattern) Token() token.Token { return p.token }
+
+func (p *WildcardPattern) Literal() string { return p.token.Literal }
+
+func (p *WildcardPattern) Bindings() []string { return nil }
+
+func (p *WildcardPattern) String() string { return "_" }
+
+// BindingPattern matches any value and assigns it to a variable. When used
+// for a map key in a destructuring assignment, it may have a default value
+// that is used if the key is missing.
+type BindingPattern struct {
+	token        token.Token
+	name         *Ident
+	defaultValue Expression
+}
+
+func NewBindingPattern(token token.Token, name *Ident) *BindingPattern {
+	return &BindingPattern{token: token, name: name}
+}
+
+func NewDefaultBindingPattern(token token.Token, name *Ident, defaultValue Expression) *BindingPattern {
+	return &BindingPattern{token: token, name: name, defaultValue: defaultValue}
+}
+
+func (p *BindingPattern) Token() token.Token { return p.token }
+
+func (p *BindingPattern) Literal() string { return p.token.Literal }
+
+func (p *BindingPattern) Name() *Ident { return p.name }
+
+// Default returns the default value expression, or nil if there is none.
+func (p *BindingPattern) Default() Expression { return p.defaultValue }
+
+func (p *BindingPattern) Bindings() []string { return []string{p.name.String()} }
+
+func (p *BindingPattern) String() string {
+	if p.defaultValue != nil {
+		return p.name.String() + " = " + p.defaultValue.String()
+	}
+	return p.name.String()
+}
+
+// BindingPatterns returns the binding patterns found within a pattern, in
+// the same order as the names returned by its Bindings method.
+func BindingPatterns(pattern Pattern) []*BindingPattern {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		return []*BindingPattern{pattern}
+	case *ListPattern:
+		var bindings []*BindingPattern
+		for _, element := range pattern.elements {
+			bindings = append(bindings, BindingPatterns(element)...)
+		}
+		if pattern.rest != nil {
+			bindings = append(bindings, BindingPatterns(pattern.rest)...)
+		}
+		return bindings
+	case *MapPattern:
+		var bindings []*BindingPattern
+		for _, value := range pattern.values {
+			bindings = append(bindings, BindingPatterns(value)...)
+		}
+		if pattern.rest != nil {
+			bindings = append(bindings, BindingPatterns(pattern.rest)...)
+		}
+		return bindings
+	case *TypePattern:
+		return BindingPatterns(pattern.value)
+	}
+	return nil
+}
+
+// ValuePattern matches values equal to a literal int, float, string, bool
+// or nil. Negative numbers are held as a Prefix expression.
+type ValuePattern struct {
+	token token.Token
+	value Expression
+}
+
+func NewValuePattern(token token.Token, value Expression) *ValuePattern {
+	return &ValuePattern{token: token, value: value}
+}
+
+func (p *ValuePattern) Token() token.Token { return p.token }
+
+func (p *ValuePattern) Literal() string { return p.token.Literal }
+
+func (p *ValuePattern) Value() Expression { return p.value }
+
+func (p *ValuePattern) Bindings() []string { return nil }
+
+func (p *ValuePattern) String() string { return p.value.String() }
+
+// ListPattern matches lists element by element. Lists with more elements
+// are matched only if the pattern ends with a rest pattern, e.g. "...tail".
+type ListPattern struct {
+	token    token.Token // the '[' token
+	elements []Pattern
+	rest     Pattern // nil, or a BindingPattern or WildcardPattern
+}
+
+func NewListPattern(token token.Token, elements []Pattern, rest Pattern) *ListPattern {
+	return &ListPattern{token: token, elements: elements, rest: rest}
+}
+
+func (p *ListPattern) Token() token.Token { return p.token }
+
+func (p *ListPattern) Literal() string { return p.token.Literal }
+
+func (p *ListPattern) Elements() []Pattern { return p.elements }
+
+func (p *ListPattern) Rest() Pattern { return p.rest }
+
+func (p *ListPattern) Bindings() []string {
+	var names []string
+	for _, element := range p.elements {
+		names = append(names, element.Bindings()...)
+	}
+	if p.rest != nil {
+		names = append(names, p.rest.Bindings()...)
+	}
+	return names
+}
+
+func (p *ListPattern) String() string {
+	var items []string
+	for _, element := range p.elements {
+		items = append(items, element.String())
+	}
+	if p.rest != nil {
+		items = append(items, "..."+p.rest.String())
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// MapPattern matches maps that contain all of the given keys, with values
+// that match the corresponding patterns. Other keys are ignored unless they
+// are collected by a rest pattern.
+type MapPattern struct {
+	token  token.Token  // the '{' token
+	keys   []Expression // literals, compared to the map keys by HashKey
+	values []Pattern
+	rest   Pattern // nil, or a BindingPattern or WildcardPattern
+}
+
+func NewMapPattern(token token.Token, keys []Expression, values []Pattern, rest Pattern) *MapPattern {
+	return &MapPattern{token: token, keys: keys, values: values, rest: rest}
+}
+
+func (p *MapPattern) Token() token.Token { return p.token }
+
+func (p *MapPattern) Literal() string { return p.token.Literal }
+
+func (p *MapPattern) Keys() []Expression { return p.keys }
+
+func (p *MapPattern) Values() []Pattern { return p.values }
+
+func (p *MapPattern) Rest() Pattern { return p.rest }
+
+func (p *MapPattern) Bindings() []string {
+	var names []string
+	for _, value := range p.values {
+		names = append(names, value.Bindings()...)
+	}
+	if p.rest != nil {
+		names = append(names, p.rest.Bindings()...)
+	}
+	return names
+}
+
+func (p *MapPattern) String() string {
+	var items []string
+	for i, key := range p.keys {
+		items = append(items, fmt.Sprintf("%s: %s", key.String(), p.values[i].String()))
+	}
+	if p.rest != nil {
+		items = append(items, "..."+p.rest.String())
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// TypePattern matches values whose type has the given name, e.g. "int(n)",
+// and then matches the value against the inner pattern. The names "ok" and
+// "err" match results instead, with the inner pattern applied to the ok
+// value or to the error message.
+type TypePattern struct {
+	token token.Token // the type name token
+	name  string
+	value Pattern
+}
+
+func NewTypePattern(token token.Token, name string, value Pattern) *TypePattern {
+	return &TypePattern{token: token, name: name, value: value}
+}
+
+func (p *TypePattern) Token() token.Token { return p.token }
+
+func (p *TypePattern) Literal() string { return p.token.Literal }
+
+func (p *TypePattern) Name() string { return p.name }
+
+func (p *TypePattern) Value() Pattern { return p.value }
+
+func (p *TypePattern) Bindings() []string { return p.value.Bindings() }
+
+func (p *TypePattern) String() string { return p.name + "(" + p.value.String() + ")" }
+
+// MatchCase is one case within a match expression. The case is chosen if
+// any of its patterns match and the optional guard is truthy.
+type MatchCase struct {
+	token token.Token
+
+	// Default branch?
+	isDefault bool
+
+	// Alternative patterns, any of which may match
+	patterns []Pattern
+
+	// Condition checked after the pattern matches, or nil
+	guard Expression
+
+	// The code to execute if this case is chosen
+	block *Block
+}
+
+func NewMatchCase(token token.Token, patterns []Pattern, guard Expression, block *Block) *MatchCase {
+	return &MatchCase{token: token, patterns: patterns, guard: guard, block: block}
+}
+
+func NewDefaultMatchCase(token token.Token, block *Block) *MatchCase {
+	return &MatchCase{token: token, isDefault: true, block: block}
+}
+
+func (c *MatchCase) ExpressionNode() {}
+
+func (c *MatchCase) Token() token.Token { return c.token }
+
+func (c *MatchCase) Literal() string { return c.token.Literal }
+
+func (c *MatchCase) IsDefault() bool { return c.isDefault }
+
+func (c *MatchCase) Patterns() []Pattern { return c.patterns }
+
+func (c *MatchCase) Guard() Expression { return c.guard }
+
+func (c *MatchCase) Block() *Block { return c.block }
+
+func (c *MatchCase) String() string {
+	var out bytes.Buffer
+	if c.isDefault {
+		out.WriteString("default")
+	} else {
+		out.WriteString("case ")
+		tmp := []string{}
+		for _, pattern := range c.patterns {
+			tmp = append(tmp, pattern.String())
+		}
+		out.WriteString(strings.Join(tmp, ", "))
+		if c.guard != nil {
+			out.WriteString(" if ")
+			out.WriteString(c.guard.String())
+		}
+	}
+	out.WriteString(":\n")
+	for i, exp := range c.block.statements {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("\t" + exp.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Match compares a value against the patterns of each case in turn and runs
+// the block of the first case that matches.
+type Match struct {
+	token token.Token  // token containing "match"
+	value Expression   // the expression to match on
+	cases []*MatchCase // match cases
+}
+
+func NewMatch(token token.Token, value Expression, cases []*MatchCase) *Match {
+	return &Match{token: token, value: value, cases: cases}
+}
+
+func (m *Match) ExpressionNode() {}
+
+func (m *Match) Token() token.Token { return m.token }
+
+func (m *Match) Literal() string { return m.token.Literal }
+
+func (m *Match) Value() Expression { return m.value }
+
+func (m *Match) Cases() []*MatchCase { return m.cases }
+
+func (m *Match) String() string {
+	var out bytes.Buffer
+	out.WriteString("\nmatch ")
+	out.WriteString(m.value.String())
+	out.WriteString(" {\n")
+	for _, tmp := range m.cases {
+		out.WriteString(tmp.String())
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// Destructure assigns the parts of a list or map to the variables bound by
+// a target pattern, e.g. "[a, [b, c]] := x" or "{name, age = 0} = person".
+// Comma separated targets like "first, ...rest := x" are held as a list
+// pattern.
+type Destructure struct {
+	token    token.Token // the first token of the target
+	target   Pattern
+	targets  []Pattern // the comma separated targets, as written
+	value    Expression
+	isWalrus bool // isWalrus is true if this is a ":=" statement.
+}
+
+func NewDestructure(token token.Token, targets []Pattern, rest Pattern, value Expression, isWalrus bool) *Destructure {
+	target := Pattern(NewListPattern(token, targets, rest))
+	if len(targets) == 1 && rest == nil {
+		target = targets[0]
+	}
+	return &Destructure{token: token, target: target, targets: targets, value: value, isWalrus: isWalrus}
+}
+
+func (d *Destructure) StatementNode() {}
+
+func (d *Destructure) Token() token.Token { return d.token }
+
+func (d *Destructure) Literal() string { return d.token.Literal }
+
+func (d *Destructure) Target() Pattern { return d.target }
+
+// Targets returns the comma separated targets of the assignment, not
+// including a trailing rest target.
+func (d *Destructure) Targets() []Pattern { return d.targets }
+
+func (d *Destructure) Value() Expression { return d.value }
+
+func (d *Destructure) IsWalrus() bool { return d.isWalrus }
+
+func (d *Destructure) String() string {
+	operator := " = "
+	if d.isWalrus {
+		operator = " := "
+	}
+	return d.target.String() + operator + d.value.String()
+}
+
+// Struct holds a struct declaration, which defines a named type with fields
+// and methods.
+type Struct struct {
+	token token.Token // the "struct" token
+
+	// name of the struct type
+	name *Ident
+
+	// fields lists the names of the fields in declaration order.
+	fields []*Ident
+
+	// defaults holds the default values of fields that declare one.
+	defaults map[string]Expression
+
+	// methods holds the methods of the struct. The first parameter of each
+	// method is its receiver.
+	methods []*Func
+}
+
+func NewStruct(token token.Token, name *Ident, fields []*Ident, defaults map[string]Expression, methods []*Func) *Struct {
+	return &Struct{
+		token:    token,
+		name:     name,
+		fields:   fields,
+		defaults: defaults,
+		methods:  methods,
+	}
+}
+
+func (s *Struct) StatementNode() {}
+
+func (s *Struct) Token() token.Token { return s.token }
+
+func (s *Struct) Literal() string { return s.token.Literal }
+
+func (s *Struct) Name() *Ident { return s.name }
+
+func (s *Struct) Fields() []*Ident { return s.fields }
+
+func (s *Struct) Defaults() map[string]Expression { return s.defaults }
+
+func (s *Struct) Methods() []*Func { return s.methods }
+
+func (s *Struct) String() string {
+	var out bytes.Buffer
+	out.WriteString(s.Literal() + " " + s.name.value + " {\n")
+	for _, field := range s.fields {
+		out.WriteString("\t" + field.value)
+		if value, ok := s.defaults[field.value]; ok {
+			out.WriteString(" = " + value.String())
+		}
+		out.WriteString("\n")
+	}
+	for _, method := range s.methods {
+		params := make([]string, 0)
+		for _, p := range method.parameters[1:] {
+			params = append(params, p.value)
+		}
+		if method.variadic {
+			params[len(params)-1] = "..." + params[len(params)-1]
+		}
+		out.WriteString(fmt.Sprintf("\t%s (%s) %s(%s) %s\n", method.Literal(),
+			method.parameters[0].value, method.name.value,
+			strings.Join(params, ", "), method.body.String()))
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
 // Import holds an import statement
 type Import struct {
 	token token.Token // the "import" token
@@ -1181,6 +2090,137 @@ func (s *Set) String() string {
 	return out.String()
 }
 
+// ComprehensionClause holds a "for" clause of a comprehension, along with
+// its optional "if" condition
+type ComprehensionClause struct {
+	token     token.Token // the "for" token
+	targets   []Pattern   // one or two targets assigned on each iteration
+	iterable  Expression  // the container being iterated over
+	condition Expression  // optional filter applied to each item
+}
+
+func NewComprehensionClause(tok token.Token, targets []Pattern, iterable, condition Expression) *ComprehensionClause {
+	return &ComprehensionClause{token: tok, targets: targets, iterable: iterable, condition: condition}
+}
+
+func (c *ComprehensionClause) Token() token.Token { return c.token }
+
+func (c *ComprehensionClause) Literal() string { return c.token.Literal }
+
+func (c *ComprehensionClause) Targets() []Pattern { return c.targets }
+
+func (c *ComprehensionClause) Iterable() Expression { return c.iterable }
+
+func (c *ComprehensionClause) Condition() Expression { return c.condition }
+
+// Bindings returns the names of the variables assigned by the clause.
+func (c *ComprehensionClause) Bindings() []string {
+	var names []string
+	for _, target := range c.targets {
+		names = append(names, target.Bindings()...)
+	}
+	return names
+}
+
+func (c *ComprehensionClause) String() string {
+	targets := make([]string, 0, len(c.targets))
+	for _, target := range c.targets {
+		targets = append(targets, target.String())
+	}
+	out := "for " + strings.Join(targets, ", ") + " in " + c.iterable.String()
+	if c.condition != nil {
+		out += " if " + c.condition.String()
+	}
+	return out
+}
+
+func clausesString(clauses []*ComprehensionClause) string {
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		parts = append(parts, clause.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// ListComprehension builds a list from the items produced by its clauses
+type ListComprehension struct {
+	token   token.Token // the '[' token
+	element Expression  // the item added on each iteration
+	clauses []*ComprehensionClause
+}
+
+func NewListComprehension(tok token.Token, element Expression, clauses []*ComprehensionClause) *ListComprehension {
+	return &ListComprehension{token: tok, element: element, clauses: clauses}
+}
+
+func (l *ListComprehension) ExpressionNode() {}
+
+func (l *ListComprehension) Token() token.Token { return l.token }
+
+func (l *ListComprehension) Literal() string { return l.token.Literal }
+
+func (l *ListComprehension) Element() Expression { return l.element }
+
+func (l *ListComprehension) Clauses() []*ComprehensionClause { return l.clauses }
+
+func (l *ListComprehension) String() string {
+	return "[" + l.element.String() + " " + clausesString(l.clauses) + "]"
+}
+
+// SetComprehension builds a set from the items produced by its clauses
+type SetComprehension struct {
+	token   token.Token // the '{' token
+	element Expression  // the item added on each iteration
+	clauses []*ComprehensionClause
+}
+
+func NewSetComprehension(tok token.Token, element Expression, clauses []*ComprehensionClause) *SetComprehension {
+	return &SetComprehension{token: tok, element: element, clauses: clauses}
+}
+
+func (s *SetComprehension) ExpressionNode() {}
+
+func (s *SetComprehension) Token() token.Token { return s.token }
+
+func (s *SetComprehension) Literal() string { return s.token.Literal }
+
+func (s *SetComprehension) Element() Expression { return s.element }
+
+func (s *SetComprehension) Clauses() []*ComprehensionClause { return s.clauses }
+
+func (s *SetComprehension) String() string {
+	return "{" + s.element.String() + " " + clausesString(s.clauses) + "}"
+}
+
+// MapComprehension builds a map from the entries produced by its clauses.
+// Unlike in a map literal, the key is always evaluated as an expression.
+type MapComprehension struct {
+	token   token.Token // the '{' token
+	key     Expression  // the key of the entry added on each iteration
+	value   Expression  // the value of the entry added on each iteration
+	clauses []*ComprehensionClause
+}
+
+func NewMapComprehension(tok token.Token, key, value Expression, clauses []*ComprehensionClause) *MapComprehension {
+	return &MapComprehension{token: tok, key: key, value: value, clauses: clauses}
+}
+
+func (m *MapComprehension) ExpressionNode() {}
+
+func (m *MapComprehension) Token() token.Token { return m.token }
+
+func (m *MapComprehension) Literal() string { return m.token.Literal }
+
+func (m *MapComprehension) Key() Expression { return m.key }
+
+func (m *MapComprehension) Value() Expression { return m.value }
+
+func (m *MapComprehension) Clauses() []*ComprehensionClause { return m.clauses }
+
+func (m *MapComprehension) String() string {
+	return "{" + m.key.String() + ": " + m.value.String() + " " + clausesString(m.clauses) + "}"
+}
+
 // In holds an "in" expression
 type In struct {
 	token token.Token
@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/cloudcmds/tamarin/token"
@@ -26,3 +28,41 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	ident := func(name string) *Ident {
+		return NewIdent(token.Token{Type: token.IDENT, Literal: name})
+	}
+	integer := func(value int64) *Int {
+		return NewInt(token.Token{Type: token.INT, Literal: fmt.Sprint(value)}, value)
+	}
+	// if x { f(1) } else { [2, x] }
+	program := NewProgram([]Node{
+		NewIf(token.Token{Type: token.IF, Literal: "if"}, ident("x"),
+			NewBlock(token.Token{}, []Node{
+				NewCall(token.Token{}, ident("f"), []Expression{integer(1)}),
+			}),
+			NewBlock(token.Token{}, []Node{
+				NewList(token.Token{}, []Expression{integer(2), ident("x")}),
+			}),
+		),
+	})
+	var visited []string
+	Inspect(program, func(node Node) bool {
+		switch node := node.(type) {
+		case *Ident:
+			visited = append(visited, node.String())
+		case *Int:
+			visited = append(visited, node.String())
+		case *List:
+			// Skip the children of the list
+			visited = append(visited, "list")
+			return false
+		}
+		return true
+	})
+	expected := []string{"x", "f", "1", "list"}
+	if strings.Join(visited, " ") != strings.Join(expected, " ") {
+		t.Errorf("Inspect visited the wrong nodes. got=%q", visited)
+	}
+}
@@ -0,0 +1,198 @@
+package ast
+
+import "sort"
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// fn for each node before its children. If fn returns false, the children
+// of that node are skipped. Children are visited in source order. The
+// default values of destructuring patterns are visited, but patterns
+// themselves are not.
+func Inspect(node Node, fn func(Node) bool) {
+	if isNil(node) || !fn(node) {
+		return
+	}
+	for _, child := range children(node) {
+		Inspect(child, fn)
+	}
+}
+
+// isNil returns true if the node is nil, including a typed nil pointer
+// stored in the interface.
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *Block:
+		return node == nil
+	case *Ident:
+		return node == nil
+	case *Index:
+		return node == nil
+	case *Slice:
+		return node == nil
+	case *GetAttr:
+		return node == nil
+	}
+	return false
+}
+
+func children(node Node) []Node {
+	var nodes []Node
+	add := func(items ...Node) {
+		for _, item := range items {
+			if !isNil(item) {
+				nodes = append(nodes, item)
+			}
+		}
+	}
+	addExpressions := func(exprs []Expression) {
+		for _, expr := range exprs {
+			add(expr)
+		}
+	}
+	addPatterns := func(patterns ...Pattern) {
+		for _, pattern := range patterns {
+			if pattern == nil {
+				continue
+			}
+			for _, binding := range BindingPatterns(pattern) {
+				add(binding.Default())
+			}
+		}
+	}
+	switch node := node.(type) {
+	case *Program:
+		add(node.Statements()...)
+	case *Block:
+		add(node.Statements()...)
+	case *Prefix:
+		add(node.Right())
+	case *Infix:
+		add(node.Left(), node.Right())
+	case *Ternary:
+		add(node.Condition(), node.IfTrue(), node.IfFalse())
+	case *Propagate:
+		add(node.Value())
+	case *In:
+		add(node.Left(), node.Right())
+	case *Index:
+		add(node.Left(), node.Index())
+	case *Slice:
+		add(node.Left(), node.FromIndex(), node.ToIndex(), node.Step())
+	case *SliceLiteral:
+		add(node.FromIndex(), node.ToIndex(), node.Step())
+	case *Var:
+		_, value := node.Value()
+		add(value)
+	case *Const:
+		_, value := node.Value()
+		add(value)
+	case *MultiVar:
+		_, value := node.Value()
+		add(value)
+	case *Assign:
+		if node.Attr() != nil {
+			add(node.Attr().Object())
+		}
+		add(node.Index(), node.Slice(), node.Value())
+	case *Destructure:
+		addPatterns(node.Target())
+		add(node.Value())
+	case *Func:
+		for _, param := range node.Parameters() {
+			if value, ok := node.Defaults()[param.String()]; ok {
+				add(value)
+			}
+		}
+		add(node.Body())
+	case *Struct:
+		for _, field := range node.Fields() {
+			if value, ok := node.Defaults()[field.String()]; ok {
+				add(value)
+			}
+		}
+		for _, method := range node.Methods() {
+			add(method)
+		}
+	case *Call:
+		add(node.Function())
+		addExpressions(node.Arguments())
+	case *ObjectCall:
+		add(node.Object(), node.Call())
+	case *GetAttr:
+		add(node.Object())
+	case *Spread:
+		add(node.Value())
+	case *KeywordArg:
+		add(node.Value())
+	case *If:
+		add(node.Condition(), node.Consequence(), node.Alternative())
+	case *For:
+		add(node.Init(), node.Condition(), node.Post(), node.Consequence())
+	case *Switch:
+		add(node.Value())
+		for _, choice := range node.Choices() {
+			addExpressions(choice.Expressions())
+			add(choice.Block())
+		}
+	case *Select:
+		for _, choice := range node.Cases() {
+			if !choice.IsDefault() {
+				add(choice.Channel(), choice.Value())
+			}
+			add(choice.Block())
+		}
+	case *Match:
+		add(node.Value())
+		for _, choice := range node.Cases() {
+			addPatterns(choice.Patterns()...)
+			add(choice.Guard(), choice.Block())
+		}
+	case *Yield:
+		add(node.Value())
+	case *Go:
+		add(node.Call())
+	case *Defer:
+		add(node.Call())
+	case *Pipe:
+		addExpressions(node.Expressions())
+	case *Control:
+		add(node.Value())
+	case *Range:
+		add(node.Container())
+	case *String:
+		addExpressions(node.TemplateExpressions())
+	case *List:
+		addExpressions(node.Items())
+	case *Set:
+		addExpressions(node.Items())
+	case *Map:
+		keys := make([]Expression, 0, len(node.Items()))
+		for key := range node.Items() {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Token().StartPosition.Char < keys[j].Token().StartPosition.Char
+		})
+		for _, key := range keys {
+			add(key, node.Items()[key])
+		}
+	case *ListComprehension:
+		add(node.Element())
+		addClauses(node.Clauses(), add, addPatterns)
+	case *SetComprehension:
+		add(node.Element())
+		addClauses(node.Clauses(), add, addPatterns)
+	case *MapComprehension:
+		add(node.Key(), node.Value())
+		addClauses(node.Clauses(), add, addPatterns)
+	}
+	return nodes
+}
+
+func addClauses(clauses []*ComprehensionClause, add func(...Node), addPatterns func(...Pattern)) {
+	for _, clause := range clauses {
+		addPatterns(clause.Targets()...)
+		add(clause.Iterable(), clause.Condition())
+	}
+}
@@ -0,0 +1,483 @@
+// Package checker validates the type annotations of a Tamarin program
+// before it runs. Annotations are optional, and only values whose type is
+// known without running the program are checked, such as literals and the
+// results of calls to annotated functions. Everything else is verified at
+// runtime by the evaluator and the VM.
+package checker
+
+import (
+	"fmt"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/resolver"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/token"
+)
+
+// Opts configures the checker.
+type Opts struct {
+	// Scope is the scope the program will be evaluated in. Structs defined
+	// in it may be used as types.
+	Scope *scope.Scope
+
+	// File is the name of the file the program was parsed from (optional).
+	File string
+
+	// Input is the source code of the program (optional). If set, the
+	// offending line is included in each diagnostic.
+	Input string
+}
+
+// builtinTypes are the type names that may be used in annotations, in
+// addition to the names of structs.
+var builtinTypes = map[string]bool{
+	"any":                        true,
+	string(object.INT):           true,
+	string(object.FLOAT):         true,
+	string(object.BIGINT):        true,
+	string(object.DECIMAL):       true,
+	string(object.BOOL):          true,
+	string(object.NIL):           true,
+	string(object.ERROR):         true,
+	string(object.FUNCTION):      true,
+	string(object.STRING):        true,
+	string(object.BYTES):         true,
+	string(object.BUILTIN):       true,
+	string(object.LIST):          true,
+	string(object.MAP):           true,
+	string(object.FILE):          true,
+	string(object.REGEXP):        true,
+	string(object.SET):           true,
+	string(object.MODULE):        true,
+	string(object.RESULT):        true,
+	string(object.HTTP_RESPONSE): true,
+	string(object.DB_CONNECTION): true,
+	string(object.TIME):          true,
+	string(object.CHAN):          true,
+	string(object.STRUCT):        true,
+	string(object.PROXY):         true,
+	string(object.SLICE):         true,
+	string(object.GENERATOR):     true,
+}
+
+type checker struct {
+	opts        Opts
+	types       map[string]bool
+	funcs       map[string]*ast.Func
+	structs     map[string]bool
+	vars        map[string]string
+	diagnostics []*resolver.Diagnostic
+}
+
+// Check validates the type annotations of the program. Unknown type names
+// and values that are known to have the wrong type are reported as errors.
+func Check(program *ast.Program, opts Opts) []*resolver.Diagnostic {
+	c := &checker{
+		opts:    opts,
+		types:   map[string]bool{},
+		funcs:   map[string]*ast.Func{},
+		structs: map[string]bool{},
+		vars:    map[string]string{},
+	}
+	c.collect(program)
+	c.walk(program, nil)
+	return c.diagnostics
+}
+
+// collect finds the structs, functions and annotated variables declared by
+// the program. Calls to a function and assignments to a variable are only
+// checked if its name is declared exactly once, since otherwise the name may
+// refer to something else where it is used.
+func (c *checker) collect(program *ast.Program) {
+	counts := map[string]int{}
+	declare := func(names ...string) {
+		for _, name := range names {
+			counts[name]++
+		}
+	}
+	declarePatterns := func(patterns ...ast.Pattern) {
+		for _, pattern := range patterns {
+			declare(pattern.Bindings()...)
+		}
+	}
+	methods := map[*ast.Func]bool{}
+	funcs := map[string]*ast.Func{}
+	structs := map[string]bool{}
+	vars := map[string]string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Var:
+			name, _ := node.Value()
+			declare(name)
+			if typ := node.TypeAnnotation(); typ != nil {
+				vars[name] = typ.String()
+			}
+		case *ast.Const:
+			name, _ := node.Value()
+			declare(name)
+		case *ast.MultiVar:
+			names, _ := node.Value()
+			declare(names...)
+		case *ast.Destructure:
+			declarePatterns(node.Target())
+		case *ast.Assign:
+			if node.Operator() == ":=" {
+				declare(node.Name())
+			}
+		case *ast.Import:
+			declare(node.Module().String())
+		case *ast.Struct:
+			declare(node.Name().String())
+			structs[node.Name().String()] = true
+			c.types[node.Name().String()] = true
+			for _, method := range node.Methods() {
+				methods[method] = true
+			}
+		case *ast.Func:
+			params := node.Parameters()
+			for i, param := range params {
+				declare(param.String())
+				// The variadic parameter holds a list of the annotated type
+				if typ := node.Types().Param(param.String()); typ != nil && !(node.Variadic() && i == len(params)-1) {
+					vars[param.String()] = typ.String()
+				}
+			}
+			if node.Name() != nil && !methods[node] {
+				declare(node.Name().String())
+				funcs[node.Name().String()] = node
+			}
+		case *ast.Select:
+			for _, choice := range node.Cases() {
+				for _, name := range choice.Names() {
+					declare(name.String())
+				}
+			}
+		case *ast.Match:
+			for _, choice := range node.Cases() {
+				declarePatterns(choice.Patterns()...)
+			}
+		case *ast.ListComprehension:
+			declareClauses(node.Clauses(), declarePatterns)
+		case *ast.SetComprehension:
+			declareClauses(node.Clauses(), declarePatterns)
+		case *ast.MapComprehension:
+			declareClauses(node.Clauses(), declarePatterns)
+		}
+		return true
+	})
+	for name, fn := range funcs {
+		if counts[name] == 1 {
+			c.funcs[name] = fn
+		}
+	}
+	for name := range structs {
+		if counts[name] == 1 {
+			c.structs[name] = true
+		}
+	}
+	for name, typ := range vars {
+		if counts[name] == 1 {
+			c.vars[name] = typ
+		}
+	}
+}
+
+func declareClauses(clauses []*ast.ComprehensionClause, declarePatterns func(...ast.Pattern)) {
+	for _, clause := range clauses {
+		declarePatterns(clause.Targets()...)
+	}
+}
+
+// walk checks the nodes within the given node. The function is the one the
+// node belongs to, or nil at the top level of the program.
+func (c *checker) walk(node ast.Node, fn *ast.Func) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Func:
+			c.checkFunc(node, fn)
+			return false
+		case *ast.Var:
+			name, value := node.Value()
+			c.checkVariable(name, node.TypeAnnotation(), value)
+		case *ast.Const:
+			name, value := node.Value()
+			c.checkVariable(name, node.TypeAnnotation(), value)
+		case *ast.Assign:
+			c.checkAssignment(node)
+		case *ast.Control:
+			if node.Token().Type == token.RETURN && fn != nil && !fn.Generator() {
+				c.checkResult(fn, node.Value())
+			}
+		case *ast.Call:
+			c.checkCall(node)
+		case *ast.ObjectCall:
+			// The method name is not a variable, so it may not refer to a
+			// function of the same name
+			c.walk(node.Object(), fn)
+			if call, ok := node.Call().(*ast.Call); ok {
+				for _, arg := range call.Arguments() {
+					c.walk(arg, fn)
+				}
+			}
+			return false
+		case *ast.Pipe:
+			// Calls in a pipe are given the piped value as an extra argument
+			for _, expr := range node.Expressions() {
+				if call, ok := expr.(*ast.Call); ok {
+					c.walk(call.Function(), fn)
+					for _, arg := range call.Arguments() {
+						c.walk(arg, fn)
+					}
+				} else {
+					c.walk(expr, fn)
+				}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// checkFunc checks the annotations of a function, its default values and
+// its body. The outer function is the one the function is declared in.
+func (c *checker) checkFunc(node *ast.Func, outer *ast.Func) {
+	types := node.Types()
+	name := funcName(node)
+	for _, param := range node.Parameters() {
+		typ := types.Param(param.String())
+		c.checkTypeName(typ)
+		value, ok := node.Defaults()[param.String()]
+		if !ok {
+			continue
+		}
+		c.walk(value, outer)
+		c.checkArgument(node, param.String(), value)
+	}
+	result := types.Result()
+	c.checkTypeName(result)
+	if result != nil && node.Generator() && c.known(result.String()) && !matches(result.String(), string(object.GENERATOR)) {
+		c.fail(result.Token(), "%s() must return %s (got generator)", name, result.String())
+	}
+	c.walk(node.Body(), node)
+	// The value of the last statement is returned if there is no return
+	// statement at the end of the body
+	if statements := node.Body().Statements(); len(statements) > 0 && !node.Generator() {
+		if expr, ok := statements[len(statements)-1].(ast.Expression); ok {
+			c.checkResult(node, expr)
+		}
+	}
+}
+
+// checkTypeName reports an error if a type annotation doesn't name a known
+// type, which is most likely a misspelling.
+func (c *checker) checkTypeName(typ *ast.Ident) {
+	if typ == nil {
+		return
+	}
+	if !c.known(typ.String()) {
+		c.report(typ.Token(), fmt.Sprintf("%q is not a known type", typ.String()), "type error", false)
+	}
+}
+
+// known returns true if the name is a builtin type or the name of a struct
+// declared by the program or the host scope.
+func (c *checker) known(name string) bool {
+	if builtinTypes[name] || c.types[name] {
+		return true
+	}
+	if c.opts.Scope != nil {
+		if value, ok := c.opts.Scope.Get(name); ok {
+			_, ok := value.(*object.Struct)
+			return ok
+		}
+	}
+	return false
+}
+
+func (c *checker) checkVariable(name string, typ *ast.Ident, value ast.Expression) {
+	if typ == nil {
+		return
+	}
+	c.checkTypeName(typ)
+	if actual, ok := c.mismatch(typ.String(), value); ok {
+		c.fail(value.Token(), "%q must be %s (got %s)", name, typ.String(), actual)
+	}
+}
+
+// checkAssignment checks an assignment to an annotated variable. The result
+// of a compound assignment is only known when it turns an int into a float,
+// e.g. x += 0.5.
+func (c *checker) checkAssignment(node *ast.Assign) {
+	if node.Index() != nil || node.Slice() != nil || node.Attr() != nil {
+		return
+	}
+	typ, ok := c.vars[node.Name()]
+	if !ok || !c.known(typ) {
+		return
+	}
+	value := node.Value()
+	switch node.Operator() {
+	case "=":
+		if actual, ok := c.mismatch(typ, value); ok {
+			c.fail(value.Token(), "%q must be %s (got %s)", node.Name(), typ, actual)
+		}
+	case "+=", "-=", "*=", "/=":
+		if typ == string(object.INT) && c.infer(value) == string(object.FLOAT) {
+			c.fail(value.Token(), "%q must be %s (got %s)", node.Name(), typ, object.FLOAT)
+		}
+	}
+}
+
+func (c *checker) checkResult(fn *ast.Func, value ast.Expression) {
+	result := fn.Types().Result()
+	if result == nil || value == nil {
+		return
+	}
+	if actual, ok := c.mismatch(result.String(), value); ok {
+		c.fail(value.Token(), "%s() must return %s (got %s)", funcName(fn), result.String(), actual)
+	}
+}
+
+// checkCall checks the arguments of a call to a function declared by the
+// program. Arguments after a spread argument are not checked, since their
+// position is not known.
+func (c *checker) checkCall(node *ast.Call) {
+	ident, ok := node.Function().(*ast.Ident)
+	if !ok {
+		return
+	}
+	fn, ok := c.funcs[ident.String()]
+	if !ok || fn.Types() == nil {
+		return
+	}
+	params := fn.Parameters()
+	for i, arg := range node.Arguments() {
+		switch arg := arg.(type) {
+		case *ast.Spread:
+			return
+		case *ast.KeywordArg:
+			c.checkArgument(fn, arg.Name(), arg.Value())
+		default:
+			switch {
+			case i < len(params):
+				c.checkArgument(fn, params[i].String(), arg)
+			case fn.Variadic() && len(params) > 0:
+				c.checkArgument(fn, params[len(params)-1].String(), arg)
+			}
+		}
+	}
+}
+
+func (c *checker) checkArgument(fn *ast.Func, param string, value ast.Expression) {
+	typ := fn.Types().Param(param)
+	if typ == nil {
+		return
+	}
+	if actual, ok := c.mismatch(typ.String(), value); ok {
+		c.fail(value.Token(), "%s() argument %q must be %s (got %s)", funcName(fn), param, typ.String(), actual)
+	}
+}
+
+// mismatch returns the type of the value if it is known and doesn't match
+// the expected type. Unknown expected types were already reported, so
+// they are not reported again. The messages reported for mismatches are the
+// same as those of the runtime checks.
+func (c *checker) mismatch(expected string, value ast.Expression) (string, bool) {
+	if !c.known(expected) {
+		return "", false
+	}
+	actual := c.infer(value)
+	if actual == "" || matches(expected, actual) {
+		return "", false
+	}
+	return actual, true
+}
+
+// matches returns true if a value of the actual type may be used where the
+// expected type is required.
+func matches(expected, actual string) bool {
+	switch expected {
+	case "any":
+		return true
+	case "function":
+		return actual == string(object.FUNCTION) || actual == string(object.BUILTIN)
+	}
+	return expected == actual
+}
+
+// infer returns the type of an expression if it is known without running
+// the program, or an empty string otherwise.
+func (c *checker) infer(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.Int:
+		return string(object.INT)
+	case *ast.BigInt:
+		return string(object.BIGINT)
+	case *ast.Float:
+		return string(object.FLOAT)
+	case *ast.String:
+		return string(object.STRING)
+	case *ast.Bool:
+		return string(object.BOOL)
+	case *ast.Nil:
+		return string(object.NIL)
+	case *ast.List, *ast.ListComprehension:
+		return string(object.LIST)
+	case *ast.Map, *ast.MapComprehension:
+		return string(object.MAP)
+	case *ast.Set, *ast.SetComprehension:
+		return string(object.SET)
+	case *ast.Func:
+		if expr.Name() == nil {
+			return string(object.FUNCTION)
+		}
+	case *ast.Prefix:
+		if expr.Operator() == "-" {
+			switch typ := c.infer(expr.Right()); typ {
+			case string(object.INT), string(object.FLOAT):
+				return typ
+			}
+		}
+	case *ast.Call:
+		ident, ok := expr.Function().(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		if c.structs[ident.String()] {
+			return ident.String()
+		}
+		if fn, ok := c.funcs[ident.String()]; ok {
+			if fn.Generator() {
+				return string(object.GENERATOR)
+			}
+			if result := fn.Types().Result(); result != nil && result.String() != "any" {
+				return result.String()
+			}
+		}
+	}
+	return ""
+}
+
+func (c *checker) fail(tok token.Token, format string, args ...interface{}) {
+	c.report(tok, fmt.Sprintf(format, args...), "type error", false)
+}
+
+func (c *checker) report(tok token.Token, message, errType string, warning bool) {
+	c.diagnostics = append(c.diagnostics, resolver.NewDiagnostic(resolver.DiagnosticOpts{
+		ErrType: errType,
+		Message: message,
+		Token:   tok,
+		File:    c.opts.File,
+		Input:   c.opts.Input,
+		Warning: warning,
+	}))
+}
+
+// funcName returns the name of a function as used in error messages.
+func funcName(fn *ast.Func) string {
+	if fn.Name() == nil {
+		return "anonymous"
+	}
+	return fn.Name().String()
+}
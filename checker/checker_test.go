@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/stretchr/testify/require"
+)
+
+func check(t *testing.T, input string, s *scope.Scope) []string {
+	t.Helper()
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	var messages []string
+	for _, d := range Check(program, Opts{Scope: s}) {
+		messages = append(messages, d.Error())
+	}
+	return messages
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"func f(x: int) { x }; f(1); f(y); f(-2)", nil},
+		{`func f(x: int) { x }; f("a")`, []string{`type error: f() argument "x" must be int (got string)`}},
+		{"func f(x: int) { x }; f(x=[])", []string{`type error: f() argument "x" must be int (got list)`}},
+		{`func f(a, ...b: string) { b }; f(1, "a", 2)`, []string{`type error: f() argument "b" must be string (got int)`}},
+		{`func f(a: int, b: int) { b }; f(...["a"], "b")`, nil},
+		{`func f(a: int = 1.5) { a }`, []string{`type error: f() argument "a" must be int (got float)`}},
+		{`func f(g: function, x: any) { g(x) }; f(func(x) { x }, {})`, nil},
+		{`func f() -> string { return 1 }`, []string{`type error: f() must return string (got int)`}},
+		{`func f() -> string { {"a": 1} }`, []string{`type error: f() must return string (got map)`}},
+		{`func f() -> string { if true { return "a" }; "b" }`, nil},
+		{`func f() -> int { func() { return "a" } }`, []string{`type error: f() must return int (got function)`}},
+		{`func f() -> int { g := func() { return "a" }; g() }`, nil},
+		{`func f() -> int { yield 1 }`, []string{`type error: f() must return int (got generator)`}},
+		{`func f() -> generator { yield 1; return nil }`, nil},
+		{`func f() -> list { [1] }; x: string := f()`, []string{`type error: "x" must be string (got list)`}},
+		{`func f() { yield 1 }; var x: list = f()`, []string{`type error: "x" must be list (got generator)`}},
+		{`const x: set = {1: 2}`, []string{`type error: "x" must be set (got map)`}},
+		{`x: bigint := 99999999999999999999; y: int := 99999999999999999999`, []string{`type error: "y" must be int (got bigint)`}},
+		{`x: int := 1; y: thing := 2`, []string{`type error: "thing" is not a known type`}},
+		{`func f(x: Strng) { x }`, []string{`type error: "Strng" is not a known type`}},
+		{"struct P { x }\np: P := P(1)", nil},
+		{"struct P { x }\np: int := P(1)", []string{`type error: "p" must be int (got P)`}},
+		{"struct P { func (p) f(x: int) { x } }\nP().f(\"a\")", nil},
+		{"func f(x: int) { x }\nstruct P { func (p) f(x) { x } }\nP().f(\"a\")", nil},
+		{`var limit: int = 10; limit = "s"`, []string{`type error: "limit" must be int (got string)`}},
+		{`var limit: int = 10; limit += 0.5`, []string{`type error: "limit" must be int (got float)`}},
+		{`var limit: float = 10.0; limit += 1; limit = 2.5; limit *= 0.5`, nil},
+		{`func f(n: int) { n = [n] }`, []string{`type error: "n" must be int (got list)`}},
+		{`func f(...xs: int) { xs = [1] }`, nil},
+		{`var n: int = 1; func f() { n := "a"; n = "b" }`, nil},
+		{`func f(x: int) { x }; [1] | f`, nil},
+		{`func f(x: int) { x }; func g(f) { f("a") }`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, check(t, tt.input, nil))
+		})
+	}
+}
+
+func TestHostStruct(t *testing.T) {
+	s := scope.New(scope.Opts{})
+	point := object.NewStruct(object.StructOpts{Name: "Point"})
+	require.Nil(t, s.Declare("Point", point, true))
+	require.Len(t, check(t, "func f(p: Point) { p }", s), 0)
+	require.Len(t, check(t, "func f(p: Point) { p }", nil), 1)
+}
+
+func TestPosition(t *testing.T) {
+	input := "func f(x: int) -> int {\n  x\n}\nf(1)\nf(\"2\")"
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	diagnostics := Check(program, Opts{File: "main.tm", Input: input})
+	require.Len(t, diagnostics, 1)
+	d := diagnostics[0]
+	require.False(t, d.IsWarning())
+	require.Equal(t, "main.tm", d.File())
+	require.Equal(t, 5, d.StartPosition().LineNumber())
+	require.Equal(t, 3, d.StartPosition().ColumnNumber())
+	require.Equal(t, `f("2")`, d.SourceCode())
+}
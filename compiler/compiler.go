@@ -0,0 +1,1826 @@
+// Package compiler lowers a parsed Tamarin program into bytecode, which is
+// executed by the vm package as an alternative to the tree-walking evaluator.
+//
+// Variables declared by the program are resolved to slots at compile time.
+// Global variables live in a fixed array, variables declared within
+// functions and loops live on the VM stack, and variables captured by
+// closures are moved into cells. Names the program does not declare itself
+// are looked up at runtime in the host scope and then in the builtins.
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// Opts configures compilation of a Tamarin program.
+type Opts struct {
+	// If set to true, the default builtins are not expected to be available.
+	DisableDefaultBuiltins bool
+
+	// Extra and/or override builtins that will be available at runtime.
+	// These are used to identify calls to error handlers such as try.
+	Builtins []*object.Builtin
+}
+
+// Bytecode is a compiled Tamarin program.
+type Bytecode struct {
+	main      *object.CompiledFunction
+	constants []object.Object
+	globals   []string
+	names     []string
+	patterns  []ast.Pattern
+}
+
+// Main returns the function containing the top-level program statements.
+func (b *Bytecode) Main() *object.CompiledFunction {
+	return b.main
+}
+
+// Constants returns the constant pool referenced by the instructions.
+func (b *Bytecode) Constants() []object.Object {
+	return b.constants
+}
+
+// Globals returns the names of the global variables, indexed by slot.
+func (b *Bytecode) Globals() []string {
+	return b.globals
+}
+
+// Names returns the names that are resolved at runtime, meaning those not
+// declared by the program itself.
+func (b *Bytecode) Names() []string {
+	return b.names
+}
+
+// Patterns returns the patterns referenced by OpMatch and OpDestructure
+// instructions.
+func (b *Bytecode) Patterns() []ast.Pattern {
+	return b.patterns
+}
+
+type constantKey struct {
+	typ   object.Type
+	value interface{}
+}
+
+// Compiler converts an AST into Bytecode.
+type Compiler struct {
+	fs            *funcState
+	constants     []object.Object
+	constantIndex map[constantKey]int
+	globals       []string
+	names         []string
+	nameIndex     map[string]int
+	patterns      []ast.Pattern
+	errorHandlers map[string]bool
+}
+
+// Compile the given program to bytecode.
+func Compile(program *ast.Program, opts Opts) (*Bytecode, error) {
+	c := &Compiler{
+		constantIndex: map[constantKey]int{},
+		nameIndex:     map[string]int{},
+		errorHandlers: map[string]bool{},
+	}
+	if !opts.DisableDefaultBuiltins {
+		for _, b := range evaluator.GlobalBuiltins() {
+			c.errorHandlers[b.Key()] = b.IsErrorHandler()
+		}
+	}
+	for _, b := range opts.Builtins {
+		c.errorHandlers[b.Key()] = b.IsErrorHandler()
+	}
+	c.fs = &funcState{scope: newBlockScope(nil, true)}
+	statements := program.Statements()
+	declaredNames(statements, c.hoist)
+	if err := c.compileStatements(statements); err != nil {
+		return nil, err
+	}
+	c.emit(OpReturnValue)
+	if err := c.checkSize(c.fs); err != nil {
+		return nil, err
+	}
+	if len(c.constants) > 0xFFFF || len(c.globals) > 0xFFFF || len(c.names) > 0xFFFF || len(c.patterns) > 0xFFFF {
+		return nil, fmt.Errorf("compile error: program is too large")
+	}
+	main := object.NewCompiledFunction(object.CompiledFunctionOpts{
+		Name:         "main",
+		Instructions: c.fs.instructions,
+		NumLocals:    len(c.fs.localNames),
+		LocalNames:   c.fs.localNames,
+		Positions:    c.fs.positions,
+	})
+	return &Bytecode{
+		main:      main,
+		constants: c.constants,
+		globals:   c.globals,
+		names:     c.names,
+		patterns:  c.patterns,
+	}, nil
+}
+
+// compile emits code for the node that leaves exactly one value on the stack.
+func (c *Compiler) compile(node ast.Node) error {
+	defer c.track(node)()
+	switch node := node.(type) {
+
+	// High level types
+	case *ast.Block:
+		return c.compileStatements(node.Statements())
+
+	// Operator expressions
+	case *ast.Prefix:
+		return c.compilePrefix(node)
+	case *ast.Postfix:
+		return c.compilePostfix(node)
+	case *ast.Infix:
+		return c.compileInfix(node)
+	case *ast.Ternary:
+		return c.compileTernary(node)
+	case *ast.Propagate:
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		c.emit(OpPropagate)
+	case *ast.In:
+		if err := c.compile(node.Left()); err != nil {
+			return err
+		}
+		if err := c.compile(node.Right()); err != nil {
+			return err
+		}
+		c.emit(OpIn)
+
+	// Miscellaneous
+	case *ast.Ident:
+		c.emitLoad(c.resolve(node.String()))
+	case *ast.Index:
+		if err := c.compile(node.Left()); err != nil {
+			return err
+		}
+		if err := c.compile(node.Index()); err != nil {
+			return err
+		}
+		c.emit(OpIndex)
+	case *ast.Slice:
+		if err := c.compile(node.Left()); err != nil {
+			return err
+		}
+		flags, err := c.compileSliceBounds(node.FromIndex(), node.ToIndex(), node.Step())
+		if err != nil {
+			return err
+		}
+		c.emit(OpSlice, flags)
+	case *ast.SliceLiteral:
+		flags, err := c.compileSliceBounds(node.FromIndex(), node.ToIndex(), node.Step())
+		if err != nil {
+			return err
+		}
+		c.emit(OpNewSlice, flags)
+	case *ast.Bool:
+		if node.Value() {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.Import:
+		return c.compileImport(node, true)
+
+	// Assignment
+	case *ast.Var:
+		name, expr := node.Value()
+		return c.compileDeclaration(name, expr, node.TypeAnnotation(), false, true)
+	case *ast.Const:
+		name, expr := node.Value()
+		return c.compileDeclaration(name, expr, node.TypeAnnotation(), true, true)
+	case *ast.Assign:
+		return c.compileAssign(node, true)
+	case *ast.MultiVar:
+		return c.compileMultiVar(node, true)
+	case *ast.Destructure:
+		return c.compileDestructure(node, true)
+
+	// Functions
+	case *ast.Func:
+		return c.compileFunc(node, true)
+	case *ast.Struct:
+		return c.compileStruct(node, true)
+
+	// Calls
+	case *ast.ObjectCall:
+		return c.compileObjectCall(node)
+	case *ast.Call:
+		return c.compileCall(node)
+	case *ast.GetAttr:
+		if err := c.compile(node.Object()); err != nil {
+			return err
+		}
+		c.emit(OpGetAttr, c.addString(node.Name()))
+
+	// Control
+	case *ast.If:
+		return c.compileIf(node)
+	case *ast.For:
+		return c.compileFor(node, true)
+	case *ast.Switch:
+		return c.compileSwitch(node)
+	case *ast.Match:
+		return c.compileMatch(node)
+	case *ast.Select:
+		return c.compileSelect(node)
+	case *ast.Yield:
+		if node.Value() == nil {
+			c.emit(OpNil)
+		} else if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		c.emit(OpYield)
+		return nil
+	case *ast.Go:
+		return c.compileDeferredCall(node.Call(), OpGo, OpGoMethod)
+	case *ast.Defer:
+		return c.compileDeferredCall(node.Call(), OpDefer, OpDeferMethod)
+	case *ast.Pipe:
+		return c.compilePipe(node)
+	case *ast.Control:
+		return c.compileControl(node)
+	case *ast.Range:
+		if err := c.compile(node.Container()); err != nil {
+			return err
+		}
+		c.emit(OpRange)
+
+	// Literals
+	case *ast.Nil:
+		c.emit(OpNil)
+	case *ast.Int:
+		c.emit(OpConstant, c.addConstant(object.NewInt(node.Value())))
+	case *ast.BigInt:
+		c.emit(OpConstant, c.addConstant(object.NewBigInt(node.Value())))
+	case *ast.Float:
+		c.emit(OpConstant, c.addConstant(object.NewFloat(node.Value())))
+	case *ast.String:
+		return c.compileString(node)
+	case *ast.List:
+		for _, item := range node.Items() {
+			if err := c.compile(item); err != nil {
+				return err
+			}
+		}
+		c.emit(OpList, len(node.Items()))
+	case *ast.Map:
+		return c.compileMap(node)
+	case *ast.Set:
+		for _, item := range node.Items() {
+			if err := c.compile(item); err != nil {
+				return err
+			}
+		}
+		c.emit(OpSet, len(node.Items()))
+	case *ast.ListComprehension:
+		return c.compileComprehension(OpList, 1, node.Clauses(), func() error {
+			return c.compile(node.Element())
+		})
+	case *ast.SetComprehension:
+		return c.compileComprehension(OpSet, 1, node.Clauses(), func() error {
+			return c.compile(node.Element())
+		})
+	case *ast.MapComprehension:
+		return c.compileComprehension(OpMap, 2, node.Clauses(), func() error {
+			if err := c.compile(node.Key()); err != nil {
+				return err
+			}
+			return c.compile(node.Value())
+		})
+
+	default:
+		return fmt.Errorf("compile error: unsupported node type: %T", node)
+	}
+	return nil
+}
+
+// compileDiscard emits code for a statement whose value is not used.
+func (c *Compiler) compileDiscard(node ast.Node) error {
+	defer c.track(node)()
+	switch node := node.(type) {
+	case *ast.Var:
+		name, expr := node.Value()
+		return c.compileDeclaration(name, expr, node.TypeAnnotation(), false, false)
+	case *ast.Const:
+		name, expr := node.Value()
+		return c.compileDeclaration(name, expr, node.TypeAnnotation(), true, false)
+	case *ast.Assign:
+		return c.compileAssign(node, false)
+	case *ast.MultiVar:
+		return c.compileMultiVar(node, false)
+	case *ast.Destructure:
+		return c.compileDestructure(node, false)
+	case *ast.Func:
+		return c.compileFunc(node, false)
+	case *ast.Struct:
+		return c.compileStruct(node, false)
+	case *ast.Import:
+		return c.compileImport(node, false)
+	case *ast.For:
+		return c.compileFor(node, false)
+	case *ast.Control:
+		return c.compileControl(node)
+	}
+	if err := c.compile(node); err != nil {
+		return err
+	}
+	c.emit(OpPop)
+	return nil
+}
+
+// compileStatements emits the statements of a block. The block evaluates
+// to the value of its final statement.
+func (c *Compiler) compileStatements(statements []ast.Node) error {
+	if len(statements) == 0 {
+		c.emit(OpNil)
+		return nil
+	}
+	last := len(statements) - 1
+	for _, statement := range statements[:last] {
+		if err := c.compileDiscard(statement); err != nil {
+			return err
+		}
+	}
+	return c.compile(statements[last])
+}
+
+func (c *Compiler) compilePrefix(node *ast.Prefix) error {
+	if err := c.compile(node.Right()); err != nil {
+		return err
+	}
+	op, ok := operatorIndex(PrefixOperators, node.Operator())
+	if !ok {
+		c.emit(OpPop)
+		c.emitRaise("syntax error: unknown operator: %s", node.Operator())
+		return nil
+	}
+	c.emit(OpPrefix, op)
+	return nil
+}
+
+func (c *Compiler) compilePostfix(node *ast.Postfix) error {
+	op, ok := operatorIndex(PostfixOperators, node.Operator())
+	if !ok {
+		c.emitRaise("syntax error: unknown operator: %s", node.Operator())
+		return nil
+	}
+	name := node.Literal()
+	ref := c.resolve(name)
+	c.emitLoad(ref)
+	c.emit(OpDup)
+	c.emit(OpPostfix, op, c.addString(name))
+	if ref.readOnly() {
+		c.emit(OpPop)
+		c.emit(OpPop)
+		c.emitRaise("assignment error: %q is read-only", name)
+		return nil
+	}
+	c.emitStore(ref)
+	return nil
+}
+
+func (c *Compiler) compileInfix(node *ast.Infix) error {
+	if err := c.compile(node.Left()); err != nil {
+		return err
+	}
+	switch node.Operator() {
+	case "&&", "||":
+		// Short circuit, leaving the left operand as the result if the
+		// right operand doesn't need to be evaluated
+		jumpOp := OpJumpIfFalseNoPop
+		if node.Operator() == "||" {
+			jumpOp = OpJumpIfTrueNoPop
+		}
+		jump := c.emit(jumpOp, 0)
+		c.emit(OpPop)
+		if err := c.compile(node.Right()); err != nil {
+			return err
+		}
+		c.patchJump(jump)
+		return nil
+	}
+	if err := c.compile(node.Right()); err != nil {
+		return err
+	}
+	op, ok := operatorIndex(BinaryOperators, node.Operator())
+	if !ok {
+		c.emit(OpPop)
+		c.emit(OpPop)
+		c.emitRaise("syntax error: unknown operator: %s", node.Operator())
+		return nil
+	}
+	c.emit(OpBinary, op)
+	return nil
+}
+
+func (c *Compiler) compileTernary(node *ast.Ternary) error {
+	if err := c.compile(node.Condition()); err != nil {
+		return err
+	}
+	jumpFalse := c.emit(OpJumpIfFalse, 0)
+	if err := c.compile(node.IfTrue()); err != nil {
+		return err
+	}
+	jumpEnd := c.emit(OpJump, 0)
+	c.patchJump(jumpFalse)
+	if err := c.compile(node.IfFalse()); err != nil {
+		return err
+	}
+	c.patchJump(jumpEnd)
+	return nil
+}
+
+// compileSliceBounds pushes the start, stop and step of a slice that are
+// given, and returns flags with bits 1, 2 and 4 set for those present.
+func (c *Compiler) compileSliceBounds(from, to, step ast.Expression) (int, error) {
+	var flags int
+	for i, bound := range []ast.Expression{from, to, step} {
+		if bound == nil {
+			continue
+		}
+		if err := c.compile(bound); err != nil {
+			return 0, err
+		}
+		flags |= 1 << i
+	}
+	return flags, nil
+}
+
+func (c *Compiler) compileImport(node *ast.Import, keep bool) error {
+	name := node.Module().String()
+	c.emit(OpImport, c.addString(name))
+	if keep {
+		c.emit(OpDup)
+	}
+	c.emitDefine(c.declare(name, true))
+	return nil
+}
+
+func (c *Compiler) compileDeclaration(name string, expr ast.Expression, typ *ast.Ident, readOnly, keep bool) error {
+	sym := c.declare(name, readOnly)
+	if err := c.compile(expr); err != nil {
+		return err
+	}
+	if typ != nil {
+		sym.typ = typ.String()
+		c.emit(OpCheckType, c.addString(sym.typ), c.addString(name))
+	}
+	if keep {
+		c.emit(OpDup)
+	}
+	c.emitDefine(sym)
+	return nil
+}
+
+func (c *Compiler) compileMultiVar(node *ast.MultiVar, keep bool) error {
+	names, expr := node.Value()
+	symbols := make([]*symbol, len(names))
+	for i, name := range names {
+		symbols[i] = c.declare(name, false)
+	}
+	if err := c.compile(expr); err != nil {
+		return err
+	}
+	if keep {
+		c.emit(OpDup)
+	}
+	c.emit(OpUnpack, len(names))
+	for i := len(symbols) - 1; i >= 0; i-- {
+		c.emitDefine(symbols[i])
+	}
+	return nil
+}
+
+func (c *Compiler) compileDestructure(node *ast.Destructure, keep bool) error {
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	if keep {
+		c.emit(OpDup)
+	}
+	return c.compileTarget(node.Target(), node.IsWalrus())
+}
+
+// compileTarget pops a value and destructures it into the variables bound by
+// a target. OpDestructure pushes the bound values with the first one on top,
+// where a missing map key is pushed as a Go nil and OpJumpIfSet skips over
+// the default value that replaces it.
+func (c *Compiler) compileTarget(target ast.Pattern, declare bool) error {
+	bindings := ast.BindingPatterns(target)
+	refs := make([]varRef, len(bindings))
+	if !declare {
+		for i, binding := range bindings {
+			name := binding.Name().String()
+			refs[i] = c.resolve(name)
+			if refs[i].readOnly() {
+				c.emit(OpPop)
+				c.emitRaise("assignment error: %q is read-only", name)
+				return nil
+			}
+		}
+	}
+	c.patterns = append(c.patterns, target)
+	c.emit(OpDestructure, len(c.patterns)-1)
+	for i, binding := range bindings {
+		if binding.Default() != nil {
+			set := c.emit(OpJumpIfSet, 0)
+			if err := c.compile(binding.Default()); err != nil {
+				return err
+			}
+			c.patchJump(set)
+		}
+		if declare {
+			c.emitDefine(c.declare(binding.Name().String(), false))
+		} else {
+			c.emitStore(refs[i])
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileAssign(node *ast.Assign, keep bool) error {
+	if attr := node.Attr(); attr != nil {
+		return c.compileSetAttr(node, attr, keep)
+	}
+	if slice := node.Slice(); slice != nil {
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		if err := c.compile(slice.Left()); err != nil {
+			return err
+		}
+		flags, err := c.compileSliceBounds(slice.FromIndex(), slice.ToIndex(), slice.Step())
+		if err != nil {
+			return err
+		}
+		c.emit(OpNewSlice, flags)
+		c.emit(OpSetItem)
+		if keep {
+			c.emit(OpNil)
+		}
+		return nil
+	}
+	if index := node.Index(); index != nil {
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		if err := c.compile(index.Left()); err != nil {
+			return err
+		}
+		if err := c.compile(index.Index()); err != nil {
+			return err
+		}
+		if node.Operator() != "=" {
+			c.emit(OpPop)
+			c.emit(OpPop)
+			c.emit(OpPop)
+			c.emitRaise("eval error: invalid set item operator: %q", node.Operator())
+			return nil
+		}
+		c.emit(OpSetItem)
+		if keep {
+			c.emit(OpNil)
+		}
+		return nil
+	}
+	name := node.Name()
+	switch node.Operator() {
+	case ":=":
+		return c.compileDeclaration(name, node.Value(), nil, false, keep)
+	case "=":
+		ref := c.resolve(name)
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		if ref.readOnly() {
+			c.emit(OpPop)
+			c.emitRaise("assignment error: %q is read-only", name)
+			return nil
+		}
+		if keep {
+			c.emit(OpDup)
+		}
+		c.emitStore(ref)
+		return nil
+	}
+	op, ok := operatorIndex(BinaryOperators, node.Operator())
+	if !ok {
+		return fmt.Errorf("compile error: unsupported assignment operator: %s", node.Operator())
+	}
+	ref := c.resolve(name)
+	c.emitLoad(ref)
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	c.emit(OpBinary, op)
+	if ref.readOnly() {
+		c.emit(OpPop)
+		c.emitRaise("assignment error: %q is read-only", name)
+		return nil
+	}
+	if keep {
+		c.emit(OpDup)
+	}
+	c.emitStore(ref)
+	return nil
+}
+
+// compileSetAttr emits an assignment to an attribute. For operators like
+// += the current value of the attribute is read and combined with the value
+// before it is set.
+func (c *Compiler) compileSetAttr(node *ast.Assign, attr *ast.GetAttr, keep bool) error {
+	if err := c.compile(attr.Object()); err != nil {
+		return err
+	}
+	name := c.addString(attr.Name())
+	if node.Operator() == "=" {
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+	} else {
+		op, ok := operatorIndex(BinaryOperators, node.Operator())
+		if !ok {
+			return fmt.Errorf("compile error: unsupported assignment operator: %s", node.Operator())
+		}
+		c.emit(OpDup)
+		c.emit(OpGetAttr, name)
+		if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		c.emit(OpBinary, op)
+	}
+	c.emit(OpSetAttr, name)
+	if keep {
+		c.emit(OpNil)
+	}
+	return nil
+}
+
+func (c *Compiler) compileFunc(node *ast.Func, keep bool) error {
+	var name string
+	var sym *symbol
+	if node.Name() != nil {
+		name = node.Name().String()
+		sym = c.declare(name, true)
+	}
+	if err := c.compileClosure(node, name); err != nil {
+		return err
+	}
+	if sym != nil {
+		c.emitDefine(sym)
+		if keep {
+			c.emit(OpNil)
+		}
+	} else if !keep {
+		c.emit(OpPop)
+	}
+	return nil
+}
+
+// compileClosure emits the instructions that create a closure for the given
+// function and push it onto the stack.
+func (c *Compiler) compileClosure(node *ast.Func, name string) error {
+	parent := c.fs
+	fs := &funcState{
+		parent:      parent,
+		parentScope: parent.scope,
+		scope:       newBlockScope(nil, false),
+		node:        node,
+	}
+	c.fs = fs
+	parameters := node.Parameters()
+	params := make([]*symbol, len(parameters))
+	for i, param := range parameters {
+		paramName := param.String()
+		params[i] = &symbol{name: paramName, index: fs.allocLocal(paramName)}
+		fs.scope.symbols[paramName] = params[i]
+		// The items collected by a variadic parameter are checked, not the
+		// list that holds them
+		if typ := node.Types().Param(paramName); typ != nil && !(node.Variadic() && i == len(parameters)-1) {
+			params[i].typ = typ.String()
+		}
+	}
+	body := node.Body()
+	declaredNames(body.Statements(), c.hoist)
+	// Evaluate defaults for any parameters that were not supplied
+	defaults := node.Defaults()
+	for i, param := range parameters {
+		expr, ok := defaults[param.String()]
+		if !ok {
+			continue
+		}
+		jump := c.emit(OpJumpIfArg, i, 0)
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+		c.emitDefine(params[i])
+		c.patchUint16(jump+2, c.pos())
+	}
+	if len(node.Types().Params()) > 0 {
+		c.emit(OpCheckParams)
+	}
+	if err := c.compile(body); err != nil {
+		return err
+	}
+	c.emit(OpReturnValue)
+	c.fs = parent
+	if err := c.checkSize(fs); err != nil {
+		return err
+	}
+	var cellParams []int
+	for i, param := range params {
+		if param.captured {
+			cellParams = append(cellParams, i)
+		}
+	}
+	freeNames := make([]string, len(fs.free))
+	for i, fv := range fs.free {
+		freeNames[i] = fv.symbol.name
+	}
+	fn := object.NewCompiledFunction(object.CompiledFunctionOpts{
+		Name:           name,
+		Instructions:   fs.instructions,
+		NumParameters:  len(parameters),
+		NumLocals:      len(fs.localNames),
+		LocalNames:     fs.localNames,
+		FreeNames:      freeNames,
+		CellParameters: cellParams,
+		HasDefaults:    len(defaults) > 0,
+		Variadic:       node.Variadic(),
+		Node:           node,
+		Positions:      fs.positions,
+	})
+	if len(fs.free) > 255 {
+		return fmt.Errorf("compile error: function captures too many variables")
+	}
+	c.emit(OpClosure, c.addConstant(fn), len(fs.free))
+	for _, fv := range fs.free {
+		c.fs.instructions = append(c.fs.instructions, fv.kind, byte(fv.index>>8), byte(fv.index))
+	}
+	return nil
+}
+
+// compileStruct emits a struct declaration. The struct name is pushed,
+// followed by the name and default value of each field and the name and
+// closure of each method, and OpStruct then builds the type from these.
+func (c *Compiler) compileStruct(node *ast.Struct, keep bool) error {
+	name := node.Name().String()
+	sym := c.declare(name, true)
+	c.emit(OpConstant, c.addString(name))
+	defaults := node.Defaults()
+	for _, field := range node.Fields() {
+		c.emit(OpConstant, c.addString(field.String()))
+		if expr, ok := defaults[field.String()]; ok {
+			if err := c.compile(expr); err != nil {
+				return err
+			}
+		} else {
+			c.emit(OpNil)
+		}
+	}
+	for _, method := range node.Methods() {
+		methodName := method.Name().String()
+		c.emit(OpConstant, c.addString(methodName))
+		if err := c.compileClosure(method, methodName); err != nil {
+			return err
+		}
+	}
+	c.emit(OpStruct, len(node.Fields()), len(node.Methods()))
+	c.emitDefine(sym)
+	if keep {
+		c.emit(OpNil)
+	}
+	return nil
+}
+
+// isErrorHandlerCall returns true if the call is to a builtin that handles
+// errors in its arguments, like try.
+func (c *Compiler) isErrorHandlerCall(node *ast.Call) bool {
+	ident, ok := node.Function().(*ast.Ident)
+	if !ok {
+		return false
+	}
+	name := ident.String()
+	return c.errorHandlers[name] && !c.isDeclared(name)
+}
+
+func (c *Compiler) compileArguments(args []ast.Expression, protected bool) error {
+	if len(args) > 255 {
+		return fmt.Errorf("compile error: too many arguments in call (%d)", len(args))
+	}
+	for _, arg := range args {
+		if !protected {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+			continue
+		}
+		// Errors raised while evaluating the argument are passed to the
+		// error handler as the argument value
+		catch := c.emit(OpCatch, 0)
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+		c.emit(OpEndCatch)
+		c.patchJump(catch)
+	}
+	return nil
+}
+
+// isExpandedCall returns true if any of the given call arguments is spread
+// or passed by keyword.
+func isExpandedCall(args []ast.Expression) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *ast.Spread, *ast.KeywordArg:
+			return true
+		}
+	}
+	return false
+}
+
+// compileExpandedCall compiles the arguments of a call that spreads one or
+// more of them or passes them by keyword, and the call itself. The function
+// must already be on the stack. OpSpread gathers the positional arguments
+// into a single list, which OpCallSpread expands back onto the stack before
+// making the call. Keyword arguments are gathered into a map which is passed
+// to OpCallKwargs instead.
+func (c *Compiler) compileExpandedCall(args []ast.Expression, kind byte) error {
+	if len(args) > 255 {
+		return fmt.Errorf("compile error: too many arguments in call (%d)", len(args))
+	}
+	flags := make([]byte, 0, len(args))
+	var keywords []*ast.KeywordArg
+	for _, arg := range args {
+		if keyword, ok := arg.(*ast.KeywordArg); ok {
+			keywords = append(keywords, keyword)
+			continue
+		}
+		var flag byte
+		if spread, ok := arg.(*ast.Spread); ok {
+			arg, flag = spread.Value(), 1
+		}
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+		flags = append(flags, flag)
+	}
+	c.emit(OpSpread, len(flags))
+	c.fs.instructions = append(c.fs.instructions, flags...)
+	if len(keywords) == 0 {
+		c.emit(OpCallSpread, int(kind))
+		return nil
+	}
+	for _, keyword := range keywords {
+		c.emit(OpConstant, c.addString(keyword.Name()))
+		if err := c.compile(keyword.Value()); err != nil {
+			return err
+		}
+	}
+	c.emit(OpMap, len(keywords))
+	c.emit(OpCallKwargs, int(kind))
+	return nil
+}
+
+func (c *Compiler) compileCall(node *ast.Call) error {
+	if err := c.compile(node.Function()); err != nil {
+		return err
+	}
+	if isExpandedCall(node.Arguments()) {
+		return c.compileExpandedCall(node.Arguments(), SpreadCall)
+	}
+	if err := c.compileArguments(node.Arguments(), c.isErrorHandlerCall(node)); err != nil {
+		return err
+	}
+	c.emit(OpCall, len(node.Arguments()))
+	return nil
+}
+
+func (c *Compiler) compileObjectCall(node *ast.ObjectCall) error {
+	if err := c.compile(node.Object()); err != nil {
+		return err
+	}
+	method, ok := node.Call().(*ast.Call)
+	if !ok {
+		c.emit(OpPop)
+		c.emitRaise("failed to evaluate object call")
+		return nil
+	}
+	if isExpandedCall(method.Arguments()) {
+		c.emit(OpGetAttr, c.addString(method.Function().String()))
+		return c.compileExpandedCall(method.Arguments(), SpreadCall)
+	}
+	if err := c.compileArguments(method.Arguments(), false); err != nil {
+		return err
+	}
+	c.emit(OpCallMethod, c.addString(method.Function().String()), len(method.Arguments()))
+	return nil
+}
+
+func (c *Compiler) compileIf(node *ast.If) error {
+	if err := c.compile(node.Condition()); err != nil {
+		return err
+	}
+	jumpFalse := c.emit(OpJumpIfFalse, 0)
+	if err := c.compile(node.Consequence()); err != nil {
+		return err
+	}
+	jumpEnd := c.emit(OpJump, 0)
+	c.patchJump(jumpFalse)
+	if node.Alternative() != nil {
+		if err := c.compile(node.Alternative()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpNil)
+	}
+	c.patchJump(jumpEnd)
+	return nil
+}
+
+// compileFor emits a for loop. Like the evaluator, the loop gets two scopes:
+// one for the init, condition and post statements, and one for the loop
+// body which is cleared on every iteration. If keep is true, the loop
+// evaluates to the value of the last completed iteration.
+func (c *Compiler) compileFor(node *ast.For, keep bool) error {
+	fs := c.fs
+	outerScope := fs.scope
+	forScope := newBlockScope(outerScope, false)
+	loopScope := newBlockScope(forScope, false)
+	fs.scope = forScope
+	defer func() { fs.scope = outerScope }()
+
+	// Locals are reused each time the loop runs, so start with them cleared
+	firstLocal := len(fs.localNames)
+	clearAll := c.emit(OpClearLocals, firstLocal, 0)
+
+	if init := node.Init(); init != nil {
+		declaredNames([]ast.Node{init}, c.hoist)
+		if err := c.compileDiscard(init); err != nil {
+			return err
+		}
+	}
+	result := -1
+	if keep {
+		result = fs.allocLocal("")
+		c.emit(OpNil)
+		c.emit(OpDefineLocal, result)
+	}
+	saveResult := func() {
+		if keep {
+			c.emit(OpSetLocal, result)
+		} else {
+			c.emit(OpPop)
+		}
+	}
+
+	loop := &loopState{}
+	fs.loops = append(fs.loops, loop)
+	defer func() { fs.loops = fs.loops[:len(fs.loops)-1] }()
+
+	var loopStart, continueTarget, clearLoop, firstLoopLocal, exit int
+	isIterator := node.IsIteratorLoop()
+
+	switch {
+	case node.IsSimpleLoop():
+		firstLoopLocal = len(fs.localNames)
+		fs.scope = loopScope
+		declaredNames(node.Consequence().Statements(), c.hoist)
+		loopStart = c.pos()
+		clearLoop = c.emit(OpClearLocals, firstLoopLocal, 0)
+		if err := c.compile(node.Consequence()); err != nil {
+			return err
+		}
+		saveResult()
+		c.emit(OpJump, loopStart)
+		continueTarget = loopStart
+		exit = c.pos()
+
+	case isIterator:
+		var names []string
+		var targets []ast.Pattern
+		var iterExpr ast.Expression
+		switch cond := node.Condition().(type) {
+		case *ast.Var:
+			name, expr := cond.Value()
+			names, iterExpr = []string{name}, expr
+		case *ast.MultiVar:
+			names, iterExpr = cond.Value()
+		case *ast.Destructure:
+			targets, iterExpr = evaluator.LoopTargets(cond), cond.Value()
+			for _, target := range targets {
+				names = append(names, target.Bindings()...)
+			}
+		}
+		count := len(names)
+		if targets != nil {
+			count = len(targets)
+		}
+		if count < 1 || count > 2 {
+			c.emitRaise("eval error: invalid for loop condition")
+			return nil
+		}
+		firstLoopLocal = len(fs.localNames)
+		fs.scope = loopScope
+		symbols := make([]*symbol, len(names))
+		for i, name := range names {
+			symbols[i] = c.declare(name, false)
+		}
+		declaredNames(node.Consequence().Statements(), c.hoist)
+		if err := c.compile(iterExpr); err != nil {
+			return err
+		}
+		loopStart = c.pos()
+		clearLoop = c.emit(OpClearLocals, firstLoopLocal, 0)
+		next := c.emit(OpIterNext, 0, count)
+		if targets != nil {
+			// The value is on top of the key, so it is assigned first
+			for i := len(targets) - 1; i >= 0; i-- {
+				if err := c.compileTarget(targets[i], true); err != nil {
+					return err
+				}
+			}
+		} else {
+			for i := len(symbols) - 1; i >= 0; i-- {
+				c.emitDefine(symbols[i])
+			}
+		}
+		if err := c.compile(node.Consequence()); err != nil {
+			return err
+		}
+		saveResult()
+		c.emit(OpJump, loopStart)
+		continueTarget = loopStart
+		exit = c.pos()
+		c.patchJump(next)
+
+	default:
+		firstLoopLocal = len(fs.localNames)
+		loopStart = c.pos()
+		clearLoop = c.emit(OpClearLocals, firstLoopLocal, 0)
+		jumpFalse := -1
+		if node.Condition() != nil {
+			if err := c.compile(node.Condition()); err != nil {
+				return err
+			}
+			jumpFalse = c.emit(OpJumpIfFalse, 0)
+		}
+		fs.scope = loopScope
+		declaredNames(node.Consequence().Statements(), c.hoist)
+		if err := c.compile(node.Consequence()); err != nil {
+			return err
+		}
+		saveResult()
+		fs.scope = forScope
+		continueTarget = c.pos()
+		if node.Post() != nil {
+			if err := c.compileDiscard(node.Post()); err != nil {
+				return err
+			}
+		}
+		c.emit(OpJump, loopStart)
+		exit = c.pos()
+		if jumpFalse >= 0 {
+			c.patchJump(jumpFalse)
+		}
+	}
+
+	for _, pos := range loop.breaks {
+		c.patchUint16(pos+1, exit)
+	}
+	for _, pos := range loop.continues {
+		c.patchUint16(pos+1, continueTarget)
+	}
+	c.patchUint16(clearAll+3, len(fs.localNames)-firstLocal)
+	c.patchUint16(clearLoop+3, len(fs.localNames)-firstLoopLocal)
+	if isIterator {
+		c.emit(OpIterClose)
+	}
+	if keep {
+		c.emit(OpGetLocal, result)
+	}
+	return nil
+}
+
+// compileComprehension emits a list, set or map comprehension. The container
+// being built is kept in a hidden local. On each iteration it is pushed
+// along with the count values that compileItem pushes, and OpCollect adds
+// the item, or the key and value for a map, to the container.
+func (c *Compiler) compileComprehension(op Opcode, count int, clauses []*ast.ComprehensionClause, compileItem func() error) error {
+	fs := c.fs
+	outerScope := fs.scope
+	defer func() { fs.scope = outerScope }()
+	result := fs.allocLocal("")
+	c.emit(op, 0)
+	c.emit(OpDefineLocal, result)
+	if err := c.compileClauses(clauses, func() error {
+		c.emit(OpGetLocal, result)
+		if err := compileItem(); err != nil {
+			return err
+		}
+		c.emit(OpCollect, count)
+		return nil
+	}); err != nil {
+		return err
+	}
+	c.emit(OpGetLocal, result)
+	return nil
+}
+
+// compileClauses emits a loop for the first clause of a comprehension, with
+// the loops for the remaining clauses nested inside it. A clause with a
+// single variable has OpIterNext push just the item found by the "in"
+// operator, and otherwise the key and the value.
+func (c *Compiler) compileClauses(clauses []*ast.ComprehensionClause, compileBody func() error) error {
+	fs := c.fs
+	clause := clauses[0]
+	if err := c.compile(clause.Iterable()); err != nil {
+		return err
+	}
+	c.emit(OpRange)
+	fs.scope = newBlockScope(fs.scope, false)
+	firstLocal := len(fs.localNames)
+	for _, name := range clause.Bindings() {
+		c.declare(name, false)
+	}
+	targets := clause.Targets()
+	count := len(targets)
+	if count == 1 {
+		count = 0
+	}
+	loopStart := c.pos()
+	clear := c.emit(OpClearLocals, firstLocal, 0)
+	next := c.emit(OpIterNext, 0, count)
+	for i := len(targets) - 1; i >= 0; i-- {
+		if err := c.compileTarget(targets[i], true); err != nil {
+			return err
+		}
+	}
+	if condition := clause.Condition(); condition != nil {
+		if err := c.compile(condition); err != nil {
+			return err
+		}
+		c.emit(OpJumpIfFalse, loopStart)
+	}
+	var err error
+	if len(clauses) > 1 {
+		err = c.compileClauses(clauses[1:], compileBody)
+	} else {
+		err = compileBody()
+	}
+	if err != nil {
+		return err
+	}
+	c.emit(OpJump, loopStart)
+	c.patchJump(next)
+	c.patchUint16(clear+3, len(fs.localNames)-firstLocal)
+	c.emit(OpIterClose)
+	return nil
+}
+
+func (c *Compiler) compileSwitch(node *ast.Switch) error {
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	var ends []int
+	var defaultCase *ast.Case
+	for _, choice := range node.Choices() {
+		if choice.IsDefault() {
+			if defaultCase == nil {
+				defaultCase = choice
+			}
+			continue
+		}
+		var matches []int
+		for _, expr := range choice.Expressions() {
+			c.emit(OpDup)
+			if err := c.compile(expr); err != nil {
+				return err
+			}
+			c.emit(OpBinary, c.binaryOperator("=="))
+			noMatch := c.emit(OpJumpIfFalse, 0)
+			matches = append(matches, c.emit(OpJump, 0))
+			c.patchJump(noMatch)
+		}
+		nextCase := c.emit(OpJump, 0)
+		for _, match := range matches {
+			c.patchJump(match)
+		}
+		c.emit(OpPop)
+		if err := c.compile(choice.Block()); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(OpJump, 0))
+		c.patchJump(nextCase)
+	}
+	// No match found, so run the default block if there is one
+	c.emit(OpPop)
+	if defaultCase != nil {
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpNil)
+	}
+	for _, end := range ends {
+		c.patchJump(end)
+	}
+	return nil
+}
+
+// compileMatch emits a match expression. The value being matched stays on
+// the stack while each case is tried. OpMatch pushes the values bound by a
+// pattern followed by true if it matches, or just false if it doesn't. Each
+// case gets a scope of its own, like the evaluator creates.
+func (c *Compiler) compileMatch(node *ast.Match) error {
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	fs := c.fs
+	outerScope := fs.scope
+	defer func() { fs.scope = outerScope }()
+	var ends []int
+	var defaultCase *ast.MatchCase
+	for _, choice := range node.Cases() {
+		if choice.IsDefault() {
+			if defaultCase == nil {
+				defaultCase = choice
+			}
+			continue
+		}
+		var matches []int
+		for _, pattern := range choice.Patterns() {
+			c.patterns = append(c.patterns, pattern)
+			c.emit(OpMatch, len(c.patterns)-1)
+			noMatch := c.emit(OpJumpIfFalse, 0)
+			matches = append(matches, c.emit(OpJump, 0))
+			c.patchJump(noMatch)
+		}
+		nextCase := c.emit(OpJump, 0)
+		for _, match := range matches {
+			c.patchJump(match)
+		}
+		fs.scope = newBlockScope(outerScope, false)
+		firstLocal := len(fs.localNames)
+		clear := c.emit(OpClearLocals, firstLocal, 0)
+		// Only a case with a single pattern may bind variables
+		names := choice.Patterns()[0].Bindings()
+		symbols := make([]*symbol, len(names))
+		for i, name := range names {
+			symbols[i] = c.declare(name, false)
+		}
+		for i := len(symbols) - 1; i >= 0; i-- {
+			c.emitDefine(symbols[i])
+		}
+		declaredNames(choice.Block().Statements(), c.hoist)
+		guardFailed := -1
+		if guard := choice.Guard(); guard != nil {
+			if err := c.compile(guard); err != nil {
+				return err
+			}
+			guardFailed = c.emit(OpJumpIfFalse, 0)
+		}
+		c.emit(OpPop)
+		if err := c.compile(choice.Block()); err != nil {
+			return err
+		}
+		c.patchUint16(clear+3, len(fs.localNames)-firstLocal)
+		fs.scope = outerScope
+		ends = append(ends, c.emit(OpJump, 0))
+		c.patchJump(nextCase)
+		if guardFailed >= 0 {
+			c.patchJump(guardFailed)
+		}
+	}
+	// No match found, so run the default block if there is one
+	c.emit(OpPop)
+	if defaultCase != nil {
+		fs.scope = newBlockScope(outerScope, false)
+		firstLocal := len(fs.localNames)
+		clear := c.emit(OpClearLocals, firstLocal, 0)
+		declaredNames(defaultCase.Block().Statements(), c.hoist)
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+		c.patchUint16(clear+3, len(fs.localNames)-firstLocal)
+	} else {
+		c.emit(OpNil)
+	}
+	for _, end := range ends {
+		c.patchJump(end)
+	}
+	return nil
+}
+
+// compileSelect emits a select statement. The channels and the values to
+// send are pushed in case order and OpSelect jumps to the block of the case
+// that proceeds, with the received value and ok flag on the stack. Each case
+// gets a scope of its own, like the evaluator creates.
+func (c *Compiler) compileSelect(node *ast.Select) error {
+	var choices []*ast.SelectCase
+	var defaultCase *ast.SelectCase
+	for _, choice := range node.Cases() {
+		if choice.IsDefault() {
+			if defaultCase == nil {
+				defaultCase = choice
+			}
+			continue
+		}
+		if err := c.compile(choice.Channel()); err != nil {
+			return err
+		}
+		if choice.IsSend() {
+			if err := c.compile(choice.Value()); err != nil {
+				return err
+			}
+		}
+		choices = append(choices, choice)
+	}
+	if len(choices) > 255 {
+		return fmt.Errorf("compile error: too many cases in select statement (%d)", len(choices))
+	}
+	hasDefault := 0
+	if defaultCase != nil {
+		hasDefault = 1
+	}
+	c.emit(OpSelect, len(choices), hasDefault)
+	table := c.pos()
+	for _, choice := range choices {
+		var send byte
+		if choice.IsSend() {
+			send = 1
+		}
+		c.fs.instructions = append(c.fs.instructions, send, 0, 0)
+	}
+	if defaultCase != nil {
+		c.fs.instructions = append(c.fs.instructions, 0, 0)
+	}
+	fs := c.fs
+	outerScope := fs.scope
+	defer func() { fs.scope = outerScope }()
+	var ends []int
+	for i, choice := range choices {
+		c.patchUint16(table+i*SelectCaseWidth+1, c.pos())
+		fs.scope = newBlockScope(outerScope, false)
+		firstLocal := len(fs.localNames)
+		clear := c.emit(OpClearLocals, firstLocal, 0)
+		names := choice.Names()
+		switch len(names) {
+		case 0:
+			c.emit(OpPop)
+			c.emit(OpPop)
+		case 1:
+			c.emit(OpPop)
+			c.emitDefine(c.declare(names[0].String(), false))
+		default:
+			c.emitDefine(c.declare(names[1].String(), false))
+			c.emitDefine(c.declare(names[0].String(), false))
+		}
+		declaredNames(choice.Block().Statements(), c.hoist)
+		if err := c.compile(choice.Block()); err != nil {
+			return err
+		}
+		c.patchUint16(clear+3, len(fs.localNames)-firstLocal)
+		fs.scope = outerScope
+		ends = append(ends, c.emit(OpJump, 0))
+	}
+	if defaultCase != nil {
+		c.patchUint16(table+len(choices)*SelectCaseWidth, c.pos())
+		fs.scope = newBlockScope(outerScope, false)
+		firstLocal := len(fs.localNames)
+		clear := c.emit(OpClearLocals, firstLocal, 0)
+		declaredNames(defaultCase.Block().Statements(), c.hoist)
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+		c.patchUint16(clear+3, len(fs.localNames)-firstLocal)
+	}
+	for _, end := range ends {
+		c.patchJump(end)
+	}
+	return nil
+}
+
+// compileGo emits a go statement, which evaluates the function and its
+// arguments and then calls it in a new goroutine.
+// compileDeferredCall emits a call that runs later, as used by go and defer
+// statements. The function and arguments are evaluated immediately and are
+// passed to the given opcode, or to methodOp when calling a method.
+func (c *Compiler) compileDeferredCall(node ast.Expression, op, methodOp Opcode) error {
+	kind := SpreadGo
+	if op == OpDefer {
+		kind = SpreadDefer
+	}
+	switch call := node.(type) {
+	case *ast.Call:
+		if err := c.compile(call.Function()); err != nil {
+			return err
+		}
+		if isExpandedCall(call.Arguments()) {
+			return c.compileExpandedCall(call.Arguments(), kind)
+		}
+		if err := c.compileArguments(call.Arguments(), false); err != nil {
+			return err
+		}
+		c.emit(op, len(call.Arguments()))
+	case *ast.ObjectCall:
+		if err := c.compile(call.Object()); err != nil {
+			return err
+		}
+		method, ok := call.Call().(*ast.Call)
+		if !ok {
+			c.emit(OpPop)
+			c.emitRaise("failed to evaluate object call")
+			return nil
+		}
+		if isExpandedCall(method.Arguments()) {
+			c.emit(OpGetAttr, c.addString(method.Function().String()))
+			return c.compileExpandedCall(method.Arguments(), kind)
+		}
+		if err := c.compileArguments(method.Arguments(), false); err != nil {
+			return err
+		}
+		c.emit(methodOp, c.addString(method.Function().String()), len(method.Arguments()))
+	default:
+		c.emitRaise("eval error: expected a function call")
+	}
+	return nil
+}
+
+func (c *Compiler) compilePipe(node *ast.Pipe) error {
+	exprs := node.Expressions()
+	if len(exprs) < 2 {
+		c.emitRaise("eval error: invalid pipe expression (got only %d arguments)", len(exprs))
+		return nil
+	}
+	if err := c.compile(exprs[0]); err != nil {
+		return err
+	}
+	for i, expr := range exprs[1:] {
+		switch expr := expr.(type) {
+		case *ast.Call:
+			if err := c.compile(expr.Function()); err != nil {
+				return err
+			}
+			if isExpandedCall(expr.Arguments()) {
+				if err := c.compileExpandedCall(expr.Arguments(), SpreadPipe); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := c.compileArguments(expr.Arguments(), false); err != nil {
+				return err
+			}
+			c.emit(OpCallPipe, len(expr.Arguments()))
+		case *ast.ObjectCall:
+			if err := c.compile(expr.Object()); err != nil {
+				return err
+			}
+			callExpr, ok := expr.Call().(*ast.Call)
+			if !ok {
+				return fmt.Errorf("compile error: invalid object call in pipe expression")
+			}
+			method, ok := callExpr.Function().(*ast.Ident)
+			if !ok {
+				c.emit(OpPop)
+				c.emit(OpPop)
+				c.emitRaise("invalid function in pipe expression: %v", callExpr.Function())
+				return nil
+			}
+			if isExpandedCall(callExpr.Arguments()) {
+				c.emit(OpGetAttr, c.addString(method.Literal()))
+				if err := c.compileExpandedCall(callExpr.Arguments(), SpreadPipe); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := c.compileArguments(callExpr.Arguments(), false); err != nil {
+				return err
+			}
+			c.emit(OpCallMethodPipe, c.addString(method.Literal()), len(callExpr.Arguments()))
+		default:
+			if err := c.compile(expr); err != nil {
+				return err
+			}
+			first := 0
+			if i == 0 {
+				first = 1
+			}
+			c.emit(OpPipeValue, first)
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileControl(node *ast.Control) error {
+	switch node.Literal() {
+	case "break", "continue":
+		if len(c.fs.loops) == 0 {
+			c.emitRaise("eval error: %s statement outside loop", node.Literal())
+			return nil
+		}
+		loop := c.fs.loops[len(c.fs.loops)-1]
+		jump := c.emit(OpJump, 0)
+		if node.Literal() == "break" {
+			loop.breaks = append(loop.breaks, jump)
+		} else {
+			loop.continues = append(loop.continues, jump)
+		}
+	case "return":
+		if node.Value() == nil {
+			c.emit(OpNil)
+		} else if err := c.compile(node.Value()); err != nil {
+			return err
+		}
+		c.emit(OpReturnValue)
+	default:
+		c.emitRaise("eval error: invalid control keyword: %s", node.Literal())
+	}
+	return nil
+}
+
+func (c *Compiler) compileString(node *ast.String) error {
+	if node.Template() == nil {
+		c.emit(OpConstant, c.addString(node.Value()))
+		return nil
+	}
+	var exprIndex int
+	fragments := node.Template().Fragments
+	for _, f := range fragments {
+		if !f.IsVariable {
+			c.emit(OpConstant, c.addString(f.Value))
+			continue
+		}
+		expr := node.TemplateExpressions()[exprIndex]
+		exprIndex++
+		if expr == nil {
+			c.emit(OpConstant, c.addString(""))
+			continue
+		}
+		if err := c.compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(OpTemplate, len(fragments))
+	return nil
+}
+
+func (c *Compiler) compileMap(node *ast.Map) error {
+	items := node.Items()
+	for keyNode, valueNode := range items {
+		if keyIdent, ok := keyNode.(*ast.Ident); ok {
+			// Key is an identifier (no quotes), e.g. { foo: 5 }
+			c.emit(OpConstant, c.addString(keyIdent.String()))
+		} else if err := c.compile(keyNode); err != nil {
+			return err
+		}
+		if err := c.compile(valueNode); err != nil {
+			return err
+		}
+	}
+	c.emit(OpMap, len(items))
+	return nil
+}
+
+// hoist declares a name in the current scope ahead of its declaration, so
+// that functions defined earlier in the scope can refer to it.
+func (c *Compiler) hoist(name string, readOnly bool) {
+	c.declare(name, readOnly)
+}
+
+// declare adds a variable to the current scope, or returns the existing
+// variable if it was already declared there.
+func (c *Compiler) declare(name string, readOnly bool) *symbol {
+	scope := c.fs.scope
+	if sym, ok := scope.symbols[name]; ok {
+		return sym
+	}
+	sym := &symbol{name: name, readOnly: readOnly}
+	if scope.global {
+		sym.global = true
+		sym.index = len(c.globals)
+		c.globals = append(c.globals, name)
+	} else {
+		sym.index = c.fs.allocLocal(name)
+	}
+	scope.symbols[name] = sym
+	return sym
+}
+
+// isDeclared returns true if the program declares the name in any scope
+// visible from the current one.
+func (c *Compiler) isDeclared(name string) bool {
+	scope := c.fs.scope
+	for fs := c.fs; fs != nil; fs = fs.parent {
+		for s := scope; s != nil; s = s.parent {
+			if _, ok := s.symbols[name]; ok {
+				return true
+			}
+		}
+		scope = fs.parentScope
+	}
+	return false
+}
+
+func (c *Compiler) resolve(name string) varRef {
+	return c.resolveIn(c.fs, c.fs.scope, name)
+}
+
+func (c *Compiler) resolveIn(fs *funcState, scope *blockScope, name string) varRef {
+	for s := scope; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			if sym.global {
+				return varRef{kind: refGlobal, index: sym.index, symbol: sym}
+			}
+			return varRef{kind: refLocal, index: sym.index, symbol: sym}
+		}
+	}
+	if fs.parent == nil {
+		return varRef{kind: refName, index: c.addName(name)}
+	}
+	outer := c.resolveIn(fs.parent, fs.parentScope, name)
+	switch outer.kind {
+	case refLocal:
+		c.capture(fs.parent, outer.symbol)
+		index := fs.addFree(CaptureLocal, outer.index, outer.symbol)
+		return varRef{kind: refFree, index: index, symbol: outer.symbol}
+	case refFree:
+		index := fs.addFree(CaptureFree, outer.index, outer.symbol)
+		return varRef{kind: refFree, index: index, symbol: outer.symbol}
+	}
+	return outer
+}
+
+// capture moves a local variable into a cell, rewriting the instructions
+// that were already emitted to access it.
+func (c *Compiler) capture(owner *funcState, sym *symbol) {
+	if sym.captured {
+		return
+	}
+	sym.captured = true
+	for _, pos := range sym.refs {
+		switch Opcode(owner.instructions[pos]) {
+		case OpGetLocal:
+			owner.instructions[pos] = byte(OpGetCell)
+		case OpSetLocal:
+			owner.instructions[pos] = byte(OpSetCell)
+		case OpDefineLocal:
+			owner.instructions[pos] = byte(OpDefineCell)
+		}
+	}
+	sym.refs = nil
+}
+
+func (c *Compiler) emitLoad(ref varRef) {
+	switch ref.kind {
+	case refGlobal:
+		c.emit(OpGetGlobal, ref.index)
+	case refLocal:
+		c.emitLocal(OpGetLocal, OpGetCell, ref.symbol)
+	case refFree:
+		c.emit(OpGetFree, ref.index)
+	case refName:
+		c.emit(OpGetName, ref.index)
+	}
+}
+
+// emitStore pops the top of the stack into an existing variable, checking
+// it against the type annotation the variable was declared with.
+func (c *Compiler) emitStore(ref varRef) {
+	if ref.symbol != nil && ref.symbol.typ != "" {
+		c.emit(OpCheckType, c.addString(ref.symbol.typ), c.addString(ref.symbol.name))
+	}
+	switch ref.kind {
+	case refGlobal:
+		c.emit(OpSetGlobal, ref.index)
+	case refLocal:
+		c.emitLocal(OpSetLocal, OpSetCell, ref.symbol)
+	case refFree:
+		c.emit(OpSetFree, ref.index)
+	case refName:
+		c.emit(OpSetName, ref.index)
+	}
+}
+
+// emitDefine pops the top of the stack into a newly declared variable.
+func (c *Compiler) emitDefine(sym *symbol) {
+	if sym.global {
+		c.emit(OpDefineGlobal, sym.index)
+		return
+	}
+	c.emitLocal(OpDefineLocal, OpDefineCell, sym)
+}
+
+func (c *Compiler) emitLocal(op, cellOp Opcode, sym *symbol) {
+	if sym.captured {
+		c.emit(cellOp, sym.index)
+		return
+	}
+	pos := c.emit(op, sym.index)
+	sym.refs = append(sym.refs, pos)
+}
+
+// emitRaise emits an instruction that stops execution with an error when
+// it is reached. This is used for errors that are detected at compile time
+// but that the evaluator reports at runtime.
+func (c *Compiler) emitRaise(format string, args ...interface{}) {
+	c.emit(OpRaise, c.addString(fmt.Sprintf(format, args...)))
+}
+
+// track marks the node as the one being compiled, so that the instructions
+// emitted for it are attributed to its position. The returned function
+// restores the previous node.
+func (c *Compiler) track(node ast.Node) func() {
+	fs := c.fs
+	prev := fs.node
+	fs.node = node
+	return func() { fs.node = prev }
+}
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	pos := len(c.fs.instructions)
+	if node := c.fs.node; node != nil && node != c.fs.positionNode {
+		tok := node.Token()
+		c.fs.positions = append(c.fs.positions, object.SourcePosition{
+			Offset: pos,
+			Start:  tok.StartPosition,
+			End:    tok.EndPosition,
+		})
+		c.fs.positionNode = node
+	}
+	c.fs.instructions = append(c.fs.instructions, Make(op, operands...)...)
+	return pos
+}
+
+func (c *Compiler) pos() int {
+	return len(c.fs.instructions)
+}
+
+// patchJump points the jump instruction at the given offset to the
+// current position.
+func (c *Compiler) patchJump(pos int) {
+	c.patchUint16(pos+1, c.pos())
+}
+
+func (c *Compiler) patchUint16(offset, value int) {
+	c.fs.instructions[offset] = byte(value >> 8)
+	c.fs.instructions[offset+1] = byte(value)
+}
+
+func (c *Compiler) checkSize(fs *funcState) error {
+	if len(fs.instructions) > 0xFFFF {
+		return fmt.Errorf("compile error: function body is too large")
+	}
+	if len(fs.localNames) > 0xFFFF {
+		return fmt.Errorf("compile error: too many local variables")
+	}
+	return nil
+}
+
+func (c *Compiler) binaryOperator(operator string) int {
+	op, _ := operatorIndex(BinaryOperators, operator)
+	return op
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	var key constantKey
+	switch obj := obj.(type) {
+	case *object.Int:
+		key = constantKey{object.INT, obj.Value()}
+	case *object.Float:
+		key = constantKey{object.FLOAT, obj.Value()}
+	case *object.String:
+		key = constantKey{object.STRING, obj.Value()}
+	default:
+		c.constants = append(c.constants, obj)
+		return len(c.constants) - 1
+	}
+	if index, ok := c.constantIndex[key]; ok {
+		return index
+	}
+	c.constants = append(c.constants, obj)
+	c.constantIndex[key] = len(c.constants) - 1
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) addString(value string) int {
+	return c.addConstant(object.NewString(value))
+}
+
+func (c *Compiler) addName(name string) int {
+	if index, ok := c.nameIndex[name]; ok {
+		return index
+	}
+	c.names = append(c.names, name)
+	c.nameIndex[name] = len(c.names) - 1
+	return len(c.names) - 1
+}
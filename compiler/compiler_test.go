@@ -0,0 +1,117 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func compile(t *testing.T, input string) *Bytecode {
+	t.Helper()
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	bytecode, err := Compile(program, Opts{})
+	require.Nil(t, err)
+	return bytecode
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpBinary, []int{3}, []byte{byte(OpBinary), 3}},
+		{OpCallMethod, []int{258, 2}, []byte{byte(OpCallMethod), 1, 2, 2}},
+		{OpPop, nil, []byte{byte(OpPop)}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, Make(tt.op, tt.operands...))
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	ins := Make(OpClearLocals, 300, 2)
+	def, err := Lookup(OpClearLocals)
+	require.Nil(t, err)
+	operands, read := ReadOperands(def, ins[1:])
+	require.Equal(t, 4, read)
+	require.Equal(t, []int{300, 2}, operands)
+}
+
+func TestDisassemble(t *testing.T) {
+	ins := append(Make(OpConstant, 1), Make(OpGetLocal, 2)...)
+	ins = append(ins, Make(OpReturnValue)...)
+	expected := "0000 OpConstant 1\n0003 OpGetLocal 2\n0006 OpReturnValue\n"
+	require.Equal(t, expected, Disassemble(ins))
+}
+
+func TestConstants(t *testing.T) {
+	bytecode := compile(t, `x := 1; y := 1; z := "a" + "a"`)
+	constants := bytecode.Constants()
+	require.Len(t, constants, 2)
+	require.Equal(t, object.NewInt(1), constants[0])
+	require.Equal(t, object.NewString("a"), constants[1])
+	require.Equal(t, []string{"x", "y", "z"}, bytecode.Globals())
+}
+
+func TestNames(t *testing.T) {
+	bytecode := compile(t, `len(x)`)
+	require.Equal(t, []string{"len", "x"}, bytecode.Names())
+}
+
+func TestLocals(t *testing.T) {
+	bytecode := compile(t, `func add(a, b) { c := a + b; return c }`)
+	var fn *object.CompiledFunction
+	for _, c := range bytecode.Constants() {
+		if f, ok := c.(*object.CompiledFunction); ok {
+			fn = f
+		}
+	}
+	require.NotNil(t, fn)
+	require.Equal(t, "add", fn.Name())
+	require.Equal(t, 2, fn.NumParameters())
+	require.Equal(t, 3, fn.NumLocals())
+	require.Equal(t, "c", fn.LocalName(2))
+}
+
+func TestCapturedLocal(t *testing.T) {
+	bytecode := compile(t, `func counter() { i := 0; return func() { i++ } }`)
+	var outer *object.CompiledFunction
+	for _, c := range bytecode.Constants() {
+		if f, ok := c.(*object.CompiledFunction); ok && f.Name() == "counter" {
+			outer = f
+		}
+	}
+	require.NotNil(t, outer)
+	listing := Disassemble(outer.Instructions())
+	require.True(t, strings.Contains(listing, "OpDefineCell 0"), listing)
+	require.False(t, strings.Contains(listing, "OpDefineLocal"), listing)
+}
+
+func TestPositions(t *testing.T) {
+	bytecode := compile(t, "x := 1\ny := x + 2")
+	main := bytecode.Main()
+	ins := main.Instructions()
+	var binary int
+	for ip := 0; ip < len(ins); {
+		def, err := Lookup(Opcode(ins[ip]))
+		require.Nil(t, err)
+		if Opcode(ins[ip]) == OpBinary {
+			binary = ip
+		}
+		_, read := ReadOperands(def, ins[ip+1:])
+		ip += 1 + read
+	}
+	pos, ok := main.Position(binary)
+	require.True(t, ok)
+	require.Equal(t, 2, pos.Start.LineNumber())
+	require.Equal(t, 8, pos.Start.ColumnNumber())
+	pos, ok = main.Position(0)
+	require.True(t, ok)
+	require.Equal(t, 1, pos.Start.LineNumber())
+}
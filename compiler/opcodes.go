@@ -0,0 +1,320 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Opcode identifies a single VM instruction.
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpDup
+	OpJump
+	OpJumpIfFalse
+	OpJumpIfFalseNoPop
+	OpJumpIfTrueNoPop
+	OpBinary
+	OpPrefix
+	OpIn
+	OpGetGlobal
+	OpSetGlobal
+	OpDefineGlobal
+	OpGetLocal
+	OpSetLocal
+	OpDefineLocal
+	OpGetCell
+	OpSetCell
+	OpDefineCell
+	OpGetFree
+	OpSetFree
+	OpGetName
+	OpSetName
+	OpClearLocals
+	OpJumpIfArg
+	OpList
+	OpMap
+	OpSet
+	OpIndex
+	OpSlice
+	OpSetItem
+	OpGetAttr
+	OpSetAttr
+	OpCall
+	OpCallMethod
+	OpCallPipe
+	OpCallMethodPipe
+	OpPipeValue
+	OpReturnValue
+	OpClosure
+	OpRange
+	OpIterNext
+	OpIterClose
+	OpUnpack
+	OpPostfix
+	OpImport
+	OpTemplate
+	OpRaise
+	OpCatch
+	OpEndCatch
+	OpGo
+	OpGoMethod
+	OpDefer
+	OpDeferMethod
+	OpSelect
+	OpStruct
+	OpSpread
+	OpCallSpread
+	OpCallKwargs
+	OpPropagate
+	OpMatch
+	OpDestructure
+	OpJumpIfSet
+	OpCollect
+	OpNewSlice
+	OpYield
+	OpCheckParams
+	OpCheckType
+)
+
+// Definition describes the name and operand widths of an opcode.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:         {"OpConstant", []int{2}},
+	OpNil:              {"OpNil", []int{}},
+	OpTrue:             {"OpTrue", []int{}},
+	OpFalse:            {"OpFalse", []int{}},
+	OpPop:              {"OpPop", []int{}},
+	OpDup:              {"OpDup", []int{}},
+	OpJump:             {"OpJump", []int{2}},
+	OpJumpIfFalse:      {"OpJumpIfFalse", []int{2}},
+	OpJumpIfFalseNoPop: {"OpJumpIfFalseNoPop", []int{2}},
+	OpJumpIfTrueNoPop:  {"OpJumpIfTrueNoPop", []int{2}},
+	OpBinary:           {"OpBinary", []int{1}},
+	OpPrefix:           {"OpPrefix", []int{1}},
+	OpIn:               {"OpIn", []int{}},
+	OpGetGlobal:        {"OpGetGlobal", []int{2}},
+	OpSetGlobal:        {"OpSetGlobal", []int{2}},
+	OpDefineGlobal:     {"OpDefineGlobal", []int{2}},
+	OpGetLocal:         {"OpGetLocal", []int{2}},
+	OpSetLocal:         {"OpSetLocal", []int{2}},
+	OpDefineLocal:      {"OpDefineLocal", []int{2}},
+	OpGetCell:          {"OpGetCell", []int{2}},
+	OpSetCell:          {"OpSetCell", []int{2}},
+	OpDefineCell:       {"OpDefineCell", []int{2}},
+	OpGetFree:          {"OpGetFree", []int{1}},
+	OpSetFree:          {"OpSetFree", []int{1}},
+	OpGetName:          {"OpGetName", []int{2}},
+	OpSetName:          {"OpSetName", []int{2}},
+	OpClearLocals:      {"OpClearLocals", []int{2, 2}},
+	OpJumpIfArg:        {"OpJumpIfArg", []int{1, 2}},
+	OpList:             {"OpList", []int{2}},
+	OpMap:              {"OpMap", []int{2}},
+	OpSet:              {"OpSet", []int{2}},
+	OpIndex:            {"OpIndex", []int{}},
+	OpSlice:            {"OpSlice", []int{1}},
+	OpSetItem:          {"OpSetItem", []int{}},
+	OpGetAttr:          {"OpGetAttr", []int{2}},
+	OpSetAttr:          {"OpSetAttr", []int{2}},
+	OpCall:             {"OpCall", []int{1}},
+	OpCallMethod:       {"OpCallMethod", []int{2, 1}},
+	OpCallPipe:         {"OpCallPipe", []int{1}},
+	OpCallMethodPipe:   {"OpCallMethodPipe", []int{2, 1}},
+	OpPipeValue:        {"OpPipeValue", []int{1}},
+	OpReturnValue:      {"OpReturnValue", []int{}},
+	OpClosure:          {"OpClosure", []int{2, 1}},
+	OpRange:            {"OpRange", []int{}},
+	OpIterNext:         {"OpIterNext", []int{2, 1}},
+	OpIterClose:        {"OpIterClose", []int{}},
+	OpUnpack:           {"OpUnpack", []int{2}},
+	OpPostfix:          {"OpPostfix", []int{1, 2}},
+	OpImport:           {"OpImport", []int{2}},
+	OpTemplate:         {"OpTemplate", []int{2}},
+	OpRaise:            {"OpRaise", []int{2}},
+	OpCatch:            {"OpCatch", []int{2}},
+	OpEndCatch:         {"OpEndCatch", []int{}},
+	OpGo:               {"OpGo", []int{1}},
+	OpGoMethod:         {"OpGoMethod", []int{2, 1}},
+	OpDefer:            {"OpDefer", []int{1}},
+	OpDeferMethod:      {"OpDeferMethod", []int{2, 1}},
+	OpSelect:           {"OpSelect", []int{1, 1}},
+	OpStruct:           {"OpStruct", []int{2, 2}},
+	OpSpread:           {"OpSpread", []int{1}},
+	OpCallSpread:       {"OpCallSpread", []int{1}},
+	OpCallKwargs:       {"OpCallKwargs", []int{1}},
+	OpPropagate:        {"OpPropagate", []int{}},
+	OpMatch:            {"OpMatch", []int{2}},
+	OpDestructure:      {"OpDestructure", []int{2}},
+	OpJumpIfSet:        {"OpJumpIfSet", []int{2}},
+	OpCollect:          {"OpCollect", []int{1}},
+	OpNewSlice:         {"OpNewSlice", []int{1}},
+	OpYield:            {"OpYield", []int{}},
+	OpCheckParams:      {"OpCheckParams", []int{}},
+	OpCheckType:        {"OpCheckType", []int{2, 2}},
+}
+
+// CaptureWidth is the number of bytes used to describe each variable
+// captured by an OpClosure instruction. Each capture is a one byte flag
+// followed by a two byte index, and the captures immediately follow the
+// OpClosure operands.
+const CaptureWidth = 3
+
+// Capture kinds used in the capture descriptors following OpClosure.
+const (
+	CaptureLocal byte = iota
+	CaptureFree
+)
+
+// SelectCaseWidth is the number of bytes used to describe each case of an
+// OpSelect instruction. Each case is a one byte flag that is 1 for a send,
+// followed by the two byte offset of the case block. The cases immediately
+// follow the OpSelect operands, and are followed by the two byte offset of
+// the default block if there is one.
+const SelectCaseWidth = 3
+
+// Each argument of an OpSpread instruction is described by a one byte flag
+// that is 1 if the argument is spread. The flags immediately follow the
+// OpSpread operand.
+
+// Call kinds used by OpCallSpread and OpCallKwargs.
+const (
+	SpreadCall byte = iota
+	SpreadGo
+	SpreadDefer
+	SpreadPipe
+)
+
+// Lookup returns the definition for the given opcode.
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Operators used by OpBinary. The operand of the instruction is the index of
+// the operator in this list.
+var BinaryOperators = []string{
+	"+", "-", "*", "/", "%", "**",
+	"==", "!=", "<", "<=", ">", ">=",
+	"+=", "-=", "*=", "/=",
+	"&", "|", "^", "&^", "<<", ">>",
+	"&=", "|=", "^=", "&^=", "<<=", ">>=",
+}
+
+// Operators used by OpPrefix.
+var PrefixOperators = []string{"!", "-", "^"}
+
+// Operators used by OpPostfix.
+var PostfixOperators = []string{"++", "--"}
+
+func operatorIndex(operators []string, operator string) (int, bool) {
+	for i, op := range operators {
+		if op == operator {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Make encodes an instruction with the given operands.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction, returning them along
+// with the number of bytes read.
+func ReadOperands(def *Definition, ins []byte) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+// ReadUint16 decodes a two byte operand.
+func ReadUint16(ins []byte) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// Disassemble returns a human readable listing of the given instructions.
+func Disassemble(ins []byte) string {
+	var out bytes.Buffer
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, o := range operands {
+			fmt.Fprintf(&out, " %d", o)
+		}
+		i += 1 + read
+		switch Opcode(ins[i-1-read]) {
+		case OpClosure:
+			for c := 0; c < operands[1]; c++ {
+				fmt.Fprintf(&out, " [%d %d]", ins[i], ReadUint16(ins[i+1:]))
+				i += CaptureWidth
+			}
+		case OpSelect:
+			for c := 0; c < operands[0]; c++ {
+				fmt.Fprintf(&out, " [%d %d]", ins[i], ReadUint16(ins[i+1:]))
+				i += SelectCaseWidth
+			}
+			if operands[1] == 1 {
+				fmt.Fprintf(&out, " [default %d]", ReadUint16(ins[i:]))
+				i += 2
+			}
+		case OpSpread:
+			for c := 0; c < operands[0]; c++ {
+				fmt.Fprintf(&out, " [%d]", ins[i])
+				i++
+			}
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
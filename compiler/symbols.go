@@ -0,0 +1,161 @@
+package compiler
+
+import (
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// symbol is a variable declared by the program being compiled.
+type symbol struct {
+	name     string
+	global   bool
+	index    int
+	readOnly bool
+
+	// typ is the type annotation the variable was declared with, if any.
+	// Every value stored in the variable is checked against it.
+	typ string
+
+	// captured is set once a nested function refers to this local variable.
+	// From then on the variable is stored in an object.Cell.
+	captured bool
+
+	// refs holds the offsets of instructions that access this local variable
+	// directly. These are rewritten to their cell variants if the variable
+	// is captured after they were emitted.
+	refs []int
+}
+
+// blockScope mirrors one scope.Scope created by the evaluator: the global
+// scope, a function scope, or the scopes created by a for loop.
+type blockScope struct {
+	parent  *blockScope
+	symbols map[string]*symbol
+	global  bool
+}
+
+func newBlockScope(parent *blockScope, global bool) *blockScope {
+	return &blockScope{
+		parent:  parent,
+		symbols: map[string]*symbol{},
+		global:  global,
+	}
+}
+
+// freeVar is a variable captured by a function from an enclosing function.
+type freeVar struct {
+	kind   byte
+	index  int
+	symbol *symbol
+}
+
+// loopState tracks the jumps emitted by break and continue statements so
+// they can be patched once the loop is fully compiled.
+type loopState struct {
+	breaks    []int
+	continues []int
+}
+
+// funcState holds the compilation state of a single function. The main
+// program is compiled as a function too.
+type funcState struct {
+	parent       *funcState
+	parentScope  *blockScope
+	scope        *blockScope
+	instructions []byte
+	localNames   []string
+	free         []freeVar
+	loops        []*loopState
+	// node is the AST node currently being compiled. The positions of the
+	// nodes that emitted instructions are recorded as they are emitted.
+	node         ast.Node
+	positions    []object.SourcePosition
+	positionNode ast.Node
+}
+
+func (fs *funcState) allocLocal(name string) int {
+	fs.localNames = append(fs.localNames, name)
+	return len(fs.localNames) - 1
+}
+
+func (fs *funcState) addFree(kind byte, index int, sym *symbol) int {
+	for i, fv := range fs.free {
+		if fv.kind == kind && fv.index == index {
+			return i
+		}
+	}
+	fs.free = append(fs.free, freeVar{kind: kind, index: index, symbol: sym})
+	return len(fs.free) - 1
+}
+
+// refKind describes where a resolved variable is stored at runtime.
+type refKind int
+
+const (
+	refGlobal refKind = iota
+	refLocal
+	refFree
+	refName
+)
+
+// varRef is the result of resolving a variable name.
+type varRef struct {
+	kind   refKind
+	index  int
+	symbol *symbol
+}
+
+func (r varRef) readOnly() bool {
+	return r.symbol != nil && r.symbol.readOnly
+}
+
+// declaredNames finds the names declared directly within the given
+// statements. This includes declarations nested in if, switch and select blocks,
+// since those share the scope of the enclosing block, but excludes those in
+// for loops and function literals which get scopes of their own.
+func declaredNames(statements []ast.Node, visit func(name string, readOnly bool)) {
+	for _, statement := range statements {
+		switch node := statement.(type) {
+		case *ast.Var:
+			name, _ := node.Value()
+			visit(name, false)
+		case *ast.Const:
+			name, _ := node.Value()
+			visit(name, true)
+		case *ast.MultiVar:
+			names, _ := node.Value()
+			for _, name := range names {
+				visit(name, false)
+			}
+		case *ast.Destructure:
+			if node.IsWalrus() {
+				for _, name := range node.Target().Bindings() {
+					visit(name, false)
+				}
+			}
+		case *ast.Func:
+			if node.Name() != nil {
+				visit(node.Name().String(), true)
+			}
+		case *ast.Struct:
+			visit(node.Name().String(), true)
+		case *ast.Import:
+			visit(node.Module().String(), true)
+		case *ast.Assign:
+			if node.Operator() == ":=" && node.Index() == nil {
+				visit(node.Name(), false)
+			}
+		case *ast.If:
+			declaredNames(node.Consequence().Statements(), visit)
+			if node.Alternative() != nil {
+				declaredNames(node.Alternative().Statements(), visit)
+			}
+		case *ast.Switch:
+			for _, choice := range node.Choices() {
+				declaredNames(choice.Block().Statements(), visit)
+			}
+		case *ast.Block:
+			declaredNames(node.Statements(), visit)
+		}
+	}
+}
@@ -14,9 +14,15 @@ func (e *Evaluator) evalVarStatement(ctx context.Context, node *ast.Var, s *scop
 	if object.IsError(value) {
 		return value
 	}
+	if err := CheckVariable(ident, node.TypeAnnotation(), value); err != nil {
+		return err
+	}
 	if err := s.Declare(ident, value, false); err != nil {
 		return object.NewError(err)
 	}
+	if typ := node.TypeAnnotation(); typ != nil {
+		s.Annotate(ident, typ.String())
+	}
 	return value
 }
 
@@ -50,6 +56,9 @@ func (e *Evaluator) evalConstStatement(ctx context.Context, node *ast.Const, s *
 	if object.IsError(value) {
 		return value
 	}
+	if err := CheckVariable(ident, node.TypeAnnotation(), value); err != nil {
+		return err
+	}
 	if err := s.Declare(ident, value, true); err != nil {
 		return object.NewError(err)
 	}
@@ -57,6 +66,9 @@ func (e *Evaluator) evalConstStatement(ctx context.Context, node *ast.Const, s *
 }
 
 func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *scope.Scope) object.Object {
+	if a.Attr() != nil {
+		return e.evalSetAttrStatement(ctx, a, s)
+	}
 	value := e.Evaluate(ctx, a.Value(), s)
 	if object.IsError(value) {
 		return value
@@ -64,6 +76,9 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 	if a.Index() != nil {
 		return e.evalSetItemStatement(ctx, a, value, s)
 	}
+	if a.Slice() != nil {
+		return e.evalSetSliceStatement(ctx, a, value, s)
+	}
 	name := a.Name()
 	switch a.Operator() {
 	case "+=":
@@ -71,10 +86,13 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 		if !ok {
 			return object.Errorf("name error: %q is not defined", name)
 		}
-		r := e.evalInfix("+=", current, value, s)
+		r := e.evalInfix(ctx, "+=", current, value, s)
 		if object.IsError(r) {
 			return r
 		}
+		if err := checkAssignment(s, name, r); err != nil {
+			return err
+		}
 		if err := s.Update(name, r); err != nil {
 			return object.NewError(err)
 		}
@@ -85,10 +103,13 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 		if !ok {
 			return object.Errorf("name error: %q is not defined", name)
 		}
-		r := e.evalInfix("-=", current, value, s)
+		r := e.evalInfix(ctx, "-=", current, value, s)
 		if object.IsError(r) {
 			return r
 		}
+		if err := checkAssignment(s, name, r); err != nil {
+			return err
+		}
 		if err := s.Update(name, r); err != nil {
 			return object.NewError(err)
 		}
@@ -99,10 +120,13 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 		if !ok {
 			return object.Errorf("name error: %q is not defined", name)
 		}
-		r := e.evalInfix("*=", current, value, s)
+		r := e.evalInfix(ctx, "*=", current, value, s)
 		if object.IsError(r) {
 			return r
 		}
+		if err := checkAssignment(s, name, r); err != nil {
+			return err
+		}
 		if err := s.Update(name, r); err != nil {
 			return object.NewError(err)
 		}
@@ -113,10 +137,30 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 		if !ok {
 			return object.Errorf("name error: %q is not defined", name)
 		}
-		r := e.evalInfix("/=", current, value, s)
+		r := e.evalInfix(ctx, "/=", current, value, s)
+		if object.IsError(r) {
+			return r
+		}
+		if err := checkAssignment(s, name, r); err != nil {
+			return err
+		}
+		if err := s.Update(name, r); err != nil {
+			return object.NewError(err)
+		}
+		return r
+
+	case "&=", "|=", "^=", "&^=", "<<=", ">>=":
+		current, ok := s.Get(name)
+		if !ok {
+			return object.Errorf("name error: %q is not defined", name)
+		}
+		r := e.evalInfix(ctx, a.Operator(), current, value, s)
 		if object.IsError(r) {
 			return r
 		}
+		if err := checkAssignment(s, name, r); err != nil {
+			return err
+		}
 		if err := s.Update(name, r); err != nil {
 			return object.NewError(err)
 		}
@@ -128,6 +172,9 @@ func (e *Evaluator) evalAssignStatement(ctx context.Context, a *ast.Assign, s *s
 		}
 
 	case "=":
+		if err := checkAssignment(s, name, value); err != nil {
+			return err
+		}
 		if err := s.Update(name, value); err != nil {
 			return object.NewError(err)
 		}
@@ -151,11 +198,69 @@ func (e *Evaluator) evalSetItemStatement(ctx context.Context, a *ast.Assign, val
 	}
 	switch a.Operator() {
 	case "=":
+		before, _ := object.Size(obj)
 		if err := container.SetItem(indexObj, value); err != nil {
 			return err
 		}
+		if err := e.limiter.Grow(obj, before); err != nil {
+			return err
+		}
 	default:
 		return object.Errorf("eval error: invalid set item operator: %q", a.Operator)
 	}
 	return object.Nil
 }
+
+func (e *Evaluator) evalSetSliceStatement(ctx context.Context, a *ast.Assign, value object.Object, s *scope.Scope) object.Object {
+	node := a.Slice()
+	obj := e.Evaluate(ctx, node.Left(), s)
+	if object.IsError(obj) {
+		return obj
+	}
+	container, ok := obj.(object.Container)
+	if !ok {
+		return object.Errorf("type error: %s is not a container", obj.Type())
+	}
+	slice, err := e.evalSliceBounds(ctx, node.FromIndex(), node.ToIndex(), node.Step(), s)
+	if err != nil {
+		return err
+	}
+	before, _ := object.Size(obj)
+	if err := container.SetItem(slice, value); err != nil {
+		return err
+	}
+	if err := e.limiter.Grow(obj, before); err != nil {
+		return err
+	}
+	return object.Nil
+}
+
+func (e *Evaluator) evalSetAttrStatement(ctx context.Context, a *ast.Assign, s *scope.Scope) object.Object {
+	attr := a.Attr()
+	obj := e.Evaluate(ctx, attr.Object(), s)
+	if object.IsError(obj) {
+		return obj
+	}
+	value := e.Evaluate(ctx, a.Value(), s)
+	if object.IsError(value) {
+		return value
+	}
+	setter, ok := obj.(object.AttrSetter)
+	if !ok {
+		return object.Errorf("type error: cannot set attribute \"%s\" on %s", attr.Name(), obj.Type())
+	}
+	if a.Operator() != "=" {
+		current, found := obj.GetAttr(attr.Name())
+		if !found {
+			return object.Errorf("attribute error: %s object has no attribute \"%s\"", obj.Type(), attr.Name())
+		}
+		value = e.evalInfix(ctx, a.Operator(), current, value, s)
+		if object.IsError(value) {
+			return value
+		}
+	}
+	if err := setter.SetAttr(attr.Name(), value); err != nil {
+		return err
+	}
+	return object.Nil
+}
@@ -0,0 +1,210 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// IntOverflow determines what happens when int arithmetic overflows the
+// range of a 64-bit integer.
+type IntOverflow int
+
+const (
+	// PromoteOnOverflow returns the exact result as a bigint. This is the
+	// default.
+	PromoteOnOverflow IntOverflow = iota
+	// ErrorOnOverflow returns an error that stops code execution.
+	ErrorOnOverflow
+)
+
+// maxShift limits the shift count of bigint left shifts, so that a typo
+// can't allocate an enormous integer.
+const maxShift = 1 << 20
+
+// intOverflow handles an int operation whose result does not fit in an
+// int64, either by redoing it with bigints or by returning an error.
+func intOverflow(operator string, left, right int64, overflow IntOverflow) object.Object {
+	if overflow == ErrorOnOverflow {
+		return object.Errorf("eval error: int overflow: %d %s %d",
+			left, strings.TrimSuffix(operator, "="), right)
+	}
+	return evalBigIntInfixExpression(operator, big.NewInt(left), big.NewInt(right))
+}
+
+func addInt(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+func subInt(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return c, false
+	}
+	return c, c/b == a
+}
+
+func powInt(a, b int64) (int64, bool) {
+	result := int64(1)
+	for b > 0 {
+		var ok bool
+		if b&1 == 1 {
+			if result, ok = mulInt(result, a); !ok {
+				return 0, false
+			}
+		}
+		if b >>= 1; b > 0 {
+			if a, ok = mulInt(a, a); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// powTooLarge returns true if base ** exp would have more bits than a left
+// shift by maxShift produces. The result has at most the bit length of the
+// base times exp bits, and exactly k * exp + 1 bits for a base of ±2**k,
+// while bases of 0 and ±1 can't grow.
+func powTooLarge(base, exp *big.Int) bool {
+	bits := int64(base.BitLen())
+	if bits <= 1 {
+		return false
+	}
+	if new(big.Int).Abs(base).TrailingZeroBits() == uint(bits-1) {
+		bits--
+	}
+	return !exp.IsInt64() || exp.Int64() > maxShift/bits
+}
+
+func shlInt(a, b int64) (int64, bool) {
+	if a == 0 {
+		return 0, true
+	}
+	if b >= 63 {
+		return 0, false
+	}
+	c := a << b
+	return c, c>>b == a
+}
+
+// toBigInt returns the value of an int or bigint as a big.Int.
+func toBigInt(obj object.Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return big.NewInt(obj.Value()), true
+	case *object.BigInt:
+		return obj.Value(), true
+	}
+	return nil, false
+}
+
+// toFloat returns the value of an int, bigint or float as a float64.
+func toFloat(obj object.Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return float64(obj.Value()), true
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value()).Float64()
+		return f, true
+	case *object.Float:
+		return obj.Value(), true
+	}
+	return 0, false
+}
+
+// evalBigIntMixedInfixExpression handles operations where at least one
+// operand is a bigint. Ints are promoted to bigints, while operations that
+// involve a float are done with floats.
+func evalBigIntMixedInfixExpression(operator string, left, right object.Object) object.Object {
+	leftInt, leftOk := toBigInt(left)
+	rightInt, rightOk := toBigInt(right)
+	if leftOk && rightOk {
+		if result := evalBigIntInfixExpression(operator, leftInt, rightInt); result != nil {
+			return result
+		}
+	} else if leftFloat, ok := toFloat(left); ok {
+		if rightFloat, ok := toFloat(right); ok {
+			return evalFloatInfixExpression(operator, object.NewFloat(leftFloat), object.NewFloat(rightFloat))
+		}
+	}
+	return object.Errorf("type error: unsupported operand types for %s: %s and %s",
+		operator, left.Type(), right.Type())
+}
+
+// evalBigIntInfixExpression returns nil if the operator is not supported.
+func evalBigIntInfixExpression(operator string, leftVal, rightVal *big.Int) object.Object {
+	switch operator {
+	case "+", "+=":
+		return object.NewBigInt(new(big.Int).Add(leftVal, rightVal))
+	case "-", "-=":
+		return object.NewBigInt(new(big.Int).Sub(leftVal, rightVal))
+	case "*", "*=":
+		return object.NewBigInt(new(big.Int).Mul(leftVal, rightVal))
+	case "/", "/=":
+		if rightVal.Sign() == 0 {
+			return object.Errorf("eval error: bigint divided by zero")
+		}
+		return object.NewBigInt(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return object.Errorf("eval error: bigint modulo by zero")
+		}
+		return object.NewBigInt(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return object.Errorf("eval error: negative bigint exponent: %s", rightVal)
+		}
+		if powTooLarge(leftVal, rightVal) {
+			return object.Errorf("eval error: bigint exponent too large: %s", rightVal)
+		}
+		return object.NewBigInt(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "&", "&=":
+		return object.NewBigInt(new(big.Int).And(leftVal, rightVal))
+	case "|", "|=":
+		return object.NewBigInt(new(big.Int).Or(leftVal, rightVal))
+	case "^", "^=":
+		return object.NewBigInt(new(big.Int).Xor(leftVal, rightVal))
+	case "&^", "&^=":
+		return object.NewBigInt(new(big.Int).AndNot(leftVal, rightVal))
+	case "<<", "<<=":
+		if rightVal.Sign() < 0 {
+			return object.Errorf("eval error: negative shift count: %s", rightVal)
+		}
+		if rightVal.Cmp(big.NewInt(maxShift)) > 0 {
+			return object.Errorf("eval error: shift count too large: %s", rightVal)
+		}
+		return object.NewBigInt(new(big.Int).Lsh(leftVal, uint(rightVal.Uint64())))
+	case ">>", ">>=":
+		if rightVal.Sign() < 0 {
+			return object.Errorf("eval error: negative shift count: %s", rightVal)
+		}
+		// Shifting by the bit length already yields 0 or -1
+		shift := uint(leftVal.BitLen())
+		if rightVal.IsUint64() && rightVal.Uint64() < uint64(shift) {
+			shift = uint(rightVal.Uint64())
+		}
+		return object.NewBigInt(new(big.Int).Rsh(leftVal, shift))
+	default:
+		return nil
+	}
+}
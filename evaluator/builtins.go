@@ -2,9 +2,11 @@ package evaluator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 	"unicode"
@@ -15,7 +17,7 @@ import (
 	"github.com/cloudcmds/tamarin/object"
 )
 
-// Len returns the length of a string, list, set, or map
+// Len returns the length of a string, bytes, list, set, or map
 func Len(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("len", 1, args); err != nil {
 		return err
@@ -23,12 +25,14 @@ func Len(ctx context.Context, args ...object.Object) object.Object {
 	switch arg := args[0].(type) {
 	case *object.String:
 		return object.NewInt(int64(utf8.RuneCountInString(arg.Value())))
-	case *object.List:
+	case *object.Bytes:
 		return object.NewInt(int64(len(arg.Value())))
+	case *object.List:
+		return object.NewInt(int64(arg.Size()))
 	case *object.Set:
-		return object.NewInt(int64(len(arg.Value())))
+		return object.NewInt(int64(arg.Size()))
 	case *object.Map:
-		return object.NewInt(int64(len(arg.Value())))
+		return object.NewInt(int64(arg.Size()))
 	default:
 		return object.Errorf("type error: len() argument is unsupported (%s given)", args[0].Type())
 	}
@@ -93,8 +97,8 @@ func Set(ctx context.Context, args ...object.Object) object.Object {
 			}
 		}
 	case *object.Map:
-		for k := range arg.Value() {
-			if res := set.Add(object.NewString(k)); object.IsError(res) {
+		for _, k := range arg.SortedKeys() {
+			if res := set.Add(k); object.IsError(res) {
 				return res
 			}
 		}
@@ -119,12 +123,38 @@ func List(ctx context.Context, args ...object.Object) object.Object {
 			items = append(items, object.NewString(string(v)))
 		}
 		return object.NewList(items)
+	case *object.Bytes:
+		items := make([]object.Object, 0, len(obj.Value()))
+		for _, v := range obj.Value() {
+			items = append(items, object.NewInt(int64(v)))
+		}
+		return object.NewList(items)
 	case *object.List:
 		return obj.Copy()
 	case *object.Set:
 		return object.NewList(obj.SortedItems())
 	case *object.Map:
 		return obj.Keys()
+	case object.Iterator:
+		// Collect the remaining values of an iterator, such as a generator,
+		// which may produce more than the limit for a list
+		limiter := object.GetLimiter(ctx)
+		var items []object.Object
+		for {
+			entry, ok := obj.Next()
+			if !ok {
+				break
+			}
+			if err := limiter.CheckSize(object.LIST, int64(len(items)+1)); err != nil {
+				object.CloseIterator(obj)
+				return err
+			}
+			items = append(items, entry.Value())
+		}
+		if err := object.IteratorErr(obj); err != nil {
+			return err
+		}
+		return object.NewList(items)
 	default:
 		return object.Errorf("type error: list() argument is unsupported (%s given)", args[0].Type())
 	}
@@ -147,11 +177,9 @@ func Map(ctx context.Context, args ...object.Object) object.Object {
 				return object.Errorf("type error: map() received a list with an unsupported structure")
 			}
 			subList := subListObj.Value()
-			key, ok := subList[0].(*object.String)
-			if !ok {
-				return object.Errorf("type error: map() received a list with an unsupported structure")
+			if err := result.SetItem(subList[0], subList[1]); err != nil {
+				return err
 			}
-			result.Set(key.Value(), subList[1])
 		}
 		return result
 	}
@@ -165,12 +193,8 @@ func Map(ctx context.Context, args ...object.Object) object.Object {
 		if !ok {
 			break
 		}
-		k, v := entry.Key(), entry.Value()
-		switch k := k.(type) {
-		case *object.String:
-			result.Set(k.Value(), v)
-		default:
-			result.Set(k.Inspect(), v)
+		if err := result.SetItem(entry.Key(), entry.Value()); err != nil {
+			return err
 		}
 	}
 	return result
@@ -178,15 +202,22 @@ func Map(ctx context.Context, args ...object.Object) object.Object {
 
 func String(ctx context.Context, args ...object.Object) object.Object {
 	nArgs := len(args)
-	if nArgs > 1 {
-		return object.Errorf("type error: string() expected at most 1 argument (%d given)", nArgs)
+	if nArgs > 2 {
+		return object.Errorf("type error: string() expected at most 2 arguments (%d given)", nArgs)
 	}
 	if nArgs == 0 {
 		return object.NewString("")
 	}
+	if nArgs == 2 {
+		if _, ok := args[0].(*object.Bytes); !ok {
+			return object.Errorf("type error: string() encoding requires a bytes argument (%s given)", args[0].Type())
+		}
+	}
 	switch arg := args[0].(type) {
 	case *object.String:
 		return object.NewString(arg.Value())
+	case *object.Bytes:
+		return arg.Decode(ctx, args[1:]...)
 	default:
 		return object.NewString(args[0].Inspect())
 	}
@@ -308,11 +339,16 @@ func Bool(ctx context.Context, args ...object.Object) object.Object {
 	return object.False
 }
 
-func Fetch(ctx context.Context, args ...object.Object) object.Object {
+// Fetch makes an HTTP request. The request may be configured with a map of
+// parameters or with the keyword arguments method, timeout, and headers.
+func Fetch(ctx context.Context, kwargs *object.Map, args ...object.Object) object.Object {
 	numArgs := len(args)
 	if numArgs < 1 || numArgs > 2 {
 		return object.NewArgsRangeError("fetch", 1, 2, len(args))
 	}
+	if err := arg.Kwargs("fetch", kwargs, "method", "timeout", "headers"); err != nil {
+		return err
+	}
 	urlArg, argErr := object.AsString(args[0])
 	if argErr != nil {
 		return argErr
@@ -325,16 +361,46 @@ func Fetch(ctx context.Context, args ...object.Object) object.Object {
 			return errObj
 		}
 	}
+	if kwargs.Size() > 0 {
+		// Keyword arguments take precedence over the parameters map
+		merged := object.NewMap(nil)
+		if params != nil {
+			merged.Update(params)
+		}
+		merged.Update(kwargs)
+		params = merged
+	}
 	client := &http.Client{Timeout: 3 * time.Second}
 	req, timeout, errObj := httputil.NewRequestFromParams(ctx, urlArg, params)
 	if errObj != nil {
 		return object.NewErrResult(errObj)
 	}
+	if policy := object.GetPolicy(ctx); policy != nil {
+		if err := policy.CheckHost(req.URL.Hostname()); err != nil {
+			return err
+		}
+		// Redirects must stay within the allowed hosts too
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if err := policy.CheckHost(req.URL.Hostname()); err != nil {
+				return err.Value()
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		}
+	}
 	if timeout != 0 {
 		client.Timeout = timeout
 	}
 	resp, err := client.Do(req)
 	if err != nil {
+		// A redirect to a denied host is a permission error like a request
+		// to one, rather than a failed request
+		var permErr *object.PermissionError
+		if errors.As(err, &permErr) {
+			return object.NewError(permErr)
+		}
 		return object.NewErrResult(object.NewError(err))
 	}
 	return object.NewOkResult(object.NewHttpResponse(resp))
@@ -409,10 +475,16 @@ func UnwrapOr(ctx context.Context, args ...object.Object) object.Object {
 	}
 }
 
-func Sorted(ctx context.Context, args ...object.Object) object.Object {
+// Sorted returns a sorted list of the items in a container. The key keyword
+// argument may give a function that computes the value to sort each item
+// by, and reverse may be true to sort in descending order.
+func Sorted(ctx context.Context, kwargs *object.Map, args ...object.Object) object.Object {
 	if err := arg.Require("sorted", 1, args); err != nil {
 		return err
 	}
+	if err := arg.Kwargs("sorted", kwargs, "key", "reverse"); err != nil {
+		return err
+	}
 	var items []object.Object
 	switch arg := args[0].(type) {
 	case *object.List:
@@ -428,7 +500,29 @@ func Sorted(ctx context.Context, args ...object.Object) object.Object {
 	}
 	resultItems := make([]object.Object, len(items))
 	copy(resultItems, items)
-	if err := object.Sort(resultItems); err != nil {
+	reverse := kwargs.GetWithDefault("reverse", object.False).IsTruthy()
+	key := kwargs.GetWithDefault("key", object.Nil)
+	if key == object.Nil && !reverse {
+		if err := object.Sort(resultItems); err != nil {
+			return err
+		}
+		return object.NewList(resultItems)
+	}
+	keys := resultItems
+	if key != object.Nil {
+		callFunc, found := object.GetCallFunc(ctx)
+		if !found {
+			return object.Errorf("eval error: context did not contain a call function")
+		}
+		keys = make([]object.Object, len(resultItems))
+		for i, item := range resultItems {
+			keys[i] = callFunc(ctx, nil, key, []object.Object{item})
+			if object.IsError(keys[i]) {
+				return keys[i]
+			}
+		}
+	}
+	if err := object.SortBy(resultItems, keys, reverse); err != nil {
 		return err
 	}
 	return object.NewList(resultItems)
@@ -466,21 +560,40 @@ func GetAttr(ctx context.Context, args ...object.Object) object.Object {
 	return object.Errorf("attribute error: %s object has no attribute %q", args[0].Type(), attrName)
 }
 
-// Call the given function with the provided arguments
-func Call(ctx context.Context, args ...object.Object) object.Object {
+// Call the given function with the provided arguments. Any keyword
+// arguments are passed on to the function.
+func Call(ctx context.Context, kwargs *object.Map, args ...object.Object) object.Object {
 	numArgs := len(args)
 	if numArgs < 1 {
 		return object.Errorf("type error: call() takes 1 or more arguments (%d given)", len(args))
 	}
+	if kwargs.Size() > 0 {
+		switch fn := args[0].(type) {
+		case *object.Function, *object.Closure:
+			callFunc, found := object.GetKwargsCallFunc(ctx)
+			if !found {
+				return object.Errorf("eval error: context did not contain a call function")
+			}
+			return callFunc(ctx, fn, args[1:], kwargs)
+		}
+	}
 	switch fn := args[0].(type) {
 	case *object.Builtin:
-		return fn.Call(ctx, args[1:]...)
+		return fn.CallWithKwargs(ctx, kwargs, args[1:]...)
+	case *object.Struct:
+		return fn.CallWithKwargs(ctx, kwargs, args[1:]...)
 	case *object.Function:
 		callFunc, found := object.GetCallFunc(ctx)
 		if !found {
 			return object.Errorf("eval error: context did not contain a call function")
 		}
 		return callFunc(ctx, fn.Scope(), fn, args[1:])
+	case *object.Closure:
+		callFunc, found := object.GetCallFunc(ctx)
+		if !found {
+			return object.Errorf("eval error: context did not contain a call function")
+		}
+		return callFunc(ctx, nil, fn, args[1:])
 	}
 	return object.Errorf("type error: unable to call object (%s given)", args[0].Type())
 }
@@ -510,15 +623,155 @@ func Int(ctx context.Context, args ...object.Object) object.Object {
 	switch obj := args[0].(type) {
 	case *object.Int:
 		return obj
+	case *object.BigInt:
+		if !obj.Value().IsInt64() {
+			return object.Errorf("value error: bigint too large for int(): %s", obj.Inspect())
+		}
+		return object.NewInt(obj.Value().Int64())
+	case *object.Decimal:
+		value := obj.Round(0, object.RoundDown).Value()
+		if !value.IsInt64() {
+			return object.Errorf("value error: decimal too large for int(): %s", obj.Inspect())
+		}
+		return object.NewInt(value.Int64())
 	case *object.Float:
 		return object.NewInt(int64(obj.Value()))
 	case *object.String:
-		if i, err := strconv.ParseInt(obj.Value(), 0, 64); err == nil {
+		i, err := strconv.ParseInt(obj.Value(), 0, 64)
+		if err == nil {
 			return object.NewInt(i)
 		}
+		// Integers that don't fit in an int are parsed as bigints, like
+		// integer literals, unless int overflows are errors
+		if errors.Is(err, strconv.ErrRange) {
+			if object.ErrorOnIntOverflow(ctx) {
+				return object.Errorf("eval error: int overflow: int(%q)", obj.Value())
+			}
+			if value, ok := new(big.Int).SetString(obj.Value(), 0); ok {
+				return object.NewBigInt(value)
+			}
+		}
 		return object.Errorf("value error: invalid literal for int(): %q", obj.Value())
 	}
-	return object.Errorf("type error: int() argument must be a string, float, or int (%s given)", args[0].Type())
+	return object.Errorf("type error: int() argument must be a string, float, int, bigint, or decimal (%s given)", args[0].Type())
+}
+
+func BigInt(ctx context.Context, args ...object.Object) object.Object {
+	nArgs := len(args)
+	if nArgs > 1 {
+		return object.Errorf("type error: bigint() expected at most 1 argument (%d given)", nArgs)
+	}
+	if nArgs == 0 {
+		return object.NewBigInt(new(big.Int))
+	}
+	switch obj := args[0].(type) {
+	case *object.BigInt:
+		return obj
+	case *object.Int:
+		return object.NewBigInt(big.NewInt(obj.Value()))
+	case *object.Decimal:
+		return object.NewBigInt(obj.Round(0, object.RoundDown).Value())
+	case *object.Float:
+		if math.IsInf(obj.Value(), 0) || math.IsNaN(obj.Value()) {
+			return object.Errorf("value error: cannot convert %s to bigint", obj.Inspect())
+		}
+		i, _ := big.NewFloat(obj.Value()).Int(nil)
+		return object.NewBigInt(i)
+	case *object.String:
+		if i, ok := new(big.Int).SetString(obj.Value(), 0); ok {
+			return object.NewBigInt(i)
+		}
+		return object.Errorf("value error: invalid literal for bigint(): %q", obj.Value())
+	}
+	return object.Errorf("type error: bigint() argument must be a string, float, int, bigint, or decimal (%s given)", args[0].Type())
+}
+
+func Decimal(ctx context.Context, args ...object.Object) object.Object {
+	nArgs := len(args)
+	if nArgs > 1 {
+		return object.Errorf("type error: decimal() expected at most 1 argument (%d given)", nArgs)
+	}
+	if nArgs == 0 {
+		return object.NewDecimal(new(big.Int), 0)
+	}
+	switch obj := args[0].(type) {
+	case *object.Decimal:
+		return obj
+	case *object.Int, *object.BigInt:
+		d, _ := object.ToDecimal(obj)
+		return d
+	case *object.Float:
+		// Use the shortest representation of the float, so that 0.1
+		// becomes exactly 0.1
+		s := strconv.FormatFloat(obj.Value(), 'f', -1, 64)
+		if d, ok := object.ParseDecimal(s); ok {
+			return d
+		}
+		return object.Errorf("value error: cannot convert %s to decimal", obj.Inspect())
+	case *object.String:
+		if d, ok := object.ParseDecimal(obj.Value()); ok {
+			return d
+		}
+		return object.Errorf("value error: invalid literal for decimal(): %q", obj.Value())
+	}
+	return object.Errorf("type error: decimal() argument must be a string, float, int, bigint, or decimal (%s given)", args[0].Type())
+}
+
+// Bytes converts a string, list of ints, or bytes to a bytes object. An int
+// argument creates bytes of that length filled with zeros. Strings are
+// encoded using the optional encoding argument, which defaults to "utf-8".
+func Bytes(ctx context.Context, args ...object.Object) object.Object {
+	nArgs := len(args)
+	if nArgs > 2 {
+		return object.Errorf("type error: bytes() expected at most 2 arguments (%d given)", nArgs)
+	}
+	if nArgs == 0 {
+		return object.NewBytes(nil)
+	}
+	if nArgs == 2 {
+		s, err := object.AsString(args[0])
+		if err != nil {
+			return object.Errorf("type error: bytes() encoding requires a string argument (%s given)", args[0].Type())
+		}
+		encoding, err := object.AsString(args[1])
+		if err != nil {
+			return err
+		}
+		b, err := object.EncodeString(s, encoding)
+		if err != nil {
+			return err
+		}
+		return object.NewBytes(b)
+	}
+	switch obj := args[0].(type) {
+	case *object.Bytes:
+		return obj
+	case *object.String:
+		return object.NewBytes([]byte(obj.Value()))
+	case *object.Int:
+		if obj.Value() < 0 {
+			return object.Errorf("value error: bytes() size must be non-negative (got %d)", obj.Value())
+		}
+		if err := object.GetLimiter(ctx).CheckSize(object.BYTES, obj.Value()); err != nil {
+			return err
+		}
+		return object.NewBytes(make([]byte, obj.Value()))
+	case *object.List:
+		items := obj.Value()
+		b := make([]byte, 0, len(items))
+		for _, item := range items {
+			i, ok := item.(*object.Int)
+			if !ok {
+				return object.Errorf("type error: bytes() list items must be ints (got %s)", item.Type())
+			}
+			if i.Value() < 0 || i.Value() > 255 {
+				return object.Errorf("value error: bytes() list items must be in range 0-255 (got %d)", i.Value())
+			}
+			b = append(b, byte(i.Value()))
+		}
+		return object.NewBytes(b)
+	}
+	return object.Errorf("type error: bytes() argument must be a string, int, list, or bytes (%s given)", args[0].Type())
 }
 
 func Float(ctx context.Context, args ...object.Object) object.Object {
@@ -532,6 +785,12 @@ func Float(ctx context.Context, args ...object.Object) object.Object {
 	switch obj := args[0].(type) {
 	case *object.Int:
 		return object.NewFloat(float64(obj.Value()))
+	case *object.BigInt:
+		f, _ := toFloat(obj)
+		return object.NewFloat(f)
+	case *object.Decimal:
+		f, _ := strconv.ParseFloat(obj.Inspect(), 64)
+		return object.NewFloat(f)
 	case *object.Float:
 		return obj
 	case *object.String:
@@ -540,7 +799,7 @@ func Float(ctx context.Context, args ...object.Object) object.Object {
 		}
 		return object.Errorf("value error: invalid literal for float(): %q", obj.Value())
 	}
-	return object.Errorf("type error: float() argument must be a string, float, or int (%s given)", args[0].Type())
+	return object.Errorf("type error: float() argument must be a string, float, int, bigint, or decimal (%s given)", args[0].Type())
 }
 
 func Ord(ctx context.Context, args ...object.Object) object.Object {
@@ -558,6 +817,24 @@ func Ord(ctx context.Context, args ...object.Object) object.Object {
 	return object.Errorf("type error: ord() expected a string of length 1 (%s given)", args[0].Type())
 }
 
+func Chan(ctx context.Context, args ...object.Object) object.Object {
+	nArgs := len(args)
+	if nArgs > 1 {
+		return object.Errorf("type error: chan() expected at most 1 argument (%d given)", nArgs)
+	}
+	if nArgs == 0 {
+		return object.NewChan(0)
+	}
+	size, err := object.AsInt(args[0])
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return object.Errorf("value error: chan() size must be non-negative (%d given)", size)
+	}
+	return object.NewChan(int(size))
+}
+
 func Chr(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("chr", 1, args); err != nil {
 		return err
@@ -592,6 +869,15 @@ func Error(ctx context.Context, args ...object.Object) object.Object {
 	return object.Errorf(msg.Value(), goArgs...)
 }
 
+// IsUnhandled returns true for errors that try can't handle, which are
+// those caused by exceeding a limit, being denied by the policy, calling
+// exit or closing a generator. This is exported so that other execution
+// backends handle errors the same way.
+func IsUnhandled(err *object.Error) bool {
+	return object.IsLimitError(err) || object.IsPermissionError(err) ||
+		object.IsExitError(err) || object.IsGeneratorClosed(err)
+}
+
 func Try(ctx context.Context, args ...object.Object) object.Object {
 	nArgs := len(args)
 	if nArgs < 1 || nArgs > 2 {
@@ -605,12 +891,24 @@ func Try(ctx context.Context, args ...object.Object) object.Object {
 				return object.Errorf("eval error: context did not contain a call function")
 			}
 			return callFunc(ctx, obj.Scope(), obj, []object.Object{err.Message()})
+		case *object.Closure:
+			callFunc, found := object.GetCallFunc(ctx)
+			if !found {
+				return object.Errorf("eval error: context did not contain a call function")
+			}
+			return callFunc(ctx, nil, obj, []object.Object{err.Message()})
 		default:
 			return obj
 		}
 	}
 	switch obj := args[0].(type) {
 	case *object.Error:
+		// Exceeding a limit, being denied by the policy or calling exit ends
+		// the program, and closing a generator unwinds its body, so these
+		// can't be handled
+		if IsUnhandled(obj) {
+			return obj
+		}
 		if nArgs == 2 {
 			return handleErr(args[1], obj)
 		}
@@ -633,11 +931,11 @@ func Iter(ctx context.Context, args ...object.Object) object.Object {
 	if nArgs != 1 {
 		return object.NewArgsError("iter", 1, len(args))
 	}
-	container, ok := args[0].(object.Container)
-	if !ok {
+	iterator, err := Iterate(args[0])
+	if err != nil {
 		return object.Errorf("type error: iter() expected a container (%s given)", args[0].Type())
 	}
-	return container.Iter()
+	return iterator
 }
 
 func Exit(ctx context.Context, args ...object.Object) object.Object {
@@ -645,14 +943,17 @@ func Exit(ctx context.Context, args ...object.Object) object.Object {
 	if nArgs > 1 {
 		return object.Errorf("type error: exit() expected at most 1 argument (%d given)", nArgs)
 	}
+	if err := object.GetPolicy(ctx).CheckExit(); err != nil {
+		return err
+	}
 	if nArgs == 0 {
-		os.Exit(0)
+		return object.NewExitError(0)
 	}
 	switch obj := args[0].(type) {
 	case *object.Int:
-		os.Exit(int(obj.Value()))
+		return object.NewExitError(int(obj.Value()))
 	case *object.Error:
-		os.Exit(1)
+		return object.NewExitError(1)
 	}
 	return object.Errorf("type error: exit() argument must be an int or error (%s given)", args[0].Type())
 }
@@ -666,14 +967,16 @@ func GlobalBuiltins() []*object.Builtin {
 		{"all", All},
 		{"any", Any},
 		{"assert", Assert},
+		{"bigint", BigInt},
 		{"bool", Bool},
-		{"call", Call},
+		{"bytes", Bytes},
+		{"chan", Chan},
 		{"chr", Chr},
+		{"decimal", Decimal},
 		{"delete", Delete},
 		{"err", Err},
 		{"error", Error},
 		{"exit", Exit},
-		{"fetch", Fetch},
 		{"float", Float},
 		{"getattr", GetAttr},
 		{"int", Int},
@@ -688,7 +991,6 @@ func GlobalBuiltins() []*object.Builtin {
 		{"printf", Printf},
 		{"reversed", Reversed},
 		{"set", Set},
-		{"sorted", Sorted},
 		{"sprintf", Sprintf},
 		{"string", String},
 		{"type", Type},
@@ -699,6 +1001,17 @@ func GlobalBuiltins() []*object.Builtin {
 	for _, b := range builtins {
 		ret = append(ret, object.NewBuiltin(b.name, b.fn))
 	}
+	kwargsBuiltins := []struct {
+		name string
+		fn   object.KwargsBuiltinFunction
+	}{
+		{"call", Call},
+		{"fetch", Fetch},
+		{"sorted", Sorted},
+	}
+	for _, b := range kwargsBuiltins {
+		ret = append(ret, object.NewKwargsBuiltin(b.name, b.fn))
+	}
 	ret = append(ret, object.NewErrorHandler("try", Try))
 	return ret
 }
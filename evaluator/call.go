@@ -14,16 +14,32 @@ func (e *Evaluator) evalCallExpression(ctx context.Context, node *ast.Call, s *s
 		return function
 	}
 	if builtin, ok := function.(*object.Builtin); ok {
-		if builtin.IsErrorHandler() {
-			return e.applyFunction(ctx, s, function,
-				e.evalExpressionsIgnoreErrors(ctx, node.Arguments(), s))
+		if builtin.IsErrorHandler() && !hasKeywordArgs(node.Arguments()) {
+			args := e.evalExpressionsIgnoreErrors(ctx, node.Arguments(), s)
+			// Error handlers don't intercept the ? operator returning from
+			// the enclosing function
+			if err, ok := findPropagation(args); ok {
+				return err
+			}
+			return e.applyFunction(ctx, s, function, args)
 		}
 	}
-	args := e.evalExpressions(ctx, node.Arguments(), s)
-	if len(args) == 1 && object.IsError(args[0]) {
-		return args[0]
+	args, kwargs, err := e.evalArguments(ctx, node.Arguments(), s)
+	if err != nil {
+		return err
 	}
-	return e.applyFunction(ctx, s, function, args)
+	return e.applyFunctionWithKwargs(ctx, s, function, args, kwargs)
+}
+
+// hasKeywordArgs returns true if any of the given call arguments is passed
+// by keyword.
+func hasKeywordArgs(args []ast.Expression) bool {
+	for _, arg := range args {
+		if _, ok := arg.(*ast.KeywordArg); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *Evaluator) evalObjectCallExpression(ctx context.Context, call *ast.ObjectCall, s *scope.Scope) object.Object {
@@ -33,22 +49,25 @@ func (e *Evaluator) evalObjectCallExpression(ctx context.Context, call *ast.Obje
 	}
 	callExpr := call.Call()
 	if method, ok := callExpr.(*ast.Call); ok {
-		args := e.evalExpressions(ctx, method.Arguments(), s)
-		if len(args) == 1 && object.IsError(args[0]) {
-			return args[0]
+		args, kwargs, err := e.evalArguments(ctx, method.Arguments(), s)
+		if err != nil {
+			return err
 		}
 		funcName := method.Function().String()
-		return e.evalObjectCall(ctx, s, obj, funcName, args)
+		return e.evalObjectCall(ctx, s, obj, funcName, args, kwargs)
 	}
 	return object.Errorf("failed to evaluate object call")
 }
 
-func (e *Evaluator) evalObjectCall(ctx context.Context, s *scope.Scope, obj object.Object, method string, args []object.Object) object.Object {
+func (e *Evaluator) evalObjectCall(ctx context.Context, s *scope.Scope, obj object.Object, method string, args []object.Object, kwargs *object.Map) object.Object {
 	if attr, found := obj.GetAttr(method); found {
 		if object.IsError(attr) {
 			return attr
 		}
-		return e.applyFunction(ctx, s, attr, args)
+		if builtin, ok := attr.(*object.Builtin); ok {
+			return e.applyBuiltin(ctx, s, obj, builtin, args, kwargs)
+		}
+		return e.applyFunctionWithKwargs(ctx, s, attr, args, kwargs)
 	}
 	return object.Errorf("attribute error: %s has no attribute \"%s\"", obj.Type(), method)
 }
@@ -64,3 +83,49 @@ func (e *Evaluator) evalGetAttributeExpression(ctx context.Context, node *ast.Ge
 	}
 	return object.Errorf("attribute error: %s object has no attribute \"%s\"", obj.Type(), name)
 }
+
+// evalCallee evaluates the function and arguments of a call without calling
+// it. This is used by statements like go and defer which run the call later.
+func (e *Evaluator) evalCallee(ctx context.Context, node ast.Expression, s *scope.Scope) (object.Object, []object.Object, *object.Map, *object.Error) {
+	var fn object.Object
+	var argExprs []ast.Expression
+	switch call := node.(type) {
+	case *ast.Call:
+		fn = e.Evaluate(ctx, call.Function(), s)
+		if err, ok := fn.(*object.Error); ok {
+			return nil, nil, nil, err
+		}
+		argExprs = call.Arguments()
+	case *ast.ObjectCall:
+		obj := e.Evaluate(ctx, call.Object(), s)
+		if err, ok := obj.(*object.Error); ok {
+			return nil, nil, nil, err
+		}
+		method, ok := call.Call().(*ast.Call)
+		if !ok {
+			return nil, nil, nil, object.Errorf("failed to evaluate object call")
+		}
+		name := method.Function().String()
+		attr, found := obj.GetAttr(name)
+		if !found {
+			return nil, nil, nil, object.Errorf("attribute error: %s has no attribute \"%s\"", obj.Type(), name)
+		}
+		if err, ok := attr.(*object.Error); ok {
+			return nil, nil, nil, err
+		}
+		fn = attr
+		argExprs = method.Arguments()
+	default:
+		return nil, nil, nil, object.Errorf("eval error: expected a function call")
+	}
+	args, kwargs, err := e.evalArguments(ctx, argExprs, s)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	switch fn.(type) {
+	case *object.Function, *object.Builtin, *object.Struct:
+	default:
+		return nil, nil, nil, object.Errorf("type error: %s is not callable", fn.Type())
+	}
+	return fn, args, kwargs, nil
+}
@@ -0,0 +1,146 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+func (e *Evaluator) evalListComprehension(ctx context.Context, node *ast.ListComprehension, s *scope.Scope) object.Object {
+	result := object.NewList([]object.Object{})
+	if err := e.evalClauses(ctx, node.Clauses(), s, func(s *scope.Scope) object.Object {
+		item := e.Evaluate(ctx, node.Element(), s)
+		if object.IsError(item) {
+			return item
+		}
+		result.Append(item)
+		if err := e.limiter.Grow(result, int64(result.Size()-1)); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return result
+}
+
+func (e *Evaluator) evalSetComprehension(ctx context.Context, node *ast.SetComprehension, s *scope.Scope) object.Object {
+	result := object.NewSetWithSize(0)
+	if err := e.evalClauses(ctx, node.Clauses(), s, func(s *scope.Scope) object.Object {
+		item := e.Evaluate(ctx, node.Element(), s)
+		if object.IsError(item) {
+			return item
+		}
+		before := int64(result.Size())
+		if err, ok := result.Add(item).(*object.Error); ok {
+			return err
+		}
+		if err := e.limiter.Grow(result, before); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return result
+}
+
+func (e *Evaluator) evalMapComprehension(ctx context.Context, node *ast.MapComprehension, s *scope.Scope) object.Object {
+	result := object.NewMap(nil)
+	if err := e.evalClauses(ctx, node.Clauses(), s, func(s *scope.Scope) object.Object {
+		key := e.Evaluate(ctx, node.Key(), s)
+		if object.IsError(key) {
+			return key
+		}
+		value := e.Evaluate(ctx, node.Value(), s)
+		if object.IsError(value) {
+			return value
+		}
+		before := int64(result.Size())
+		if err := result.SetItem(key, value); err != nil {
+			return err
+		}
+		if err := e.limiter.Grow(result, before); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return result
+}
+
+// evalClauses runs the given body once for each combination of items
+// produced by the clauses of a comprehension. The variables of each clause
+// live in a scope nested within the scope of the clause before it.
+// Iteration stops if the body returns an error.
+func (e *Evaluator) evalClauses(
+	ctx context.Context,
+	clauses []*ast.ComprehensionClause,
+	s *scope.Scope,
+	body func(s *scope.Scope) object.Object,
+) object.Object {
+	clause := clauses[0]
+	value := e.Evaluate(ctx, clause.Iterable(), s)
+	if object.IsError(value) {
+		return value
+	}
+	iterator, err := Iterate(value)
+	if err != nil {
+		return err
+	}
+	defer object.CloseIterator(iterator)
+	targets := clause.Targets()
+	for {
+		entry, ok := iterator.Next()
+		if !ok {
+			if err := object.IteratorErr(iterator); err != nil {
+				return err
+			}
+			return nil
+		}
+		// A new scope each time lets closures capture the current item
+		clauseScope := s.NewChild(scope.Opts{Name: "comprehension"})
+		values := []object.Object{entry.Key(), entry.Value()}
+		if len(targets) == 1 {
+			values[0] = ComprehensionItem(iterator, entry)
+		}
+		for i, target := range targets {
+			if err := e.assignTarget(ctx, target, values[i], true, clauseScope); err != nil {
+				return err
+			}
+		}
+		if condition := clause.Condition(); condition != nil {
+			result := e.Evaluate(ctx, condition, clauseScope)
+			if object.IsError(result) {
+				return result
+			}
+			if !result.IsTruthy() {
+				continue
+			}
+		}
+		var err object.Object
+		if len(clauses) > 1 {
+			err = e.evalClauses(ctx, clauses[1:], clauseScope, body)
+		} else {
+			err = body(clauseScope)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ComprehensionItem returns the value assigned to the variable of a
+// comprehension clause that has only one. This is the item that the "in"
+// operator looks for in the container: the keys of a map or set, and the
+// values of anything else.
+func ComprehensionItem(iterator object.Iterator, entry object.IteratorEntry) object.Object {
+	switch iterator.(type) {
+	case *object.MapIter, *object.SetIter:
+		return entry.Key()
+	}
+	return entry.Value()
+}
@@ -0,0 +1,81 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+// evalGo handles a `go` statement. The function and its arguments are
+// evaluated immediately, then the call runs in a new goroutine. Its result
+// is discarded, so goroutines should use channels to report back. An error
+// returned by the call stops the program.
+func (e *Evaluator) evalGo(ctx context.Context, node *ast.Go, s *scope.Scope) object.Object {
+	fn, args, kwargs, err := e.evalCallee(ctx, node.Call(), s)
+	if err != nil {
+		return err
+	}
+	child := e.fork()
+	e.goroutines.Go(func() object.Object {
+		return child.CallWithKwargs(ctx, fn, args, kwargs)
+	})
+	return object.Nil
+}
+
+// evalSelect handles a `select` statement, which waits until one of its
+// channel operations can proceed and then runs the block for that case. If
+// there is a default case, it runs when no operation is ready immediately.
+// Like match, each case gets a scope of its own that holds the variables it
+// binds and declares.
+func (e *Evaluator) evalSelect(ctx context.Context, node *ast.Select, s *scope.Scope) object.Object {
+	var cases []object.SelectCase
+	var choices []*ast.SelectCase
+	var defaultCase *ast.SelectCase
+	for _, choice := range node.Cases() {
+		if choice.IsDefault() {
+			defaultCase = choice
+			continue
+		}
+		obj := e.Evaluate(ctx, choice.Channel(), s)
+		if object.IsError(obj) {
+			return obj
+		}
+		ch, ok := obj.(*object.Chan)
+		if !ok {
+			return object.Errorf("type error: select case expected a chan (got %s)", obj.Type())
+		}
+		selectCase := object.SelectCase{Chan: ch, Send: choice.IsSend()}
+		if choice.IsSend() {
+			value := e.Evaluate(ctx, choice.Value(), s)
+			if object.IsError(value) {
+				return value
+			}
+			selectCase.Value = value
+		}
+		cases = append(cases, selectCase)
+		choices = append(choices, choice)
+	}
+	chosen, value, ok, err := object.Select(ctx, cases, defaultCase == nil)
+	if err != nil {
+		return err
+	}
+	caseScope := s.NewChild(scope.Opts{Name: "select-case"})
+	if chosen < 0 {
+		return e.evalBlockStatement(ctx, defaultCase.Block(), caseScope)
+	}
+	choice := choices[chosen]
+	names := choice.Names()
+	if len(names) > 0 {
+		if err := caseScope.Declare(names[0].String(), value, false); err != nil {
+			return object.NewError(err)
+		}
+	}
+	if len(names) > 1 {
+		if err := caseScope.Declare(names[1].String(), object.NewBool(ok), false); err != nil {
+			return object.NewError(err)
+		}
+	}
+	return e.evalBlockStatement(ctx, choice.Block(), caseScope)
+}
@@ -26,7 +26,6 @@ func (e *Evaluator) evalIf(ctx context.Context, ie *ast.If, s *scope.Scope) obje
 func (e *Evaluator) evalFor(ctx context.Context, fle *ast.For, s *scope.Scope) object.Object {
 
 	forScope := s.NewChild(scope.Opts{Name: "for"})
-	loopScope := forScope.NewChild(scope.Opts{Name: "for-loop"})
 
 	// Evaluate the initialization statement if there is one
 	init := fle.Init()
@@ -39,10 +38,10 @@ func (e *Evaluator) evalFor(ctx context.Context, fle *ast.For, s *scope.Scope) o
 	if fle.IsSimpleLoop() {
 		// This is a simple for loop, like "for { ... }". It will run until
 		// an error occurs or a break or return statement is encountered.
-		return e.evalSimpleForLoop(ctx, fle, loopScope)
+		return e.evalSimpleForLoop(ctx, fle, forScope)
 	} else if fle.IsIteratorLoop() {
 		// This is an iterator loop, like "for k, v := range m { ... }"
-		return e.evalIteratorForLoop(ctx, fle, loopScope)
+		return e.evalIteratorForLoop(ctx, fle, forScope)
 	}
 
 	// The for loop evaluates to this value. It is set to the last value
@@ -52,7 +51,7 @@ func (e *Evaluator) evalFor(ctx context.Context, fle *ast.For, s *scope.Scope) o
 	// This is a standard for loop that runs until a specified condition is met.
 forLoop:
 	for {
-		loopScope.Clear()
+		loopScope := newLoopScope(forScope)
 		// Evaluate the condition
 		condition := e.Evaluate(ctx, fle.Condition(), forScope)
 		if object.IsError(condition) {
@@ -87,12 +86,18 @@ forLoop:
 	return latestValue
 }
 
-func (e *Evaluator) evalSimpleForLoop(ctx context.Context, fle *ast.For, s *scope.Scope) object.Object {
+// newLoopScope returns the scope for one iteration of a loop. Each iteration
+// gets a scope of its own, so that closures created by the loop body keep
+// the variables of the iteration that created them.
+func newLoopScope(forScope *scope.Scope) *scope.Scope {
+	return forScope.NewChild(scope.Opts{Name: "for-loop"})
+}
+
+func (e *Evaluator) evalSimpleForLoop(ctx context.Context, fle *ast.For, forScope *scope.Scope) object.Object {
 	var latestValue object.Object = object.Nil
 forLoop:
 	for {
-		s.Clear()
-		result := e.Evaluate(ctx, fle.Consequence(), s)
+		result := e.Evaluate(ctx, fle.Consequence(), newLoopScope(forScope))
 		switch result := result.(type) {
 		case *object.Error:
 			return result
@@ -111,12 +116,13 @@ forLoop:
 	return latestValue
 }
 
-func (e *Evaluator) evalIteratorForLoop(ctx context.Context, fle *ast.For, s *scope.Scope) object.Object {
+func (e *Evaluator) evalIteratorForLoop(ctx context.Context, fle *ast.For, forScope *scope.Scope) object.Object {
 	var latestValue object.Object = object.Nil
 
 	// The "condition" here is the assignment statement with a RHS iterator.
 	var iterExpr ast.Node
 	var names []string
+	var targets []ast.Pattern
 	switch cond := fle.Condition().(type) {
 	case *ast.Var:
 		name, expr := cond.Value()
@@ -124,16 +130,19 @@ func (e *Evaluator) evalIteratorForLoop(ctx context.Context, fle *ast.For, s *sc
 		iterExpr = expr
 	case *ast.MultiVar:
 		names, iterExpr = cond.Value()
+	case *ast.Destructure:
+		targets, iterExpr = LoopTargets(cond), cond.Value()
 	default:
 		return object.Errorf("eval error: invalid for loop condition")
 	}
 
-	if len(names) < 1 || len(names) > 2 {
+	count := len(names) + len(targets)
+	if count < 1 || count > 2 {
 		return object.Errorf("eval error: invalid for loop condition")
 	}
 
 	// Evaluate the RHS expression to get the iterator.
-	iterObj := e.Evaluate(ctx, iterExpr, s)
+	iterObj := e.Evaluate(ctx, iterExpr, newLoopScope(forScope))
 	if object.IsError(iterObj) {
 		return iterObj
 	}
@@ -141,19 +150,24 @@ func (e *Evaluator) evalIteratorForLoop(ctx context.Context, fle *ast.For, s *sc
 	if !ok {
 		return object.Errorf("eval error: cannot iterate over %s", iterObj.Type())
 	}
+	defer object.CloseIterator(iterator)
 
 forLoop:
 	for {
-		s.Clear()
+		s := newLoopScope(forScope)
 		entry, ok := iterator.Next()
 		if !ok {
+			if err := object.IteratorErr(iterator); err != nil {
+				return err
+			}
 			break
 		}
-		if err := s.Declare(names[0], entry.Key(), false); err != nil {
-			return object.NewError(err)
-		}
-		if len(names) > 1 {
-			if err := s.Declare(names[1], entry.Value(), false); err != nil {
+		for i, value := range []object.Object{entry.Key(), entry.Value()}[:count] {
+			if targets != nil {
+				if err := e.assignTarget(ctx, targets[i], value, true, s); err != nil {
+					return err
+				}
+			} else if err := s.Declare(names[i], value, false); err != nil {
 				return object.NewError(err)
 			}
 		}
@@ -230,18 +244,15 @@ func (e *Evaluator) evalPipe(ctx context.Context, pe *ast.Pipe, s *scope.Scope)
 				return function
 			}
 			// Resolve the call arguments
-			var args []object.Object
-			if len(expression.Arguments()) > 0 {
-				args = e.evalExpressions(ctx, expression.Arguments(), s)
-				if len(args) == 1 && object.IsError(args[0]) {
-					return args[0]
-				}
+			args, kwargs, err := e.evalArguments(ctx, expression.Arguments(), s)
+			if err != nil {
+				return err
 			}
 			// Prepend any arguments present from the previous pipeline stage and then run the call
 			if nextArg != nil {
 				args = prependObject(args, nextArg)
 			}
-			res := e.applyFunction(ctx, s, function, args)
+			res := e.applyFunctionWithKwargs(ctx, s, function, args, kwargs)
 			if object.IsError(res) {
 				return res
 			}
@@ -255,12 +266,9 @@ func (e *Evaluator) evalPipe(ctx context.Context, pe *ast.Pipe, s *scope.Scope)
 			}
 			// Resolve the call arguments
 			callExpr := expression.Call().(*ast.Call)
-			var args []object.Object
-			if len(callExpr.Arguments()) > 0 {
-				args = e.evalExpressions(ctx, callExpr.Arguments(), s)
-				if len(args) == 1 && object.IsError(args[0]) {
-					return args[0]
-				}
+			args, kwargs, err := e.evalArguments(ctx, callExpr.Arguments(), s)
+			if err != nil {
+				return err
 			}
 			// Prepend any arguments present from the previous pipeline stage and then run the call
 			if nextArg != nil {
@@ -270,7 +278,7 @@ func (e *Evaluator) evalPipe(ctx context.Context, pe *ast.Pipe, s *scope.Scope)
 			if !ok {
 				return object.Errorf("invalid function in pipe expression: %v", callExpr.Function)
 			}
-			res := e.evalObjectCall(ctx, s, obj, method.Literal(), args)
+			res := e.evalObjectCall(ctx, s, obj, method.Literal(), args, kwargs)
 			if object.IsError(res) {
 				return res
 			}
@@ -284,7 +292,7 @@ func (e *Evaluator) evalPipe(ctx context.Context, pe *ast.Pipe, s *scope.Scope)
 				return obj
 			}
 			switch obj := obj.(type) {
-			case *object.Function, *object.Builtin:
+			case *object.Function, *object.Builtin, *object.Struct:
 				var args []object.Object
 				if nextArg != nil {
 					args = []object.Object{nextArg}
@@ -295,6 +303,15 @@ func (e *Evaluator) evalPipe(ctx context.Context, pe *ast.Pipe, s *scope.Scope)
 				}
 				// Save the output as arguments for the next stage
 				nextArg = res
+			case *object.Int, *object.BigInt:
+				// Between two integers the pipe operator is a bitwise OR
+				if _, ok := toBigInt(nextArg); ok {
+					nextArg = e.evalInfix(ctx, "|", nextArg, obj, s)
+				} else if i == 0 {
+					nextArg = obj
+				} else {
+					return object.Errorf("type error: unexpected %s object in pipe expression", obj.Type())
+				}
 			default:
 				if i == 0 {
 					// Save the output as arguments for the next stage
@@ -344,9 +361,22 @@ func (e *Evaluator) evalRange(ctx context.Context, node *ast.Range, s *scope.Sco
 	if object.IsError(value) {
 		return value
 	}
-	container, ok := value.(object.Container)
-	if !ok {
-		return object.Errorf("type error: %s is not a container", value.Type())
+	iterator, err := Iterate(value)
+	if err != nil {
+		return err
+	}
+	return iterator
+}
+
+// Iterate returns an iterator over a container. Iterators, such as the
+// generators returned by generator functions, are returned as-is. This is
+// exported so that other execution backends iterate the same way.
+func Iterate(value object.Object) (object.Iterator, *object.Error) {
+	switch value := value.(type) {
+	case object.Container:
+		return value.Iter(), nil
+	case object.Iterator:
+		return value, nil
 	}
-	return container.Iter()
+	return nil, object.Errorf("type error: %s is not a container", value.Type())
 }
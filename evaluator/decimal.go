@@ -0,0 +1,58 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// evalDecimalInfixExpression handles operations where at least one operand
+// is a decimal. Ints and bigints are converted to decimals, while floats
+// are rejected since mixing them in would lose the exactness of decimals.
+// The context sets the number of places kept by inexact divisions.
+func evalDecimalInfixExpression(ctx context.Context, operator string, left, right object.Object) object.Object {
+	leftVal, leftOk := object.ToDecimal(left)
+	rightVal, rightOk := object.ToDecimal(right)
+	if !leftOk || !rightOk {
+		return object.Errorf("type error: unsupported operand types for %s: %s and %s",
+			operator, left.Type(), right.Type())
+	}
+	switch operator {
+	case "+", "+=":
+		return leftVal.Add(rightVal)
+	case "-", "-=":
+		return leftVal.Sub(rightVal)
+	case "*", "*=":
+		return leftVal.Mul(rightVal)
+	case "/", "/=":
+		if result, ok := leftVal.Quo(rightVal, object.GetDecimalDivisionPlaces(ctx)); ok {
+			return result
+		}
+		return object.Errorf("eval error: decimal divided by zero")
+	case "%":
+		if result, ok := leftVal.Rem(rightVal); ok {
+			return result
+		}
+		return object.Errorf("eval error: decimal modulo by zero")
+	case "**":
+		exp, ok := right.(*object.Int)
+		if !ok {
+			return object.Errorf("type error: decimal exponent must be an int (got %s)", right.Type())
+		}
+		if result, ok := leftVal.Pow(exp.Value()); ok {
+			return result
+		}
+		return object.Errorf("value error: invalid decimal exponent: %d", exp.Value())
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	default:
+		return object.Errorf("type error: unsupported operand types for %s: %s and %s",
+			operator, left.Type(), right.Type())
+	}
+}
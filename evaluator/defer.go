@@ -0,0 +1,39 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/stack"
+)
+
+// evalDefer handles a `defer` statement. The function and its arguments are
+// evaluated immediately, while the call itself runs when the surrounding
+// function returns.
+func (e *Evaluator) evalDefer(ctx context.Context, node *ast.Defer, s *scope.Scope) object.Object {
+	fn, args, kwargs, err := e.evalCallee(ctx, node.Call(), s)
+	if err != nil {
+		return err
+	}
+	e.stack.Top().Defer(stack.DeferredCall{Function: fn, Arguments: args, Kwargs: kwargs})
+	return object.Nil
+}
+
+// runDeferred runs the calls deferred in the given frame, most recent first.
+// They run even if the function failed. An error from a deferred call is
+// returned in place of the function's result, unless the function had
+// already failed with an error of its own.
+func (e *Evaluator) runDeferred(ctx context.Context, frame *stack.Frame, result object.Object) object.Object {
+	for {
+		call, ok := frame.PopDeferred()
+		if !ok {
+			return result
+		}
+		value := e.applyFunctionWithKwargs(ctx, frame.Scope(), call.Function, call.Arguments, call.Kwargs)
+		if object.IsError(value) && !object.IsError(result) {
+			result = value
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+func (e *Evaluator) evalDestructure(ctx context.Context, node *ast.Destructure, s *scope.Scope) object.Object {
+	value := e.Evaluate(ctx, node.Value(), s)
+	if object.IsError(value) {
+		return value
+	}
+	if err := e.assignTarget(ctx, node.Target(), value, node.IsWalrus(), s); err != nil {
+		return err
+	}
+	return value
+}
+
+// LoopTargets returns the targets that the key and value of each entry are
+// assigned to when a destructuring assignment is used in a for loop. Two
+// comma separated targets, as in "for i, [a, b] := range pairs", receive the
+// key and the value. A single pattern, as in "for [a, b] := range pairs", is
+// applied to the value and the key is discarded.
+func LoopTargets(node *ast.Destructure) []ast.Pattern {
+	if list, ok := node.Target().(*ast.ListPattern); ok && len(node.Targets()) > 1 && list.Rest() == nil {
+		return node.Targets()
+	}
+	return []ast.Pattern{ast.NewWildcardPattern(node.Token()), node.Target()}
+}
+
+// assignTarget destructures a value into the variables bound by a target.
+// The variables are declared in the given scope if declare is true, and are
+// otherwise updated.
+func (e *Evaluator) assignTarget(ctx context.Context, target ast.Pattern, value object.Object, declare bool, s *scope.Scope) object.Object {
+	values, err := Destructure(target, value)
+	if err != nil {
+		return err
+	}
+	for i, binding := range ast.BindingPatterns(target) {
+		item := values[i]
+		if item == nil {
+			// The key was missing, so use the default value
+			item = e.Evaluate(ctx, binding.Default(), s)
+			if object.IsError(item) {
+				return item
+			}
+		}
+		name := binding.Name().String()
+		if declare {
+			if err := s.Declare(name, item, false); err != nil {
+				return object.NewError(err)
+			}
+		} else if err := checkAssignment(s, name, item); err != nil {
+			return err
+		} else if err := s.Update(name, item); err != nil {
+			return object.NewError(err)
+		}
+	}
+	return nil
+}
+
+// Destructure extracts the parts of a value that are assigned to the
+// variables bound by a target. The values are returned in the same order
+// as the names from target.Bindings(). A nil entry means a map key was
+// missing and the default value of that variable should be used instead.
+func Destructure(target ast.Pattern, value object.Object) ([]object.Object, *object.Error) {
+	return destructure(target, value, nil)
+}
+
+func destructure(target ast.Pattern, value object.Object, values []object.Object) ([]object.Object, *object.Error) {
+	var err *object.Error
+	switch target := target.(type) {
+	case *ast.WildcardPattern:
+		return values, nil
+	case *ast.BindingPattern:
+		return append(values, value), nil
+	case *ast.ListPattern:
+		list, ok := value.(*object.List)
+		if !ok {
+			return nil, object.Errorf("type error: cannot destructure %s as a list", value.Type())
+		}
+		items := list.Value()
+		elements := target.Elements()
+		rest := target.Rest()
+		if rest == nil && len(items) != len(elements) {
+			return nil, object.Errorf("eval error: invalid destructuring assignment (list size: %d; targets: %d)",
+				len(items), len(elements))
+		}
+		if len(items) < len(elements) {
+			return nil, object.Errorf("eval error: invalid destructuring assignment (list size: %d; targets: at least %d)",
+				len(items), len(elements))
+		}
+		for i, element := range elements {
+			if values, err = destructure(element, items[i], values); err != nil {
+				return nil, err
+			}
+		}
+		if rest != nil {
+			remaining := make([]object.Object, len(items)-len(elements))
+			copy(remaining, items[len(elements):])
+			return destructure(rest, object.NewList(remaining), values)
+		}
+		return values, nil
+	case *ast.MapPattern:
+		m, ok := value.(*object.Map)
+		if !ok {
+			return nil, object.Errorf("type error: cannot destructure %s as a map", value.Type())
+		}
+		keys := patternKeys(target)
+		for i, key := range keys {
+			element := target.Values()[i]
+			item, found, err := m.Lookup(key)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				if binding, ok := element.(*ast.BindingPattern); ok && binding.Default() != nil {
+					values = append(values, nil)
+					continue
+				}
+				return nil, object.Errorf("key error: %s", key.Inspect())
+			}
+			if values, err = destructure(element, item, values); err != nil {
+				return nil, err
+			}
+		}
+		if rest := target.Rest(); rest != nil {
+			remaining := m.Copy()
+			for _, key := range keys {
+				remaining.DelItem(key)
+			}
+			return destructure(rest, remaining, values)
+		}
+		return values, nil
+	}
+	return nil, object.Errorf("eval error: invalid assignment target: %s", target)
+}
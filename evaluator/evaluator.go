@@ -42,14 +42,53 @@ type Opts struct {
 
 	// Breakpoints for debugging
 	Breakpoints []Breakpoint
+
+	// IntOverflow determines what happens when int arithmetic overflows.
+	// By default, the result is promoted to a bigint.
+	IntOverflow IntOverflow
+
+	// Limiter restricts the resources used by the program, such as the
+	// number of steps it runs and the size of the objects it creates. If
+	// nil, resources are not limited.
+	Limiter *object.Limiter
+
+	// MaxCallDepth is the maximum depth of nested function calls. Calls
+	// beyond it fail with an error instead of exhausting the Go stack. If
+	// zero, stack.DefaultMaxDepth is used.
+	MaxCallDepth int
+
+	// Policy declares the capabilities that builtins and modules may use on
+	// behalf of the program. If nil, all capabilities are allowed.
+	Policy *object.Policy
+
+	// Goroutines runs the goroutines started by go statements. The first
+	// error returned by one of them is recorded there and stops the program.
+	// If nil, errors returned by goroutines are discarded.
+	Goroutines *object.Goroutines
+
+	// Slots holds the locations of variables found by the resolver package
+	// for the program being evaluated. Identifiers without a slot are looked
+	// up by name.
+	Slots Slots
 }
 
-// Evaluator is used to execute Tamarin AST nodes.
+// Evaluator is used to execute Tamarin AST nodes. Goroutines started by go
+// statements are run by forks of the Evaluator that each have their own
+// call stack.
 type Evaluator struct {
 	importer    Importer
 	builtins    map[string]*object.Builtin
 	stack       *stack.Stack
 	breakpoints map[string]*Breakpoint
+	intOverflow IntOverflow
+	limiter     *object.Limiter
+	policy      *object.Policy
+	goroutines  *object.Goroutines
+	slots       Slots
+	// yield is set when running the body of a generator function
+	yield object.YieldFunc
+	// ctx is the last context returned by withContext
+	ctx context.Context
 }
 
 // New returns a new Evaluator
@@ -57,8 +96,13 @@ func New(opts Opts) *Evaluator {
 	e := &Evaluator{
 		importer:    opts.Importer,
 		builtins:    map[string]*object.Builtin{},
-		stack:       stack.New(),
+		stack:       stack.NewWithOpts(stack.Opts{MaxDepth: opts.MaxCallDepth}),
 		breakpoints: map[string]*Breakpoint{},
+		intOverflow: opts.IntOverflow,
+		limiter:     opts.Limiter,
+		policy:      opts.Policy,
+		goroutines:  opts.Goroutines,
+		slots:       opts.Slots,
 	}
 	// Conditionally register default global builtins
 	if !opts.DisableDefaultBuiltins {
@@ -77,6 +121,27 @@ func New(opts Opts) *Evaluator {
 	return e
 }
 
+// fork returns an Evaluator with the same configuration and its own call
+// stack, so that it can run a goroutine alongside this one.
+func (e *Evaluator) fork() *Evaluator {
+	return &Evaluator{
+		importer:    e.importer,
+		builtins:    e.builtins,
+		stack:       stack.NewWithOpts(stack.Opts{MaxDepth: e.stack.MaxDepth()}),
+		breakpoints: e.breakpoints,
+		intOverflow: e.intOverflow,
+		limiter:     e.limiter,
+		policy:      e.policy,
+		goroutines:  e.goroutines,
+		slots:       e.slots,
+	}
+}
+
+// Importer returns the importer used to import Tamarin code modules, if any.
+func (e *Evaluator) Importer() Importer {
+	return e.importer
+}
+
 // Returns a function that implements object.CallFunc
 func (e *Evaluator) getCallFunc() object.CallFunc {
 	return func(ctx context.Context, s interface{}, fn object.Object, args []object.Object) object.Object {
@@ -88,6 +153,13 @@ func (e *Evaluator) getCallFunc() object.CallFunc {
 	}
 }
 
+// Returns a function that implements object.KwargsCallFunc
+func (e *Evaluator) getKwargsCallFunc() object.KwargsCallFunc {
+	return func(ctx context.Context, fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
+		return e.applyFunctionWithKwargs(ctx, nil, fn, args, kwargs)
+	}
+}
+
 func (e *Evaluator) GetBreakpoint(tok token.Token) (*Breakpoint, bool) {
 	if len(e.breakpoints) == 0 {
 		return nil, false
@@ -129,12 +201,69 @@ func (e *Evaluator) trackExecution(statement ast.Statement, s *scope.Scope) obje
 }
 
 // Evaluate an AST node. The context can be used to cancel a running evaluation.
-// If evaluation encounters an error, a Tamarin error object is returned.
+// If evaluation encounters an error, a Tamarin error object is returned which
+// carries an object.RuntimeError describing where the error occurred.
 func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope) object.Object {
+	result := e.evaluate(ctx, node, s)
+	if e.limiter != nil {
+		result = e.trackResult(node, result)
+	}
+	if err, ok := result.(*object.Error); ok {
+		return e.annotateError(err, node)
+	}
+	return result
+}
 
+// annotateError attaches the position of the given node and the current call
+// stack to an error. The innermost node that produced the error is the first
+// to see it, so errors that are already annotated are returned unchanged.
+func (e *Evaluator) annotateError(err *object.Error, node ast.Node) *object.Error {
+	if _, ok := err.RuntimeError(); ok {
+		return err
+	}
+	tok := node.Token()
+	return err.WithRuntimeError(object.RuntimeErrorOpts{
+		StartPosition: tok.StartPosition,
+		EndPosition:   tok.EndPosition,
+		Stack:         e.stack.Trace(tok.StartPosition),
+	})
+}
+
+// withContext adds the values used by objects to call back into this
+// evaluator to the context. Contexts that already hold them are returned
+// unchanged, since every value added lengthens the chain of parents that is
+// walked each time the context is checked for cancellation, and evaluation
+// would otherwise slow down with the depth of the call stack.
+func (e *Evaluator) withContext(ctx context.Context) context.Context {
+	if ctx == e.ctx {
+		return ctx
+	}
 	// Add an object.CallFunc to the context so that objects can call Tamarin
 	// functions if needed
 	ctx = object.WithCallFunc(ctx, e.getCallFunc())
+	ctx = object.WithKwargsCallFunc(ctx, e.getKwargsCallFunc())
+	if e.limiter != nil {
+		ctx = object.WithLimiter(ctx, e.limiter)
+	}
+	if e.policy != nil {
+		ctx = object.WithPolicy(ctx, e.policy)
+	}
+	if e.intOverflow == ErrorOnOverflow {
+		ctx = object.WithErrorOnIntOverflow(ctx)
+	}
+	e.ctx = ctx
+	return ctx
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, node ast.Node, s *scope.Scope) object.Object {
+
+	ctx = e.withContext(ctx)
+
+	// Count the step against the limits of the program. This is checked
+	// first since running out of time also cancels the context.
+	if err := e.limiter.Step(); err != nil {
+		return err
+	}
 
 	// Check for context timeout
 	select {
@@ -143,9 +272,10 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 	default:
 	}
 
-	// Track statement execution for tracing
-	if statement, ok := node.(ast.Statement); ok {
-		if result := e.trackExecution(statement, s); result != nil {
+	// Track statement execution for tracing. Programs are skipped since they
+	// push a frame of their own when they are evaluated.
+	if _, isProgram := node.(*ast.Program); !isProgram {
+		if result := e.trackExecution(node, s); result != nil {
 			return result
 		}
 	}
@@ -163,11 +293,13 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 	case *ast.Prefix:
 		return e.evalPrefixExpression(ctx, node, s)
 	case *ast.Postfix:
-		return e.evalPostfixExpression(s, node.Operator(), node)
+		return e.evalPostfixExpression(ctx, s, node.Operator(), node)
 	case *ast.Infix:
 		return e.evalInfixExpression(ctx, node, s)
 	case *ast.Ternary:
 		return e.evalTernaryExpression(ctx, node, s)
+	case *ast.Propagate:
+		return e.evalPropagate(ctx, node, s)
 	case *ast.In:
 		return e.evalIn(ctx, node, s)
 
@@ -178,6 +310,8 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 		return e.evalIndexExpression(ctx, node, s)
 	case *ast.Slice:
 		return e.evalSliceExpression(ctx, node, s)
+	case *ast.SliceLiteral:
+		return e.evalSliceLiteral(ctx, node, s)
 	case *ast.Bool:
 		return nativeBoolToBooleanObject(node.Value())
 	case *ast.Import:
@@ -192,10 +326,14 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 		return e.evalAssignStatement(ctx, node, s)
 	case *ast.MultiVar:
 		return e.evalMultiVarStatement(ctx, node, s)
+	case *ast.Destructure:
+		return e.evalDestructure(ctx, node, s)
 
 	// Functions
 	case *ast.Func:
 		return e.evalFunctionLiteral(ctx, node, s)
+	case *ast.Struct:
+		return e.evalStruct(ctx, node, s)
 
 	// Calls
 	case *ast.ObjectCall:
@@ -212,6 +350,16 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 		return e.evalFor(ctx, node, s)
 	case *ast.Switch:
 		return e.evalSwitch(ctx, node, s)
+	case *ast.Select:
+		return e.evalSelect(ctx, node, s)
+	case *ast.Match:
+		return e.evalMatch(ctx, node, s)
+	case *ast.Yield:
+		return e.evalYield(ctx, node, s)
+	case *ast.Go:
+		return e.evalGo(ctx, node, s)
+	case *ast.Defer:
+		return e.evalDefer(ctx, node, s)
 	case *ast.Pipe:
 		return e.evalPipe(ctx, node, s)
 	case *ast.Control:
@@ -224,6 +372,8 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 		return object.Nil
 	case *ast.Int:
 		return object.NewInt(node.Value())
+	case *ast.BigInt:
+		return object.NewBigInt(node.Value())
 	case *ast.Float:
 		return object.NewFloat(node.Value())
 	case *ast.String:
@@ -234,6 +384,12 @@ func (e *Evaluator) Evaluate(ctx context.Context, node ast.Node, s *scope.Scope)
 		return e.evalMapLiteral(ctx, node, s)
 	case *ast.Set:
 		return e.evalSetLiteral(ctx, node, s)
+	case *ast.ListComprehension:
+		return e.evalListComprehension(ctx, node, s)
+	case *ast.SetComprehension:
+		return e.evalSetComprehension(ctx, node, s)
+	case *ast.MapComprehension:
+		return e.evalMapComprehension(ctx, node, s)
 	}
 
 	panic(fmt.Sprintf("unknown ast node type: %T", node))
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"strings"
 	"testing"
@@ -580,6 +581,18 @@ func TestMapIndexExpression(t *testing.T) {
 			`{foo:42}["foo"]`,
 			42,
 		},
+		{
+			`{1: 7, "1": 8}[1]`,
+			7,
+		},
+		{
+			`{true: 3, false: 4}[false]`,
+			4,
+		},
+		{
+			`{2.5: 6}[2.5]`,
+			6,
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -626,6 +639,26 @@ myvar
 	// require.True(t, false)
 }
 
+func TestForLoopClosures(t *testing.T) {
+	// Each iteration declares fresh range and body variables, so closures
+	// keep the values of their own iteration, while the variable of a
+	// three-clause loop is shared by all iterations
+	input := `
+byRange := []
+for _, v := range [1, 2, 3] {
+	byRange.append(func() { v })
+}
+byCounter := []
+for i := 0; i < 2; i++ {
+	x := i * 10
+	byCounter.append(func() { [i, x] })
+}
+[byRange[0](), byRange[2](), byCounter[0](), byCounter[1]()]
+`
+	evaluated := testEval(input)
+	require.Equal(t, "[1, 3, [2, 0], [2, 10]]", evaluated.Inspect())
+}
+
 func TestForLoopVariant(t *testing.T) {
 	input := `
 sum := 0
@@ -898,6 +931,18 @@ func TestMisc(t *testing.T) {
 		{`x := [9,8,7]; x[-2:-1]`, []any{int64(8)}},
 		{`x := [9,8,7]; x[-7:-1]`, errors.New("slice error: start index is out of range")},
 		{`x := [9,8,7]; x[1:-7]`, errors.New("slice error: stop index is out of range")},
+		{`x := [9,8,7]; x[::-1]`, []any{int64(7), int64(8), int64(9)}},
+		{`x := [9,8,7,6]; x[::2]`, []any{int64(9), int64(7)}},
+		{`x := [9,8,7,6]; x[2:0:-1]`, []any{int64(7), int64(8)}},
+		{`"hello"[::-2]`, "olh"},
+		{`x := [9,8,7]; x[::0]`, errors.New("slice error: slice step cannot be zero")},
+		{`x := [9,8,7]; x[1:3] = [1, 2, 3]; x`, []any{int64(9), int64(1), int64(2), int64(3)}},
+		{`x := [9,8,7]; x[::2] = [0, 1]; x`, []any{int64(0), int64(8), int64(1)}},
+		{`x := [9,8,7]; x[:] = "a"`, errors.New("type error: slice assignment expected a list (got string)")},
+		{`x := 1; x[:] = [1]`, errors.New("type error: int is not a container")},
+		{`x := [9,8,7]; delete(x, 1:3); x`, []any{int64(9)}},
+		{`x := [9,8,7]; delete(x, ::2); x`, []any{int64(8)}},
+		{`x := [9,8,7]; delete(x, 0); x`, []any{int64(8), int64(7)}},
 		{`1 == 1.0`, true},
 		{`1.0 == 1`, true},
 		{`1 != 1.0`, false},
@@ -929,6 +974,480 @@ func TestMisc(t *testing.T) {
 	}
 }
 
+func TestGoStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`c := chan(); go c.send(42); c.recv()`, int64(42)},
+		{`c := chan(); go func(x) { c.send(x * 2) }(21); c.recv()`, int64(42)},
+		{`c := chan(3); go func() { for i := 0; i < 3; i++ { c.send(i) } }(); [c.recv(), c.recv(), c.recv()]`,
+			[]any{int64(0), int64(1), int64(2)}},
+		{`go 42()`, errors.New("type error: int is not callable")},
+		{`go nope()`, errors.New("name error: \"nope\" is not defined")},
+		{`go chan().nope()`, errors.New("attribute error: chan has no attribute \"nope\"")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`c := chan(1); c.send("hi")
+select {
+case msg := c.recv():
+	msg
+}`, "hi"},
+		{`c := chan(1)
+select {
+case c.send(1):
+	"sent"
+default:
+	"skipped"
+}`, "sent"},
+		{`c := chan()
+select {
+case c.recv():
+	"received"
+default:
+	"skipped"
+}`, "skipped"},
+		{`c := chan(); c.close()
+select {
+case value, ok := c.recv():
+	[value, ok]
+}`, []any{nil, false}},
+		{`x := 42
+select {
+case x.recv():
+	1
+}`, errors.New("type error: select case expected a chan (got int)")},
+		{`c := chan(); c.close(); c.close()`, errors.New("value error: close of closed channel")},
+		{`c := chan(1); c.close(); c.send(1)`, errors.New("value error: send on closed channel")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	route := `func route(msg) {
+	return match msg {
+	case {"type": "user", "id": int(id)}:
+		"user " + string(id)
+	case {"type": "batch", "items": [first, ...rest]} if len(rest) > 0:
+		[first, rest]
+	case {"type": t, ...fields}:
+		[t, fields]
+	case ok(value):
+		value
+	case err(msg):
+		"failed: " + msg
+	case 0, -1, "zero":
+		"small"
+	case float(f):
+		f * 2
+	default:
+		"unknown"
+	}
+}
+`
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`route({"type": "user", "id": 5, "name": "a"})`, "user 5"},
+		{`route({"type": "user", "id": "5"})`, []any{"user", map[string]any{"id": "5"}}},
+		{`route({"type": "batch", "items": [1, 2, 3]})`, []any{int64(1), []any{int64(2), int64(3)}}},
+		{`route({"type": "batch", "items": [1]})`, []any{"batch", map[string]any{"items": []any{int64(1)}}}},
+		{`route(ok(3))`, int64(3)},
+		{`route(err("boom"))`, "failed: boom"},
+		{`route(-1)`, "small"},
+		{`route("zero")`, "small"},
+		{`route(1.5)`, 3.0},
+		{`route([1])`, "unknown"},
+		{`match [1, 2] {
+case [a]:
+	a
+case [a, b, c]:
+	c
+}`, nil},
+		{`x := 1
+match 2 {
+case x:
+	x
+}`, int64(2)},
+		{`x := 1
+match 2 {
+case y:
+	x = y
+}
+[x, type(y)]`, errors.New("name error: \"y\" is not defined")},
+		{`match 1 {
+case x if x.foo:
+	1
+}`, errors.New("attribute error: int object has no attribute \"foo\"")},
+	}
+	for _, tt := range tests {
+		input := tt.input
+		if strings.HasPrefix(input, "route(") {
+			input = route + input
+		}
+		require.Equal(t, tt.expected, testEval(input).Interface(), tt.input)
+	}
+}
+
+func TestDestructure(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"[a, [b, c]] := [1, [2, 3]]; [c, b, a]", []any{int64(3), int64(2), int64(1)}},
+		{"first, ...rest := [1, 2, 3]; [first, rest]", []any{int64(1), []any{int64(2), int64(3)}}},
+		{"[_, ...rest] := [1]; rest", []any{}},
+		{`{name, age = 30} := {"name": "ada"}; [name, age]`, []any{"ada", int64(30)}},
+		{`{"id": id, ...others} := {"id": 1, "x": 2}; [id, others]`, []any{int64(1), map[string]any{"x": int64(2)}}},
+		{`{a, b = a + 1} := {"a": 1}; b`, int64(2)},
+		{"a := 1; b := 2; [a, b] = [b, a]; [a, b]", []any{int64(2), int64(1)}},
+		{"x := 0; y := 0; x, y = [3, 4]; x + y", int64(7)},
+		{`total := 0
+for i, [a, b] := range [[1, 2], [3, 4]] {
+	total += i * a * b
+}
+total`, int64(12)},
+		{`names := []
+for _, {name, title = "none"} := range [{"name": "a"}, {"name": "b", "title": "dr"}] {
+	names.append(name + ":" + title)
+}
+names`, []any{"a:none", "b:dr"}},
+		{"[a, b] := [1]", errors.New("eval error: invalid destructuring assignment (list size: 1; targets: 2)")},
+		{"[a, b, ...c] := [1]", errors.New("eval error: invalid destructuring assignment (list size: 1; targets: at least 2)")},
+		{"[a] := 1", errors.New("type error: cannot destructure int as a list")},
+		{"{a} := [1]", errors.New("type error: cannot destructure list as a map")},
+		{"{a} := {}", errors.New("key error: \"a\"")},
+		{"[a] = [1]", errors.New("name error: \"a\" is not defined")},
+		{"const a = 1\n[a] = [2]", errors.New("assignment error: \"a\" is read-only")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"[x * 2 for x in [3, -1, 4] if x > 0]", []any{int64(6), int64(8)}},
+		{`{k: v + 1 for k, v in {"a": 1, "b": 2}}`, map[string]any{"a": int64(2), "b": int64(3)}},
+		{"{x % 2 for x in [1, 2, 3]}", []any{int64(0), int64(1)}},
+		{`[k for k in {"b": 1, "a": 2}]`, []any{"a", "b"}},
+		{`[c for c in "hi"]`, []any{"h", "i"}},
+		{"[i for i, _ in [5, 6, 7] if i > 0]", []any{int64(1), int64(2)}},
+		{"[[a, b] for a in [1, 2] for b in [1, 2] if a != b]", []any{[]any{int64(1), int64(2)}, []any{int64(2), int64(1)}}},
+		{`[name for {name} in [{"name": "a"}, {"name": "b"}]]`, []any{"a", "b"}},
+		{"x := 1; y := [x for x in [2, 3]]; [x, y]", []any{int64(1), []any{int64(2), int64(3)}}},
+		{"fns := [func() { i } for i in [1, 2]]; [f() for f in fns]", []any{int64(1), int64(2)}},
+		{"[x for x in []]", []any{}},
+		{"[x for x in 5]", errors.New("type error: int is not a container")},
+		{"{x: 1 for x in [1]}", map[any]any{int64(1): int64(1)}},
+		{"{x: 1 for x in [[1]]}", errors.New("type error: list object is unhashable")},
+		{"{[x] for x in [1]}", errors.New("type error: list object is unhashable")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestStruct(t *testing.T) {
+	point := `struct Point {
+	x
+	y = 0
+
+	func (p) sum() {
+		return p.x + p.y
+	}
+
+	func (p) scale(factor) {
+		p.x *= factor
+		p.y *= factor
+		return p
+	}
+}
+`
+	account := `struct Account {
+	owner, balance
+
+	func (a) init(owner, balance=10) {
+		a.owner = owner
+		a.balance = balance
+	}
+}
+`
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{point + `Point(1, 2).sum()`, int64(3)},
+		{point + `Point(1).sum()`, int64(1)},
+		{point + `p := Point(); p.x = 5; p.x`, int64(5)},
+		{point + `Point(1, 2).scale(3).sum()`, int64(9)},
+		{point + `p := Point(1, 2); Point.sum(p)`, int64(3)},
+		{point + `type(Point(1, 2))`, "Point"},
+		{point + `type(Point)`, "struct"},
+		{point + `Point(1, 2) == Point(1, 2)`, true},
+		{point + `Point(1, 2) == Point(1, 3)`, false},
+		{point + `Point(1, 2) == {x: 1, y: 2}`, false},
+		{point + `Point(1, 2)`, map[string]any{"x": int64(1), "y": int64(2)}},
+		{account + `a := Account("ben"); [a.owner, a.balance]`, []any{"ben", int64(10)}},
+		{account + `Account("noa", 5).balance`, int64(5)},
+		{point + `p := Point(); p.z = 1`, errors.New("attribute error: Point has no field \"z\"")},
+		{point + `Point(1, 2, 3)`, errors.New("type error: Point() takes at most 2 arguments (3 given)")},
+		{point + `Point(1).nope`, errors.New("attribute error: Point object has no attribute \"nope\"")},
+		{point + `Point = 1`, errors.New("assignment error: \"Point\" is read-only")},
+		{`x := 1; x.y = 2`, errors.New("type error: cannot set attribute \"y\" on int")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestStructInspect(t *testing.T) {
+	input := `struct Point { x, y }
+[Point, Point(1, "a")]`
+	require.Equal(t, `[struct(Point), Point{x: 1, y: "a"}]`, testEval(input).Inspect())
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`log := []
+func f() {
+	defer log.append(1)
+	defer log.append(2)
+	log.append(0)
+}
+f()
+log`, []any{int64(0), int64(2), int64(1)}},
+		{`log := []
+func f(x) {
+	defer log.append(x)
+	x = 5
+	return x * 2
+}
+[f(1), log]`, []any{int64(10), []any{int64(1)}}},
+		{`log := []
+func f() {
+	for i := 0; i < 3; i++ {
+		defer func(n) { log.append(n) }(i)
+		if i == 1 {
+			return "early"
+		}
+	}
+}
+[f(), log]`, []any{"early", []any{int64(1), int64(0)}}},
+		{`log := []
+func f() {
+	defer log.append("cleanup")
+	return 1 / 0
+}
+[try(f(), "failed"), log]`, []any{"failed", []any{"cleanup"}}},
+		{`func f() {
+	defer func() { 1 / 0 }()
+	return "ok"
+}
+f()`, errors.New("eval error: int divided by zero")},
+		{`log := []
+defer log.append("last")
+log.append("first")
+log`, []any{"first", "last"}},
+		{`defer 1()`, errors.New("type error: int is not callable")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`func sum(...nums) {
+	total := 0
+	for _, n := range nums { total += n }
+	return total
+}
+items := [1, 2, 3]
+[sum(), sum(1, 2), sum(...items), sum(0, ...items, 10)]`, []any{int64(0), int64(3), int64(6), int64(16)}},
+		{`func f(a, b = 2, ...rest) { [a, b, rest] }
+[f(1), f(1, 3), f(1, 3, 4, 5)]`, []any{
+			[]any{int64(1), int64(2), []any{}},
+			[]any{int64(1), int64(3), []any{}},
+			[]any{int64(1), int64(3), []any{int64(4), int64(5)}},
+		}},
+		{`func format(f, ...args) { sprintf(f, ...args) }
+format("%d-%s", 1, "a")`, "1-a"},
+		{`struct P { func (p) f(...args) { args } }
+P().f(...[1, 2])`, []any{int64(1), int64(2)}},
+		{`func f(a, ...rest) { rest }
+f()`, errors.New("type error: function expected at least 1 arguments (0 given)")},
+		{`func f(...rest) { rest }
+f(...1)`, errors.New("type error: spread argument must be a list (int given)")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestKeywordArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`func f(a, b = 2, c = 3) { [a, b, c] }
+[f(1, c=9), f(a=0), f(1, c=8, b=7)]`, []any{
+			[]any{int64(1), int64(2), int64(9)},
+			[]any{int64(0), int64(2), int64(3)},
+			[]any{int64(1), int64(7), int64(8)},
+		}},
+		{`func f(a, ...rest) { [a, rest] }
+[f(1, 2, 3), f(a=4)]`, []any{
+			[]any{int64(1), []any{int64(2), int64(3)}},
+			[]any{int64(4), []any{}},
+		}},
+		{`struct P { x; y = 5 }
+[P(y=1, x=2).x, P(x=3).y]`, []any{int64(2), int64(5)}},
+		{`struct C {
+	n
+	func (c) init(n = 1) { c.n = n }
+	func (c) times(by = 2) { c.n * by }
+}
+[C(n=4).times(by=3), C().times()]`, []any{int64(12), int64(2)}},
+		{`sorted(["pear", "fig", "banana"], key=len, reverse=true)`, []any{"banana", "pear", "fig"}},
+		{`call(func(a, b) { a - b }, b=1, a=3)`, int64(2)},
+		{`func f(a) { a }
+f(b=1)`, errors.New(`type error: f() got an unexpected keyword argument "b"`)},
+		{`func f(a) { a }
+f(1, a=2)`, errors.New(`type error: f() got multiple values for argument "a"`)},
+		{`func f(a, b) { a }
+f(b=2)`, errors.New(`type error: f() missing argument "a"`)},
+		{`len("abc", x=1)`, errors.New(`type error: len() got an unexpected keyword argument "x"`)},
+		{`sorted([1], reversed=true)`, errors.New(`type error: sorted() got an unexpected keyword argument "reversed"`)},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`[0xff, 0o17, 0b1010, 1_000, 0x_FF_FF]`, []any{int64(255), int64(15), int64(10), int64(1000), int64(65535)}},
+		{`[6 & 3, 6 ^ 3, 6 &^ 3, 1 << 4, -16 >> 2, ^0]`, []any{int64(2), int64(5), int64(4), int64(16), int64(-4), int64(-1)}},
+		{`1 | 2 | 4`, int64(7)},
+		{`x := 1 | 6; x`, int64(7)},
+		{`func double(x) { x * 2 }
+3 | double | 1`, int64(7)},
+		{`x := 0b1100
+x &= 0b1010
+x |= 1
+x ^= 0b11
+x <<= 2
+x >>= 1
+x &^= 4
+x`, int64(16)},
+		{`struct P { flags = 0 }
+p := P()
+p.flags |= 4
+p.flags`, int64(4)},
+		{`1 << -1`, errors.New("eval error: negative shift count: -1")},
+		{`^1.5`, errors.New("type error: expected int to follow ^ operator (got float)")},
+		{`1.0 & 2`, errors.New("type error: unsupported operand types for &: float and int")},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, testEval(tt.input).Interface(), tt.input)
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`func f(r) { ok(r? + 1) }
+[f(ok(1)), f(err("bad"))]`, []any{int64(2), errors.New("bad")}},
+		{`log := []
+func f(r) {
+	defer log.append("deferred")
+	v := r?
+	log.append(v)
+	return ok(v)
+}
+f(err("x"))
+log`, []any{"deferred"}},
+		{`func f(r) { try(r?, 0) }
+f(err("bad")).is_err()`, true},
+		{`[ok(1), err("no")].map(func(r) { r? * 10 })[1].is_err()`, true},
+		{`err("top")?; 5`, errors.New("top")},
+		{`ok(5)?`, int64(5)},
+		{`5?`, errors.New("type error: ? operator expected a result (got int)")},
+	}
+	for _, tt := range tests {
+		result := testEval(tt.input)
+		if r, ok := result.(*object.Result); ok {
+			if r.IsOk() {
+				result = r.Unwrap()
+			} else {
+				result = r.UnwrapErr()
+			}
+		}
+		require.Equal(t, tt.expected, result.Interface(), tt.input)
+	}
+}
+
+func TestRuntimeErrorPosition(t *testing.T) {
+	input := `func add(a, b) {
+	return a + b
+}
+func run() {
+	add(1, "x")
+}
+run()`
+	result := testEval(input)
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok, "got %T", result)
+	rtErr, ok := errObj.RuntimeError()
+	require.True(t, ok)
+	require.Equal(t, "type error: unsupported operand types for +: int and string", rtErr.Error())
+	require.Equal(t, 2, rtErr.StartPosition().LineNumber())
+	require.Equal(t, 11, rtErr.StartPosition().ColumnNumber())
+	var trace []string
+	for _, frame := range rtErr.Stack() {
+		trace = append(trace, fmt.Sprintf("%s:%d", frame.Name, frame.Position.LineNumber()))
+	}
+	require.Equal(t, []string{"main:7", "run:5", "add:2"}, trace)
+}
+
+func TestSelectTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	program, err := parser.Parse(`chan().recv()`)
+	require.Nil(t, err)
+	result := New(Opts{}).Evaluate(ctx, program, scope.New(scope.Opts{}))
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok)
+	require.Contains(t, errObj.Message().Value(), "deadline")
+}
+
 func FuzzEval(f *testing.F) {
 	testcases := []string{
 		"1/2+4+=5-[1,2,{}]",
@@ -968,3 +1487,299 @@ func FuzzEval(f *testing.F) {
 		}
 	})
 }
+
+// vector is a custom object type used to test operator overloading
+type vector struct {
+	x, y int64
+}
+
+func (v *vector) Type() object.Type                         { return "vector" }
+func (v *vector) Inspect() string                           { return fmt.Sprintf("vector(%d, %d)", v.x, v.y) }
+func (v *vector) Interface() interface{}                    { return v }
+func (v *vector) GetAttr(name string) (object.Object, bool) { return nil, false }
+func (v *vector) IsTruthy() bool                            { return true }
+
+func (v *vector) Equals(other object.Object) object.Object {
+	o, ok := other.(*vector)
+	return object.NewBool(ok && v.x == o.x && v.y == o.y)
+}
+
+func (v *vector) BinaryOp(ctx context.Context, operator string, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *vector:
+		switch operator {
+		case "+":
+			return &vector{v.x + right.x, v.y + right.y}
+		case "-":
+			return &vector{v.x - right.x, v.y - right.y}
+		}
+	case *object.Int:
+		if operator == "*" {
+			return &vector{v.x * right.Value(), v.y * right.Value()}
+		}
+	}
+	return nil
+}
+
+func (v *vector) ReflectedBinaryOp(ctx context.Context, operator string, left object.Object) object.Object {
+	if operator == "*" {
+		return v.BinaryOp(ctx, operator, left)
+	}
+	return nil
+}
+
+func (v *vector) UnaryOp(ctx context.Context, operator string) object.Object {
+	if operator == "-" {
+		return &vector{-v.x, -v.y}
+	}
+	return nil
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a + b", "vector(4, 6)"},
+		{"b - a", "vector(2, 2)"},
+		{"a * 3", "vector(3, 6)"},
+		{"2 * b", "vector(6, 8)"},
+		{"-a", "vector(-1, -2)"},
+		{"c := a; c += b; c", "vector(4, 6)"},
+		{"a == b", "false"},
+		{"a != b", "true"},
+		{"a + (b - a) == b", "true"},
+		{"a / b", "syntax error: invalid operation / for types: vector and vector"},
+		{"a + 1", "type error: unsupported operand types for +: vector and int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.Parse(tt.input)
+			require.Nil(t, err)
+			s := scope.New(scope.Opts{})
+			require.Nil(t, s.Declare("a", &vector{1, 2}, false))
+			require.Nil(t, s.Declare("b", &vector{3, 4}, false))
+			result := New(Opts{}).Evaluate(context.Background(), program, s)
+			if errObj, ok := result.(*object.Error); ok {
+				require.Equal(t, tt.expected, errObj.Message().Value())
+				return
+			}
+			require.Equal(t, tt.expected, result.Inspect())
+		})
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`func f(x: int, y: string = "a") -> string { y + string(x) }; f(2)`, "a2"},
+		{`func f(x: int) { x }; f("a")`, errors.New(`type error: f() argument "x" must be int (got string)`)},
+		{`func f(x, y: float = 1) { y }; f(1)`, errors.New(`type error: f() argument "y" must be float (got int)`)},
+		{`func f(x: int) { x }; f(x=1.5)`, errors.New(`type error: f() argument "x" must be int (got float)`)},
+		{`func f(...xs: int) { xs }; f(1, 2, "3")`, errors.New(`type error: f() argument "xs" must be int (got string)`)},
+		{`func f(x: any, g: function) { g(x) }; f(1, string)`, "1"},
+		{`func f() -> int { return "a" }; f()`, errors.New(`type error: f() must return int (got string)`)},
+		{`func f() -> nil { }; f()`, nil},
+		{`f := func(x) -> list { [x] }; f(1)`, []any{int64(1)}},
+		{`func f(n: int) -> generator { yield n }; list(f(1))`, []any{int64(1)}},
+		{`func f(n: int) { yield n }; f("a")`, errors.New(`type error: f() argument "n" must be int (got string)`)},
+		{"struct P { x; func (p) add(n: int) -> int { p.x + n } }\nP(1).add(2)", int64(3)},
+		{"struct P { x }\nfunc f(p: P) -> P { p }; f(P(1)).x", int64(1)},
+		{"struct P { x }\nfunc f(p: P) { p }; f(1)", errors.New(`type error: f() argument "p" must be P (got int)`)},
+		{`var x: int = 1; x`, int64(1)},
+		{`var x: int = "a"`, errors.New(`type error: "x" must be int (got string)`)},
+		{`const x: float = 1`, errors.New(`type error: "x" must be float (got int)`)},
+		{`x: set := {1}; x = 2; x`, errors.New(`type error: "x" must be set (got int)`)},
+		{`var limit: int = 10; limit = "s"`, errors.New(`type error: "limit" must be int (got string)`)},
+		{`var limit: int = 10; limit += 0.5`, errors.New(`type error: "limit" must be int (got float)`)},
+		{`var limit: int = 10; limit += 5; limit++; limit`, int64(16)},
+		{`var n: int = 1; [n, _] = ["a", 2]`, errors.New(`type error: "n" must be int (got string)`)},
+		{`var n: int = 1; func f() { n = "a" }; f()`, errors.New(`type error: "n" must be int (got string)`)},
+		{`var n: int = 1; func f() { n := "a"; n }; f()`, "a"},
+		{`func f(x: int) { x = "a" }; f(1)`, errors.New(`type error: "x" must be int (got string)`)},
+		{`func f(...xs: int) { xs = [1]; xs }; f()`, []any{int64(1)}},
+	}
+	for _, tt := range tests {
+		result := testEval(tt.input)
+		if result == object.Nil {
+			require.Nil(t, tt.expected, tt.input)
+			continue
+		}
+		require.Equal(t, tt.expected, result.Interface(), tt.input)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"func f() { yield 1; yield 2 }; list(f())", []any{int64(1), int64(2)}},
+		{"func f(n) { for i := 0; i < n; i++ { yield i * i } }; [x for _, x in f(4)]", []any{int64(0), int64(1), int64(4), int64(9)}},
+		{"func f() { for i := 0; true; i++ { yield i } }; g := f(); [g.next().value, g.next().value, g.next().key]", []any{int64(0), int64(1), int64(2)}},
+		{"func f() { yield 1 }; g := f(); g.next(); g.next()", nil},
+		{"func f() { yield 1; yield 2 }; g := f(); g.close(); g.next()", nil},
+		{"func f() { yield }; list(f())", []any{nil}},
+		{"x := []; func f() { x.append(1); yield 1; x.append(2) }; g := f(); [len(x), g.next().value, len(x)]", []any{int64(0), int64(1), int64(1)}},
+		{"func f() { for { yield 1 } }; s := 0; for _, x := range f() { s += x; if s == 3 { break } }; s", int64(3)},
+		{"func f() { yield 1; 1 / 0 }; list(f())", errors.New("eval error: int divided by zero")},
+	}
+	for _, tt := range tests {
+		result := testEval(tt.input)
+		if result == object.Nil {
+			require.Nil(t, tt.expected, tt.input)
+			continue
+		}
+		require.Equal(t, tt.expected, result.Interface(), tt.input)
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := 123456789012345678901234567890; [x, type(x)]", `[123456789012345678901234567890, "bigint"]`},
+		{"bigint(2) ** 100", "1267650600228229401496703205376"},
+		{"x := bigint(10); [x + 1, x - 1, x * 2, x / 3, x % 3, -x, ^x]", "[11, 9, 20, 3, 1, -10, -11]"},
+		{"x := bigint(6); [x & 3, x | 1, x ^ 2, x &^ 2, x << 64, x >> 1]", "[2, 7, 4, 4, 110680464442257309696, 3]"},
+		{"x := bigint(2); [x == 2, 2 == x, x < 3, x > 1.5, x + 0.5, x == 2.0]", "[true, true, true, true, 2.5, true]"},
+		{"x := bigint(1); x++; x += 1; [x, type(x)]", `[3, "bigint"]`},
+		{`[int(bigint(7)), float(bigint(7)), string(bigint(7)), bigint("0xff"), bigint(2.9)]`, `[7, 7, "7", 255, 2]`},
+		{`{bigint(1): "a"}[1]`, `"a"`},
+		{"match bigint(3) { case 3: true; default: false }", "true"},
+		{"match 99999999999999999999 { case 99999999999999999999: true; default: false }", "true"},
+		{"bigint(1) / 0", "eval error: bigint divided by zero"},
+		{"bigint(2) ** -1", "eval error: negative bigint exponent: -1"},
+		{"3 ** 100000000", "eval error: bigint exponent too large: 100000000"},
+		{"bigint(10) ** 1000000", "eval error: bigint exponent too large: 1000000"},
+		{"[bigint(0) ** 100000000, bigint(1) ** 100000000, bigint(-1) ** 100000001]", "[0, 1, -1]"},
+		{"len(string(2 ** 1000000))", "301030"},
+		{"4 ** 600000", "eval error: bigint exponent too large: 600000"},
+		{`bigint(1) + "a"`, "type error: unsupported operand types for +: bigint and string"},
+		{"int(2 ** 64)", "value error: bigint too large for int(): 18446744073709551616"},
+		{`bigint("1.5")`, `value error: invalid literal for bigint(): "1.5"`},
+		{`x := int("123456789012345678901234567890"); [x, type(x)]`, `[123456789012345678901234567890, "bigint"]`},
+		{`[int("-0x10000000000000000"), int("9223372036854775807")]`, "[-18446744073709551616, 9223372036854775807]"},
+		{`int("99999999999999999999x")`, `value error: invalid literal for int(): "99999999999999999999x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := testEval(tt.input)
+			if errObj, ok := result.(*object.Error); ok {
+				require.Equal(t, tt.expected, errObj.Message().Value())
+				return
+			}
+			require.Equal(t, tt.expected, result.Inspect())
+		})
+	}
+}
+
+func TestIntOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		promoted string
+		err      string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808", "eval error: int overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "-9223372036854775809", "eval error: int overflow: -9223372036854775807 - 2"},
+		{"4294967296 * 4294967296", "18446744073709551616", "eval error: int overflow: 4294967296 * 4294967296"},
+		{"x := -9223372036854775807 - 1; x / -1", "9223372036854775808", "eval error: int overflow: -9223372036854775808 / -1"},
+		{"x := -9223372036854775807 - 1; -x", "9223372036854775808", "eval error: int overflow: -(-9223372036854775808)"},
+		{"3 ** 41", "36472996377170786403", "eval error: int overflow: 3 ** 41"},
+		{"1 << 63", "9223372036854775808", "eval error: int overflow: 1 << 63"},
+		{"x := 9223372036854775807; x++; x", "9223372036854775808", "eval error: int overflow: 9223372036854775807 + 1"},
+		{"x := 9223372036854775807; x += 1; x", "9223372036854775808", "eval error: int overflow: 9223372036854775807 + 1"},
+		{`int("9223372036854775808")`, "9223372036854775808", `eval error: int overflow: int("9223372036854775808")`},
+		{"[2 ** 62, 3 ** 39, 2 ** -1, -4611686018427387904 * 2]", "[4611686018427387904, 4052555153018976267, 0, -9223372036854775808]", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.Parse(tt.input)
+			require.Nil(t, err)
+			result := New(Opts{}).Evaluate(context.Background(), program, scope.New(scope.Opts{}))
+			require.Equal(t, tt.promoted, result.Inspect())
+			result = New(Opts{IntOverflow: ErrorOnOverflow}).Evaluate(context.Background(), program, scope.New(scope.Opts{}))
+			if tt.err == "" {
+				require.Equal(t, tt.promoted, result.Inspect())
+				return
+			}
+			errObj, ok := result.(*object.Error)
+			require.True(t, ok, "got %s", result.Inspect())
+			require.Equal(t, tt.err, errObj.Message().Value())
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`decimal("0.1") + decimal("0.2")`, "0.3"},
+		{`decimal("0.1") + decimal("0.2") == decimal("0.3")`, "true"},
+		{`x := decimal("19.99"); [x * 3, x - 1, x / 4, x % 5, -x, x ** 2]`, "[59.97, 18.99, 4.9975, 4.99, -19.99, 399.6001]"},
+		{`x := decimal("1.10"); x += 1; x++; [x, type(x)]`, `[3.10, "decimal"]`},
+		{`x := decimal("2.5"); [x > 2, x <= 2, x == 2.5, x.scale()]`, "[true, false, false, 1]"},
+		{`x := decimal("2.345"); [x.round(2), x.round(2, "down"), x.round(0, "ceiling"), x.round(5)]`, "[2.34, 2.34, 3, 2.34500]"},
+		{`decimal(10).div(3, 4, "half_up")`, "3.3333"},
+		{`[decimal(0.1), decimal(3), decimal(bigint(2) ** 70), decimal("1e2")]`, "[0.1, 3, 1180591620717411303424, 100]"},
+		{`x := decimal("7.9"); [int(x), float(x), string(x), bigint(-x)]`, `[7, 7.9, "7.9", -7]`},
+		{`{decimal("1.0"): "a", decimal("1.5"): "b"}[1]`, `"a"`},
+		{`sorted([decimal("1.5"), 1, decimal("0.5")])`, "[0.5, 1, 1.5]"},
+		{`decimal("1") / 0`, "eval error: decimal divided by zero"},
+		{`decimal("1") + 0.5`, "type error: unsupported operand types for +: decimal and float"},
+		{`decimal("abc")`, `value error: invalid literal for decimal(): "abc"`},
+		{`decimal("1").round(1, "sideways")`, `value error: decimal.round() unknown rounding mode: "sideways"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := testEval(tt.input)
+			if errObj, ok := result.(*object.Error); ok {
+				require.Equal(t, tt.expected, errObj.Message().Value())
+				return
+			}
+			require.Equal(t, tt.expected, result.Inspect())
+		})
+	}
+}
+
+func TestBytesInspect(t *testing.T) {
+	// The inspected form of bytes evaluates to the same bytes
+	for _, value := range [][]byte{nil, []byte("héllo"), []byte("a\"b\\c\n\r\td"), {0, 255}, {0xc3}, []byte("a\x01")} {
+		b := object.NewBytes(value)
+		result := testEval(b.Inspect())
+		require.Equal(t, object.True, b.Equals(result), b.Inspect())
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`b := bytes("héllo"); [len(b), b[0], b[-1], b[1:3], type(b)]`, `[6, 104, 111, bytes("é"), "bytes"]`},
+		{`b := bytes([104, 105]); b += bytes([0]); [b, list(b), 105 in b, bytes("hi") in b]`, `[bytes("686900", "hex"), [104, 105, 0], true, true]`},
+		{`[bytes("aGk=", "base64"), bytes("ff00", "hex"), bytes("é", "latin-1"), bytes(2)]`, `[bytes("hi"), bytes("ff00", "hex"), bytes("e9", "hex"), bytes("0000", "hex")]`},
+		{`b := bytes("héllo"); ["é" in b, "x" in b, 104.0 in b, bigint(104) in b, 256 in b]`, "[true, false, true, true, false]"},
+		{`b := bytes("é"); [b.decode(), string(b), string(b, "hex"), b.hex(), string(bytes([233]), "latin-1")]`, `["é", "é", "c3a9", "c3a9", "é"]`},
+		{`[bytes("a") < bytes("b"), bytes("a") == bytes("a"), bytes("a") == "a", {bytes("k"): 1}[bytes("k")]]`, "[true, true, false, 1]"},
+		{`bytes([256])`, "value error: bytes() list items must be in range 0-255 (got 256)"},
+		{`bytes([255]).decode()`, "value error: bytes are not valid utf-8"},
+		{`bytes("é", "ascii")`, "value error: cannot encode 'é' as ascii"},
+		{`bytes("a") + "b"`, "type error: unsupported operand types for +: bytes and string"},
+		{`b := bytes("a"); b[0] = 98`, "eval error: bytes does not support set item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := testEval(tt.input)
+			if errObj, ok := result.(*object.Error); ok {
+				require.Equal(t, tt.expected, errObj.Message().Value())
+				return
+			}
+			require.Equal(t, tt.expected, result.Inspect())
+		})
+	}
+}
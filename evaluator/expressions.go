@@ -13,13 +13,21 @@ func (e *Evaluator) evalExpressions(
 	exps []ast.Expression,
 	s *scope.Scope,
 ) []object.Object {
-	values := make([]object.Object, len(exps))
-	for i, exp := range exps {
+	values := make([]object.Object, 0, len(exps))
+	for _, exp := range exps {
+		if spread, ok := exp.(*ast.Spread); ok {
+			items, err := e.evalSpread(ctx, spread, s)
+			if err != nil {
+				return []object.Object{err}
+			}
+			values = append(values, items...)
+			continue
+		}
 		value := e.Evaluate(ctx, exp, s)
 		if object.IsError(value) {
 			return []object.Object{value}
 		}
-		values[i] = value
+		values = append(values, value)
 	}
 	return values
 }
@@ -29,9 +37,68 @@ func (e *Evaluator) evalExpressionsIgnoreErrors(
 	exps []ast.Expression,
 	s *scope.Scope,
 ) []object.Object {
-	values := make([]object.Object, len(exps))
-	for i, exp := range exps {
-		values[i] = e.Evaluate(ctx, exp, s)
+	values := make([]object.Object, 0, len(exps))
+	for _, exp := range exps {
+		if spread, ok := exp.(*ast.Spread); ok {
+			items, err := e.evalSpread(ctx, spread, s)
+			if err != nil {
+				values = append(values, err)
+			} else {
+				values = append(values, items...)
+			}
+			continue
+		}
+		values = append(values, e.Evaluate(ctx, exp, s))
 	}
 	return values
 }
+
+// evalArguments evaluates the arguments of a call. Keyword arguments, which
+// always follow the positional ones, are returned in a map that is nil if
+// there are none.
+func (e *Evaluator) evalArguments(
+	ctx context.Context,
+	exps []ast.Expression,
+	s *scope.Scope,
+) ([]object.Object, *object.Map, *object.Error) {
+	positional := len(exps)
+	for i, exp := range exps {
+		if _, ok := exp.(*ast.KeywordArg); ok {
+			positional = i
+			break
+		}
+	}
+	args := e.evalExpressions(ctx, exps[:positional], s)
+	if len(args) == 1 {
+		if err, ok := args[0].(*object.Error); ok {
+			return nil, nil, err
+		}
+	}
+	if positional == len(exps) {
+		return args, nil, nil
+	}
+	kwargs := object.NewMap(nil)
+	for _, exp := range exps[positional:] {
+		keyword := exp.(*ast.KeywordArg)
+		value := e.Evaluate(ctx, keyword.Value(), s)
+		if err, ok := value.(*object.Error); ok {
+			return nil, nil, err
+		}
+		kwargs.Set(keyword.Name(), value)
+	}
+	return args, kwargs, nil
+}
+
+// evalSpread evaluates a spread argument, which must be a list, and returns
+// the items to pass as individual arguments.
+func (e *Evaluator) evalSpread(ctx context.Context, node *ast.Spread, s *scope.Scope) ([]object.Object, *object.Error) {
+	value := e.Evaluate(ctx, node.Value(), s)
+	if err, ok := value.(*object.Error); ok {
+		return nil, err
+	}
+	list, ok := value.(*object.List)
+	if !ok {
+		return nil, object.Errorf("type error: spread argument must be a list (%s given)", value.Type())
+	}
+	return list.Value(), nil
+}
@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/cloudcmds/tamarin/ast"
@@ -13,54 +14,173 @@ import (
 func (e *Evaluator) evalFunctionLiteral(ctx context.Context, node *ast.Func, s *scope.Scope) object.Object {
 	if node.Name() != nil {
 		name := node.Name().String()
-		fn := object.NewFunction(name, node.Parameters(), node.Body(), node.Defaults(), s)
+		fn := object.NewFunction(name, node.Parameters(), node.Body(), node.Defaults(), s, node.Variadic(), node.Generator(), node.Types())
 		if err := s.Declare(name, fn, true); err != nil {
 			return object.NewError(err)
 		}
 		return object.Nil
 	}
-	return object.NewFunction("", node.Parameters(), node.Body(), node.Defaults(), s)
+	return object.NewFunction("", node.Parameters(), node.Body(), node.Defaults(), s, node.Variadic(), node.Generator(), node.Types())
+}
+
+// Call invokes a Tamarin function or builtin with the given arguments.
+func (e *Evaluator) Call(ctx context.Context, fn object.Object, args []object.Object) object.Object {
+	return e.CallWithKwargs(ctx, fn, args, nil)
+}
+
+// CallWithKwargs invokes a Tamarin function or builtin with the given
+// positional and keyword arguments. The keyword arguments may be nil.
+func (e *Evaluator) CallWithKwargs(ctx context.Context, fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
+	ctx = object.WithCallFunc(ctx, e.getCallFunc())
+	ctx = object.WithKwargsCallFunc(ctx, e.getKwargsCallFunc())
+	return e.applyFunctionWithKwargs(ctx, nil, fn, args, kwargs)
 }
 
 func (e *Evaluator) applyFunction(ctx context.Context, s *scope.Scope, fn object.Object, args []object.Object) object.Object {
+	return e.applyFunctionWithKwargs(ctx, s, fn, args, nil)
+}
+
+// applyFunctionWithKwargs calls a function with the given positional and
+// keyword arguments. The keyword arguments are nil if there are none.
+func (e *Evaluator) applyFunctionWithKwargs(ctx context.Context, s *scope.Scope, fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
 		// Use the function's scope, not the current execution scope! This is
 		// what enables closures to work as expected!
-		nestedScope, err := e.newFunctionScope(ctx, fn.Scope().(*scope.Scope), fn, args)
+		nestedScope, err := e.newFunctionScope(ctx, fn.Scope().(*scope.Scope), fn, args, kwargs)
 		if err != nil {
 			return object.NewError(err)
 		}
+		if err := e.checkParams(fn, nestedScope); err != nil {
+			return err
+		}
+		if fn.Generator() {
+			return e.newGenerator(ctx, fn, nestedScope)
+		}
 		funcBody := fn.Body()
-		e.stack.Push(stack.NewFrame(stack.FrameOpts{
+		frame := stack.NewFrame(stack.FrameOpts{
 			Name:  fn.Name(),
 			Scope: nestedScope,
-		}))
+		})
+		if err := e.stack.Push(frame); err != nil {
+			return err
+		}
 		defer e.stack.Pop()
 		result := e.Evaluate(ctx, funcBody, nestedScope)
-		return e.upwrapReturnValue(result)
+		result = e.runDeferred(ctx, frame, unwrapPropagation(e.upwrapReturnValue(result)))
+		if object.IsError(result) {
+			return result
+		}
+		if err := CheckResult(fn.Name(), fn.Types(), result); err != nil {
+			return err
+		}
+		return result
 	case *object.Builtin:
-		e.stack.Push(stack.NewFrame(stack.FrameOpts{
-			Name:  fn.Key(),
+		return e.applyBuiltin(ctx, s, nil, fn, args, kwargs)
+	case *object.Struct:
+		if err := e.stack.Push(stack.NewFrame(stack.FrameOpts{
+			Name:  fn.Name(),
 			Scope: s,
-		}))
+		})); err != nil {
+			return err
+		}
 		defer e.stack.Pop()
-		if priorityBuiltin, found := e.builtins[fn.Key()]; found {
-			// This is a priority builtin, possibly an override, so
-			// we should use this one
-			return priorityBuiltin.Call(ctx, args...)
+		if kwargs != nil {
+			return fn.CallWithKwargs(ctx, kwargs, args...)
 		}
-		// This is a non-priority builtin
 		return fn.Call(ctx, args...)
 	default:
 		return object.Errorf("type error: %s is not callable", fn.Type())
 	}
 }
 
-func (e *Evaluator) newFunctionScope(ctx context.Context, s *scope.Scope, fn *object.Function, args []object.Object) (*scope.Scope, error) {
+// applyBuiltin calls a builtin function. The receiver is the object whose
+// method is being called, if any, which is used to count the items the
+// method adds to it against the limits of the program.
+func (e *Evaluator) applyBuiltin(ctx context.Context, s *scope.Scope, receiver object.Object, fn *object.Builtin, args []object.Object, kwargs *object.Map) object.Object {
+	if err := e.stack.Push(stack.NewFrame(stack.FrameOpts{
+		Name:  fn.Key(),
+		Scope: s,
+	})); err != nil {
+		return err
+	}
+	defer e.stack.Pop()
+	if priorityBuiltin, found := e.builtins[fn.Key()]; found {
+		// This is a priority builtin, possibly an override, so
+		// we should use this one
+		fn = priorityBuiltin
+	}
+	if e.limiter == nil {
+		if kwargs != nil {
+			return fn.CallWithKwargs(ctx, kwargs, args...)
+		}
+		return fn.Call(ctx, args...)
+	}
+	var result object.Object
+	before, _ := object.Size(receiver)
+	if kwargs != nil {
+		result = fn.CallWithKwargs(ctx, kwargs, args...)
+	} else {
+		result = fn.Call(ctx, args...)
+	}
+	if receiver == nil {
+		if err := e.limiter.TrackResult(result, args...); err != nil {
+			return err
+		}
+		return result
+	}
+	if err := e.limiter.Grow(receiver, before); err != nil {
+		return err
+	}
+	if result != receiver {
+		if err := e.limiter.TrackResult(result, args...); err != nil {
+			return err
+		}
+	}
+	return result
+}
+
+// checkParams verifies the arguments bound in the scope of a function call
+// against the type annotations of the function's parameters. The annotations
+// are recorded in the scope, so that assignments to the parameters within
+// the function are checked too.
+func (e *Evaluator) checkParams(fn *object.Function, s *scope.Scope) *object.Error {
+	if fn.Types() == nil {
+		return nil
+	}
+	params := fn.Parameters()
+	values := make([]object.Object, len(params))
+	contents := s.Contents()
+	for i, param := range params {
+		values[i] = contents[param.String()]
+	}
+	if err := CheckParams(fn.Name(), fn.Types(), params, fn.Variadic(), values); err != nil {
+		return err
+	}
+	for i, param := range params {
+		// The items collected by a variadic parameter are checked, not the
+		// list that holds them
+		if fn.Variadic() && i == len(params)-1 {
+			continue
+		}
+		if typ := fn.Types().Param(param.String()); typ != nil {
+			s.Annotate(param.String(), typ.String())
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) newFunctionScope(ctx context.Context, s *scope.Scope, fn *object.Function, args []object.Object, kwargs *object.Map) (*scope.Scope, error) {
 	declared := map[string]bool{}
 	nestedScope := s.NewChild(scope.Opts{Name: "function"})
-	for key, val := range fn.Defaults() {
+	// Defaults are declared in parameter order, which is the order the
+	// resolver package assigns their slots
+	for _, param := range fn.Parameters() {
+		key := param.String()
+		val, ok := fn.Defaults()[key]
+		if !ok {
+			continue
+		}
 		evaluatedValue := e.Evaluate(ctx, val, s)
 		if object.IsError(evaluatedValue) {
 			return nil, fmt.Errorf("failed to evaluate parameter: %s", key)
@@ -70,11 +190,32 @@ func (e *Evaluator) newFunctionScope(ctx context.Context, s *scope.Scope, fn *ob
 		}
 		declared[key] = true
 	}
-	if len(fn.Defaults()) == 0 && len(args) != len(fn.Parameters()) {
+	params := fn.Parameters()
+	if fn.Variadic() {
+		// Any arguments beyond the fixed parameters are collected into a
+		// list, which is assigned to the last parameter
+		fixed := len(params) - 1
+		if len(fn.Defaults()) == 0 && kwargs == nil && len(args) < fixed {
+			return nil, fmt.Errorf("type error: function expected at least %d arguments (%d given)",
+				fixed, len(args))
+		}
+		rest := []object.Object{}
+		if len(args) > fixed {
+			rest = append(rest, args[fixed:]...)
+			args = args[:fixed]
+		}
+		if err := nestedScope.Declare(params[fixed].String(), object.NewList(rest), false); err != nil {
+			return nil, err
+		}
+		params = params[:fixed]
+	} else if len(fn.Defaults()) == 0 && kwargs == nil && len(args) != len(params) {
 		return nil, fmt.Errorf("type error: function expected %d arguments (%d given)",
-			len(fn.Parameters()), len(args))
+			len(params), len(args))
 	}
-	for paramIdx, param := range fn.Parameters() {
+	if kwargs != nil {
+		return nestedScope, e.bindKwargs(nestedScope, fn, params, args, kwargs, declared)
+	}
+	for paramIdx, param := range params {
 		if paramIdx < len(args) {
 			name := param.String()
 			if declared[name] {
@@ -92,3 +233,40 @@ func (e *Evaluator) newFunctionScope(ctx context.Context, s *scope.Scope, fn *ob
 	}
 	return nestedScope, nil
 }
+
+// bindKwargs declares the parameters of a function called with keyword
+// arguments in its scope. Parameters with a default value have already been
+// declared, and are updated if they were given an argument.
+func (e *Evaluator) bindKwargs(
+	s *scope.Scope,
+	fn *object.Function,
+	params []*ast.Ident,
+	args []object.Object,
+	kwargs *object.Map,
+	declared map[string]bool,
+) error {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.String()
+	}
+	values, err := object.BindArguments(fn.Name(), names, args, kwargs)
+	if err != nil {
+		return errors.New(err.Message().Value())
+	}
+	for i, name := range names {
+		switch {
+		case values[i] == nil && !declared[name]:
+			return errors.New(object.NewMissingArgError(fn.Name(), name).Message().Value())
+		case values[i] == nil:
+		case declared[name]:
+			if err := s.Update(name, values[i]); err != nil {
+				return err
+			}
+		default:
+			if err := s.Declare(name, values[i], false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/stack"
+)
+
+// newGenerator returns the generator for a call to a generator function.
+// The arguments are already bound in the given scope, while the body runs
+// lazily in a fork of the evaluator that pauses at each yield statement.
+// The return value of the function is discarded.
+func (e *Evaluator) newGenerator(ctx context.Context, fn *object.Function, s *scope.Scope) object.Object {
+	return object.NewGenerator(ctx, fn.Name(), func(ctx context.Context, yield object.YieldFunc) object.Object {
+		child := e.fork()
+		child.yield = yield
+		ctx = object.WithCallFunc(ctx, child.getCallFunc())
+		ctx = object.WithKwargsCallFunc(ctx, child.getKwargsCallFunc())
+		frame := stack.NewFrame(stack.FrameOpts{
+			Name:  fn.Name(),
+			Scope: s,
+		})
+		if err := child.stack.Push(frame); err != nil {
+			return err
+		}
+		defer child.stack.Pop()
+		result := child.Evaluate(ctx, fn.Body(), s)
+		return child.runDeferred(ctx, frame, unwrapPropagation(child.upwrapReturnValue(result)))
+	})
+}
+
+func (e *Evaluator) evalYield(ctx context.Context, node *ast.Yield, s *scope.Scope) object.Object {
+	var value object.Object = object.Nil
+	if node.Value() != nil {
+		value = e.Evaluate(ctx, node.Value(), s)
+		if object.IsError(value) {
+			return value
+		}
+	}
+	if e.yield == nil {
+		return object.Errorf("eval error: yield outside generator")
+	}
+	if !e.yield(value) {
+		// The generator was closed or its context was canceled
+		if err := ctx.Err(); err != nil {
+			return object.NewError(err)
+		}
+		return object.NewError(object.ErrGeneratorClosed)
+	}
+	return object.Nil
+}
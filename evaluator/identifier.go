@@ -6,8 +6,26 @@ import (
 	"github.com/cloudcmds/tamarin/scope"
 )
 
+// Slot is the location of the variable an identifier refers to, as found by
+// the resolver package: the number of scopes between the identifier and the
+// scope declaring the variable, and the index of the variable in that scope.
+type Slot struct {
+	Depth int
+	Index int
+}
+
+// Slots maps the identifiers of a program to the variables they refer to.
+// It is kept apart from the program so that the same program may be
+// resolved and evaluated by several goroutines at once.
+type Slots map[*ast.Ident]Slot
+
 func (e *Evaluator) evalIdentifier(node *ast.Ident, s *scope.Scope) object.Object {
 	name := node.String()
+	if slot, ok := e.slots[node]; ok {
+		if val, ok := s.GetAt(slot.Depth, slot.Index, name); ok {
+			return val
+		}
+	}
 	if val, ok := s.Get(name); ok {
 		return val
 	}
@@ -19,6 +19,9 @@ type Importer interface {
 type SimpleImporter struct{}
 
 func (si *SimpleImporter) Import(ctx context.Context, e *Evaluator, name string) (*object.Module, error) {
+	if err := object.GetPolicy(ctx).CheckFile(name); err != nil {
+		return nil, err.Value()
+	}
 	contents, err := os.ReadFile(name)
 	if err != nil {
 		return nil, err
@@ -45,7 +48,7 @@ func (e *Evaluator) evalImportStatement(ctx context.Context, node *ast.Import, s
 	name := fmt.Sprintf("%s.tm", moduleName)
 	module, err := e.importer.Import(ctx, e, name)
 	if err != nil {
-		return object.Errorf(err.Error())
+		return object.NewError(err)
 	}
 	// TODO: overrides
 	if err := s.Declare(moduleName, module, true); err != nil {
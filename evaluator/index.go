@@ -39,22 +39,40 @@ func (e *Evaluator) evalSliceExpression(ctx context.Context, node *ast.Slice, s
 		return object.Errorf("type error: %s object is not scriptable", left.Type())
 	}
 	// Retrieve a slice of items with a range of indices
-	var startIndex, stopIndex object.Object
-	if node.FromIndex() != nil {
-		startIndex = e.Evaluate(ctx, node.FromIndex(), s)
-		if object.IsError(startIndex) {
-			return startIndex
-		}
-	}
-	if node.ToIndex() != nil {
-		stopIndex = e.Evaluate(ctx, node.ToIndex(), s)
-		if object.IsError(stopIndex) {
-			return stopIndex
-		}
+	slice, err := e.evalSliceBounds(ctx, node.FromIndex(), node.ToIndex(), node.Step(), s)
+	if err != nil {
+		return err
 	}
-	items, err := container.GetSlice(object.Slice{Start: startIndex, Stop: stopIndex})
+	items, err := container.GetSlice(slice)
 	if err != nil {
 		return err
 	}
 	return items
 }
+
+func (e *Evaluator) evalSliceLiteral(ctx context.Context, node *ast.SliceLiteral, s *scope.Scope) object.Object {
+	slice, err := e.evalSliceBounds(ctx, node.FromIndex(), node.ToIndex(), node.Step(), s)
+	if err != nil {
+		return err
+	}
+	return slice
+}
+
+// evalSliceBounds evaluates the optional start, stop and step of a slice.
+func (e *Evaluator) evalSliceBounds(ctx context.Context, from, to, step ast.Expression, s *scope.Scope) (object.Slice, *object.Error) {
+	var slice object.Slice
+	for _, bound := range []struct {
+		node  ast.Expression
+		value *object.Object
+	}{{from, &slice.Start}, {to, &slice.Stop}, {step, &slice.Step}} {
+		if bound.node == nil {
+			continue
+		}
+		value := e.Evaluate(ctx, bound.node, s)
+		if err, ok := value.(*object.Error); ok {
+			return slice, err
+		}
+		*bound.value = value
+	}
+	return slice, nil
+}
@@ -1,8 +1,10 @@
 package evaluator
 
 import (
+	"bytes"
 	"context"
 	"math"
+	"strings"
 
 	"github.com/cloudcmds/tamarin/ast"
 	"github.com/cloudcmds/tamarin/object"
@@ -23,10 +25,36 @@ func (e *Evaluator) evalInfixExpression(ctx context.Context, node *ast.Infix, s
 	if object.IsError(right) {
 		return right
 	}
-	return e.evalInfix(node.Operator(), left, right, s)
+	return e.evalInfix(ctx, node.Operator(), left, right, s)
 }
 
-func (e *Evaluator) evalInfix(operator string, left, right object.Object, s *scope.Scope) object.Object {
+func (e *Evaluator) evalInfix(ctx context.Context, operator string, left, right object.Object, s *scope.Scope) object.Object {
+	if e.limiter != nil {
+		if typ, size, ok := InfixSize(operator, left, right); ok {
+			if err := e.limiter.CheckSize(typ, size); err != nil {
+				return err
+			}
+		}
+	}
+	result := Infix(ctx, operator, left, right, e.intOverflow)
+	if err := e.limiter.TrackResult(result, left, right); err != nil {
+		return err
+	}
+	return result
+}
+
+// Infix applies a binary operator to the given operands. This is exported so
+// that other execution backends share the evaluator's operator semantics.
+// The context is passed to objects that implement the operator themselves,
+// and the overflow argument determines the result of int operations that
+// overflow.
+func Infix(ctx context.Context, operator string, left, right object.Object, overflow IntOverflow) object.Object {
+	// Objects may implement operators themselves
+	if operator != "&&" && operator != "||" {
+		if result := overloadedInfix(ctx, operator, left, right); result != nil {
+			return result
+		}
+	}
 	// Expressions that are handled the same for all types
 	switch operator {
 	case "==":
@@ -49,15 +77,21 @@ func (e *Evaluator) evalInfix(operator string, left, right object.Object, s *sco
 	rightType := right.Type()
 	switch {
 	case leftType == object.INT && rightType == object.INT:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, overflow)
 	case leftType == object.FLOAT && rightType == object.FLOAT:
 		return evalFloatInfixExpression(operator, left, right)
 	case leftType == object.FLOAT && rightType == object.INT:
 		return evalFloatIntegerInfixExpression(operator, left, right)
 	case leftType == object.INT && rightType == object.FLOAT:
 		return evalIntegerFloatInfixExpression(operator, left, right)
+	case leftType == object.DECIMAL || rightType == object.DECIMAL:
+		return evalDecimalInfixExpression(ctx, operator, left, right)
+	case leftType == object.BIGINT || rightType == object.BIGINT:
+		return evalBigIntMixedInfixExpression(operator, left, right)
 	case leftType == object.STRING && rightType == object.STRING:
 		return evalStringInfixExpression(operator, left, right)
+	case leftType == object.BYTES && rightType == object.BYTES:
+		return evalBytesInfixExpression(operator, left, right)
 	case leftType == object.BOOL && rightType == object.BOOL:
 		return evalBooleanInfixExpression(operator, left, right)
 	case leftType != rightType:
@@ -69,6 +103,41 @@ func (e *Evaluator) evalInfix(operator string, left, right object.Object, s *sco
 	}
 }
 
+// overloadedInfix dispatches the operator to the operands if they implement
+// object.BinaryOperator or object.ReflectedBinaryOperator. Nil is returned if
+// neither operand supports the operation.
+func overloadedInfix(ctx context.Context, operator string, left, right object.Object) object.Object {
+	name := operator
+	switch operator {
+	case "==", "<=", ">=":
+	case "!=":
+		name = "=="
+	default:
+		// Compound assignments use the same operator, e.g. += uses +
+		name = strings.TrimSuffix(operator, "=")
+	}
+	var result object.Object
+	if op, ok := left.(object.BinaryOperator); ok {
+		result = op.BinaryOp(ctx, name, right)
+	}
+	if result == nil {
+		if op, ok := right.(object.ReflectedBinaryOperator); ok {
+			result = op.ReflectedBinaryOp(ctx, name, left)
+		}
+	}
+	if result == nil || operator != "!=" {
+		return result
+	}
+	switch result := result.(type) {
+	case *object.Bool:
+		return object.Not(result)
+	case *object.Error:
+		return result
+	default:
+		return object.Errorf("type error: == operator returned %s (expected bool)", result.Type())
+	}
+}
+
 func evalBooleanInfixExpression(operator string, left, right object.Object) object.Object {
 	l := object.NewString(string(left.Inspect()))
 	r := object.NewString(string(right.Inspect()))
@@ -87,39 +156,33 @@ func evalBooleanInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left, right object.Object, overflow IntOverflow) object.Object {
 	leftVal := left.(*object.Int).Value()
 	rightVal := right.(*object.Int).Value()
+	var result int64
+	var ok bool
 	switch operator {
-	case "+":
-		return object.NewInt(leftVal + rightVal)
-	case "+=":
-		return object.NewInt(leftVal + rightVal)
+	case "+", "+=":
+		result, ok = addInt(leftVal, rightVal)
+	case "-", "-=":
+		result, ok = subInt(leftVal, rightVal)
+	case "*", "*=":
+		result, ok = mulInt(leftVal, rightVal)
+	case "**":
+		if rightVal < 0 {
+			return object.NewInt(int64(math.Pow(float64(leftVal), float64(rightVal))))
+		}
+		result, ok = powInt(leftVal, rightVal)
 	case "%":
 		if rightVal == 0 {
 			return object.Errorf("eval error: int modulo by zero")
 		}
 		return object.NewInt(leftVal % rightVal)
-	case "**":
-		return object.NewInt(int64(math.Pow(float64(leftVal), float64(rightVal))))
-	case "-":
-		return object.NewInt(leftVal - rightVal)
-	case "-=":
-		return object.NewInt(leftVal - rightVal)
-	case "*":
-		return object.NewInt(leftVal * rightVal)
-	case "*=":
-		return object.NewInt(leftVal * rightVal)
-	case "/":
-		if rightVal == 0 {
-			return object.Errorf("eval error: int divided by zero")
-		}
-		return object.NewInt(leftVal / rightVal)
-	case "/=":
+	case "/", "/=":
 		if rightVal == 0 {
 			return object.Errorf("eval error: int divided by zero")
 		}
-		return object.NewInt(leftVal / rightVal)
+		result, ok = leftVal/rightVal, leftVal != math.MinInt64 || rightVal != -1
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case "<=":
@@ -128,10 +191,32 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case ">=":
 		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "&", "&=":
+		return object.NewInt(leftVal & rightVal)
+	case "|", "|=":
+		return object.NewInt(leftVal | rightVal)
+	case "^", "^=":
+		return object.NewInt(leftVal ^ rightVal)
+	case "&^", "&^=":
+		return object.NewInt(leftVal &^ rightVal)
+	case "<<", "<<=":
+		if rightVal < 0 {
+			return object.Errorf("eval error: negative shift count: %d", rightVal)
+		}
+		result, ok = shlInt(leftVal, rightVal)
+	case ">>", ">>=":
+		if rightVal < 0 {
+			return object.Errorf("eval error: negative shift count: %d", rightVal)
+		}
+		return object.NewInt(leftVal >> rightVal)
 	default:
 		return object.Errorf("type error: unsupported operand types for %s: %s and %s",
 			operator, left.Type(), right.Type())
 	}
+	if !ok {
+		return intOverflow(operator, leftVal, rightVal, overflow)
+	}
+	return object.NewInt(result)
 }
 
 func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
@@ -281,3 +366,24 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 			operator, left.Type(), right.Type())
 	}
 }
+
+func evalBytesInfixExpression(operator string, left, right object.Object) object.Object {
+	l := left.(*object.Bytes).Value()
+	r := right.(*object.Bytes).Value()
+	switch operator {
+	case ">=":
+		return nativeBoolToBooleanObject(bytes.Compare(l, r) >= 0)
+	case ">":
+		return nativeBoolToBooleanObject(bytes.Compare(l, r) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(bytes.Compare(l, r) <= 0)
+	case "<":
+		return nativeBoolToBooleanObject(bytes.Compare(l, r) < 0)
+	case "+", "+=":
+		result := make([]byte, 0, len(l)+len(r))
+		return object.NewBytes(append(append(result, l...), r...))
+	default:
+		return object.Errorf("type error: unsupported operand types for %s: %s and %s",
+			operator, left.Type(), right.Type())
+	}
+}
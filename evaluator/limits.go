@@ -0,0 +1,131 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// trackResult counts the objects created by literals and slices against the
+// limits of the program. Objects created by operators and builtins are
+// counted where those are applied, and comprehensions count each item as it
+// is added.
+func (e *Evaluator) trackResult(node ast.Node, result object.Object) object.Object {
+	switch node := node.(type) {
+	case *ast.String:
+		if len(node.TemplateExpressions()) == 0 {
+			return result
+		}
+	case *ast.List, *ast.Map, *ast.Set, *ast.Slice:
+	default:
+		return result
+	}
+	if err := e.limiter.Track(result); err != nil {
+		return err
+	}
+	return result
+}
+
+// InfixSize estimates the size of the result of a binary operator that may
+// allocate a large string, bytes, bigint or decimal, so that it can be
+// checked with Limiter.CheckSize before it is allocated. False is returned
+// for other operations. This is exported so that other execution backends
+// check the same operations.
+func InfixSize(operator string, left, right object.Object) (object.Type, int64, bool) {
+	operator = strings.TrimSuffix(operator, "=")
+	switch left := left.(type) {
+	case *object.String:
+		if right, ok := right.(*object.String); ok && operator == "+" {
+			return object.STRING, int64(len(left.Value()) + len(right.Value())), true
+		}
+		return "", 0, false
+	case *object.Bytes:
+		if right, ok := right.(*object.Bytes); ok && operator == "+" {
+			return object.BYTES, int64(len(left.Value()) + len(right.Value())), true
+		}
+		return "", 0, false
+	case *object.Decimal:
+		return decimalSize(operator, left, right)
+	}
+	switch right.(type) {
+	case *object.Decimal:
+		return decimalSize(operator, left, right)
+	case *object.Int:
+		// The product of two ints has at most 128 bits
+		if _, ok := left.(*object.Int); ok && operator == "*" {
+			return "", 0, false
+		}
+	}
+	if operator != "*" && operator != "**" && operator != "<<" {
+		return "", 0, false
+	}
+	a, ok := toBigInt(left)
+	if !ok {
+		return "", 0, false
+	}
+	b, ok := toBigInt(right)
+	if !ok {
+		return "", 0, false
+	}
+	var bits int64
+	switch operator {
+	case "*":
+		bits = int64(a.BitLen() + b.BitLen())
+	case "**":
+		bits = powBits(a, b)
+	case "<<":
+		switch {
+		case b.Sign() < 0:
+		case !b.IsInt64() || b.Int64() > math.MaxInt64-int64(a.BitLen()):
+			bits = math.MaxInt64
+		default:
+			bits = int64(a.BitLen()) + b.Int64()
+		}
+	default:
+		return "", 0, false
+	}
+	return object.BIGINT, object.BitsSize(object.BIGINT, bits), true
+}
+
+// decimalSize estimates the number of digits of the result of multiplying
+// decimals or raising one to an integer power.
+func decimalSize(operator string, left, right object.Object) (object.Type, int64, bool) {
+	a, ok := object.ToDecimal(left)
+	if !ok {
+		return "", 0, false
+	}
+	b, ok := object.ToDecimal(right)
+	if !ok {
+		return "", 0, false
+	}
+	var bits int64
+	switch operator {
+	case "*":
+		bits = int64(a.Value().BitLen() + b.Value().BitLen())
+	case "**":
+		if b.Scale() != 0 {
+			return "", 0, false
+		}
+		bits = powBits(a.Value(), b.Value())
+	default:
+		return "", 0, false
+	}
+	return object.DECIMAL, object.BitsSize(object.DECIMAL, bits), true
+}
+
+// powBits returns an upper bound on the bit length of base ** exp, which is
+// at most the bit length of the base times exp. Powers of 0 and ±1 don't
+// grow, and negative exponents don't produce larger integers.
+func powBits(base, exp *big.Int) int64 {
+	bits := int64(base.BitLen())
+	if bits <= 1 || exp.Sign() <= 0 {
+		return bits
+	}
+	if !exp.IsInt64() || exp.Int64() > math.MaxInt64/bits {
+		return math.MaxInt64
+	}
+	return bits * exp.Int64()
+}
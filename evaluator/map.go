@@ -9,29 +9,26 @@ import (
 )
 
 func (e *Evaluator) evalMapLiteral(ctx context.Context, node *ast.Map, s *scope.Scope) object.Object {
-	items := make(map[string]object.Object, len(node.Items()))
+	m := object.NewMapWithSize(len(node.Items()))
 	for keyNode, valueNode := range node.Items() {
 		value := e.Evaluate(ctx, valueNode, s)
 		if object.IsError(value) {
 			return value
 		}
-		var key string
+		var key object.Object
 		if keyIdent, ok := keyNode.(*ast.Ident); ok {
 			// Key is an identifier (no quotes), e.g. { foo: 5 }
-			key = keyIdent.String()
+			key = object.NewString(keyIdent.String())
 		} else {
-			// Key is an expression, e.g. { "foo": 5 }
-			keyObj := e.Evaluate(ctx, keyNode, s)
-			if object.IsError(keyObj) {
-				return keyObj
-			}
-			var err *object.Error
-			key, err = object.AsString(keyObj)
-			if err != nil {
-				return err
+			// Key is an expression, e.g. { "foo": 5 } or { 1: 5 }
+			key = e.Evaluate(ctx, keyNode, s)
+			if object.IsError(key) {
+				return key
 			}
 		}
-		items[key] = value
+		if err := m.SetItem(key, value); err != nil {
+			return err
+		}
 	}
-	return object.NewMap(items)
+	return m
 }
@@ -0,0 +1,167 @@
+package evaluator
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+func (e *Evaluator) evalMatch(ctx context.Context, me *ast.Match, s *scope.Scope) object.Object {
+	value := e.Evaluate(ctx, me.Value(), s)
+	if object.IsError(value) {
+		return value
+	}
+	for _, choice := range me.Cases() {
+		if choice.IsDefault() {
+			continue
+		}
+		for _, pattern := range choice.Patterns() {
+			bindings, ok := MatchPattern(pattern, value)
+			if !ok {
+				continue
+			}
+			// Each case has its own scope holding the variables it binds
+			caseScope := s.NewChild(scope.Opts{Name: "match-case"})
+			for i, name := range pattern.Bindings() {
+				if err := caseScope.Declare(name, bindings[i], false); err != nil {
+					return object.NewError(err)
+				}
+			}
+			if guard := choice.Guard(); guard != nil {
+				result := e.Evaluate(ctx, guard, caseScope)
+				if object.IsError(result) {
+					return result
+				}
+				if !result.IsTruthy() {
+					break
+				}
+			}
+			return e.evalBlockStatement(ctx, choice.Block(), caseScope)
+		}
+	}
+	// No match found, so run the default block if there is one
+	for _, choice := range me.Cases() {
+		if choice.IsDefault() {
+			return e.evalBlockStatement(ctx, choice.Block(), s.NewChild(scope.Opts{Name: "match-case"}))
+		}
+	}
+	return object.Nil
+}
+
+// MatchPattern checks whether a value matches a pattern from a match
+// expression. If it does, the values bound by the pattern are returned in
+// the same order as the names from pattern.Bindings().
+func MatchPattern(pattern ast.Pattern, value object.Object) ([]object.Object, bool) {
+	return matchPattern(pattern, value, nil)
+}
+
+func matchPattern(pattern ast.Pattern, value object.Object, bindings []object.Object) ([]object.Object, bool) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return bindings, true
+	case *ast.BindingPattern:
+		return append(bindings, value), true
+	case *ast.ValuePattern:
+		return bindings, object.Equals(literalValue(pattern.Value()), value)
+	case *ast.ListPattern:
+		list, ok := value.(*object.List)
+		if !ok {
+			return nil, false
+		}
+		items := list.Value()
+		elements := pattern.Elements()
+		rest := pattern.Rest()
+		if len(items) < len(elements) || (rest == nil && len(items) > len(elements)) {
+			return nil, false
+		}
+		for i, element := range elements {
+			if bindings, ok = matchPattern(element, items[i], bindings); !ok {
+				return nil, false
+			}
+		}
+		if rest != nil {
+			remaining := make([]object.Object, len(items)-len(elements))
+			copy(remaining, items[len(elements):])
+			return matchPattern(rest, object.NewList(remaining), bindings)
+		}
+		return bindings, true
+	case *ast.MapPattern:
+		m, ok := value.(*object.Map)
+		if !ok {
+			return nil, false
+		}
+		keys := patternKeys(pattern)
+		for i, key := range keys {
+			item, found, err := m.Lookup(key)
+			if err != nil || !found {
+				return nil, false
+			}
+			if bindings, ok = matchPattern(pattern.Values()[i], item, bindings); !ok {
+				return nil, false
+			}
+		}
+		if rest := pattern.Rest(); rest != nil {
+			remaining := m.Copy()
+			for _, key := range keys {
+				remaining.DelItem(key)
+			}
+			return matchPattern(rest, remaining, bindings)
+		}
+		return bindings, true
+	case *ast.TypePattern:
+		switch pattern.Name() {
+		case "ok", "err":
+			result, ok := value.(*object.Result)
+			if !ok || result.IsOk() != (pattern.Name() == "ok") {
+				return nil, false
+			}
+			if result.IsOk() {
+				return matchPattern(pattern.Value(), result.Unwrap(), bindings)
+			}
+			return matchPattern(pattern.Value(), result.ErrMsg(), bindings)
+		}
+		if !IsType(pattern.Name(), value) {
+			return nil, false
+		}
+		return matchPattern(pattern.Value(), value, bindings)
+	}
+	return nil, false
+}
+
+// patternKeys returns the objects for the keys of a map pattern.
+func patternKeys(pattern *ast.MapPattern) []object.Object {
+	keys := make([]object.Object, len(pattern.Keys()))
+	for i, key := range pattern.Keys() {
+		keys[i] = literalValue(key)
+	}
+	return keys
+}
+
+// literalValue returns the object for a literal used in a value pattern.
+func literalValue(expr ast.Expression) object.Object {
+	switch expr := expr.(type) {
+	case *ast.Int:
+		return object.NewInt(expr.Value())
+	case *ast.BigInt:
+		return object.NewBigInt(expr.Value())
+	case *ast.Float:
+		return object.NewFloat(expr.Value())
+	case *ast.String:
+		return object.NewString(expr.Value())
+	case *ast.Bool:
+		return object.NewBool(expr.Value())
+	case *ast.Prefix:
+		switch right := expr.Right().(type) {
+		case *ast.Int:
+			return object.NewInt(-right.Value())
+		case *ast.BigInt:
+			return object.NewBigInt(new(big.Int).Neg(right.Value()))
+		case *ast.Float:
+			return object.NewFloat(-right.Value())
+		}
+	}
+	return object.Nil
+}
@@ -1,56 +1,56 @@
 package evaluator
 
 import (
+	"context"
+
 	"github.com/cloudcmds/tamarin/ast"
 	"github.com/cloudcmds/tamarin/object"
 	"github.com/cloudcmds/tamarin/scope"
 )
 
 func (e *Evaluator) evalPostfixExpression(
+	ctx context.Context,
 	s *scope.Scope,
 	operator string,
 	node *ast.Postfix,
 ) object.Object {
+	val, ok := s.Get(node.Literal())
+	if !ok {
+		return object.Errorf("name error: %q is not defined", node.Literal())
+	}
+	result := Postfix(ctx, operator, node.Literal(), val, e.intOverflow)
+	if object.IsError(result) {
+		return result
+	}
+	if err := checkAssignment(s, node.Literal(), result); err != nil {
+		return err
+	}
+	if err := s.Update(node.Literal(), result); err != nil {
+		return object.Errorf(err.Error())
+	}
+	return val
+}
+
+// Postfix returns the new value of the named variable after applying the
+// ++ or -- operator to its current value. This is exported so that other
+// execution backends share the evaluator's operator semantics.
+func Postfix(ctx context.Context, operator, name string, value object.Object, overflow IntOverflow) object.Object {
+	var delta int64 = 1
+	verb := "increment"
 	switch operator {
 	case "++":
-		val, ok := s.Get(node.Literal())
-		if !ok {
-			return object.Errorf("name error: %q is not defined", node.Literal())
-		}
-		switch arg := val.(type) {
-		case *object.Int:
-			if err := s.Update(node.Literal(), object.NewInt(arg.Value()+1)); err != nil {
-				return object.Errorf(err.Error())
-			}
-			return arg
-		case *object.Float:
-			if err := s.Update(node.Literal(), object.NewFloat(arg.Value()+1)); err != nil {
-				return object.Errorf(err.Error())
-			}
-			return arg
-		default:
-			return object.Errorf("type error: cannot increment %s (type %s)", node.Literal(), arg)
-		}
 	case "--":
-		val, ok := s.Get(node.Literal())
-		if !ok {
-			return object.Errorf("name error: %q is not defined", node.Literal())
-		}
-		switch arg := val.(type) {
-		case *object.Int:
-			if err := s.Update(node.Literal(), object.NewInt(arg.Value()-1)); err != nil {
-				return object.Errorf(err.Error())
-			}
-			return arg
-		case *object.Float:
-			if err := s.Update(node.Literal(), object.NewFloat(arg.Value()-1)); err != nil {
-				return object.Errorf(err.Error())
-			}
-			return arg
-		default:
-			return object.Errorf("type error: cannot decrement %s (type %s)", node.Literal(), arg)
-		}
+		delta = -1
+		verb = "decrement"
 	default:
 		return object.Errorf("syntax error: unknown operator: %s", operator)
 	}
+	switch value := value.(type) {
+	case *object.Int, *object.BigInt, *object.Decimal:
+		return Infix(ctx, "+", value, object.NewInt(delta), overflow)
+	case *object.Float:
+		return object.NewFloat(value.Value() + float64(delta))
+	default:
+		return object.Errorf("type error: cannot %s %s (type %s)", verb, name, value)
+	}
 }
@@ -2,6 +2,8 @@ package evaluator
 
 import (
 	"context"
+	"math"
+	"math/big"
 
 	"github.com/cloudcmds/tamarin/ast"
 	"github.com/cloudcmds/tamarin/object"
@@ -17,12 +19,26 @@ func (e *Evaluator) evalPrefixExpression(
 	if object.IsError(right) {
 		return right
 	}
-	operator := node.Operator()
+	return Prefix(ctx, node.Operator(), right, e.intOverflow)
+}
+
+// Prefix applies a unary operator to the given operand. This is exported so
+// that other execution backends share the evaluator's operator semantics.
+// The context is passed to objects that implement the operator themselves.
+func Prefix(ctx context.Context, operator string, right object.Object, overflow IntOverflow) object.Object {
+	// Objects may implement operators themselves
+	if op, ok := right.(object.UnaryOperator); ok {
+		if result := op.UnaryOp(ctx, operator); result != nil {
+			return result
+		}
+	}
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, overflow)
+	case "^":
+		return evalCaretPrefixOperatorExpression(right)
 	default:
 		return object.Errorf("syntax error: unknown operator: %s", operator)
 	}
@@ -39,13 +55,34 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+func evalMinusPrefixOperatorExpression(right object.Object, overflow IntOverflow) object.Object {
 	switch obj := right.(type) {
 	case *object.Int:
+		if obj.Value() == math.MinInt64 {
+			if overflow == ErrorOnOverflow {
+				return object.Errorf("eval error: int overflow: -(%d)", obj.Value())
+			}
+			return object.NewBigInt(new(big.Int).Neg(big.NewInt(obj.Value())))
+		}
 		return object.NewInt(-obj.Value())
+	case *object.BigInt:
+		return object.NewBigInt(new(big.Int).Neg(obj.Value()))
+	case *object.Decimal:
+		return obj.Neg()
 	case *object.Float:
 		return object.NewFloat(-obj.Value())
 	default:
 		return object.Errorf("type error: expected int or float to follow - operator (got %s)", right.Type())
 	}
 }
+
+func evalCaretPrefixOperatorExpression(right object.Object) object.Object {
+	switch obj := right.(type) {
+	case *object.Int:
+		return object.NewInt(^obj.Value())
+	case *object.BigInt:
+		return object.NewBigInt(new(big.Int).Not(obj.Value()))
+	default:
+		return object.Errorf("type error: expected int to follow ^ operator (got %s)", right.Type())
+	}
+}
@@ -6,12 +6,32 @@ import (
 	"github.com/cloudcmds/tamarin/ast"
 	"github.com/cloudcmds/tamarin/object"
 	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/stack"
 )
 
 func (e *Evaluator) evalProgram(
 	ctx context.Context,
 	program *ast.Program,
 	s *scope.Scope,
+) object.Object {
+	// The program gets a frame of its own, so that calls deferred at the
+	// top level run once it completes
+	name := "main"
+	if e.stack.Size() > 0 {
+		name = s.Name()
+	}
+	frame := stack.NewFrame(stack.FrameOpts{Name: name, Scope: s})
+	if err := e.stack.Push(frame); err != nil {
+		return err
+	}
+	defer e.stack.Pop()
+	return e.runDeferred(ctx, frame, unwrapPropagation(e.evalStatements(ctx, program, s)))
+}
+
+func (e *Evaluator) evalStatements(
+	ctx context.Context,
+	program *ast.Program,
+	s *scope.Scope,
 ) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements() {
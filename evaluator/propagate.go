@@ -0,0 +1,57 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+// evalPropagate handles the postfix `?` operator. An ok result evaluates to
+// its value, while an err result is carried out of the enclosing function by
+// a propagation error, which unwrapPropagation turns back into the result.
+func (e *Evaluator) evalPropagate(ctx context.Context, node *ast.Propagate, s *scope.Scope) object.Object {
+	value := e.Evaluate(ctx, node.Value(), s)
+	if object.IsError(value) {
+		return value
+	}
+	return Propagate(value)
+}
+
+// Propagate applies the `?` operator to the given value. This is exported so
+// that other execution backends share the evaluator's semantics.
+func Propagate(value object.Object) object.Object {
+	result, ok := value.(*object.Result)
+	if !ok {
+		return object.Errorf("type error: ? operator expected a result (got %s)", value.Type())
+	}
+	if result.IsErr() {
+		return object.NewPropagationError(result)
+	}
+	return result.Unwrap()
+}
+
+// unwrapPropagation returns the err result carried by a propagation error,
+// which becomes the return value of a function or program. Any other object
+// is returned as-is.
+func unwrapPropagation(obj object.Object) object.Object {
+	if err, ok := obj.(*object.Error); ok {
+		if result, ok := err.Propagated(); ok {
+			return result
+		}
+	}
+	return obj
+}
+
+// findPropagation returns the first propagation error in the given values.
+func findPropagation(values []object.Object) (*object.Error, bool) {
+	for _, value := range values {
+		if err, ok := value.(*object.Error); ok {
+			if _, ok := err.Propagated(); ok {
+				return err, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,46 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+// evalStruct declares the type defined by a struct statement. The default
+// values of fields are evaluated once, when the declaration is evaluated.
+func (e *Evaluator) evalStruct(ctx context.Context, node *ast.Struct, s *scope.Scope) object.Object {
+	name := node.Name().String()
+	fields := make([]string, 0, len(node.Fields()))
+	defaults := map[string]object.Object{}
+	for _, field := range node.Fields() {
+		fieldName := field.String()
+		fields = append(fields, fieldName)
+		expr, ok := node.Defaults()[fieldName]
+		if !ok {
+			continue
+		}
+		value := e.Evaluate(ctx, expr, s)
+		if object.IsError(value) {
+			return value
+		}
+		defaults[fieldName] = value
+	}
+	methods := map[string]object.Object{}
+	for _, method := range node.Methods() {
+		methodName := method.Name().String()
+		methods[methodName] = object.NewFunction(methodName, method.Parameters(),
+			method.Body(), method.Defaults(), s, method.Variadic(), method.Generator(), method.Types())
+	}
+	typ := object.NewStruct(object.StructOpts{
+		Name:     name,
+		Fields:   fields,
+		Defaults: defaults,
+		Methods:  methods,
+	})
+	if err := s.Declare(name, typ, true); err != nil {
+		return object.NewError(err)
+	}
+	return object.Nil
+}
@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+// IsType returns true if the value has the named type. The name is a type
+// as written in a type annotation or a type pattern. The "any" type matches
+// every value, and "function" matches both Tamarin functions and builtins.
+// Struct instances have the name of their struct as their type.
+func IsType(name string, value object.Object) bool {
+	switch name {
+	case "any":
+		return true
+	case "function":
+		switch value.Type() {
+		case object.FUNCTION, object.COMPILED_FUNCTION, object.BUILTIN:
+			return true
+		}
+		return false
+	}
+	return string(value.Type()) == name
+}
+
+// CheckParams verifies the arguments of a function against the types of
+// its annotated parameters. The values are given in the same order as the
+// parameters, and nil values are skipped. If the function is variadic, each
+// item collected by the last parameter is checked. This is exported so that
+// other execution backends share the evaluator's semantics.
+func CheckParams(fn string, types *ast.FuncTypes, params []*ast.Ident, variadic bool, values []object.Object) *object.Error {
+	if len(types.Params()) == 0 {
+		return nil
+	}
+	for i, param := range params {
+		typ := types.Param(param.String())
+		if typ == nil || i >= len(values) || values[i] == nil {
+			continue
+		}
+		items := []object.Object{values[i]}
+		if variadic && i == len(params)-1 {
+			if rest, ok := values[i].(*object.List); ok {
+				items = rest.Value()
+			}
+		}
+		for _, item := range items {
+			if !IsType(typ.String(), item) {
+				return object.Errorf("type error: %s() argument %q must be %s (got %s)",
+					fn, param.String(), typ.String(), item.Type())
+			}
+		}
+	}
+	return nil
+}
+
+// CheckResult verifies the value returned by a function against the type
+// of its annotated result, if it has one.
+func CheckResult(fn string, types *ast.FuncTypes, value object.Object) *object.Error {
+	typ := types.Result()
+	if typ == nil || IsType(typ.String(), value) {
+		return nil
+	}
+	return object.Errorf("type error: %s() must return %s (got %s)", fn, typ.String(), value.Type())
+}
+
+// CheckVariable verifies the value of a variable or constant against its
+// type annotation. A nil annotation accepts any value.
+func CheckVariable(name string, typ *ast.Ident, value object.Object) *object.Error {
+	if typ == nil {
+		return nil
+	}
+	return checkType(name, typ.String(), value)
+}
+
+// checkAssignment verifies a value assigned to a variable against the type
+// annotation the variable was declared with, if it has one.
+func checkAssignment(s *scope.Scope, name string, value object.Object) *object.Error {
+	return checkType(name, s.Annotation(name), value)
+}
+
+func checkType(name, typ string, value object.Object) *object.Error {
+	if typ == "" || IsType(typ, value) {
+		return nil
+	}
+	return object.Errorf("type error: %q must be %s (got %s)", name, typ, value.Type())
+}
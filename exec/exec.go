@@ -4,9 +4,12 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/checker"
+	"github.com/cloudcmds/tamarin/compiler"
 	"github.com/cloudcmds/tamarin/evaluator"
 	modJson "github.com/cloudcmds/tamarin/modules/json"
 	modMath "github.com/cloudcmds/tamarin/modules/math"
@@ -14,11 +17,14 @@ import (
 	modRand "github.com/cloudcmds/tamarin/modules/rand"
 	modStrconv "github.com/cloudcmds/tamarin/modules/strconv"
 	modStrings "github.com/cloudcmds/tamarin/modules/strings"
+	modSync "github.com/cloudcmds/tamarin/modules/sync"
 	modTime "github.com/cloudcmds/tamarin/modules/time"
 	modUuid "github.com/cloudcmds/tamarin/modules/uuid"
 	"github.com/cloudcmds/tamarin/object"
 	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/resolver"
 	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/vm"
 )
 
 // ModuleFunc is the signature of a function that returns a module
@@ -35,6 +41,7 @@ func init() {
 	moduleFuncs["math"] = modMath.Module
 	moduleFuncs["json"] = modJson.Module
 	moduleFuncs["strings"] = modStrings.Module
+	moduleFuncs["sync"] = modSync.Module
 	moduleFuncs["time"] = modTime.Module
 	moduleFuncs["uuid"] = modUuid.Module
 	moduleFuncs["rand"] = modRand.Module
@@ -42,15 +49,37 @@ func init() {
 	moduleFuncs["pgx"] = modPgx.Module
 }
 
+// Backend identifies the engine used to execute a Tamarin program.
+type Backend string
+
+const (
+	// BackendEvaluator walks the AST using the evaluator package. This is
+	// the default.
+	BackendEvaluator Backend = "evaluator"
+
+	// BackendVM compiles the program to bytecode using the compiler package
+	// and runs it on the vm package.
+	BackendVM Backend = "vm"
+)
+
 // Opts is used configure the execution of a Tamarin program.
 type Opts struct {
 	// Input is the main source code to execute.
 	Input string
 
 	// InputProgram may be used instead of Input to provide an AST that
-	// was already parsed.
+	// was already parsed. The AST isn't modified, so one program may be
+	// shared by concurrent calls.
 	InputProgram *ast.Program
 
+	// InputBytecode may be used instead of Input to provide a program that
+	// was already compiled. It is always executed using BackendVM.
+	InputBytecode *compiler.Bytecode
+
+	// Backend selects how the program is executed. If not set, the
+	// evaluator is used.
+	Backend Backend
+
 	// File is the name of the file being executed (optional).
 	File string
 
@@ -74,8 +103,42 @@ type Opts struct {
 	// Supplies extra and/or override builtins for evaluation.
 	Builtins []*object.Builtin
 
-	// Breakpoints to set
+	// Breakpoints to set. These are only supported by the evaluator.
 	Breakpoints []evaluator.Breakpoint
+
+	// If set to true, the program is not checked by the resolver before it
+	// runs, so undefined names are only reported when they are evaluated.
+	DisableResolver bool
+
+	// If set to true, type annotations are not checked before the program
+	// runs. They are still verified at runtime.
+	DisableTypeCheck bool
+
+	// IntOverflow determines what happens when int arithmetic overflows.
+	// By default, the result is promoted to a bigint.
+	IntOverflow evaluator.IntOverflow
+
+	// DecimalDivisionPlaces is the number of digits after the decimal point
+	// kept when dividing decimals with the / operator gives an inexact
+	// quotient. If zero, object.DecimalDivisionPlaces is used.
+	DecimalDivisionPlaces int
+
+	// Limits restricts the resources the program may use, which is useful
+	// when running untrusted code. A program that exceeds a limit stops
+	// with an error that wraps an *object.LimitError. The zero value does
+	// not limit anything.
+	Limits object.Limits
+
+	// MaxCallDepth is the maximum depth of nested function calls. A program
+	// that recurses deeper fails with an error instead of crashing the
+	// process. If zero, stack.DefaultMaxDepth is used.
+	MaxCallDepth int
+
+	// Policy declares the capabilities the program may use, such as the
+	// hosts fetch may connect to. Uses of capabilities it doesn't allow fail
+	// with an error that wraps an *object.PermissionError. If nil, all
+	// capabilities are allowed.
+	Policy *object.Policy
 }
 
 // AutoImport adds the default modules to the given scope.
@@ -128,6 +191,38 @@ func Execute(ctx context.Context, opts Opts) (result object.Object, err error) {
 		}
 	}()
 
+	// All goroutines of the program share the same resource limits
+	var limiter *object.Limiter
+	if opts.Limits != (object.Limits{}) {
+		limiter = object.NewLimiter(opts.Limits)
+	}
+
+	// Goroutines started by the program are stopped once it completes. The
+	// context is also canceled when the program runs out of time.
+	ctx, cancel := limiter.WithTimeout(ctx)
+	defer cancel()
+
+	if opts.DecimalDivisionPlaces > 0 {
+		ctx = object.WithDecimalDivisionPlaces(ctx, opts.DecimalDivisionPlaces)
+	}
+
+	// An error returned by a goroutine stops the program too
+	goroutines := object.NewGoroutines(cancel)
+
+	// A program that exceeded a limit reports that, even if the operation
+	// that was interrupted returned normally. Operations interrupted because
+	// a goroutine failed report that failure rather than the cancellation,
+	// and a goroutine that called exit ends the program with its exit code
+	// whatever the interrupted operation returned.
+	defer func() {
+		if limitErr := limiter.Err(); limitErr != nil {
+			result, err = nil, limitErr.Interface().(error)
+		} else if goErr := goroutines.Err(); goErr != nil && (err == nil || errors.Is(err, context.Canceled) || object.IsExitError(goErr)) {
+			result, err = toResult(goErr)
+			err = withSourceCode(err, opts)
+		}
+	}()
+
 	// Create the top-level scope if one was not provided
 	s := opts.Scope
 	if s == nil {
@@ -141,6 +236,11 @@ func Execute(ctx context.Context, opts Opts) (result object.Object, err error) {
 		}
 	}
 
+	// Run precompiled programs directly on the VM
+	if opts.InputBytecode != nil {
+		return runBytecode(ctx, opts.InputBytecode, s, limiter, goroutines, opts)
+	}
+
 	// Get the AST for the program, parsing it from opts.Input or accepting
 	// it directly from opts.InputProgram if that is set
 	var program *ast.Program
@@ -156,14 +256,86 @@ func Execute(ctx context.Context, opts Opts) (result object.Object, err error) {
 		}
 	}
 
+	// Check the names used by the program before running any of it. The
+	// slots of its variables are kept here rather than in the program, which
+	// may be shared by concurrent executions.
+	var slots evaluator.Slots
+	if !opts.DisableResolver {
+		slots = evaluator.Slots{}
+		diagnostics := resolver.Resolve(program, resolver.Opts{
+			Scope:                  s,
+			DisableDefaultBuiltins: opts.DisableDefaultBuiltins,
+			Builtins:               opts.Builtins,
+			File:                   opts.File,
+			Input:                  opts.Input,
+			Slots:                  slots,
+		})
+		if errs := resolver.Errors(diagnostics); len(errs) > 0 {
+			return nil, errs[0]
+		}
+	}
+
+	// Check the type annotations that can be verified without running it
+	if !opts.DisableTypeCheck {
+		diagnostics := checker.Check(program, checker.Opts{
+			Scope: s,
+			File:  opts.File,
+			Input: opts.Input,
+		})
+		if errs := resolver.Errors(diagnostics); len(errs) > 0 {
+			return nil, errs[0]
+		}
+	}
+
+	if opts.Backend == BackendVM {
+		bytecode, err := compiler.Compile(program, compiler.Opts{
+			DisableDefaultBuiltins: opts.DisableDefaultBuiltins,
+			Builtins:               opts.Builtins,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result, err := runBytecode(ctx, bytecode, s, limiter, goroutines, opts)
+		return result, withSourceCode(err, opts)
+	}
+
 	// Evaluate the program
 	result = evaluator.New(evaluator.Opts{
 		Importer:               opts.Importer,
 		DisableDefaultBuiltins: opts.DisableDefaultBuiltins,
 		Builtins:               opts.Builtins,
 		Breakpoints:            opts.Breakpoints,
+		IntOverflow:            opts.IntOverflow,
+		Limiter:                limiter,
+		MaxCallDepth:           opts.MaxCallDepth,
+		Policy:                 opts.Policy,
+		Goroutines:             goroutines,
+		Slots:                  slots,
 	}).Evaluate(ctx, program, s)
 
+	result, err = toResult(result)
+	return result, withSourceCode(err, opts)
+}
+
+// runBytecode executes a compiled program on the VM.
+func runBytecode(ctx context.Context, bytecode *compiler.Bytecode, s *scope.Scope, limiter *object.Limiter, goroutines *object.Goroutines, opts Opts) (object.Object, error) {
+	result := vm.New(bytecode, vm.Opts{
+		Scope:                  s,
+		Importer:               opts.Importer,
+		DisableDefaultBuiltins: opts.DisableDefaultBuiltins,
+		Builtins:               opts.Builtins,
+		IntOverflow:            opts.IntOverflow,
+		Limiter:                limiter,
+		MaxCallDepth:           opts.MaxCallDepth,
+		Policy:                 opts.Policy,
+		Goroutines:             goroutines,
+	}).Run(ctx)
+	return toResult(result)
+}
+
+// toResult converts the final object produced by a program into the values
+// returned by Execute.
+func toResult(result object.Object) (object.Object, error) {
 	// Let's guarantee that if there's no error we return a
 	// Tamarin object, so defaulting to object.Nil may make sense
 	if result == nil {
@@ -171,8 +343,12 @@ func Execute(ctx context.Context, opts Opts) (result object.Object, err error) {
 	}
 
 	// If evaluation failed, we will have a Tamarin error object
-	// and we should transform that into a Go error
+	// and we should transform that into a Go error. When known, the
+	// location of the error is included.
 	if errObj, ok := result.(*object.Error); ok {
+		if rtErr, ok := errObj.RuntimeError(); ok {
+			return nil, rtErr
+		}
 		return nil, errObj.Interface().(error)
 	}
 
@@ -180,3 +356,23 @@ func Execute(ctx context.Context, opts Opts) (result object.Object, err error) {
 	// just return the final Tamarin object as-is
 	return result, nil
 }
+
+// withSourceCode adds the offending line of the input to a runtime error
+// that occurred in the main source code.
+func withSourceCode(err error, opts Opts) error {
+	rtErr, ok := err.(*object.RuntimeError)
+	if !ok || opts.Input == "" || rtErr.File() != opts.File {
+		return err
+	}
+	// Positions are measured in runes, the same as in the lexer
+	input := []rune(opts.Input)
+	start := rtErr.StartPosition().LineStart
+	if start < 0 || start > len(input) {
+		return err
+	}
+	end := start
+	for end < len(input) && input[end] != '\n' {
+		end++
+	}
+	return rtErr.WithSourceCode(string(input[start:end]))
+}
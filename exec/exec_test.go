@@ -2,9 +2,19 @@ package exec_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/cloudcmds/tamarin/evaluator"
 	"github.com/cloudcmds/tamarin/exec"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/scope"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,3 +33,485 @@ func TestExecError(t *testing.T) {
 	require.NotNil(t, err)
 	require.Equal(t, "name error: \"bogus\" is not defined", err.Error())
 }
+
+func TestExecBackendVM(t *testing.T) {
+	ctx := context.Background()
+	result, err := exec.Execute(ctx, exec.Opts{
+		Input:   `func double(x) { x * 2 }; [1, 2, 3].map(double)`,
+		Backend: exec.BackendVM,
+	})
+	require.Nil(t, err)
+	require.Equal(t, "[2, 4, 6]", result.Inspect())
+}
+
+func TestExecBackendVMError(t *testing.T) {
+	ctx := context.Background()
+	_, err := exec.Execute(ctx, exec.Opts{Input: `bogus()`, Backend: exec.BackendVM})
+	require.NotNil(t, err)
+	require.Equal(t, "name error: \"bogus\" is not defined", err.Error())
+}
+
+func TestExecRuntimeError(t *testing.T) {
+	ctx := context.Background()
+	input := "x := 1\nfunc f() {\n  return x[0]\n}\nf()"
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(ctx, exec.Opts{Input: input, File: "main.tm", Backend: backend})
+		require.NotNil(t, err)
+		var rtErr *object.RuntimeError
+		require.True(t, errors.As(err, &rtErr), backend)
+		require.Equal(t, "type error: int object is not scriptable", err.Error())
+		require.Equal(t, "main.tm", rtErr.File())
+		require.Equal(t, "  return x[0]", rtErr.SourceCode())
+		require.Len(t, rtErr.Stack(), 2)
+		require.Equal(t, `type error: int object is not scriptable
+
+location: main.tm:3:11 (line 3, column 11)
+
+  return x[0]
+          ^
+
+stack trace (most recent call last):
+  main.tm:5 - in main
+  main.tm:3 - in f`, rtErr.FriendlyMessage())
+	}
+}
+
+func TestExecResolverError(t *testing.T) {
+	ctx := context.Background()
+	input := "func f() {\n  return totl\n}\nprint(\"never printed\")"
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(ctx, exec.Opts{Input: input, File: "main.tm", Backend: backend})
+		require.NotNil(t, err)
+		var parserErr parser.ParserError
+		require.True(t, errors.As(err, &parserErr), backend)
+		require.Equal(t, `name error: "totl" is not defined
+
+location: main.tm:2:10 (line 2, column 10)
+
+  return totl
+         ^^^^`, parserErr.FriendlyMessage())
+	}
+}
+
+func TestExecResolverModules(t *testing.T) {
+	ctx := context.Background()
+	_, err := exec.Execute(ctx, exec.Opts{Input: `strings.to_upper("a")`, DisableAutoImport: true})
+	require.NotNil(t, err)
+	require.Equal(t, `name error: "strings" is not defined`, err.Error())
+	result, err := exec.Execute(ctx, exec.Opts{Input: `strings.to_upper("a")`})
+	require.Nil(t, err)
+	require.Equal(t, `"A"`, result.Inspect())
+}
+
+func TestExecTypeCheck(t *testing.T) {
+	ctx := context.Background()
+	input := "func f(x: int) { x }\nif false { f(\"a\") }\n1"
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(ctx, exec.Opts{Input: input, Backend: backend})
+		require.NotNil(t, err)
+		var parserErr parser.ParserError
+		require.True(t, errors.As(err, &parserErr), backend)
+		require.Equal(t, `type error: f() argument "x" must be int (got string)`, err.Error())
+		require.Equal(t, 2, parserErr.StartPosition().LineNumber())
+		require.Equal(t, 14, parserErr.StartPosition().ColumnNumber())
+
+		// The call is never made, so the program runs without the checker
+		result, err := exec.Execute(ctx, exec.Opts{Input: input, Backend: backend, DisableTypeCheck: true})
+		require.Nil(t, err)
+		require.Equal(t, "1", result.Inspect())
+	}
+	// A misspelled type name stops the program
+	_, err := exec.Execute(ctx, exec.Opts{Input: "func f(x: Strng) { x }\nf(1)"})
+	require.NotNil(t, err)
+	require.Equal(t, `type error: "Strng" is not a known type`, err.Error())
+}
+
+func TestExecDisableResolver(t *testing.T) {
+	ctx := context.Background()
+	result, err := exec.Execute(ctx, exec.Opts{
+		Input:           "if false { bogus }; 1",
+		DisableResolver: true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, "1", result.Inspect())
+}
+
+func TestExecLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits object.Limits
+		limit  string
+	}{
+		{"for {}", object.Limits{MaxSteps: 1000}, "MaxSteps"},
+		{`try(func() { for {} }(), "x"); 1`, object.Limits{MaxSteps: 1000}, "MaxSteps"},
+		{`s := "ab"; for i := 0; i < 20; i++ { s += s }`, object.Limits{MaxStringLength: 1 << 16}, "MaxStringLength"},
+		{`bytes(1 << 40)`, object.Limits{MaxStringLength: 1 << 16}, "MaxStringLength"},
+		{`l := []; for { l.append(1) }`, object.Limits{MaxListLength: 100}, "MaxListLength"},
+		{`l := [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10]; [x * y for x in l for y in l]`, object.Limits{MaxListLength: 100}, "MaxListLength"},
+		{`m := {}; for i := 0; i < 200; i++ { m[i] = i }`, object.Limits{MaxMapLength: 100}, "MaxMapLength"},
+		{`s := set(); for i := 0; i < 200; i++ { s.add(i) }`, object.Limits{MaxMapLength: 100}, "MaxMapLength"},
+		{`for { x := [1, 2, 3, 4] }`, object.Limits{MaxAllocations: 1000}, "MaxAllocations"},
+		{`for { x := "abc".to_upper() }`, object.Limits{MaxAllocations: 1000}, "MaxAllocations"},
+		{"for {}", object.Limits{MaxDuration: 20 * time.Millisecond}, "MaxDuration"},
+		{"time.sleep(10); 1", object.Limits{MaxDuration: 20 * time.Millisecond}, "MaxDuration"},
+		{"time.sleep(10)", object.Limits{MaxDuration: 20 * time.Millisecond}, "MaxDuration"},
+		{`3 ** 100000000`, object.Limits{MaxStringLength: 1 << 16}, "MaxStringLength"},
+		{`x := bigint(3); for { x *= x }`, object.Limits{MaxStringLength: 1 << 16}, "MaxStringLength"},
+		{`decimal("1.5") ** 1000000`, object.Limits{MaxStringLength: 1 << 16}, "MaxStringLength"},
+		{`for { x := 2 ** 100 }`, object.Limits{MaxAllocations: 1000}, "MaxAllocations"},
+		{`l := []; for i := 0; i < 100; i++ { l.append("abcdefghij") }; ",".join(l)`, object.Limits{MaxStringLength: 500}, "MaxStringLength"},
+		{`func g() { for { yield 1 } }; list(g())`, object.Limits{MaxListLength: 100}, "MaxListLength"},
+		{`func g() { for { yield 1 } }; [x for _, x in g()]`, object.Limits{MaxListLength: 100}, "MaxListLength"},
+	}
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		for _, tt := range tests {
+			t.Run(string(backend)+"/"+tt.input, func(t *testing.T) {
+				_, err := exec.Execute(context.Background(), exec.Opts{
+					Input:   tt.input,
+					Backend: backend,
+					Limits:  tt.limits,
+				})
+				var limitErr *object.LimitError
+				require.True(t, errors.As(err, &limitErr), err)
+				require.Equal(t, tt.limit, limitErr.Limit())
+			})
+		}
+	}
+}
+
+func TestExecMaxCallDepth(t *testing.T) {
+	input := `
+	func f(n) {
+		return f(n + 1)
+	}
+	f(0)
+	`
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(context.Background(), exec.Opts{
+			Input:        input,
+			Backend:      backend,
+			MaxCallDepth: 100,
+		})
+		require.NotNil(t, err, backend)
+		require.Equal(t, "recursion error: maximum call depth exceeded (100)", err.Error())
+		rtErr, ok := err.(*object.RuntimeError)
+		require.True(t, ok, backend)
+		require.Len(t, rtErr.Stack(), object.MaxStackFrames+1, backend)
+		require.Contains(t, rtErr.FriendlyMessage(), "more frames", backend)
+
+		result, err := exec.Execute(context.Background(), exec.Opts{
+			Input:        `func f(n) { if n == 0 { return 0 }; return 1 + f(n - 1) }; f(90)`,
+			Backend:      backend,
+			MaxCallDepth: 100,
+		})
+		require.Nil(t, err, backend)
+		require.Equal(t, int64(90), result.Interface(), backend)
+	}
+}
+
+func TestExecIntOverflow(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"math.abs(-9223372036854775807 - 1)", "eval error: int overflow: math.abs(-9223372036854775808)"},
+		{`int("9223372036854775808")`, `eval error: int overflow: int("9223372036854775808")`},
+	}
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		for _, tt := range tests {
+			_, err := exec.Execute(context.Background(), exec.Opts{
+				Input:       tt.input,
+				Backend:     backend,
+				IntOverflow: evaluator.ErrorOnOverflow,
+			})
+			require.NotNil(t, err, backend)
+			require.Equal(t, tt.err, err.Error(), backend)
+
+			result, err := exec.Execute(context.Background(), exec.Opts{
+				Input:   tt.input,
+				Backend: backend,
+			})
+			require.Nil(t, err, backend)
+			require.Equal(t, "9223372036854775808", result.Inspect(), backend)
+		}
+	}
+}
+
+func TestExecDecimalDivisionPlaces(t *testing.T) {
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		result, err := exec.Execute(context.Background(), exec.Opts{
+			Input:                 `decimal("2") / 3`,
+			Backend:               backend,
+			DecimalDivisionPlaces: 4,
+		})
+		require.Nil(t, err, backend)
+		require.Equal(t, "0.6667", result.Inspect(), backend)
+
+		result, err = exec.Execute(context.Background(), exec.Opts{
+			Input:   `decimal("2") / 3`,
+			Backend: backend,
+		})
+		require.Nil(t, err, backend)
+		require.Equal(t, "0.6666666666666667", result.Inspect(), backend)
+	}
+}
+
+func TestExecPolicy(t *testing.T) {
+	tests := []struct {
+		input      string
+		capability string
+	}{
+		{`fetch("https://example.com/data")`, "AllowedHosts"},
+		{`exit(0)`, "AllowExit"},
+		{`time.now()`, "AllowClock"},
+		{`rand.intn(10)`, "AllowRandom"},
+		{`uuid.v4()`, "AllowRandom"},
+		{`pgx.connect("postgres://localhost/app")`, "AllowedDatabases"},
+		{`pgx.connect("postgres://u@evil.com/db?x=@db.internal/x")`, "AllowedDatabases"},
+		{`pgx.connect("host=evil.com port=5432 dbname=db")`, "AllowedDatabases"},
+		{`pgx.connect("host=evil.com,db.internal dbname=db")`, "AllowedDatabases"},
+		{`pgx.connect("postgres://db.internal:port/db")`, "AllowedDatabases"},
+		{`import lib`, "FileRoots"},
+	}
+	policy := &object.Policy{
+		AllowedHosts:     []string{"api.example.com"},
+		AllowedDatabases: []string{"postgres://*@db.internal/*"},
+		FileRoots:        []string{t.TempDir()},
+	}
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		for _, tt := range tests {
+			t.Run(string(backend)+"/"+tt.input, func(t *testing.T) {
+				_, err := exec.Execute(context.Background(), exec.Opts{
+					Input:    tt.input,
+					Backend:  backend,
+					Importer: &evaluator.SimpleImporter{},
+					Policy:   policy,
+				})
+				var permErr *object.PermissionError
+				require.True(t, errors.As(err, &permErr), err)
+				require.Equal(t, tt.capability, permErr.Capability())
+			})
+		}
+	}
+	// A redirect to a denied host is denied like a request to it, so try
+	// can't handle it either
+	server := httptest.NewServer(http.RedirectHandler("http://denied.example.com/", http.StatusFound))
+	defer server.Close()
+	redirectPolicy := &object.Policy{AllowedHosts: []string{"127.0.0.1"}}
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   fmt.Sprintf(`try(func() { fetch(%q).unwrap() }(), "caught")`, server.URL),
+			Backend: backend,
+			Policy:  redirectPolicy,
+		})
+		var permErr *object.PermissionError
+		require.True(t, errors.As(err, &permErr), backend)
+		require.Equal(t, "AllowedHosts", permErr.Capability())
+	}
+	fetched := evaluator.Fetch(object.WithPolicy(context.Background(), redirectPolicy), object.NewMap(nil), object.NewString(server.URL))
+	errObj, ok := fetched.(*object.Error)
+	require.True(t, ok, fetched.Inspect())
+	require.True(t, object.IsPermissionError(errObj))
+
+	// A key=value connection string to an allowed database passes the policy
+	// and only fails to connect
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		result, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   `pgx.connect("host=127.0.0.1 port=1 user=app dbname=app connect_timeout=5").err_msg()`,
+			Backend: backend,
+			Policy:  &object.Policy{AllowedDatabases: []string{"postgres://app@127.0.0.1:1/app"}},
+		})
+		require.Nil(t, err, backend)
+		require.NotContains(t, result.Inspect(), "permission error", backend)
+	}
+	// Permission errors end the program like limit errors, so a denied
+	// program can't carry on as if the capability had failed
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   `try(time.now(), func(e) { string(e) })`,
+			Backend: backend,
+			Policy:  policy,
+		})
+		require.NotNil(t, err, backend)
+		require.Equal(t, "permission error: clock access is not allowed", err.Error())
+	}
+}
+
+func TestExecExit(t *testing.T) {
+	input := `
+	func f() {
+		defer log.append("deferred")
+		[1, 2].map(func(x) { try(exit(3), "handled") })
+		log.append("not reached")
+	}
+	f()
+	`
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		log := object.NewList(nil)
+		s := scope.New(scope.Opts{})
+		require.Nil(t, s.Declare("log", log, false))
+		_, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   input,
+			Backend: backend,
+			Scope:   s,
+		})
+		var exitErr *object.ExitError
+		require.True(t, errors.As(err, &exitErr), err)
+		require.Equal(t, 3, exitErr.Code(), backend)
+		require.Equal(t, `["deferred"]`, log.Inspect(), backend)
+	}
+	_, err := exec.Execute(context.Background(), exec.Opts{Input: `exit()`})
+	var exitErr *object.ExitError
+	require.True(t, errors.As(err, &exitErr), err)
+	require.Equal(t, 0, exitErr.Code())
+}
+
+func TestExecExitGoroutine(t *testing.T) {
+	// An exit in a goroutine runs its deferred calls and then stops the
+	// whole program, however the main program is blocked
+	tests := []string{
+		`go func() { exit(3) }(); for {}`,
+		`go func() { exit(3) }(); c := chan(); c.recv()`,
+		`go func() { exit(3) }(); try(time.sleep(10), 0); error("not reached")`,
+		`c := chan(); go func() { defer c.send(1); exit(3) }(); c.recv(); for {}`,
+		`wg := sync.wait_group(); wg.add(); go func() { defer wg.done(); for {} }(); go func() { exit(3) }(); wg.wait()`,
+	}
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		for _, input := range tests {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_, err := exec.Execute(ctx, exec.Opts{
+				Input:   input,
+				Backend: backend,
+				Policy:  &object.Policy{AllowExit: true, AllowClock: true},
+			})
+			cancel()
+			var exitErr *object.ExitError
+			require.True(t, errors.As(err, &exitErr), "%s %s: %v", backend, input, err)
+			require.Equal(t, 3, exitErr.Code(), backend)
+		}
+	}
+	// The policy is checked in goroutines too
+	_, err := exec.Execute(context.Background(), exec.Opts{
+		Input:  `go func() { exit(3) }(); for {}`,
+		Policy: &object.Policy{},
+	})
+	var permErr *object.PermissionError
+	require.True(t, errors.As(err, &permErr), err)
+	require.Equal(t, "AllowExit", permErr.Capability())
+}
+
+func TestExecSharedContainers(t *testing.T) {
+	input := `
+	struct Counter { n = 0 }
+	m := {}
+	l := []
+	s := set()
+	c := Counter()
+	wg := sync.wait_group()
+	for i := 0; i < 8; i++ {
+		wg.add()
+		go func(i) {
+			defer wg.done()
+			for j := 0; j < 100; j++ {
+				key := i * 100 + j
+				m[key] = j
+				l.append(key)
+				s.add(key)
+				c.n = key
+				string(m)
+				len(l)
+			}
+		}(i)
+	}
+	wg.wait()
+	[len(m), len(l), len(s), c.n >= 0]
+	`
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		result, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   input,
+			Backend: backend,
+		})
+		require.Nil(t, err, backend)
+		require.Equal(t, "[800, 800, 800, true]", result.Inspect(), backend)
+	}
+}
+
+func TestExecGoroutineError(t *testing.T) {
+	input := `
+	done := chan()
+	go func() {
+		x := 1
+		x.missing()
+	}()
+	done.recv()
+	`
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		_, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   input,
+			Backend: backend,
+		})
+		require.NotNil(t, err, backend)
+		require.Contains(t, err.Error(), "attribute error", backend)
+	}
+}
+
+func TestExecConcurrentReuse(t *testing.T) {
+	program, err := parser.Parse(`
+	func scale(items, factor) {
+		return [x * factor for x in items]
+	}
+	total := 0
+	for _, x := range scale([1, 2, 3], input) {
+		total += x
+	}
+	total
+	`)
+	require.Nil(t, err)
+	// Executing the same program from several goroutines must not race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			backend := []exec.Backend{exec.BackendEvaluator, exec.BackendVM}[i%2]
+			for j := 0; j < 10; j++ {
+				s := scope.New(scope.Opts{})
+				require.Nil(t, s.Declare("input", object.NewInt(int64(i)), false))
+				result, err := exec.Execute(context.Background(), exec.Opts{
+					InputProgram: program,
+					Scope:        s,
+					Backend:      backend,
+				})
+				require.Nil(t, err, backend)
+				require.Equal(t, object.NewInt(int64(i*6)), result, backend)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestExecWithinLimits(t *testing.T) {
+	input := `
+	l := []
+	for i := 0; i < 50; i++ {
+		l.append(i)
+	}
+	m := {x: x * 2 for x in l}
+	'{len(l)} {len(m)}'
+	`
+	for _, backend := range []exec.Backend{exec.BackendEvaluator, exec.BackendVM} {
+		result, err := exec.Execute(context.Background(), exec.Opts{
+			Input:   input,
+			Backend: backend,
+			Limits: object.Limits{
+				MaxSteps:        5000,
+				MaxStringLength: 10,
+				MaxListLength:   50,
+				MaxMapLength:    50,
+				MaxAllocations:  200,
+			},
+		})
+		require.Nil(t, err, backend)
+		require.Equal(t, `"50 50"`, result.Inspect())
+	}
+}
@@ -54,8 +54,12 @@ func NewRequestFromParams(
 		if err != nil {
 			return nil, 0, err
 		}
-		for k, v := range headersMap.Value() {
-			switch v := v.(type) {
+		for _, key := range headersMap.SortedKeys() {
+			k, err := object.AsString(key)
+			if err != nil {
+				return nil, 0, err
+			}
+			switch v := headersMap.Get(k).(type) {
 			case *object.String:
 				req.Header.Add(k, v.Value())
 			case *object.List:
@@ -204,12 +204,30 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.AND, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('^') && l.peekCharAt(2) == rune('=') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.AND_NOT_EQUALS, "&^=")
+		} else if l.peekChar() == rune('^') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.AND_NOT, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('=') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.AMPERSAND_EQUALS, string(ch)+string(l.ch))
+		} else {
+			tok = l.newToken(token.AMPERSAND, string(l.ch))
 		}
 	case rune('|'):
 		if l.peekChar() == rune('|') {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.OR, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('=') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.PIPE_EQUALS, string(ch)+string(l.ch))
 		} else {
 			tok = l.newToken(token.PIPE, string(l.ch))
 		}
@@ -232,7 +250,13 @@ func (l *Lexer) NextToken() (token.Token, error) {
 	case rune(','):
 		tok = l.newToken(token.COMMA, string(l.ch))
 	case rune('.'):
-		tok = l.newToken(token.PERIOD, string(l.ch))
+		if l.peekChar() == rune('.') && l.peekCharAt(2) == rune('.') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.ELLIPSIS, "...")
+		} else {
+			tok = l.newToken(token.PERIOD, string(l.ch))
+		}
 	case rune('+'):
 		if l.peekChar() == rune('+') {
 			ch := l.ch
@@ -260,6 +284,10 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.MINUS_EQUALS, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('>') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.ARROW, string(ch)+string(l.ch))
 		} else {
 			tok = l.newToken(token.MINUS, string(l.ch))
 		}
@@ -283,8 +311,24 @@ func (l *Lexer) NextToken() (token.Token, error) {
 		} else {
 			tok = l.newToken(token.ASTERISK, string(l.ch))
 		}
-	case rune('<'):
+	case rune('^'):
 		if l.peekChar() == rune('=') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.CARET_EQUALS, string(ch)+string(l.ch))
+		} else {
+			tok = l.newToken(token.CARET, string(l.ch))
+		}
+	case rune('<'):
+		if l.peekChar() == rune('<') && l.peekCharAt(2) == rune('=') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.LT_LT_EQUALS, "<<=")
+		} else if l.peekChar() == rune('<') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.LT_LT, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('=') {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.LT_EQUALS, string(ch)+string(l.ch))
@@ -292,7 +336,15 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			tok = l.newToken(token.LT, string(l.ch))
 		}
 	case rune('>'):
-		if l.peekChar() == rune('=') {
+		if l.peekChar() == rune('>') && l.peekCharAt(2) == rune('=') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.GT_GT_EQUALS, ">>=")
+		} else if l.peekChar() == rune('>') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.GT_GT, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('=') {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.GT_EQUALS, string(ch)+string(l.ch))
@@ -427,17 +479,16 @@ func (l *Lexer) skipMultiLineComment() {
 // Read number. This handles 0x1234 and 0b101010101 too.
 func (l *Lexer) readNumber() string {
 	str := string(l.ch)
-	// We usually just accept digits
-	accept := "0123456789"
-	// But if we have `0x` as a prefix we accept hexadecimal instead
-	if l.ch == '0' && l.peekChar() == 'x' {
-		accept = "0x123456789abcdefABCDEF"
-	}
-	// If we have `0b` as a prefix we accept binary digits only
-	if l.ch == '0' && l.peekChar() == 'b' {
-		accept = "b01"
+	// We usually just accept digits, with underscores as separators
+	accept := "0123456789_"
+	// But if we have a `0x`, `0o` or `0b` prefix we read any hexadecimal
+	// digits and leave it to the parser to reject digits invalid for the base
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		l.readChar()
+		str += string(l.ch)
+		accept = "0123456789abcdefABCDEF_"
 	}
-	for strings.Contains(accept, string(l.peekChar())) {
+	for strings.ContainsRune(accept, l.peekChar()) {
 		l.readChar()
 		str += string(l.ch)
 	}
@@ -448,8 +499,9 @@ func (l *Lexer) readNumber() string {
 func (l *Lexer) readDecimal() token.Token {
 	// Read an integer
 	integer := l.readNumber()
-	// Check for a period which indicates a floating point
-	if l.peekChar() == rune('.') {
+	// Check for a period which indicates a floating point. Prefixed integers
+	// like `0x1F` never have a fractional part.
+	if l.peekChar() == rune('.') && !isPrefixedInt(integer) {
 		l.readChar()
 		if isDigit(l.peekChar()) {
 			l.readChar()
@@ -520,6 +572,16 @@ func (l *Lexer) peekChar() rune {
 	return l.characters[l.nextPosition]
 }
 
+// peekCharAt returns the character the given number of positions ahead of
+// the current one, where peekCharAt(1) is equivalent to peekChar().
+func (l *Lexer) peekCharAt(offset int) rune {
+	position := l.nextPosition + offset - 1
+	if position >= len(l.characters) {
+		return rune(0)
+	}
+	return l.characters[position]
+}
+
 func isIdentifier(ch rune) bool {
 	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_'
 }
@@ -533,3 +595,7 @@ func isWhitespace(ch rune) bool {
 func isDigit(ch rune) bool {
 	return rune('0') <= ch && ch <= rune('9')
 }
+
+func isPrefixedInt(s string) bool {
+	return len(s) > 1 && s[0] == '0' && strings.ContainsRune("xXoObB", rune(s[1]))
+}
@@ -366,7 +366,7 @@ var a = 1;
 }
 
 func TestIntegers(t *testing.T) {
-	input := `10 0x10 0xF0 0xFE 0b0101 0xFF 0b101 0xFF;`
+	input := `10 0x10 0xF0 0xFE 0b0101 0xFF 0b101 0xFF 0o17 0O7 0X1f 1_000 0b1010_0101 0xFF.foo 1_0.5;`
 
 	tests := []struct {
 		expectedType    token.Type
@@ -380,6 +380,15 @@ func TestIntegers(t *testing.T) {
 		{token.INT, "0xFF"},
 		{token.INT, "0b101"},
 		{token.INT, "0xFF"},
+		{token.INT, "0o17"},
+		{token.INT, "0O7"},
+		{token.INT, "0X1f"},
+		{token.INT, "1_000"},
+		{token.INT, "0b1010_0101"},
+		{token.INT, "0xFF"},
+		{token.PERIOD, "."},
+		{token.IDENT, "foo"},
+		{token.FLOAT, "1_0.5"},
 		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
@@ -635,3 +644,93 @@ func TestStringTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestEllipsis(t *testing.T) {
+	input := "f(a, ...rest) x.. ..."
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RPAREN, ")"},
+		{token.IDENT, "x"},
+		{token.PERIOD, "."},
+		{token.PERIOD, "."},
+		{token.ELLIPSIS, "..."},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type, "tests[%d]", i)
+		require.Equal(t, tt.expectedLiteral, tok.Literal, "tests[%d]", i)
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	input := "a & b | c ^ d &^ e << f >> g && h || i &= |= ^= &^= <<= >>= <= >="
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.IDENT, "d"},
+		{token.AND_NOT, "&^"},
+		{token.IDENT, "e"},
+		{token.LT_LT, "<<"},
+		{token.IDENT, "f"},
+		{token.GT_GT, ">>"},
+		{token.IDENT, "g"},
+		{token.AND, "&&"},
+		{token.IDENT, "h"},
+		{token.OR, "||"},
+		{token.IDENT, "i"},
+		{token.AMPERSAND_EQUALS, "&="},
+		{token.PIPE_EQUALS, "|="},
+		{token.CARET_EQUALS, "^="},
+		{token.AND_NOT_EQUALS, "&^="},
+		{token.LT_LT_EQUALS, "<<="},
+		{token.GT_GT_EQUALS, ">>="},
+		{token.LT_EQUALS, "<="},
+		{token.GT_EQUALS, ">="},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		require.Nil(t, err)
+		require.Equal(t, tt.expectedType, tok.Type, "tests[%d]", i)
+		require.Equal(t, tt.expectedLiteral, tok.Literal, "tests[%d]", i)
+	}
+}
+
+func TestArrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Type
+	}{
+		{"f() -> int", []token.Type{token.IDENT, token.LPAREN, token.RPAREN, token.ARROW, token.IDENT, token.EOF}},
+		{"a->b", []token.Type{token.IDENT, token.ARROW, token.IDENT, token.EOF}},
+		{"a - >b", []token.Type{token.IDENT, token.MINUS, token.GT, token.IDENT, token.EOF}},
+		{"a-->b", []token.Type{token.IDENT, token.MINUS_MINUS, token.GT, token.IDENT, token.EOF}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok, err := l.NextToken()
+			require.Nil(t, err)
+			require.Equal(t, expected, tok.Type, "%s: tokens[%d]", tt.input, i)
+		}
+	}
+}
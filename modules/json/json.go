@@ -1,9 +1,12 @@
 package json
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/cloudcmds/tamarin/arg"
 	"github.com/cloudcmds/tamarin/object"
@@ -14,16 +17,26 @@ import (
 // Name of this module
 const Name = "json"
 
-func Unmarshal(ctx context.Context, args ...object.Object) object.Object {
+// Unmarshal parses a JSON string. Numbers are parsed as floats, unless the
+// decimal keyword argument is true, in which case they are parsed as
+// decimals without any loss of precision.
+func Unmarshal(ctx context.Context, kwargs *object.Map, args ...object.Object) object.Object {
 	if err := arg.Require("json.unmarshal", 1, args); err != nil {
 		return err
 	}
+	if err := arg.Kwargs("json.unmarshal", kwargs, "decimal"); err != nil {
+		return err
+	}
 	s, ok := args[0].(*object.String)
 	if !ok {
 		return object.Errorf("type error: expected a string (got %v)", args[0].Type())
 	}
 	var obj interface{}
-	if err := json.Unmarshal([]byte(s.Value()), &obj); err != nil {
+	unmarshal := json.Unmarshal
+	if kwargs.GetWithDefault("decimal", object.False).IsTruthy() {
+		unmarshal = unmarshalNumbers
+	}
+	if err := unmarshal([]byte(s.Value()), &obj); err != nil {
 		return object.NewErrResult(object.Errorf("value error: json.unmarshal failed with: %s", object.NewError(err)))
 	}
 	scriptObj := object.FromGoType(obj)
@@ -88,7 +101,21 @@ func Diff(ctx context.Context, args ...object.Object) object.Object {
 		return object.NewErrResult(object.NewError(err))
 	}
 	unmarshalArgs := []object.Object{object.NewString(string(patchJSON))}
-	return Unmarshal(ctx, unmarshalArgs...)
+	return Unmarshal(ctx, object.NewMap(nil), unmarshalArgs...)
+}
+
+// unmarshalNumbers is like json.Unmarshal, except that numbers are decoded
+// as json.Number rather than float64.
+func unmarshalNumbers(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
 }
 
 func Module(parentScope *scope.Scope) (*object.Module, error) {
@@ -100,7 +127,7 @@ func Module(parentScope *scope.Scope) (*object.Module, error) {
 	m := object.NewModule(Name, s)
 
 	if err := s.AddBuiltins([]*object.Builtin{
-		object.NewBuiltin("unmarshal", Unmarshal, m),
+		object.NewKwargsBuiltin("unmarshal", Unmarshal, m),
 		object.NewBuiltin("marshal", Marshal, m),
 		object.NewBuiltin("valid", Valid, m),
 		object.NewBuiltin("diff", Diff, m),
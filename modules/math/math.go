@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/big"
 
 	"github.com/cloudcmds/tamarin/arg"
 	"github.com/cloudcmds/tamarin/object"
@@ -20,10 +21,19 @@ func Abs(ctx context.Context, args ...object.Object) object.Object {
 	switch arg := args[0].(type) {
 	case *object.Int:
 		v := arg.Value()
+		if v == math.MinInt64 {
+			// The absolute value doesn't fit in an int
+			if object.ErrorOnIntOverflow(ctx) {
+				return object.Errorf("eval error: int overflow: math.abs(%d)", v)
+			}
+			return object.NewBigInt(new(big.Int).Neg(big.NewInt(v)))
+		}
 		if v < 0 {
 			v = v * -1
 		}
 		return object.NewInt(v)
+	case *object.BigInt:
+		return object.NewBigInt(new(big.Int).Abs(arg.Value()))
 	case *object.Float:
 		v := arg.Value()
 		if v < 0 {
@@ -43,6 +53,9 @@ func Sqrt(ctx context.Context, args ...object.Object) object.Object {
 	case *object.Int:
 		v := arg.Value()
 		return object.NewFloat(math.Sqrt(float64(v)))
+	case *object.BigInt:
+		v, _ := object.AsFloat(arg)
+		return object.NewFloat(math.Sqrt(v))
 	case *object.Float:
 		v := arg.Value()
 		return object.NewFloat(math.Sqrt(v))
@@ -69,15 +82,13 @@ func Max(ctx context.Context, args ...object.Object) object.Object {
 		return object.Errorf("value error: math.max argument is an empty sequence")
 	}
 	var maxFlt float64
-	var maxInt int64
-	var hasFlt, hasInt bool
+	var maxInt object.Object
+	var hasFlt bool
 	for _, value := range array {
 		switch val := value.(type) {
-		case *object.Int:
-			v := val.Value()
-			if !hasInt || maxInt < v {
-				maxInt = v
-				hasInt = true
+		case *object.Int, *object.BigInt:
+			if maxInt == nil || compare(val, maxInt) > 0 {
+				maxInt = val
 			}
 		case *object.Float:
 			v := val.Value()
@@ -89,13 +100,10 @@ func Max(ctx context.Context, args ...object.Object) object.Object {
 			return object.Errorf("invalid array item for math.max: %s", val.Type())
 		}
 	}
-	if hasFlt {
-		if hasInt && float64(maxInt) > maxFlt {
-			return object.NewFloat(float64(maxInt))
-		}
+	if hasFlt && (maxInt == nil || compare(maxInt, object.NewFloat(maxFlt)) <= 0) {
 		return object.NewFloat(maxFlt)
 	}
-	return object.NewFloat(float64(maxInt))
+	return integerResult(maxInt)
 }
 
 func Min(ctx context.Context, args ...object.Object) object.Object {
@@ -116,15 +124,13 @@ func Min(ctx context.Context, args ...object.Object) object.Object {
 		return object.Errorf("value error: math.min argument is an empty sequence")
 	}
 	var minFlt float64
-	var minInt int64
-	var hasFlt, hasInt bool
+	var minInt object.Object
+	var hasFlt bool
 	for _, value := range array {
 		switch val := value.(type) {
-		case *object.Int:
-			v := val.Value()
-			if !hasInt || minInt > v {
-				minInt = v
-				hasInt = true
+		case *object.Int, *object.BigInt:
+			if minInt == nil || compare(val, minInt) < 0 {
+				minInt = val
 			}
 		case *object.Float:
 			v := val.Value()
@@ -136,13 +142,26 @@ func Min(ctx context.Context, args ...object.Object) object.Object {
 			return object.Errorf("type error: invalid array item for math.min: %s", val.Type())
 		}
 	}
-	if hasFlt {
-		if hasInt && float64(minInt) < minFlt {
-			return object.NewFloat(float64(minInt))
-		}
+	if hasFlt && (minInt == nil || compare(minInt, object.NewFloat(minFlt)) >= 0) {
 		return object.NewFloat(minFlt)
 	}
-	return object.NewFloat(float64(minInt))
+	return integerResult(minInt)
+}
+
+// compare compares two numbers exactly.
+func compare(a, b object.Object) int {
+	result, _ := a.(object.Comparable).Compare(b)
+	return result
+}
+
+// integerResult returns the result of math.min or math.max when it is an
+// integer. Ints are returned as floats, while bigints are returned as is
+// since they may not fit in a float.
+func integerResult(obj object.Object) object.Object {
+	if i, ok := obj.(*object.Int); ok {
+		return object.NewFloat(float64(i.Value()))
+	}
+	return obj
 }
 
 func Sum(ctx context.Context, args ...object.Object) object.Object {
@@ -162,17 +181,31 @@ func Sum(ctx context.Context, args ...object.Object) object.Object {
 	if len(array) == 0 {
 		return object.NewFloat(0)
 	}
+	// Integers are also summed exactly, which is the result if there are
+	// bigints but no floats
 	var sum float64
+	intSum := new(big.Int)
+	var hasFlt, hasBigInt bool
 	for _, value := range array {
 		switch val := value.(type) {
 		case *object.Int:
 			sum += float64(val.Value())
+			intSum.Add(intSum, big.NewInt(val.Value()))
+		case *object.BigInt:
+			v, _ := object.AsFloat(val)
+			sum += v
+			intSum.Add(intSum, val.Value())
+			hasBigInt = true
 		case *object.Float:
 			sum += val.Value()
+			hasFlt = true
 		default:
 			return object.Errorf("value error: invalid input for math.sum: %s", val.Type())
 		}
 	}
+	if hasBigInt && !hasFlt {
+		return object.NewBigInt(intSum)
+	}
 	return object.NewFloat(sum)
 }
 
@@ -181,7 +214,7 @@ func Ceil(ctx context.Context, args ...object.Object) object.Object {
 		return err
 	}
 	switch arg := args[0].(type) {
-	case *object.Int:
+	case *object.Int, *object.BigInt:
 		return arg
 	case *object.Float:
 		return object.NewFloat(math.Ceil(arg.Value()))
@@ -195,7 +228,7 @@ func Floor(ctx context.Context, args ...object.Object) object.Object {
 		return err
 	}
 	switch arg := args[0].(type) {
-	case *object.Int:
+	case *object.Int, *object.BigInt:
 		return arg
 	case *object.Float:
 		return object.NewFloat(math.Floor(arg.Value()))
@@ -211,6 +244,9 @@ func Sin(ctx context.Context, args ...object.Object) object.Object {
 	switch arg := args[0].(type) {
 	case *object.Int:
 		return object.NewFloat(math.Sin(float64(arg.Value())))
+	case *object.BigInt:
+		v, _ := object.AsFloat(arg)
+		return object.NewFloat(math.Sin(v))
 	case *object.Float:
 		return object.NewFloat(math.Sin(arg.Value()))
 	default:
@@ -225,6 +261,9 @@ func Cos(ctx context.Context, args ...object.Object) object.Object {
 	switch arg := args[0].(type) {
 	case *object.Int:
 		return object.NewFloat(math.Cos(float64(arg.Value())))
+	case *object.BigInt:
+		v, _ := object.AsFloat(arg)
+		return object.NewFloat(math.Cos(v))
 	case *object.Float:
 		return object.NewFloat(math.Cos(arg.Value()))
 	default:
@@ -262,33 +301,37 @@ func Log(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("math.log", 1, args); err != nil {
 		return err
 	}
-	x, err := object.AsFloat(args[0])
-	if err != nil {
-		return err
-	}
-	return object.NewFloat(math.Log(x))
+	return logOf(args[0], math.Log)
 }
 
 func Log10(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("math.log10", 1, args); err != nil {
 		return err
 	}
-	x, err := object.AsFloat(args[0])
-	if err != nil {
-		return err
-	}
-	return object.NewFloat(math.Log10(x))
+	return logOf(args[0], math.Log10)
 }
 
 func Log2(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("math.log2", 1, args); err != nil {
 		return err
 	}
-	x, err := object.AsFloat(args[0])
+	return logOf(args[0], math.Log2)
+}
+
+// logOf applies a logarithm to a number. Bigints too large to convert to a
+// float are shifted right until they fit, and the logarithm of the power of
+// two that was dropped is added to the result.
+func logOf(obj object.Object, log func(float64) float64) object.Object {
+	var shift int
+	if b, ok := obj.(*object.BigInt); ok && b.Value().Sign() > 0 && b.Value().BitLen() > 1000 {
+		shift = b.Value().BitLen() - 64
+		obj = object.NewBigInt(new(big.Int).Rsh(b.Value(), uint(shift)))
+	}
+	x, err := object.AsFloat(obj)
 	if err != nil {
 		return err
 	}
-	return object.NewFloat(math.Log2(x))
+	return object.NewFloat(log(x) + float64(shift)*log(2))
 }
 
 func Pow(ctx context.Context, args ...object.Object) object.Object {
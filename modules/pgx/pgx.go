@@ -20,13 +20,48 @@ func Connect(ctx context.Context, args ...object.Object) object.Object {
 	if !ok {
 		return object.Errorf("type error: pgx.connect() expected a string argument (got %s)", args[0].Type())
 	}
-	conn, err := pgx.Connect(ctx, url.Value())
+	policy := object.GetPolicy(ctx)
+	config, err := pgx.ParseConfig(url.Value())
+	if err != nil {
+		if err := policy.CheckDatabase(nil); err != nil {
+			return err
+		}
+		return object.NewErrResult(object.NewError(err))
+	}
+	// The policy is checked against the parsed configuration, including
+	// any fallback hosts, and that same configuration is used to connect
+	for _, db := range databases(config) {
+		if err := policy.CheckDatabase(db); err != nil {
+			return err
+		}
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
 	if err != nil {
 		return object.NewErrResult(object.NewError(err))
 	}
 	return object.NewOkResult(New(ctx, conn))
 }
 
+// databases returns the databases a connection may be made to, which are
+// the primary host and each fallback host of the configuration.
+func databases(config *pgx.ConnConfig) []*object.Database {
+	dbs := []*object.Database{{
+		Host:     config.Host,
+		Port:     config.Port,
+		Database: config.Database,
+		User:     config.User,
+	}}
+	for _, fallback := range config.Fallbacks {
+		dbs = append(dbs, &object.Database{
+			Host:     fallback.Host,
+			Port:     fallback.Port,
+			Database: config.Database,
+			User:     config.User,
+		})
+	}
+	return dbs
+}
+
 // Module returns the `pgx` module object
 func Module(parentScope *scope.Scope) (*object.Module, error) {
 	s := scope.New(scope.Opts{
@@ -87,6 +87,10 @@ func (c *PgxConn) Query(ctx context.Context, args ...object.Object) object.Objec
 	// Build list of query args as their Go types
 	var queryArgs []interface{}
 	for _, queryArg := range args[1:] {
+		if d, ok := queryArg.(*object.Decimal); ok {
+			queryArgs = append(queryArgs, toNumeric(d))
+			continue
+		}
 		queryArgs = append(queryArgs, queryArg.Interface())
 	}
 
@@ -114,6 +118,8 @@ func (c *PgxConn) Query(ctx context.Context, args ...object.Object) object.Objec
 			if timeVal, ok := value.(pgtype.Time); ok {
 				usec := timeVal.Microseconds
 				val = object.FromGoType(usec)
+			} else if numericVal, ok := value.(pgtype.Numeric); ok {
+				val = fromNumeric(numericVal)
 			} else {
 				val = object.FromGoType(value)
 			}
@@ -131,3 +137,25 @@ func (c *PgxConn) Query(ctx context.Context, args ...object.Object) object.Objec
 	}
 	return object.NewOkResult(object.NewList(results))
 }
+
+// toNumeric converts a decimal to a value for a numeric column.
+func toNumeric(d *object.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{Int: d.Value(), Exp: -d.Scale(), Valid: true}
+}
+
+// fromNumeric converts the value of a numeric column to a decimal. NaN and
+// infinite values can't be represented by a decimal, so they are returned
+// as floats.
+func fromNumeric(n pgtype.Numeric) object.Object {
+	if !n.Valid {
+		return object.Nil
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		f, err := n.Float64Value()
+		if err != nil {
+			return object.NewError(err)
+		}
+		return object.NewFloat(f.Float64)
+	}
+	return object.NewDecimal(n.Int, -n.Exp)
+}
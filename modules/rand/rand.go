@@ -27,6 +27,9 @@ func Float(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.float", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	return object.NewFloat(rand.Float64())
 }
 
@@ -34,6 +37,9 @@ func Int(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.int", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	return object.NewInt(rand.Int63())
 }
 
@@ -41,6 +47,9 @@ func IntN(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.intn", 1, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	n, err := object.AsInt(args[0])
 	if err != nil {
 		return err
@@ -52,6 +61,9 @@ func NormFloat(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.norm_float", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	return object.NewFloat(rand.NormFloat64())
 }
 
@@ -59,6 +71,9 @@ func ExpFloat(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.exp_float", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	return object.NewFloat(rand.ExpFloat64())
 }
 
@@ -66,6 +81,9 @@ func Shuffle(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("rand.shuffle", 1, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	ls, err := object.AsList(args[0])
 	if err != nil {
 		return err
@@ -74,6 +92,7 @@ func Shuffle(ctx context.Context, args ...object.Object) object.Object {
 	rand.Shuffle(len(items), func(i, j int) {
 		items[i], items[j] = items[j], items[i]
 	})
+	ls.Replace(items)
 	return ls
 }
 
@@ -91,7 +91,7 @@ func Join(ctx context.Context, args ...object.Object) object.Object {
 	if err != nil {
 		return err
 	}
-	return sep.Join(list)
+	return sep.Join(ctx, list)
 }
 
 func Split(ctx context.Context, args ...object.Object) object.Object {
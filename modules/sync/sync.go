@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudcmds/tamarin/arg"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+)
+
+// Name of this module
+const Name = "sync"
+
+func NewWaitGroupBuiltin(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sync.wait_group", 0, args); err != nil {
+		return err
+	}
+	return NewWaitGroup()
+}
+
+func Module(parentScope *scope.Scope) (*object.Module, error) {
+	s := scope.New(scope.Opts{
+		Name:   fmt.Sprintf("module:%s", Name),
+		Parent: parentScope,
+	})
+
+	m := object.NewModule(Name, s)
+
+	if err := s.AddBuiltins([]*object.Builtin{
+		object.NewBuiltin("wait_group", NewWaitGroupBuiltin, m),
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
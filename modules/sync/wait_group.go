@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudcmds/tamarin/object"
+)
+
+const WAIT_GROUP = object.Type("wait_group")
+
+// WaitGroup waits for a collection of goroutines to finish.
+type WaitGroup struct {
+	wg sync.WaitGroup
+}
+
+func (w *WaitGroup) Type() object.Type {
+	return WAIT_GROUP
+}
+
+func (w *WaitGroup) Inspect() string {
+	return "wait_group()"
+}
+
+func (w *WaitGroup) Interface() interface{} {
+	return &w.wg
+}
+
+func (w *WaitGroup) Equals(other object.Object) object.Object {
+	if o, ok := other.(*WaitGroup); ok && w == o {
+		return object.True
+	}
+	return object.False
+}
+
+func (w *WaitGroup) IsTruthy() bool {
+	return true
+}
+
+func (w *WaitGroup) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "add":
+		return object.NewBuiltin("wait_group.add", w.Add), true
+	case "done":
+		return object.NewBuiltin("wait_group.done", w.Done), true
+	case "wait":
+		return object.NewBuiltin("wait_group.wait", w.Wait), true
+	}
+	return nil, false
+}
+
+// Add adds the given delta, which defaults to 1, to the counter.
+func (w *WaitGroup) Add(ctx context.Context, args ...object.Object) (result object.Object) {
+	if len(args) > 1 {
+		return object.NewArgsRangeError("wait_group.add", 0, 1, len(args))
+	}
+	delta := int64(1)
+	if len(args) == 1 {
+		var err *object.Error
+		if delta, err = object.AsInt(args[0]); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result = object.Errorf("value error: negative wait group counter")
+		}
+	}()
+	w.wg.Add(int(delta))
+	return object.Nil
+}
+
+// Done decrements the counter by one.
+func (w *WaitGroup) Done(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewArgsError("wait_group.done", 0, len(args))
+	}
+	return w.Add(ctx, object.NewInt(-1))
+}
+
+// Wait blocks until the counter is zero or the context is cancelled.
+func (w *WaitGroup) Wait(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewArgsError("wait_group.wait", 0, len(args))
+	}
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return object.Nil
+	case <-ctx.Done():
+		return object.NewError(ctx.Err())
+	}
+}
+
+func NewWaitGroup() *WaitGroup {
+	return &WaitGroup{}
+}
@@ -17,6 +17,9 @@ func Now(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("time.now", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckClock(); err != nil {
+		return err
+	}
 	return object.NewTime(time.Now())
 }
 
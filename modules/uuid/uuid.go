@@ -16,6 +16,9 @@ func V4(ctx context.Context, args ...object.Object) object.Object {
 	if err := arg.Require("uuid.v4", 0, args); err != nil {
 		return err
 	}
+	if err := object.GetPolicy(ctx).CheckRandom(); err != nil {
+		return err
+	}
 	value, err := uuid.NewV4()
 	if err != nil {
 		return object.Errorf(err.Error())
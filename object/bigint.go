@@ -0,0 +1,114 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// BigInt wraps an arbitrary-precision integer and implements Object and
+// Hashable interfaces. The wrapped value is never modified, so operations
+// on a BigInt always return a new object.
+type BigInt struct {
+	// value holds the big.Int wrapped by this object.
+	value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+	return b.value.String()
+}
+
+func (b *BigInt) Type() Type {
+	return BIGINT
+}
+
+// Value returns the wrapped big.Int, which must not be modified.
+func (b *BigInt) Value() *big.Int {
+	return b.value
+}
+
+// HashKey returns the same key as an equal int when the value fits in an
+// int64, so that an int and a bigint with the same value are the same key.
+func (b *BigInt) HashKey() HashKey {
+	if b.value.IsInt64() {
+		return HashKey{Type: INT, IntValue: b.value.Int64()}
+	}
+	return HashKey{Type: b.Type(), StrValue: b.value.String()}
+}
+
+func (b *BigInt) GetAttr(name string) (Object, bool) {
+	return nil, false
+}
+
+func (b *BigInt) Interface() interface{} {
+	return new(big.Int).Set(b.value)
+}
+
+func (b *BigInt) String() string {
+	return fmt.Sprintf("bigint(%s)", b.value.String())
+}
+
+func (b *BigInt) Compare(other Object) (int, error) {
+	switch other := other.(type) {
+	case *BigInt:
+		return b.value.Cmp(other.value), nil
+	case *Int:
+		return b.value.Cmp(big.NewInt(other.value)), nil
+	case *Float:
+		return compareBigIntFloat(b.value, other.value), nil
+	case *Decimal:
+		return -other.Cmp(NewDecimal(b.value, 0)), nil
+	default:
+		return CompareTypes(b, other), nil
+	}
+}
+
+func (b *BigInt) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *BigInt:
+		return NewBool(b.value.Cmp(other.value) == 0)
+	case *Int:
+		return NewBool(b.value.IsInt64() && b.value.Int64() == other.value)
+	case *Float:
+		return NewBool(!math.IsNaN(other.value) && compareBigIntFloat(b.value, other.value) == 0)
+	case *Decimal:
+		return other.Equals(b)
+	}
+	return False
+}
+
+func (b *BigInt) IsTruthy() bool {
+	return b.value.Sign() != 0
+}
+
+// compareBigIntFloat compares an integer and a float exactly. NaN is
+// considered smaller than every integer.
+func compareBigIntFloat(i *big.Int, f float64) int {
+	if math.IsNaN(f) {
+		return 1
+	}
+	return new(big.Float).SetInt(i).Cmp(big.NewFloat(f))
+}
+
+// NewBigInt returns a BigInt wrapping the given value. The value must not
+// be modified afterwards.
+func NewBigInt(value *big.Int) *BigInt {
+	return &BigInt{value: value}
+}
+
+const intOverflowKey = contextKey("int_overflow")
+
+// WithErrorOnIntOverflow marks the context of a program whose int
+// arithmetic fails when it overflows, rather than promoting the result to a
+// bigint. Builtins that compute ints, such as math.abs, follow it too.
+func WithErrorOnIntOverflow(ctx context.Context) context.Context {
+	return context.WithValue(ctx, intOverflowKey, true)
+}
+
+// ErrorOnIntOverflow returns true if int overflows are errors in programs
+// run with the context.
+func ErrorOnIntOverflow(ctx context.Context) bool {
+	errorOnOverflow, _ := ctx.Value(intOverflowKey).(bool)
+	return errorOnOverflow
+}
@@ -0,0 +1,63 @@
+package object
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigIntBasics(t *testing.T) {
+	n, ok := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	require.True(t, ok)
+	value := NewBigInt(n)
+	require.Equal(t, BIGINT, value.Type())
+	require.Equal(t, n, value.Value())
+	require.Equal(t, "bigint(-123456789012345678901234567890)", value.String())
+	require.Equal(t, "-123456789012345678901234567890", value.Inspect())
+	require.Equal(t, n, value.Interface())
+	require.True(t, value.IsTruthy())
+	require.False(t, NewBigInt(new(big.Int)).IsTruthy())
+}
+
+func TestBigIntCompare(t *testing.T) {
+	huge := NewBigInt(new(big.Int).Lsh(big.NewInt(1), 70))
+	two := NewBigInt(big.NewInt(2))
+	tests := []struct {
+		first    Comparable
+		second   Object
+		expected int
+	}{
+		{huge, two, 1},
+		{two, huge, -1},
+		{two, NewInt(2), 0},
+		{NewInt(2), huge, -1},
+		{huge, NewInt(2), 1},
+		{two, NewFloat(2.5), -1},
+		{NewFloat(2.5), two, 1},
+		{NewFloat(1e30), huge, 1},
+	}
+	for _, tc := range tests {
+		result, err := tc.first.Compare(tc.second)
+		require.Nil(t, err)
+		require.Equal(t, tc.expected, result,
+			"first: %v, second: %v", tc.first, tc.second)
+	}
+}
+
+func TestBigIntEquals(t *testing.T) {
+	huge := NewBigInt(new(big.Int).Lsh(big.NewInt(1), 70))
+	two := NewBigInt(big.NewInt(2))
+	require.Equal(t, True, two.Equals(NewInt(2)))
+	require.Equal(t, True, NewInt(2).Equals(two))
+	require.Equal(t, True, NewFloat(2).Equals(two))
+	require.Equal(t, True, two.Equals(NewBigInt(big.NewInt(2))))
+	require.Equal(t, False, huge.Equals(NewInt(0)))
+	require.Equal(t, False, two.Equals(NewString("2")))
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	require.Equal(t, NewInt(5).HashKey(), NewBigInt(big.NewInt(5)).HashKey())
+	huge := NewBigInt(new(big.Int).Lsh(big.NewInt(1), 70))
+	require.Equal(t, HashKey{Type: BIGINT, StrValue: "1180591620717411303424"}, huge.HashKey())
+}
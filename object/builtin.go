@@ -8,11 +8,19 @@ import (
 // BuiltinFunction holds the type of a built-in function.
 type BuiltinFunction func(ctx context.Context, args ...Object) Object
 
+// KwargsBuiltinFunction holds the type of a built-in function that accepts
+// keyword arguments in addition to positional ones.
+type KwargsBuiltinFunction func(ctx context.Context, kwargs *Map, args ...Object) Object
+
 // Builtin wraps func and implements Object interface.
 type Builtin struct {
 	// The function that this object wraps.
 	fn BuiltinFunction
 
+	// The keyword argument aware function that this object wraps, if it
+	// was created with NewKwargsBuiltin.
+	kwfn KwargsBuiltinFunction
+
 	// The name of the function.
 	name string
 
@@ -44,10 +52,28 @@ func (b *Builtin) IsErrorHandler() bool {
 	return b.isErrorHandler
 }
 
+// AcceptsKwargs returns true if the function accepts keyword arguments.
+func (b *Builtin) AcceptsKwargs() bool {
+	return b.kwfn != nil
+}
+
 func (b *Builtin) Call(ctx context.Context, args ...Object) Object {
 	return b.fn(ctx, args...)
 }
 
+// CallWithKwargs calls the function with the given keyword arguments. An
+// error is returned if keyword arguments are given to a function that does
+// not accept them.
+func (b *Builtin) CallWithKwargs(ctx context.Context, kwargs *Map, args ...Object) Object {
+	if b.kwfn != nil {
+		return b.kwfn(ctx, kwargs, args...)
+	}
+	if kwargs.Size() > 0 {
+		return NewKwargsError(b.Key(), kwargs.StringKeys()[0])
+	}
+	return b.fn(ctx, args...)
+}
+
 func (b *Builtin) Inspect() string {
 	if b.module == nil {
 		return fmt.Sprintf("builtin(%s)", b.name)
@@ -126,6 +152,16 @@ func NewBuiltin(name string, fn BuiltinFunction, module ...*Module) *Builtin {
 	return b
 }
 
+// NewKwargsBuiltin creates a builtin function that accepts keyword
+// arguments. When it is called without any, kwargs is an empty map.
+func NewKwargsBuiltin(name string, fn KwargsBuiltinFunction, module ...*Module) *Builtin {
+	b := NewBuiltin(name, func(ctx context.Context, args ...Object) Object {
+		return fn(ctx, NewMap(nil), args...)
+	}, module...)
+	b.kwfn = fn
+	return b
+}
+
 func NewErrorHandler(name string, fn BuiltinFunction, module ...*Module) *Builtin {
 	b := NewBuiltin(name, fn, module...)
 	b.isErrorHandler = true
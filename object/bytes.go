@@ -0,0 +1,276 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Bytes wraps an immutable []byte and implements Object, Container and
+// Hashable interfaces. Items of a Bytes object are ints between 0 and 255.
+type Bytes struct {
+	// value holds the []byte wrapped by this object.
+	value []byte
+}
+
+// Inspect returns a call to the bytes built-in that creates the same bytes.
+// Printable UTF-8 text is given as a string, and anything else, such as
+// binary data, as a hex encoded string.
+func (b *Bytes) Inspect() string {
+	if text, ok := quoteText(b.value); ok {
+		return fmt.Sprintf("bytes(%s)", text)
+	}
+	return fmt.Sprintf(`bytes("%s", "hex")`, hex.EncodeToString(b.value))
+}
+
+// quoteText quotes the bytes as a string literal if they are valid UTF-8
+// made of printable characters and the escapes the lexer understands.
+func quoteText(value []byte) (string, bool) {
+	if !utf8.Valid(value) {
+		return "", false
+	}
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, r := range string(value) {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case unicode.IsPrint(r):
+			out.WriteRune(r)
+		default:
+			return "", false
+		}
+	}
+	out.WriteByte('"')
+	return out.String(), true
+}
+
+func (b *Bytes) Type() Type {
+	return BYTES
+}
+
+// Value returns the wrapped []byte, which must not be modified.
+func (b *Bytes) Value() []byte {
+	return b.value
+}
+
+func (b *Bytes) HashKey() HashKey {
+	return HashKey{Type: b.Type(), StrValue: string(b.value)}
+}
+
+func (b *Bytes) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "decode":
+		return NewBuiltin("bytes.decode", b.Decode), true
+	case "hex":
+		return NewBuiltin("bytes.hex", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("bytes.hex", 0, len(args))
+			}
+			return NewString(hex.EncodeToString(b.value))
+		}), true
+	}
+	return nil, false
+}
+
+// Interface returns a copy of the wrapped []byte.
+func (b *Bytes) Interface() interface{} {
+	return append([]byte(nil), b.value...)
+}
+
+func (b *Bytes) String() string {
+	return b.Inspect()
+}
+
+func (b *Bytes) Compare(other Object) (int, error) {
+	if other, ok := other.(*Bytes); ok {
+		return bytes.Compare(b.value, other.value), nil
+	}
+	return CompareTypes(b, other), nil
+}
+
+func (b *Bytes) Equals(other Object) Object {
+	if other, ok := other.(*Bytes); ok {
+		return NewBool(bytes.Equal(b.value, other.value))
+	}
+	return False
+}
+
+func (b *Bytes) IsTruthy() bool {
+	return len(b.value) > 0
+}
+
+func (b *Bytes) GetItem(key Object) (Object, *Error) {
+	indexObj, ok := key.(*Int)
+	if !ok {
+		return nil, Errorf("index error: bytes index must be an int (got %s)", key.Type())
+	}
+	index, err := ResolveIndex(indexObj.value, int64(len(b.value)))
+	if err != nil {
+		return nil, Errorf(err.Error())
+	}
+	return NewInt(int64(b.value[index])), nil
+}
+
+func (b *Bytes) GetSlice(slice Slice) (Object, *Error) {
+	if slice.Step == nil {
+		start, stop, err := ResolveIntSlice(slice, int64(len(b.value)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		return NewBytes(b.value[start:stop]), nil
+	}
+	indices, err := SliceIndices(slice, int64(len(b.value)))
+	if err != nil {
+		return nil, Errorf(err.Error())
+	}
+	result := make([]byte, 0, len(indices))
+	for _, idx := range indices {
+		result = append(result, b.value[idx])
+	}
+	return NewBytes(result), nil
+}
+
+func (b *Bytes) SetItem(key, value Object) *Error {
+	return Errorf("eval error: bytes does not support set item")
+}
+
+func (b *Bytes) DelItem(key Object) *Error {
+	return Errorf("eval error: bytes does not support del item")
+}
+
+// Contains returns true if the item is a number equal to one of the bytes,
+// or if it is a Bytes object or a string whose UTF-8 encoding is found as a
+// subsequence.
+func (b *Bytes) Contains(obj Object) *Bool {
+	switch obj := obj.(type) {
+	case *Int:
+		return NewBool(b.containsByte(obj.value))
+	case *BigInt:
+		return NewBool(obj.value.IsInt64() && b.containsByte(obj.value.Int64()))
+	case *Float:
+		return NewBool(obj.value >= 0 && obj.value <= 255 && obj.value == math.Trunc(obj.value) &&
+			b.containsByte(int64(obj.value)))
+	case *Decimal:
+		n := obj.normalize(0)
+		return NewBool(n.scale == 0 && n.value.IsInt64() && b.containsByte(n.value.Int64()))
+	case *Bytes:
+		return NewBool(bytes.Contains(b.value, obj.value))
+	case *String:
+		return NewBool(bytes.Contains(b.value, []byte(obj.value)))
+	}
+	return False
+}
+
+func (b *Bytes) containsByte(value int64) bool {
+	return value >= 0 && value <= 255 && bytes.IndexByte(b.value, byte(value)) >= 0
+}
+
+func (b *Bytes) Len() *Int {
+	return NewInt(int64(len(b.value)))
+}
+
+func (b *Bytes) Iter() Iterator {
+	return NewBytesIter(b)
+}
+
+// Decode converts the bytes to a string using the given encoding, which
+// defaults to "utf-8".
+func (b *Bytes) Decode(ctx context.Context, args ...Object) Object {
+	if len(args) > 1 {
+		return Errorf("type error: bytes.decode() takes at most 1 argument (%d given)", len(args))
+	}
+	encoding := "utf-8"
+	if len(args) == 1 {
+		var err *Error
+		if encoding, err = AsString(args[0]); err != nil {
+			return err
+		}
+	}
+	s, err := DecodeBytes(b.value, encoding)
+	if err != nil {
+		return err
+	}
+	return NewString(s)
+}
+
+// EncodeString converts a string to bytes using the named encoding. The
+// supported encodings are "utf-8", "ascii", "latin-1", "hex" and "base64".
+// For "hex" and "base64", the string holds the encoded form of the bytes.
+func EncodeString(s, encoding string) ([]byte, *Error) {
+	switch strings.ToLower(encoding) {
+	case "utf-8", "utf8":
+		return []byte(s), nil
+	case "ascii", "latin-1", "latin1":
+		limit := rune(0xff)
+		if strings.ToLower(encoding) == "ascii" {
+			limit = 0x7f
+		}
+		result := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > limit {
+				return nil, Errorf("value error: cannot encode %q as %s", r, encoding)
+			}
+			result = append(result, byte(r))
+		}
+		return result, nil
+	case "hex":
+		result, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, Errorf("value error: invalid hex string: %s", err)
+		}
+		return result, nil
+	case "base64":
+		result, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, Errorf("value error: invalid base64 string: %s", err)
+		}
+		return result, nil
+	}
+	return nil, Errorf("value error: unknown encoding: %q", encoding)
+}
+
+// DecodeBytes converts bytes to a string using the named encoding. It is
+// the inverse of EncodeString.
+func DecodeBytes(b []byte, encoding string) (string, *Error) {
+	switch strings.ToLower(encoding) {
+	case "utf-8", "utf8":
+		if !utf8.Valid(b) {
+			return "", Errorf("value error: bytes are not valid utf-8")
+		}
+		return string(b), nil
+	case "ascii", "latin-1", "latin1":
+		isASCII := strings.ToLower(encoding) == "ascii"
+		runes := make([]rune, 0, len(b))
+		for _, c := range b {
+			if isASCII && c > 0x7f {
+				return "", Errorf("value error: byte 0x%02x is not valid ascii", c)
+			}
+			runes = append(runes, rune(c))
+		}
+		return string(runes), nil
+	case "hex":
+		return hex.EncodeToString(b), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+	return "", Errorf("value error: unknown encoding: %q", encoding)
+}
+
+// NewBytes returns a Bytes object holding a copy of the given slice.
+func NewBytes(value []byte) *Bytes {
+	return &Bytes{value: append([]byte(nil), value...)}
+}
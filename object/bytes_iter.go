@@ -0,0 +1,77 @@
+package object
+
+import (
+	"context"
+	"fmt"
+)
+
+type BytesIter struct {
+	b   *Bytes
+	pos int64
+}
+
+func (iter *BytesIter) Type() Type {
+	return BYTES_ITER
+}
+
+func (iter *BytesIter) Inspect() string {
+	return fmt.Sprintf("bytes_iter(%s)", iter.b.Inspect())
+}
+
+func (iter *BytesIter) Interface() interface{} {
+	var entries []map[string]interface{}
+	for {
+		entry, ok := iter.Next()
+		if !ok {
+			break
+		}
+		entries = append(entries, entry.Interface().(map[string]interface{}))
+	}
+	return entries
+}
+
+func (iter *BytesIter) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *BytesIter:
+		return NewBool(iter == other)
+	default:
+		return False
+	}
+}
+
+func (iter *BytesIter) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("bytes_iter.next", 0, len(args))
+				}
+				entry, ok := iter.Next()
+				if !ok {
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (iter *BytesIter) IsTruthy() bool {
+	return iter.pos < int64(len(iter.b.value))
+}
+
+func (iter *BytesIter) Next() (IteratorEntry, bool) {
+	if iter.pos >= int64(len(iter.b.value)) {
+		return nil, false
+	}
+	entry := NewEntry(NewInt(iter.pos), NewInt(int64(iter.b.value[iter.pos])))
+	iter.pos++
+	return entry, true
+}
+
+func NewBytesIter(b *Bytes) *BytesIter {
+	return &BytesIter{b: b, pos: 0}
+}
@@ -0,0 +1,86 @@
+package object
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytesBasics(t *testing.T) {
+	value := []byte("a\"\x00\xff")
+	b := NewBytes(value)
+	value[0] = 'z'
+	require.Equal(t, BYTES, b.Type())
+	require.Equal(t, []byte("a\"\x00\xff"), b.Value())
+	require.Equal(t, `bytes("612200ff", "hex")`, b.Inspect())
+	require.Equal(t, `bytes("a\"\\\n\té")`, NewBytes([]byte("a\"\\\n\té")).Inspect())
+	require.Equal(t, `bytes("")`, NewBytes(nil).Inspect())
+	require.Equal(t, []byte("a\"\x00\xff"), b.Interface())
+	require.True(t, b.IsTruthy())
+	require.False(t, NewBytes(nil).IsTruthy())
+	require.Equal(t, True, b.Equals(NewBytes([]byte("a\"\x00\xff"))))
+	require.Equal(t, False, b.Equals(NewString("a\"\x00\xff")))
+	require.NotEqual(t, b.HashKey(), NewString("a\"\x00\xff").HashKey())
+}
+
+func TestBytesContainer(t *testing.T) {
+	b := NewBytes([]byte("hello"))
+	require.Equal(t, int64(5), b.Len().Value())
+
+	item, err := b.GetItem(NewInt(-1))
+	require.Nil(t, err)
+	require.Equal(t, NewInt('o'), item)
+	_, err = b.GetItem(NewInt(5))
+	require.NotNil(t, err)
+
+	slice, err := b.GetSlice(Slice{Start: NewInt(1), Stop: NewInt(3)})
+	require.Nil(t, err)
+	require.Equal(t, []byte("el"), slice.(*Bytes).Value())
+
+	require.True(t, b.Contains(NewInt('h')).Value())
+	require.False(t, b.Contains(NewInt(256)).Value())
+	require.True(t, b.Contains(NewBytes([]byte("ell"))).Value())
+	require.True(t, b.Contains(NewString("ell")).Value())
+	require.False(t, b.Contains(NewString("x")).Value())
+	require.True(t, b.Contains(NewFloat('h')).Value())
+	require.False(t, b.Contains(NewFloat('h'+0.5)).Value())
+	require.True(t, b.Contains(NewBigInt(big.NewInt('h'))).Value())
+	require.True(t, b.Contains(NewDecimal(big.NewInt('h'*10), 1)).Value())
+	require.False(t, b.Contains(Nil).Value())
+	require.NotNil(t, b.SetItem(NewInt(0), NewInt(1)))
+
+	iter := b.Iter()
+	entry, ok := iter.Next()
+	require.True(t, ok)
+	require.Equal(t, NewInt(0), entry.Key())
+	require.Equal(t, NewInt('h'), entry.Value())
+}
+
+func TestBytesEncodings(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		value    []byte
+	}{
+		{"utf-8", "é", []byte{0xc3, 0xa9}},
+		{"latin-1", "é", []byte{0xe9}},
+		{"ascii", "hi", []byte("hi")},
+		{"hex", "00ff", []byte{0x00, 0xff}},
+		{"base64", "aGk=", []byte("hi")},
+	}
+	for _, tt := range tests {
+		value, err := EncodeString(tt.text, tt.encoding)
+		require.Nil(t, err, tt.encoding)
+		require.Equal(t, tt.value, value, tt.encoding)
+		text, err := DecodeBytes(tt.value, tt.encoding)
+		require.Nil(t, err, tt.encoding)
+		require.Equal(t, tt.text, text, tt.encoding)
+	}
+	_, err := EncodeString("é", "ascii")
+	require.Equal(t, "value error: cannot encode 'é' as ascii", err.Message().Value())
+	_, err = DecodeBytes([]byte{0xff}, "utf-8")
+	require.Equal(t, "value error: bytes are not valid utf-8", err.Message().Value())
+	_, err = DecodeBytes(nil, "rot13")
+	require.Equal(t, `value error: unknown encoding: "rot13"`, err.Message().Value())
+}
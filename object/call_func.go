@@ -24,3 +24,39 @@ func GetCallFunc(ctx context.Context) (CallFunc, bool) {
 	fn, ok := ctx.Value(callFuncKey).(CallFunc)
 	return fn, ok
 }
+
+// KwargsCallFunc defines a type signature for a function that can call a
+// Tamarin function with keyword arguments.
+type KwargsCallFunc func(ctx context.Context, fn Object, args []Object, kwargs *Map) Object
+
+const kwargsCallFuncKey = contextKey("kwargs_evaluator")
+
+// WithKwargsCallFunc adds a KwargsCallFunc to the context, which can be
+// used by objects to call a Tamarin function with keyword arguments.
+func WithKwargsCallFunc(ctx context.Context, fn KwargsCallFunc) context.Context {
+	return context.WithValue(ctx, kwargsCallFuncKey, fn)
+}
+
+// GetKwargsCallFunc returns the KwargsCallFunc from the context, if it
+// exists.
+func GetKwargsCallFunc(ctx context.Context) (KwargsCallFunc, bool) {
+	fn, ok := ctx.Value(kwargsCallFuncKey).(KwargsCallFunc)
+	return fn, ok
+}
+
+// callFunction calls a Tamarin function using the call function found in
+// the context. The name is used in the error returned if there isn't one.
+func callFunction(ctx context.Context, name string, fn Object, args []Object, kwargs *Map) Object {
+	if kwargs.Size() == 0 {
+		callFunc, found := GetCallFunc(ctx)
+		if !found {
+			return Errorf("eval error: %s() context did not contain a call function", name)
+		}
+		return callFunc(ctx, nil, fn, args)
+	}
+	callFunc, found := GetKwargsCallFunc(ctx)
+	if !found {
+		return Errorf("eval error: %s() context did not contain a call function", name)
+	}
+	return callFunc(ctx, fn, args, kwargs)
+}
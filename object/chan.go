@@ -0,0 +1,148 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Chan is a channel used to communicate between goroutines started by go
+// statements.
+type Chan struct {
+	value chan Object
+}
+
+func (c *Chan) Type() Type {
+	return CHAN
+}
+
+func (c *Chan) Value() chan Object {
+	return c.value
+}
+
+func (c *Chan) Inspect() string {
+	return fmt.Sprintf("chan(%d)", cap(c.value))
+}
+
+func (c *Chan) Interface() interface{} {
+	return c.value
+}
+
+func (c *Chan) Equals(other Object) Object {
+	if o, ok := other.(*Chan); ok && c == o {
+		return True
+	}
+	return False
+}
+
+func (c *Chan) IsTruthy() bool {
+	return true
+}
+
+func (c *Chan) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "send":
+		return NewBuiltin("chan.send", c.Send), true
+	case "recv":
+		return NewBuiltin("chan.recv", c.Recv), true
+	case "close":
+		return NewBuiltin("chan.close", c.Close), true
+	}
+	return nil, false
+}
+
+// Send blocks until the given value is sent on the channel.
+func (c *Chan) Send(ctx context.Context, args ...Object) Object {
+	if len(args) != 1 {
+		return NewArgsError("chan.send", 1, len(args))
+	}
+	if _, _, _, err := Select(ctx, []SelectCase{{Chan: c, Send: true, Value: args[0]}}, true); err != nil {
+		return err
+	}
+	return Nil
+}
+
+// Recv blocks until a value is received from the channel. Once the channel
+// is closed and empty, nil is returned.
+func (c *Chan) Recv(ctx context.Context, args ...Object) Object {
+	if len(args) != 0 {
+		return NewArgsError("chan.recv", 0, len(args))
+	}
+	_, value, _, err := Select(ctx, []SelectCase{{Chan: c}}, true)
+	if err != nil {
+		return err
+	}
+	return value
+}
+
+// Close closes the channel, after which sending on it is an error.
+func (c *Chan) Close(ctx context.Context, args ...Object) (result Object) {
+	if len(args) != 0 {
+		return NewArgsError("chan.close", 0, len(args))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result = Errorf("value error: close of closed channel")
+		}
+	}()
+	close(c.value)
+	return Nil
+}
+
+func NewChan(size int) *Chan {
+	return &Chan{value: make(chan Object, size)}
+}
+
+// SelectCase describes one channel operation passed to Select.
+type SelectCase struct {
+	// Chan is the channel to operate on.
+	Chan *Chan
+
+	// Send is true to send Value on the channel and false to receive.
+	Send bool
+
+	// Value is the object to send.
+	Value Object
+}
+
+// Select waits until one of the given channel operations can proceed and
+// performs it, returning the index of the chosen case. When the chosen case
+// is a receive, the received value is returned along with a flag that is
+// false if the channel was closed. If block is false and no operation is
+// ready, the index is -1. Waiting stops with an error if the context is
+// cancelled.
+func Select(ctx context.Context, cases []SelectCase, block bool) (chosen int, value Object, ok bool, err *Error) {
+	selectCases := make([]reflect.SelectCase, 0, len(cases)+1)
+	for _, c := range cases {
+		selectCase := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.Chan.value)}
+		if c.Send {
+			selectCase.Dir = reflect.SelectSend
+			selectCase.Send = reflect.ValueOf(&c.Value).Elem()
+		}
+		selectCases = append(selectCases, selectCase)
+	}
+	if block {
+		selectCases = append(selectCases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	} else {
+		selectCases = append(selectCases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			chosen, value, ok, err = -1, nil, false, Errorf("value error: send on closed channel")
+		}
+	}()
+	chosen, recv, recvOK := reflect.Select(selectCases)
+	if chosen == len(cases) {
+		if block {
+			return -1, nil, false, NewError(ctx.Err())
+		}
+		return -1, nil, false, nil
+	}
+	if cases[chosen].Send {
+		return chosen, nil, false, nil
+	}
+	if !recvOK {
+		return chosen, Nil, false, nil
+	}
+	return chosen, recv.Interface().(Object), true, nil
+}
@@ -0,0 +1,44 @@
+package object
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanSendRecv(t *testing.T) {
+	ctx := context.Background()
+	c := NewChan(1)
+	require.Equal(t, "chan(1)", c.Inspect())
+	require.Equal(t, Nil, c.Send(ctx, NewInt(42)))
+	require.Equal(t, NewInt(42), c.Recv(ctx))
+	require.Equal(t, Nil, c.Close(ctx))
+	require.Equal(t, Nil, c.Recv(ctx))
+	require.Equal(t, Errorf("value error: close of closed channel"), c.Close(ctx))
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	a, b := NewChan(0), NewChan(1)
+
+	// Nothing is ready, so a non-blocking select chooses no case
+	chosen, _, _, err := Select(ctx, []SelectCase{{Chan: a}, {Chan: b}}, false)
+	require.Nil(t, err)
+	require.Equal(t, -1, chosen)
+
+	chosen, _, _, err = Select(ctx, []SelectCase{{Chan: a}, {Chan: b, Send: true, Value: True}}, true)
+	require.Nil(t, err)
+	require.Equal(t, 1, chosen)
+
+	chosen, value, ok, err := Select(ctx, []SelectCase{{Chan: a}, {Chan: b}}, true)
+	require.Nil(t, err)
+	require.Equal(t, 1, chosen)
+	require.Equal(t, True, value)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, _, err = Select(ctx, []SelectCase{{Chan: a}}, true)
+	require.NotNil(t, err)
+}
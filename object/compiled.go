@@ -0,0 +1,246 @@
+package object
+
+import (
+	"sort"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/token"
+)
+
+// SourcePosition records the source code that the instructions starting at
+// Offset were compiled from.
+type SourcePosition struct {
+	Offset int
+	Start  token.Position
+	End    token.Position
+}
+
+// CompiledFunctionOpts configures a new CompiledFunction.
+type CompiledFunctionOpts struct {
+	// Name of the function, or an empty string for anonymous functions.
+	Name string
+
+	// Instructions is the bytecode for the function body.
+	Instructions []byte
+
+	// NumParameters is the number of declared parameters.
+	NumParameters int
+
+	// NumLocals is the number of local variable slots, including parameters.
+	NumLocals int
+
+	// LocalNames contains the name of each local variable slot.
+	LocalNames []string
+
+	// FreeNames contains the name of each variable captured by the function.
+	FreeNames []string
+
+	// CellParameters lists the parameters that are captured by closures.
+	CellParameters []int
+
+	// HasDefaults is true if any parameter has a default value.
+	HasDefaults bool
+
+	// Variadic is true if the last parameter collects any extra arguments
+	// into a list.
+	Variadic bool
+
+	// Node is the AST the function was compiled from.
+	Node *ast.Func
+
+	// Positions maps instructions to source code, ordered by offset.
+	Positions []SourcePosition
+}
+
+// CompiledFunction contains the bytecode for a function produced by the
+// compiler package. It is not directly visible to Tamarin code, which
+// instead sees a Closure created from it.
+type CompiledFunction struct {
+	name           string
+	instructions   []byte
+	numParameters  int
+	numLocals      int
+	localNames     []string
+	freeNames      []string
+	cellParameters []int
+	hasDefaults    bool
+	variadic       bool
+	node           *ast.Func
+	positions      []SourcePosition
+}
+
+func (f *CompiledFunction) Type() Type {
+	return COMPILED_FUNCTION
+}
+
+func (f *CompiledFunction) Name() string {
+	if f.name == "" {
+		return "anonymous"
+	}
+	return f.name
+}
+
+func (f *CompiledFunction) Inspect() string {
+	if f.node == nil {
+		return "compiled_function()"
+	}
+	return inspectFunction(f.name, f.node.Parameters(), f.node.Defaults(), f.node.Body(), f.variadic, f.node.Types())
+}
+
+func (f *CompiledFunction) Instructions() []byte {
+	return f.instructions
+}
+
+func (f *CompiledFunction) NumParameters() int {
+	return f.numParameters
+}
+
+func (f *CompiledFunction) NumLocals() int {
+	return f.numLocals
+}
+
+// LocalName returns the name of the local variable in the given slot.
+func (f *CompiledFunction) LocalName(index int) string {
+	if index < len(f.localNames) {
+		return f.localNames[index]
+	}
+	return ""
+}
+
+// FreeName returns the name of the captured variable at the given index.
+func (f *CompiledFunction) FreeName(index int) string {
+	if index < len(f.freeNames) {
+		return f.freeNames[index]
+	}
+	return ""
+}
+
+func (f *CompiledFunction) CellParameters() []int {
+	return f.cellParameters
+}
+
+func (f *CompiledFunction) HasDefaults() bool {
+	return f.hasDefaults
+}
+
+func (f *CompiledFunction) Variadic() bool {
+	return f.variadic
+}
+
+// Generator returns true if the function contains a yield statement, in
+// which case calling it returns a generator.
+func (f *CompiledFunction) Generator() bool {
+	return f.node != nil && f.node.Generator()
+}
+
+// Node returns the AST the function was compiled from.
+func (f *CompiledFunction) Node() *ast.Func {
+	return f.node
+}
+
+// Position returns the source position of the instruction at the given
+// offset, if it is known.
+func (f *CompiledFunction) Position(offset int) (SourcePosition, bool) {
+	i := sort.Search(len(f.positions), func(i int) bool {
+		return f.positions[i].Offset > offset
+	})
+	if i == 0 {
+		return SourcePosition{}, false
+	}
+	return f.positions[i-1], true
+}
+
+func (f *CompiledFunction) GetAttr(name string) (Object, bool) {
+	return nil, false
+}
+
+func (f *CompiledFunction) Interface() interface{} {
+	return "function()"
+}
+
+func (f *CompiledFunction) Equals(other Object) Object {
+	if other.Type() == COMPILED_FUNCTION && f == other.(*CompiledFunction) {
+		return True
+	}
+	return False
+}
+
+func (f *CompiledFunction) IsTruthy() bool {
+	return true
+}
+
+func NewCompiledFunction(opts CompiledFunctionOpts) *CompiledFunction {
+	return &CompiledFunction{
+		name:           opts.Name,
+		instructions:   opts.Instructions,
+		numParameters:  opts.NumParameters,
+		numLocals:      opts.NumLocals,
+		localNames:     opts.LocalNames,
+		freeNames:      opts.FreeNames,
+		cellParameters: opts.CellParameters,
+		hasDefaults:    opts.HasDefaults,
+		variadic:       opts.Variadic,
+		node:           opts.Node,
+		positions:      opts.Positions,
+	}
+}
+
+// Cell holds a variable that is shared between a function and the
+// closures that capture it.
+type Cell struct {
+	Value Object
+}
+
+// Closure is a CompiledFunction combined with the variables it captured
+// from enclosing functions. To Tamarin code it behaves like a Function.
+type Closure struct {
+	fn   *CompiledFunction
+	free []*Cell
+}
+
+func (c *Closure) Type() Type {
+	return FUNCTION
+}
+
+func (c *Closure) Name() string {
+	return c.fn.Name()
+}
+
+func (c *Closure) Inspect() string {
+	return c.fn.Inspect()
+}
+
+func (c *Closure) Function() *CompiledFunction {
+	return c.fn
+}
+
+func (c *Closure) Free() []*Cell {
+	return c.free
+}
+
+func (c *Closure) NumParameters() int {
+	return c.fn.numParameters
+}
+
+func (c *Closure) GetAttr(name string) (Object, bool) {
+	return nil, false
+}
+
+func (c *Closure) Interface() interface{} {
+	return "function()"
+}
+
+func (c *Closure) Equals(other Object) Object {
+	if o, ok := other.(*Closure); ok && c == o {
+		return True
+	}
+	return False
+}
+
+func (c *Closure) IsTruthy() bool {
+	return true
+}
+
+func NewClosure(fn *CompiledFunction, free []*Cell) *Closure {
+	return &Closure{fn: fn, free: free}
+}
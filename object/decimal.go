@@ -0,0 +1,510 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode determines how a Decimal is rounded when digits are dropped.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest neighbor, and to the even
+	// neighbor if both are equally near. This is the default mode.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest neighbor, and away from zero if
+	// both are equally near.
+	RoundHalfUp
+	// RoundHalfDown rounds to the nearest neighbor, and toward zero if both
+	// are equally near.
+	RoundHalfDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds toward zero, truncating the dropped digits.
+	RoundDown
+	// RoundCeiling rounds toward positive infinity.
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor
+)
+
+var roundingModes = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+// ParseRoundingMode returns the rounding mode with the given name, such as
+// "half_even" or "floor".
+func ParseRoundingMode(name string) (RoundingMode, bool) {
+	mode, ok := roundingModes[name]
+	return mode, ok
+}
+
+// DecimalDivisionPlaces is the default number of digits after the decimal
+// point kept by the / operator when the quotient is not exact. It may be
+// changed with WithDecimalDivisionPlaces. The div method of a decimal
+// accepts any number of places instead.
+const DecimalDivisionPlaces = 16
+
+// maxDecimalScale limits the number of digits after the decimal point, so
+// that a typo in an exponent or repeated multiplication can't allocate an
+// enormous number. Results with more digits are rounded half to even.
+const maxDecimalScale = 1 << 16
+
+var bigTen = big.NewInt(10)
+
+// Decimal is an exact decimal number, represented by an arbitrary-precision
+// integer and a scale that gives the number of digits after the decimal
+// point. For example, 12.50 has the value 1250 and the scale 2. The scale is
+// never negative. The wrapped integer is never modified, so operations on a
+// Decimal always return a new object.
+type Decimal struct {
+	value *big.Int
+	scale int32
+}
+
+func (d *Decimal) Inspect() string {
+	digits := new(big.Int).Abs(d.value).String()
+	if d.scale > 0 {
+		scale := int(d.scale)
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if d.value.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func (d *Decimal) Type() Type {
+	return DECIMAL
+}
+
+// Value returns the unscaled integer value, which must not be modified.
+func (d *Decimal) Value() *big.Int {
+	return d.value
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d *Decimal) Scale() int32 {
+	return d.scale
+}
+
+// HashKey returns the same key for decimals that are equal regardless of
+// their scale. Whole numbers have the same key as an equal int or bigint.
+func (d *Decimal) HashKey() HashKey {
+	n := d.normalize(0)
+	if n.scale == 0 {
+		return NewBigInt(n.value).HashKey()
+	}
+	return HashKey{Type: d.Type(), StrValue: n.Inspect()}
+}
+
+func (d *Decimal) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "round":
+		return NewBuiltin("decimal.round", d.roundMethod), true
+	case "div":
+		return NewBuiltin("decimal.div", d.divMethod), true
+	case "scale":
+		return NewBuiltin("decimal.scale", d.scaleMethod), true
+	}
+	return nil, false
+}
+
+// Interface returns the decimal as a json.Number, which is marshalled as a
+// JSON number without any loss of precision.
+func (d *Decimal) Interface() interface{} {
+	return json.Number(d.Inspect())
+}
+
+func (d *Decimal) String() string {
+	return fmt.Sprintf("decimal(%s)", d.Inspect())
+}
+
+func (d *Decimal) Compare(other Object) (int, error) {
+	if o, ok := ToDecimal(other); ok {
+		return d.Cmp(o), nil
+	}
+	return CompareTypes(d, other), nil
+}
+
+func (d *Decimal) Equals(other Object) Object {
+	if o, ok := ToDecimal(other); ok {
+		return NewBool(d.Cmp(o) == 0)
+	}
+	return False
+}
+
+func (d *Decimal) IsTruthy() bool {
+	return d.value.Sign() != 0
+}
+
+// Cmp compares two decimals by value and returns -1, 0, or 1.
+func (d *Decimal) Cmp(other *Decimal) int {
+	a, b := align(d, other)
+	return a.Cmp(b)
+}
+
+// Add returns the sum of two decimals.
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	a, b := align(d, other)
+	return NewDecimal(new(big.Int).Add(a, b), maxScale(d, other))
+}
+
+// Sub returns the difference of two decimals.
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	a, b := align(d, other)
+	return NewDecimal(new(big.Int).Sub(a, b), maxScale(d, other))
+}
+
+// Mul returns the product of two decimals. The scale of the product is the
+// sum of their scales, so it is rounded if that exceeds the maximum scale.
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return NewDecimal(new(big.Int).Mul(d.value, other.value), d.scale+other.scale)
+}
+
+// Neg returns the negation of the decimal.
+func (d *Decimal) Neg() *Decimal {
+	return NewDecimal(new(big.Int).Neg(d.value), d.scale)
+}
+
+// Pow returns the decimal raised to a non-negative integer power.
+func (d *Decimal) Pow(exp int64) (*Decimal, bool) {
+	if exp < 0 || (d.scale > 0 && exp > maxDecimalScale/int64(d.scale)) {
+		return nil, false
+	}
+	value := new(big.Int).Exp(d.value, big.NewInt(exp), nil)
+	return NewDecimal(value, d.scale*int32(exp)), true
+}
+
+// Div returns the quotient of two decimals with the given number of digits
+// after the decimal point, rounded using the given mode. The places are
+// limited to the maximum scale. False is returned if the divisor is zero.
+func (d *Decimal) Div(other *Decimal, places int32, mode RoundingMode) (*Decimal, bool) {
+	if other.value.Sign() == 0 {
+		return nil, false
+	}
+	places = clampPlaces(places)
+	// Scale the dividend so that the integer quotient has one more digit
+	// than requested, which is enough to round correctly when combined with
+	// the remainder.
+	shift := int64(places) + 1 + int64(other.scale) - int64(d.scale)
+	num, den := new(big.Int).Set(d.value), new(big.Int).Set(other.value)
+	if shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() != 0 {
+		// Append a digit that marks the quotient as inexact, so that it is
+		// never rounded as a tie
+		q.Mul(q, bigTen)
+		if num.Sign() != den.Sign() {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+		return round(q, int64(places)+2, places, mode), true
+	}
+	return round(q, int64(places)+1, places, mode), true
+}
+
+// Quo returns the quotient of two decimals as computed by the / operator.
+// Exact quotients are returned as is, while others are rounded half to
+// even after the given number of places, or the scale of the operands if
+// that is larger. Trailing zeros beyond the scale of the operands are
+// removed. False is returned if the divisor is zero.
+func (d *Decimal) Quo(other *Decimal, places int32) (*Decimal, bool) {
+	if scale := maxScale(d, other); places < scale {
+		places = scale
+	}
+	q, ok := d.Div(other, places, RoundHalfEven)
+	if !ok {
+		return nil, false
+	}
+	return q.normalize(maxScale(d, other)), true
+}
+
+// Rem returns the remainder of dividing two decimals, truncating the
+// quotient toward zero like the % operator on ints. False is returned if
+// the divisor is zero.
+func (d *Decimal) Rem(other *Decimal) (*Decimal, bool) {
+	if other.value.Sign() == 0 {
+		return nil, false
+	}
+	a, b := align(d, other)
+	return NewDecimal(new(big.Int).Rem(a, b), maxScale(d, other)), true
+}
+
+// Round returns the decimal with exactly the given number of digits after
+// the decimal point, rounding with the given mode if digits are dropped.
+// The places are limited to the maximum scale.
+func (d *Decimal) Round(places int32, mode RoundingMode) *Decimal {
+	return round(d.value, int64(d.scale), clampPlaces(places), mode)
+}
+
+// round returns the value with the given scale rounded to the given number
+// of places.
+func round(value *big.Int, scale int64, places int32, mode RoundingMode) *Decimal {
+	if int64(places) >= scale {
+		value := new(big.Int).Mul(value, pow10(int64(places)-scale))
+		return NewDecimal(value, places)
+	}
+	dropped := scale - int64(places)
+	if dropped > int64(value.BitLen())/3+1 {
+		// All digits are dropped and the value is less than half of the
+		// last place kept, which avoids computing a huge power of ten
+		var away bool
+		switch mode {
+		case RoundUp:
+			away = true
+		case RoundCeiling:
+			away = value.Sign() > 0
+		case RoundFloor:
+			away = value.Sign() < 0
+		}
+		q := new(big.Int)
+		if away {
+			q.SetInt64(int64(value.Sign()))
+		}
+		return NewDecimal(q, places)
+	}
+	divisor := pow10(dropped)
+	q, r := new(big.Int).QuoRem(value, divisor, new(big.Int))
+	if r.Sign() == 0 {
+		return NewDecimal(q, places)
+	}
+	// Compare twice the remainder against the divisor to find ties
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(divisor)
+	var away bool
+	switch mode {
+	case RoundHalfEven:
+		away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundHalfDown:
+		away = cmp > 0
+	case RoundUp:
+		away = true
+	case RoundCeiling:
+		away = r.Sign() > 0
+	case RoundFloor:
+		away = r.Sign() < 0
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	return NewDecimal(q, places)
+}
+
+// normalize removes trailing zeros after the decimal point, keeping at
+// least minScale digits.
+func (d *Decimal) normalize(minScale int32) *Decimal {
+	value, scale := new(big.Int).Set(d.value), d.scale
+	r := new(big.Int)
+	for scale > minScale {
+		q, _ := new(big.Int).QuoRem(value, bigTen, r)
+		if r.Sign() != 0 {
+			break
+		}
+		value, scale = q, scale-1
+	}
+	return NewDecimal(value, scale)
+}
+
+func (d *Decimal) roundMethod(ctx context.Context, args ...Object) Object {
+	if len(args) > 2 {
+		return Errorf("type error: decimal.round() takes at most 2 arguments (%d given)", len(args))
+	}
+	places, mode, err := decimalPlaces("decimal.round", args)
+	if err != nil {
+		return err
+	}
+	return d.Round(places, mode)
+}
+
+func (d *Decimal) divMethod(ctx context.Context, args ...Object) Object {
+	if len(args) < 2 || len(args) > 3 {
+		return Errorf("type error: decimal.div() takes 2 or 3 arguments (%d given)", len(args))
+	}
+	other, ok := ToDecimal(args[0])
+	if !ok {
+		return Errorf("type error: decimal.div() expected a decimal, int, or bigint argument (%s given)", args[0].Type())
+	}
+	places, mode, err := decimalPlaces("decimal.div", args[1:])
+	if err != nil {
+		return err
+	}
+	result, ok := d.Div(other, places, mode)
+	if !ok {
+		return Errorf("eval error: decimal divided by zero")
+	}
+	return result
+}
+
+func (d *Decimal) scaleMethod(ctx context.Context, args ...Object) Object {
+	if len(args) != 0 {
+		return NewArgsError("decimal.scale", 0, len(args))
+	}
+	return NewInt(int64(d.scale))
+}
+
+// decimalPlaces parses the optional places and rounding mode arguments of
+// the decimal methods. Both default to zero places and half_even.
+func decimalPlaces(name string, args []Object) (int32, RoundingMode, *Error) {
+	var places int64
+	mode := RoundHalfEven
+	if len(args) > 0 {
+		i, err := AsInt(args[0])
+		if err != nil {
+			return 0, mode, err
+		}
+		if i < 0 || i > maxDecimalScale {
+			return 0, mode, Errorf("value error: %s() places must be between 0 and %d (got %d)", name, maxDecimalScale, i)
+		}
+		places = i
+	}
+	if len(args) > 1 {
+		s, err := AsString(args[1])
+		if err != nil {
+			return 0, mode, err
+		}
+		var ok bool
+		if mode, ok = ParseRoundingMode(s); !ok {
+			return 0, mode, Errorf("value error: %s() unknown rounding mode: %q", name, s)
+		}
+	}
+	return int32(places), mode, nil
+}
+
+// align returns the unscaled values of two decimals, scaled up so that
+// they have the same scale.
+func align(a, b *Decimal) (*big.Int, *big.Int) {
+	switch {
+	case a.scale < b.scale:
+		return new(big.Int).Mul(a.value, pow10(int64(b.scale-a.scale))), b.value
+	case a.scale > b.scale:
+		return a.value, new(big.Int).Mul(b.value, pow10(int64(a.scale-b.scale)))
+	}
+	return a.value, b.value
+}
+
+// clampPlaces limits a number of places to the maximum scale.
+func clampPlaces(places int32) int32 {
+	if places > maxDecimalScale {
+		return maxDecimalScale
+	}
+	return places
+}
+
+func maxScale(a, b *Decimal) int32 {
+	if a.scale > b.scale {
+		return a.scale
+	}
+	return b.scale
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(n), nil)
+}
+
+// ToDecimal converts a decimal, int, or bigint to a Decimal. Floats are not
+// converted implicitly, since their binary representation is inexact.
+func ToDecimal(obj Object) (*Decimal, bool) {
+	switch obj := obj.(type) {
+	case *Decimal:
+		return obj, true
+	case *Int:
+		return NewDecimal(big.NewInt(obj.value), 0), true
+	case *BigInt:
+		return NewDecimal(obj.value, 0), true
+	}
+	return nil, false
+}
+
+// ParseDecimal parses a decimal number such as "12.50", "-3" or "1.5e3".
+func ParseDecimal(s string) (*Decimal, bool) {
+	mantissa, exponent := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil || exp > maxDecimalScale || exp < -maxDecimalScale {
+			return nil, false
+		}
+		mantissa, exponent = s[:i], exp
+	}
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	digits := whole + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, false
+	}
+	value, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return nil, false
+	}
+	scale := int64(len(frac)) - exponent
+	if scale > maxDecimalScale {
+		return nil, false
+	}
+	if scale < 0 {
+		value.Mul(value, pow10(-scale))
+		scale = 0
+	}
+	return NewDecimal(value, int32(scale)), true
+}
+
+// NewDecimal returns a Decimal with the given unscaled value and scale. The
+// value must not be modified afterwards. A negative scale multiplies the
+// value by a power of ten, and a scale above the maximum of 65536 digits
+// rounds the value half to even.
+func NewDecimal(value *big.Int, scale int32) *Decimal {
+	if scale > maxDecimalScale {
+		return round(value, int64(scale), maxDecimalScale, RoundHalfEven)
+	}
+	if scale < 0 {
+		return &Decimal{value: new(big.Int).Mul(value, pow10(int64(-scale)))}
+	}
+	return &Decimal{value: value, scale: scale}
+}
+
+const decimalDivisionPlacesKey = contextKey("decimal_division_places")
+
+// WithDecimalDivisionPlaces sets the number of digits after the decimal
+// point kept by the / operator when dividing decimals in programs run with
+// the context. The places are limited to between zero and the maximum
+// scale of a decimal.
+func WithDecimalDivisionPlaces(ctx context.Context, places int) context.Context {
+	if places < 0 {
+		places = 0
+	} else if places > maxDecimalScale {
+		places = maxDecimalScale
+	}
+	return context.WithValue(ctx, decimalDivisionPlacesKey, int32(places))
+}
+
+// GetDecimalDivisionPlaces returns the number of places kept by the /
+// operator set in the context, or DecimalDivisionPlaces if there isn't one.
+func GetDecimalDivisionPlaces(ctx context.Context) int32 {
+	if places, ok := ctx.Value(decimalDivisionPlacesKey).(int32); ok {
+		return places
+	}
+	return DecimalDivisionPlaces
+}
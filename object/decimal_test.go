@@ -0,0 +1,173 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecimal(t *testing.T, s string) *Decimal {
+	t.Helper()
+	d, ok := ParseDecimal(s)
+	require.True(t, ok, s)
+	return d
+}
+
+func TestDecimalBasics(t *testing.T) {
+	d := mustDecimal(t, "-12.50")
+	require.Equal(t, DECIMAL, d.Type())
+	require.Equal(t, big.NewInt(-1250), d.Value())
+	require.Equal(t, int32(2), d.Scale())
+	require.Equal(t, "decimal(-12.50)", d.String())
+	require.Equal(t, "-12.50", d.Inspect())
+	require.Equal(t, json.Number("-12.50"), d.Interface())
+	require.True(t, d.IsTruthy())
+	require.False(t, mustDecimal(t, "0.00").IsTruthy())
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0"},
+		{"-0.05", "-0.05"},
+		{"+3", "3"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{"1.5e3", "1500"},
+		{"1.5E-3", "0.0015"},
+		{"123456789012345678901234567890.123", "123456789012345678901234567890.123"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, mustDecimal(t, tt.input).Inspect(), tt.input)
+	}
+	for _, input := range []string{"", "-", ".", "1.2.3", "1e", "abc", "1_000", "--1", "NaN", "1e99999999"} {
+		_, ok := ParseDecimal(input)
+		require.False(t, ok, input)
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a, b := mustDecimal(t, "0.1"), mustDecimal(t, "0.20")
+	require.Equal(t, "0.30", a.Add(b).Inspect())
+	require.Equal(t, "-0.10", a.Sub(b).Inspect())
+	require.Equal(t, "0.020", a.Mul(b).Inspect())
+	q, ok := mustDecimal(t, "1").Quo(mustDecimal(t, "3"), DecimalDivisionPlaces)
+	require.True(t, ok)
+	require.Equal(t, "0.3333333333333333", q.Inspect())
+	q, ok = mustDecimal(t, "2").Quo(mustDecimal(t, "3"), 4)
+	require.True(t, ok)
+	require.Equal(t, "0.6667", q.Inspect())
+	q, ok = mustDecimal(t, "10.00").Quo(mustDecimal(t, "4"), DecimalDivisionPlaces)
+	require.True(t, ok)
+	require.Equal(t, "2.50", q.Inspect())
+	_, ok = a.Quo(mustDecimal(t, "0"), DecimalDivisionPlaces)
+	require.False(t, ok)
+	r, ok := mustDecimal(t, "-7.5").Rem(mustDecimal(t, "2"))
+	require.True(t, ok)
+	require.Equal(t, "-1.5", r.Inspect())
+	p, ok := mustDecimal(t, "1.5").Pow(3)
+	require.True(t, ok)
+	require.Equal(t, "3.375", p.Inspect())
+}
+
+func TestDecimalMaxScale(t *testing.T) {
+	// Squaring doubles the scale each time, which is rounded once it
+	// exceeds the maximum instead of growing without bound
+	d := mustDecimal(t, "1.1")
+	for i := 0; i < 20; i++ {
+		d = d.Mul(d)
+		require.LessOrEqual(t, d.Scale(), int32(maxDecimalScale))
+	}
+	require.Equal(t, int32(maxDecimalScale), d.Scale())
+	small := NewDecimal(big.NewInt(15), maxDecimalScale)
+	require.Equal(t, int32(maxDecimalScale), small.Mul(mustDecimal(t, "0.1")).Scale())
+	require.Equal(t, 0, small.Mul(small).Value().Sign())
+	huge := NewDecimal(big.NewInt(1), math.MaxInt32)
+	require.Equal(t, int32(maxDecimalScale), huge.Scale())
+	require.Equal(t, int32(maxDecimalScale), huge.Mul(huge).Scale())
+	require.Equal(t, int32(maxDecimalScale), huge.Add(mustDecimal(t, "1")).Scale())
+	require.Equal(t, int32(maxDecimalScale), mustDecimal(t, "1").Round(math.MaxInt32, RoundHalfEven).Scale())
+	q, ok := mustDecimal(t, "1").Div(mustDecimal(t, "3"), math.MaxInt32, RoundHalfEven)
+	require.True(t, ok)
+	require.Equal(t, int32(maxDecimalScale), q.Scale())
+	_, ok = mustDecimal(t, "1.5").Pow(math.MaxInt64)
+	require.False(t, ok)
+}
+
+func TestDecimalDivisionPlacesContext(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, int32(DecimalDivisionPlaces), GetDecimalDivisionPlaces(ctx))
+	require.Equal(t, int32(4), GetDecimalDivisionPlaces(WithDecimalDivisionPlaces(ctx, 4)))
+	require.Equal(t, int32(0), GetDecimalDivisionPlaces(WithDecimalDivisionPlaces(ctx, -1)))
+	require.Equal(t, int32(maxDecimalScale), GetDecimalDivisionPlaces(WithDecimalDivisionPlaces(ctx, math.MaxInt32)))
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     RoundingMode
+		expected string
+	}{
+		{"2.5", RoundHalfEven, "2"},
+		{"3.5", RoundHalfEven, "4"},
+		{"-2.5", RoundHalfEven, "-2"},
+		{"2.5", RoundHalfUp, "3"},
+		{"-2.5", RoundHalfUp, "-3"},
+		{"2.5", RoundHalfDown, "2"},
+		{"2.51", RoundHalfDown, "3"},
+		{"2.1", RoundUp, "3"},
+		{"-2.1", RoundUp, "-3"},
+		{"2.9", RoundDown, "2"},
+		{"-2.9", RoundDown, "-2"},
+		{"2.1", RoundCeiling, "3"},
+		{"-2.9", RoundCeiling, "-2"},
+		{"2.9", RoundFloor, "2"},
+		{"-2.1", RoundFloor, "-3"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, mustDecimal(t, tt.input).Round(0, tt.mode).Inspect(), tt.input)
+	}
+	require.Equal(t, "1.50", mustDecimal(t, "1.5").Round(2, RoundHalfEven).Inspect())
+	require.Equal(t, "1.24", mustDecimal(t, "1.235").Round(2, RoundHalfEven).Inspect())
+}
+
+func TestDecimalDiv(t *testing.T) {
+	// The quotient of 2 / 8 is exactly 0.25, a tie when rounding to one place
+	q, ok := mustDecimal(t, "2").Div(mustDecimal(t, "8"), 1, RoundHalfEven)
+	require.True(t, ok)
+	require.Equal(t, "0.2", q.Inspect())
+	// Slightly above the tie rounds up even though the truncated quotient
+	// looks like a tie
+	q, ok = mustDecimal(t, "2.0000001").Div(mustDecimal(t, "8"), 1, RoundHalfEven)
+	require.True(t, ok)
+	require.Equal(t, "0.3", q.Inspect())
+	q, ok = mustDecimal(t, "-2.0000001").Div(mustDecimal(t, "8"), 1, RoundHalfEven)
+	require.True(t, ok)
+	require.Equal(t, "-0.3", q.Inspect())
+	q, ok = mustDecimal(t, "100").Div(mustDecimal(t, "3"), 2, RoundFloor)
+	require.True(t, ok)
+	require.Equal(t, "33.33", q.Inspect())
+}
+
+func TestDecimalEquals(t *testing.T) {
+	d := mustDecimal(t, "2.00")
+	require.Equal(t, True, d.Equals(mustDecimal(t, "2")))
+	require.Equal(t, True, d.Equals(NewInt(2)))
+	require.Equal(t, True, NewInt(2).Equals(d))
+	require.Equal(t, True, NewBigInt(big.NewInt(2)).Equals(d))
+	require.Equal(t, False, d.Equals(NewFloat(2)))
+	require.Equal(t, NewInt(2).HashKey(), d.HashKey())
+	require.Equal(t, mustDecimal(t, "1.5").HashKey(), mustDecimal(t, "1.50").HashKey())
+	cmp, err := d.Compare(NewInt(3))
+	require.Nil(t, err)
+	require.Equal(t, -1, cmp)
+	cmp, err = NewInt(3).Compare(d)
+	require.Nil(t, err)
+	require.Equal(t, 1, cmp)
+}
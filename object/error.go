@@ -8,6 +8,12 @@ import (
 type Error struct {
 	// err is the Go error being wrapped.
 	err error
+
+	// runtime describes where the error occurred, once known.
+	runtime *RuntimeError
+
+	// propagated is the err result being returned by the ? operator.
+	propagated *Result
 }
 
 func (e *Error) Type() Type {
@@ -69,6 +75,27 @@ func (e *Error) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// RuntimeError returns the position and call stack where the error occurred,
+// if the error was raised while executing a program.
+func (e *Error) RuntimeError() (*RuntimeError, bool) {
+	return e.runtime, e.runtime != nil
+}
+
+// WithRuntimeError returns a copy of the error that carries the given
+// position and call stack information.
+func (e *Error) WithRuntimeError(opts RuntimeErrorOpts) *Error {
+	opts.Cause = e.err
+	copied := *e
+	copied.runtime = NewRuntimeError(opts)
+	return &copied
+}
+
+// Propagated returns the err result carried by an error created with
+// NewPropagationError.
+func (e *Error) Propagated() (*Result, bool) {
+	return e.propagated, e.propagated != nil
+}
+
 func Errorf(format string, a ...interface{}) *Error {
 	var args []interface{}
 	for _, arg := range a {
@@ -85,6 +112,14 @@ func NewError(err error) *Error {
 	return &Error{err: err}
 }
 
+// NewPropagationError returns an error that is used by the ? operator to
+// return an err result from the enclosing function. It stops execution like
+// any other error until it reaches the function, which then returns the
+// result as its value.
+func NewPropagationError(result *Result) *Error {
+	return &Error{err: result.err.err, propagated: result}
+}
+
 func IsError(obj Object) bool {
 	if obj != nil {
 		return obj.Type() == ERROR
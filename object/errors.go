@@ -17,3 +17,15 @@ func NewArgsRangeError(fn string, takesMin, takesMax, given int) *Error {
 	return Errorf("type error: %s() takes between %d and %d arguments (%d given)",
 		fn, takesMin, takesMax, given)
 }
+
+// NewKwargsError returns an error for a call that was given a keyword
+// argument the function does not accept.
+func NewKwargsError(fn, name string) *Error {
+	return Errorf("type error: %s() got an unexpected keyword argument \"%s\"", fn, name)
+}
+
+// NewMissingArgError returns an error for a call that did not give an
+// argument for the named parameter, which has no default value.
+func NewMissingArgError(fn, name string) *Error {
+	return Errorf("type error: %s() missing argument \"%s\"", fn, name)
+}
@@ -0,0 +1,34 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExitError is the error that ends a program which calls exit. It unwinds
+// the program like any other error, running deferred calls on the way, but
+// it can't be handled by try. Hosts may detect it using errors.As to find
+// the exit code.
+type ExitError struct {
+	code int
+}
+
+// Code returns the exit code given to exit.
+func (e *ExitError) Code() int {
+	return e.code
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// NewExitError returns an error that ends the program with the given code.
+func NewExitError(code int) *Error {
+	return NewError(&ExitError{code: code})
+}
+
+// IsExitError returns true if the error was caused by a call to exit.
+func IsExitError(err *Error) bool {
+	var exitErr *ExitError
+	return errors.As(err.err, &exitErr)
+}
@@ -0,0 +1,18 @@
+package object
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitError(t *testing.T) {
+	err := NewExitError(2)
+	require.True(t, IsExitError(err))
+	require.False(t, IsExitError(Errorf("value error: oops")))
+	require.Equal(t, "exit status 2", err.Message().Value())
+	var exitErr *ExitError
+	require.True(t, errors.As(err.Value(), &exitErr))
+	require.Equal(t, 2, exitErr.Code())
+}
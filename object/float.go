@@ -57,6 +57,8 @@ func (f *Float) Compare(other Object) (int, error) {
 			return 1, nil
 		}
 		return -1, nil
+	case *BigInt:
+		return -compareBigIntFloat(other.value, f.value), nil
 	default:
 		return CompareTypes(f, other), nil
 	}
@@ -72,6 +74,8 @@ func (f *Float) Equals(other Object) Object {
 		if f.value == other.(*Float).value {
 			return True
 		}
+	case BIGINT:
+		return other.Equals(f)
 	}
 	return False
 }
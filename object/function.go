@@ -14,6 +14,9 @@ type Function struct {
 	body       *ast.Block
 	defaults   map[string]ast.Expression
 	scope      Scope
+	variadic   bool
+	generator  bool
+	types      *ast.FuncTypes
 }
 
 func (f *Function) Type() Type {
@@ -28,34 +31,7 @@ func (f *Function) Name() string {
 }
 
 func (f *Function) Inspect() string {
-	var out bytes.Buffer
-	parameters := make([]string, 0)
-	for _, p := range f.parameters {
-		ident := p.String()
-		if def, ok := f.defaults[p.String()]; ok {
-			ident += "=" + def.String()
-		}
-		parameters = append(parameters, ident)
-	}
-	out.WriteString("func")
-	if f.name != "" {
-		out.WriteString(" " + f.name)
-	}
-	out.WriteString("(")
-	out.WriteString(strings.Join(parameters, ", "))
-	out.WriteString(") {")
-	lines := strings.Split(f.body.String(), "\n")
-	if len(lines) == 1 {
-		out.WriteString(" " + lines[0] + " }")
-	} else if len(lines) == 0 {
-		out.WriteString(" }")
-	} else {
-		for _, line := range lines {
-			out.WriteString("\n    " + line)
-		}
-		out.WriteString("\n}")
-	}
-	return out.String()
+	return inspectFunction(f.name, f.parameters, f.defaults, f.body, f.variadic, f.types)
 }
 
 func (f *Function) Body() *ast.Block {
@@ -74,6 +50,23 @@ func (f *Function) Scope() Scope {
 	return f.scope
 }
 
+// Variadic returns true if the last parameter of the function collects any
+// extra arguments into a list.
+func (f *Function) Variadic() bool {
+	return f.variadic
+}
+
+// Generator returns true if the function contains a yield statement, in
+// which case calling it returns a generator.
+func (f *Function) Generator() bool {
+	return f.generator
+}
+
+// Types returns the type annotations of the function, which may be nil.
+func (f *Function) Types() *ast.FuncTypes {
+	return f.types
+}
+
 func (f *Function) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
@@ -93,12 +86,44 @@ func (f *Function) IsTruthy() bool {
 	return true
 }
 
+// BindArguments assigns positional and keyword arguments to the parameters
+// of the named function. The value of each parameter is returned in order,
+// and is nil for parameters that were not given an argument.
+func BindArguments(fn string, params []string, args []Object, kwargs *Map) ([]Object, *Error) {
+	if len(args) > len(params) {
+		return nil, Errorf("type error: function expected %d arguments (%d given)",
+			len(params), len(args)+kwargs.Size())
+	}
+	values := make([]Object, len(params))
+	copy(values, args)
+	for _, name := range kwargs.StringKeys() {
+		index := -1
+		for i, param := range params {
+			if param == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, NewKwargsError(fn, name)
+		}
+		if values[index] != nil {
+			return nil, Errorf("type error: %s() got multiple values for argument \"%s\"", fn, name)
+		}
+		values[index] = kwargs.Get(name)
+	}
+	return values, nil
+}
+
 func NewFunction(
 	name string,
 	parameters []*ast.Ident,
 	body *ast.Block,
 	defaults map[string]ast.Expression,
 	scope Scope,
+	variadic bool,
+	generator bool,
+	types *ast.FuncTypes,
 ) *Function {
 	return &Function{
 		name:       name,
@@ -106,5 +131,58 @@ func NewFunction(
 		body:       body,
 		defaults:   defaults,
 		scope:      scope,
+		variadic:   variadic,
+		generator:  generator,
+		types:      types,
+	}
+}
+
+// inspectFunction renders a function's source from its parts. It is shared
+// by Function and CompiledFunction so both display identically.
+func inspectFunction(
+	name string,
+	parameters []*ast.Ident,
+	defaults map[string]ast.Expression,
+	body *ast.Block,
+	variadic bool,
+	types *ast.FuncTypes,
+) string {
+	var out bytes.Buffer
+	params := make([]string, 0)
+	for _, p := range parameters {
+		ident := p.String()
+		if typ := types.Param(ident); typ != nil {
+			ident += ": " + typ.String()
+		}
+		if def, ok := defaults[p.String()]; ok {
+			ident += "=" + def.String()
+		}
+		params = append(params, ident)
+	}
+	if variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
 	}
+	out.WriteString("func")
+	if name != "" {
+		out.WriteString(" " + name)
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if result := types.Result(); result != nil {
+		out.WriteString(" -> " + result.String())
+	}
+	out.WriteString(" {")
+	lines := strings.Split(body.String(), "\n")
+	if len(lines) == 1 {
+		out.WriteString(" " + lines[0] + " }")
+	} else if len(lines) == 0 {
+		out.WriteString(" }")
+	} else {
+		for _, line := range lines {
+			out.WriteString("\n    " + line)
+		}
+		out.WriteString("\n}")
+	}
+	return out.String()
 }
@@ -0,0 +1,249 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrGeneratorClosed is the error that unwinds the body of a generator which
+// is closed while paused at a yield. Like exit, it runs deferred calls on the
+// way but can't be handled by try.
+var ErrGeneratorClosed = errors.New("generator closed")
+
+// IsGeneratorClosed returns true if the error was caused by closing a
+// generator.
+func IsGeneratorClosed(err *Error) bool {
+	return errors.Is(err.err, ErrGeneratorClosed)
+}
+
+// YieldFunc is called by a running generator for each value it produces. It
+// blocks until the next value is requested and returns false if the
+// generator was closed or its context was canceled, in which case the
+// generator function should stop running. A generator that was closed
+// should return ErrGeneratorClosed, running any deferred calls first.
+type YieldFunc func(value Object) bool
+
+// GeneratorFunc runs the body of a generator function, calling yield for
+// each value produced. If the body fails, the error object is returned.
+type GeneratorFunc func(ctx context.Context, yield YieldFunc) Object
+
+// Generator is the iterator returned by calling a generator function, which
+// is a function containing a yield statement. The function body runs in its
+// own goroutine, which is paused at each yield until the next value is
+// requested, so values are produced on demand.
+type Generator struct {
+	mutex  sync.Mutex
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    GeneratorFunc
+	values chan Object
+	resume chan struct{}
+	// closed is closed by Close, which then waits for finished to be closed
+	// when the generator function returns
+	closed    chan struct{}
+	closeOnce sync.Once
+	finished  chan struct{}
+	started   bool
+	done      bool
+	pos       int64
+	err       *Error
+	// failure is set by the generator goroutine before it closes values
+	failure *Error
+}
+
+func (g *Generator) Type() Type {
+	return GENERATOR
+}
+
+func (g *Generator) Inspect() string {
+	return fmt.Sprintf("generator(%s)", g.name)
+}
+
+// Interface returns nil, since the values of a generator are produced on
+// demand and converting it mustn't consume them.
+func (g *Generator) Interface() interface{} {
+	return nil
+}
+
+func (g *Generator) Equals(other Object) Object {
+	switch other := other.(type) {
+	case *Generator:
+		return NewBool(g == other)
+	default:
+		return False
+	}
+}
+
+func (g *Generator) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return &Builtin{
+			name: "generator.next",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("generator.next", 0, len(args))
+				}
+				entry, ok := g.Next()
+				if !ok {
+					if err := g.Err(); err != nil {
+						return err
+					}
+					return Nil
+				}
+				return entry
+			},
+		}, true
+	case "close":
+		return &Builtin{
+			name: "generator.close",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("generator.close", 0, len(args))
+				}
+				g.Close()
+				return Nil
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func (g *Generator) IsTruthy() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !g.done
+}
+
+// Next resumes the generator function until it yields its next value. The
+// entry key is the index of the value. If the generator function returns or
+// fails, (nil, false) is returned and Err reports any failure.
+func (g *Generator) Next() (IteratorEntry, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.done {
+		return nil, false
+	}
+	if !g.started {
+		g.started = true
+		go g.start()
+	} else {
+		select {
+		case g.resume <- struct{}{}:
+		case <-g.ctx.Done():
+			return g.stop()
+		}
+	}
+	select {
+	case value, ok := <-g.values:
+		if !ok {
+			g.err = g.failure
+			return g.stop()
+		}
+		entry := NewEntry(NewInt(g.pos), value)
+		g.pos++
+		return entry, true
+	case <-g.ctx.Done():
+		return g.stop()
+	}
+}
+
+// Err returns the error that ended the generator, if any. This is nil while
+// the generator is running and after it completes successfully.
+func (g *Generator) Err() *Error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.err
+}
+
+// Close stops the generator, and no more values are produced. A generator
+// that is paused at a yield is unwound, and Close waits for its deferred
+// calls to run.
+func (g *Generator) Close() {
+	g.mutex.Lock()
+	g.done = true
+	started := g.started
+	g.mutex.Unlock()
+	g.closeOnce.Do(func() { close(g.closed) })
+	if started {
+		select {
+		case <-g.finished:
+		case <-g.ctx.Done():
+		}
+	}
+	g.cancel()
+}
+
+func (g *Generator) stop() (IteratorEntry, bool) {
+	if g.err == nil && !g.done {
+		if err := g.ctx.Err(); err != nil && err != context.Canceled {
+			g.err = NewError(err)
+		}
+	}
+	g.done = true
+	g.cancel()
+	return nil, false
+}
+
+func (g *Generator) start() {
+	defer close(g.finished)
+	defer close(g.values)
+	err, ok := g.run(g.ctx, g.yield).(*Error)
+	if ok && g.ctx.Err() == nil && !IsGeneratorClosed(err) {
+		g.failure = err
+	}
+}
+
+func (g *Generator) yield(value Object) bool {
+	select {
+	case g.values <- value:
+	case <-g.closed:
+		return false
+	case <-g.ctx.Done():
+		return false
+	}
+	select {
+	case <-g.resume:
+		return true
+	case <-g.closed:
+		return false
+	case <-g.ctx.Done():
+		return false
+	}
+}
+
+// NewGenerator returns a generator that calls run to produce its values. The
+// run function is not called until the first value is requested.
+func NewGenerator(ctx context.Context, name string, run GeneratorFunc) *Generator {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Generator{
+		name:     name,
+		ctx:      ctx,
+		cancel:   cancel,
+		run:      run,
+		values:   make(chan Object),
+		resume:   make(chan struct{}),
+		closed:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// IteratorErr returns the error that ended an iteration early, for iterators
+// such as generators whose iteration can fail. Nil is returned otherwise.
+func IteratorErr(iter Iterator) *Error {
+	if g, ok := iter.(*Generator); ok {
+		return g.Err()
+	}
+	return nil
+}
+
+// CloseIterator is called when a loop over an iterator ends, which closes a
+// generator left paused by a break, return or error so that its goroutine
+// exits and its deferred calls run. Other iterators need no cleanup.
+func CloseIterator(iter Iterator) {
+	if g, ok := iter.(*Generator); ok {
+		g.Close()
+	}
+}
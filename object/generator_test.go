@@ -0,0 +1,78 @@
+package object
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator(t *testing.T) {
+	produced := 0
+	g := NewGenerator(context.Background(), "count", func(ctx context.Context, yield YieldFunc) Object {
+		for i := 0; i < 3; i++ {
+			produced++
+			if !yield(NewInt(int64(i))) {
+				return Nil
+			}
+		}
+		return Nil
+	})
+	require.Equal(t, "generator(count)", g.Inspect())
+	require.True(t, g.IsTruthy())
+
+	// Nothing runs until the first value is requested
+	require.Equal(t, 0, produced)
+	entry, ok := g.Next()
+	require.True(t, ok)
+	require.Equal(t, NewInt(0), entry.Key())
+	require.Equal(t, NewInt(0), entry.Value())
+	require.Equal(t, 1, produced)
+
+	entry, ok = g.Next()
+	require.True(t, ok)
+	require.Equal(t, NewInt(1), entry.Value())
+
+	g.Close()
+	_, ok = g.Next()
+	require.False(t, ok)
+	require.False(t, g.IsTruthy())
+	require.Nil(t, g.Err())
+	require.Equal(t, 2, produced)
+}
+
+func TestGeneratorErr(t *testing.T) {
+	g := NewGenerator(context.Background(), "fail", func(ctx context.Context, yield YieldFunc) Object {
+		yield(NewInt(1))
+		return Errorf("value error: boom")
+	})
+	entry, ok := g.Next()
+	require.True(t, ok)
+	require.Equal(t, NewInt(1), entry.Value())
+	_, ok = g.Next()
+	require.False(t, ok)
+	require.Equal(t, Errorf("value error: boom"), g.Err())
+	require.Equal(t, Errorf("value error: boom"), IteratorErr(g))
+	require.Nil(t, IteratorErr(NewListIter(NewList(nil))))
+}
+
+func TestGeneratorClose(t *testing.T) {
+	cleanedUp := false
+	g := NewGenerator(context.Background(), "count", func(ctx context.Context, yield YieldFunc) Object {
+		defer func() { cleanedUp = ctx.Err() == nil }()
+		for i := 0; ; i++ {
+			if !yield(NewInt(int64(i))) {
+				return NewError(ErrGeneratorClosed)
+			}
+		}
+	})
+	_, ok := g.Next()
+	require.True(t, ok)
+
+	// Close waits for the generator function to return, before the context
+	// is canceled
+	CloseIterator(g)
+	require.True(t, cleanedUp)
+	require.Nil(t, g.Err())
+	require.Nil(t, g.Interface())
+}
@@ -0,0 +1,55 @@
+package object
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Goroutines runs the goroutines started by the go statements of one
+// execution of a program. As with a panic in a Go goroutine, the first error
+// returned by any of them stops the whole program: it is recorded and the
+// context of the program is canceled. The methods of a nil Goroutines run
+// the goroutines but discard their errors.
+type Goroutines struct {
+	cancel context.CancelFunc
+	err    atomic.Pointer[Error]
+}
+
+// NewGoroutines returns a Goroutines that calls cancel when a goroutine
+// fails, which should cancel the context the program is running with. The
+// cancel function may be nil.
+func NewGoroutines(cancel context.CancelFunc) *Goroutines {
+	return &Goroutines{cancel: cancel}
+}
+
+// Go calls fn in a new goroutine. A panic in fn is recovered and treated as
+// an error.
+func (g *Goroutines) Go(fn func() Object) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				g.fail(Errorf("panic: %v", r))
+			}
+		}()
+		if err, ok := fn().(*Error); ok {
+			g.fail(err)
+		}
+	}()
+}
+
+// Err returns the first error returned by a goroutine, if any.
+func (g *Goroutines) Err() *Error {
+	if g == nil {
+		return nil
+	}
+	return g.err.Load()
+}
+
+func (g *Goroutines) fail(err *Error) {
+	if g == nil {
+		return
+	}
+	if g.err.CompareAndSwap(nil, err) && g.cancel != nil {
+		g.cancel()
+	}
+}
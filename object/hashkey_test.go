@@ -2,6 +2,7 @@ package object
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -18,3 +19,13 @@ func TestStringHashKey(t *testing.T) {
 
 	require.Equal(t, HashKey{Type: STRING, StrValue: "hello"}, a.HashKey())
 }
+
+func TestTimeHashKey(t *testing.T) {
+	now := time.Now().UTC()
+	a := NewTime(now)
+	b := NewTime(now)
+	c := NewTime(now.Add(time.Second))
+
+	require.Equal(t, a.HashKey(), b.HashKey())
+	require.NotEqual(t, a.HashKey(), c.HashKey())
+}
@@ -58,6 +58,16 @@ func (r *HttpResponse) GetAttr(name string) (Object, bool) {
 
 			},
 		}, true
+	case "bytes":
+		return &Builtin{
+			name: "http_response.bytes",
+			fn: func(ctx context.Context, args ...Object) Object {
+				if len(args) != 0 {
+					return NewArgsError("bytes", 0, len(args))
+				}
+				return r.Bytes()
+			},
+		}, true
 	}
 	return nil, false
 }
@@ -99,6 +109,14 @@ func (r *HttpResponse) Text() *Result {
 	return NewOkResult(NewString(string(r.body)))
 }
 
+// Bytes returns the raw response body, which is useful for binary content.
+func (r *HttpResponse) Bytes() *Result {
+	if r.bodyErr != nil {
+		return NewErrResult(NewError(r.bodyErr))
+	}
+	return NewOkResult(NewBytes(r.body))
+}
+
 func (r *HttpResponse) Status() *String {
 	return NewString(r.resp.Status)
 }
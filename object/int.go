@@ -2,6 +2,7 @@ package object
 
 import (
 	"fmt"
+	"math/big"
 )
 
 // Int wraps int64 and implements Object and Hashable interfaces.
@@ -56,6 +57,10 @@ func (i *Int) Compare(other Object) (int, error) {
 			return 1, nil
 		}
 		return -1, nil
+	case *BigInt:
+		return -other.value.Cmp(big.NewInt(i.value)), nil
+	case *Decimal:
+		return -other.Cmp(NewDecimal(big.NewInt(i.value), 0)), nil
 	default:
 		return CompareTypes(i, other), nil
 	}
@@ -71,6 +76,8 @@ func (i *Int) Equals(other Object) Object {
 		if float64(i.value) == other.(*Float).value {
 			return True
 		}
+	case BIGINT, DECIMAL:
+		return other.Equals(i)
 	}
 	return False
 }
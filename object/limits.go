@@ -0,0 +1,290 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Limits restricts the resources used while running a program. A limit
+// that is zero is not enforced.
+type Limits struct {
+	// MaxSteps is the maximum number of steps a program may run. The
+	// evaluator counts each AST node it evaluates, while the VM counts each
+	// instruction it executes.
+	MaxSteps int64
+
+	// MaxStringLength is the maximum length of a string or bytes, in bytes.
+	// It also limits the size of a bigint, in bytes, and the number of
+	// digits of a decimal.
+	MaxStringLength int64
+
+	// MaxListLength is the maximum number of items in a list.
+	MaxListLength int64
+
+	// MaxMapLength is the maximum number of items in a map or set.
+	MaxMapLength int64
+
+	// MaxAllocations is the maximum total number of elements that may be
+	// allocated, counting the bytes of new strings and the items of new
+	// lists, maps and sets, as well as items added to existing ones.
+	MaxAllocations int64
+
+	// MaxDuration is the maximum wall-clock time a program may run.
+	MaxDuration time.Duration
+}
+
+// LimitError is the error that ends a program which exceeds one of its
+// Limits. Hosts may detect it using errors.As.
+type LimitError struct {
+	limit string
+	max   int64
+}
+
+// Limit returns the name of the limit that was exceeded, which matches the
+// name of the Limits field.
+func (e *LimitError) Limit() string {
+	return e.limit
+}
+
+// Max returns the configured value of the limit that was exceeded. For
+// MaxDuration, this is a number of nanoseconds.
+func (e *LimitError) Max() int64 {
+	return e.max
+}
+
+func (e *LimitError) Error() string {
+	if e.limit == "MaxDuration" {
+		return fmt.Sprintf("limit error: %s exceeded (%s)", e.limit, time.Duration(e.max))
+	}
+	return fmt.Sprintf("limit error: %s exceeded (%d)", e.limit, e.max)
+}
+
+// IsLimitError returns true if the error was caused by exceeding a limit.
+// These errors end the program and aren't handled by try.
+func IsLimitError(err *Error) bool {
+	var limitErr *LimitError
+	return errors.As(err.err, &limitErr)
+}
+
+// Limiter tracks the resources used by one execution of a program against
+// its Limits. It is shared by all goroutines started by the program. Once a
+// limit is exceeded, every following check fails with the same error, so
+// that the program stops even if the error is handled. The methods of a
+// nil Limiter never fail.
+type Limiter struct {
+	limits      Limits
+	steps       atomic.Int64
+	allocations atomic.Int64
+	err         atomic.Pointer[Error]
+}
+
+// NewLimiter returns a Limiter that enforces the given limits.
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{limits: limits}
+}
+
+// Limits returns the limits enforced by this Limiter.
+func (l *Limiter) Limits() Limits {
+	return l.limits
+}
+
+// WithTimeout returns a context that is canceled when the program has run
+// for longer than MaxDuration, which also causes all following checks to
+// fail. If MaxDuration isn't set, the context is only canceled by the
+// returned function, which must be called once the program completes.
+func (l *Limiter) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if l == nil || l.limits.MaxDuration <= 0 {
+		return ctx, cancel
+	}
+	timer := time.AfterFunc(l.limits.MaxDuration, func() {
+		l.fail("MaxDuration", int64(l.limits.MaxDuration))
+		cancel()
+	})
+	return ctx, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// Err returns the error for the limit that was exceeded, if any.
+func (l *Limiter) Err() *Error {
+	if l == nil {
+		return nil
+	}
+	return l.err.Load()
+}
+
+// Step counts one step of execution.
+func (l *Limiter) Step() *Error {
+	if l == nil {
+		return nil
+	}
+	if err := l.err.Load(); err != nil {
+		return err
+	}
+	if l.limits.MaxSteps > 0 && l.steps.Add(1) > l.limits.MaxSteps {
+		return l.fail("MaxSteps", l.limits.MaxSteps)
+	}
+	return nil
+}
+
+// Alloc counts the allocation of the given number of elements.
+func (l *Limiter) Alloc(n int64) *Error {
+	if l == nil {
+		return nil
+	}
+	if err := l.err.Load(); err != nil {
+		return err
+	}
+	if l.limits.MaxAllocations > 0 && n > 0 && l.allocations.Add(n) > l.limits.MaxAllocations {
+		return l.fail("MaxAllocations", l.limits.MaxAllocations)
+	}
+	return nil
+}
+
+// Check returns an error if the object is larger than the limit for its
+// type. Objects that aren't strings or containers pass unless a limit was
+// already exceeded.
+func (l *Limiter) Check(obj Object) *Error {
+	if l == nil {
+		return nil
+	}
+	size, ok := Size(obj)
+	if !ok {
+		return l.err.Load()
+	}
+	return l.CheckSize(obj.Type(), size)
+}
+
+// CheckSize returns an error if an object of the given type and size would
+// be larger than the limit for its type. This allows operations to check
+// their result before allocating it.
+func (l *Limiter) CheckSize(typ Type, size int64) *Error {
+	if l == nil {
+		return nil
+	}
+	if err := l.err.Load(); err != nil {
+		return err
+	}
+	switch typ {
+	case STRING, BYTES, BIGINT, DECIMAL:
+		if l.limits.MaxStringLength > 0 && size > l.limits.MaxStringLength {
+			return l.fail("MaxStringLength", l.limits.MaxStringLength)
+		}
+	case LIST:
+		if l.limits.MaxListLength > 0 && size > l.limits.MaxListLength {
+			return l.fail("MaxListLength", l.limits.MaxListLength)
+		}
+	case MAP, SET:
+		if l.limits.MaxMapLength > 0 && size > l.limits.MaxMapLength {
+			return l.fail("MaxMapLength", l.limits.MaxMapLength)
+		}
+	}
+	return nil
+}
+
+// Track checks the size of a newly allocated object and counts its elements
+// as allocated.
+func (l *Limiter) Track(obj Object) *Error {
+	if l == nil {
+		return nil
+	}
+	size, ok := Size(obj)
+	if !ok {
+		return l.err.Load()
+	}
+	if err := l.CheckSize(obj.Type(), size); err != nil {
+		return err
+	}
+	return l.Alloc(size)
+}
+
+// TrackResult checks the object returned by a builtin. It is counted as
+// allocated unless it is one of the given inputs, such as the arguments of
+// the call or the object whose method was called.
+func (l *Limiter) TrackResult(result Object, inputs ...Object) *Error {
+	if l == nil {
+		return nil
+	}
+	for _, input := range inputs {
+		if input == result {
+			return l.Check(result)
+		}
+	}
+	return l.Track(result)
+}
+
+// Grow checks the size of an object that was modified in place, given its
+// size beforehand, and counts any elements added to it as allocated.
+func (l *Limiter) Grow(obj Object, before int64) *Error {
+	if l == nil {
+		return nil
+	}
+	if err := l.Check(obj); err != nil {
+		return err
+	}
+	after, _ := Size(obj)
+	return l.Alloc(after - before)
+}
+
+func (l *Limiter) fail(limit string, max int64) *Error {
+	l.err.CompareAndSwap(nil, NewError(&LimitError{limit: limit, max: max}))
+	return l.err.Load()
+}
+
+// Size returns the number of elements held by a string, bytes, list, map
+// or set. The size of a string is its length in bytes, the size of a bigint
+// is the number of bytes needed to hold its value, and the size of a decimal
+// is its number of digits. False is returned for other objects.
+func Size(obj Object) (int64, bool) {
+	switch obj := obj.(type) {
+	case *String:
+		return int64(len(obj.value)), true
+	case *Bytes:
+		return int64(len(obj.value)), true
+	case *BigInt:
+		return BitsSize(BIGINT, int64(obj.value.BitLen())), true
+	case *Decimal:
+		return BitsSize(DECIMAL, int64(obj.value.BitLen())), true
+	case *List:
+		return int64(obj.Size()), true
+	case *Map:
+		return int64(obj.Size()), true
+	case *Set:
+		return int64(obj.Size()), true
+	}
+	return 0, false
+}
+
+// BitsSize returns the size reported by Size for a bigint or decimal whose
+// value has the given number of bits. A decimal may have one digit fewer.
+func BitsSize(typ Type, bits int64) int64 {
+	if typ == DECIMAL {
+		return int64(float64(bits)*math.Log10(2)) + 1
+	}
+	size := bits / 8
+	if bits%8 != 0 {
+		size++
+	}
+	return size
+}
+
+const limiterKey = contextKey("limiter")
+
+// WithLimiter adds a Limiter to the context, which can be used by builtins
+// to check the size of the objects they create.
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey, l)
+}
+
+// GetLimiter returns the Limiter from the context. A nil Limiter, whose
+// checks never fail, is returned if there isn't one.
+func GetLimiter(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(limiterKey).(*Limiter)
+	return l
+}
@@ -0,0 +1,88 @@
+package object
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterNil(t *testing.T) {
+	var l *Limiter
+	require.Nil(t, l.Step())
+	require.Nil(t, l.Alloc(1000))
+	require.Nil(t, l.Track(NewString("abc")))
+	require.Nil(t, l.Err())
+}
+
+func TestLimiterSteps(t *testing.T) {
+	l := NewLimiter(Limits{MaxSteps: 2})
+	require.Nil(t, l.Step())
+	require.Nil(t, l.Step())
+	err := l.Step()
+	require.NotNil(t, err)
+	require.Equal(t, "limit error: MaxSteps exceeded (2)", err.Message().Value())
+	require.True(t, IsLimitError(err))
+	require.False(t, IsLimitError(Errorf("value error: oops")))
+
+	// Once exceeded, all checks fail with the same error
+	require.Equal(t, err, l.Err())
+	require.Equal(t, err, l.Alloc(1))
+	require.Equal(t, err, l.Check(NewInt(1)))
+
+	var limitErr *LimitError
+	require.True(t, errors.As(err.Interface().(error), &limitErr))
+	require.Equal(t, "MaxSteps", limitErr.Limit())
+	require.Equal(t, int64(2), limitErr.Max())
+}
+
+func TestLimiterSizes(t *testing.T) {
+	l := NewLimiter(Limits{MaxStringLength: 3, MaxListLength: 2, MaxMapLength: 1})
+	require.Nil(t, l.Check(NewString("abc")))
+	require.Nil(t, l.Check(NewList([]Object{Nil, Nil})))
+	require.Nil(t, l.Check(NewInt(1000)))
+	require.Nil(t, l.CheckSize(BYTES, 3))
+
+	list := NewList([]Object{Nil, Nil, Nil})
+	require.Equal(t, "limit error: MaxListLength exceeded (2)", l.Check(list).Message().Value())
+
+	l = NewLimiter(Limits{MaxStringLength: 3})
+	require.NotNil(t, l.Check(NewBytes([]byte("abcd"))))
+
+	l = NewLimiter(Limits{MaxMapLength: 1})
+	require.NotNil(t, l.Check(NewSet([]Object{NewInt(1), NewInt(2)})))
+}
+
+func TestLimiterAllocations(t *testing.T) {
+	l := NewLimiter(Limits{MaxAllocations: 10})
+	s := NewString("abcd")
+	list := NewList([]Object{s})
+
+	// Inputs returned as the result aren't counted again
+	require.Nil(t, l.TrackResult(s, s))
+	require.Nil(t, l.TrackResult(s))
+	require.Nil(t, l.TrackResult(list))
+
+	// Only the items added to a container are counted
+	list.Append(s)
+	require.Nil(t, l.Grow(list, 1))
+	require.Nil(t, l.Alloc(4))
+	err := l.Alloc(1)
+	require.NotNil(t, err)
+	require.Equal(t, "limit error: MaxAllocations exceeded (10)", err.Message().Value())
+}
+
+func TestSizeNumbers(t *testing.T) {
+	size, ok := Size(NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100)))
+	require.True(t, ok)
+	require.Equal(t, int64(13), size)
+	d, ok := ParseDecimal("123.45")
+	require.True(t, ok)
+	size, ok = Size(d)
+	require.True(t, ok)
+	require.Equal(t, int64(5), size)
+
+	l := NewLimiter(Limits{MaxStringLength: 12})
+	require.NotNil(t, l.Check(NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100))))
+}
@@ -5,38 +5,66 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-// List of objects
+// List of objects. A list may be shared by goroutines, so its items are
+// guarded by a mutex. Methods that call out to other objects, such as to
+// compare items, work on a copy of the items so that the mutex isn't held.
 type List struct {
+	mutex sync.RWMutex
+
 	// items holds the list of objects
 	items []Object
 
 	// Used to avoid the possibility of infinite recursion when inspecting.
 	// Similar to the usage of Py_ReprEnter in CPython.
-	inspectActive bool
+	inspectActive atomic.Bool
 }
 
 func (ls *List) Type() Type {
 	return LIST
 }
 
+// Value returns a copy of the items in the list.
 func (ls *List) Value() []Object {
-	return ls.items
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+	items := make([]Object, len(ls.items))
+	copy(items, ls.items)
+	return items
+}
+
+// Size returns the number of items in the list.
+func (ls *List) Size() int {
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+	return len(ls.items)
+}
+
+// At returns the item at the given index, which must not be negative, and
+// whether the index was in range.
+func (ls *List) At(index int) (Object, bool) {
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+	if index >= len(ls.items) {
+		return nil, false
+	}
+	return ls.items[index], true
 }
 
 func (ls *List) Inspect() string {
 	// A list can contain itself. Detect if we're already inspecting the list
 	// and return a placeholder if so.
-	if ls.inspectActive {
+	if !ls.inspectActive.CompareAndSwap(false, true) {
 		return "[...]"
 	}
-	ls.inspectActive = true
-	defer func() { ls.inspectActive = false }()
+	defer ls.inspectActive.Store(false)
 
 	var out bytes.Buffer
 	items := make([]string, 0)
-	for _, e := range ls.items {
+	for _, e := range ls.Value() {
 		items = append(items, e.Inspect())
 	}
 	out.WriteString("[")
@@ -172,9 +200,11 @@ func (ls *List) GetAttr(name string) (Object, bool) {
 				if len(args) != 0 {
 					return NewArgsError("list.sort", 0, len(args))
 				}
-				if err := Sort(ls.items); err != nil {
+				items := ls.Value()
+				if err := Sort(items); err != nil {
 					return err
 				}
+				ls.Replace(items)
 				return ls
 			},
 		}, true
@@ -223,6 +253,8 @@ func (ls *List) Map(ctx context.Context, fn Object) Object {
 		numParameters = 1
 	case *Function:
 		numParameters = len(obj.parameters)
+	case *Closure:
+		numParameters = obj.NumParameters()
 	default:
 		return Errorf("type error: list.map() expected a function (%s given)", obj.Type())
 	}
@@ -231,8 +263,9 @@ func (ls *List) Map(ctx context.Context, fn Object) Object {
 	}
 	var index Int
 	mapArgs := make([]Object, 2)
-	result := make([]Object, 0, len(ls.items))
-	for i, value := range ls.items {
+	items := ls.Value()
+	result := make([]Object, 0, len(items))
+	for i, value := range items {
 		index.value = int64(i)
 		mapArgs[0] = &index
 		mapArgs[1] = value
@@ -256,14 +289,14 @@ func (ls *List) Filter(ctx context.Context, fn Object) Object {
 		return Errorf("eval error: list.filter() context did not contain a call function")
 	}
 	switch obj := fn.(type) {
-	case *Function, *Builtin:
+	case *Function, *Closure, *Builtin:
 		// Nothing do do here
 	default:
 		return Errorf("type error: list.filter() expected a function (%s given)", obj.Type())
 	}
 	filterArgs := make([]Object, 1)
 	var result []Object
-	for _, value := range ls.items {
+	for _, value := range ls.Value() {
 		filterArgs[0] = value
 		decision := callFunc(ctx, nil, fn, filterArgs)
 		if IsError(decision) {
@@ -282,13 +315,13 @@ func (ls *List) Each(ctx context.Context, fn Object) Object {
 		return Errorf("eval error: list.each() context did not contain a call function")
 	}
 	switch obj := fn.(type) {
-	case *Function, *Builtin:
+	case *Function, *Closure, *Builtin:
 		// Nothing do do here
 	default:
 		return Errorf("type error: list.each() expected a function (%s given)", obj.Type())
 	}
 	eachArgs := make([]Object, 1)
-	for _, value := range ls.items {
+	for _, value := range ls.Value() {
 		eachArgs[0] = value
 		result := callFunc(ctx, nil, fn, eachArgs)
 		if IsError(result) {
@@ -300,25 +333,32 @@ func (ls *List) Each(ctx context.Context, fn Object) Object {
 
 // Append adds an item at the end of the list.
 func (ls *List) Append(obj Object) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
 	ls.items = append(ls.items, obj)
 }
 
 // Clear removes all the items from the list.
 func (ls *List) Clear() {
-	ls.items = []Object{}
+	ls.Replace([]Object{})
+}
+
+// Replace replaces all the items in the list.
+func (ls *List) Replace(items []Object) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	ls.items = items
 }
 
 // Copy returns a shallow copy of the list.
 func (ls *List) Copy() *List {
-	result := &List{items: make([]Object, len(ls.items))}
-	copy(result.items, ls.items)
-	return result
+	return &List{items: ls.Value()}
 }
 
 // Count returns the number of items with the specified value.
 func (ls *List) Count(obj Object) int64 {
 	count := int64(0)
-	for _, item := range ls.items {
+	for _, item := range ls.Value() {
 		if Equals(obj, item) {
 			count++
 		}
@@ -328,12 +368,15 @@ func (ls *List) Count(obj Object) int64 {
 
 // Extend adds the items of a list to the end of the current list.
 func (ls *List) Extend(other *List) {
-	ls.items = append(ls.items, other.items...)
+	items := other.Value()
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	ls.items = append(ls.items, items...)
 }
 
 // Index returns the index of the first item with the specified value.
 func (ls *List) Index(obj Object) int64 {
-	for i, item := range ls.items {
+	for i, item := range ls.Value() {
 		if Equals(obj, item) {
 			return int64(i)
 		}
@@ -343,6 +386,8 @@ func (ls *List) Index(obj Object) int64 {
 
 // Insert adds an item at the specified position.
 func (ls *List) Insert(index int64, obj Object) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
 	// Negative index is relative to the end of the list
 	if index < 0 {
 		index = int64(len(ls.items)) + index
@@ -365,6 +410,8 @@ func (ls *List) Insert(index int64, obj Object) {
 
 // Pop removes the item at the specified position.
 func (ls *List) Pop(index int64) Object {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
 	idx, err := ResolveIndex(index, int64(len(ls.items)))
 	if err != nil {
 		return Errorf(err.Error())
@@ -376,23 +423,34 @@ func (ls *List) Pop(index int64) Object {
 
 // Remove removes the first item with the specified value.
 func (ls *List) Remove(obj Object) {
-	index := ls.Index(obj)
-	if index == -1 {
+	items := ls.Value()
+	for index, item := range items {
+		if !Equals(obj, item) {
+			continue
+		}
+		ls.mutex.Lock()
+		defer ls.mutex.Unlock()
+		// Skip the removal if the list changed while it was searched
+		if index < len(ls.items) && ls.items[index] == item {
+			ls.items = append(ls.items[:index], ls.items[index+1:]...)
+		}
 		return
 	}
-	ls.items = append(ls.items[:index], ls.items[index+1:]...)
 }
 
 // Reverse reverses the order of the list.
 func (ls *List) Reverse() {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
 	for i, j := 0, len(ls.items)-1; i < j; i, j = i+1, j-1 {
 		ls.items[i], ls.items[j] = ls.items[j], ls.items[i]
 	}
 }
 
 func (ls *List) Interface() interface{} {
-	items := make([]interface{}, 0, len(ls.items))
-	for _, item := range ls.items {
+	values := ls.Value()
+	items := make([]interface{}, 0, len(values))
+	for _, item := range values {
 		items = append(items, item.Interface())
 	}
 	return items
@@ -401,14 +459,14 @@ func (ls *List) Interface() interface{} {
 func (ls *List) String() string {
 	// A list can contain itself. Detect if we're already inspecting the list
 	// and return a placeholder if so.
-	if ls.inspectActive {
+	if !ls.inspectActive.CompareAndSwap(false, true) {
 		return "[...]"
 	}
-	ls.inspectActive = true
-	defer func() { ls.inspectActive = false }()
+	defer ls.inspectActive.Store(false)
 
-	items := make([]string, 0, len(ls.items))
-	for _, item := range ls.items {
+	values := ls.Value()
+	items := make([]string, 0, len(values))
+	for _, item := range values {
 		items = append(items, fmt.Sprintf("%s", item))
 	}
 	return fmt.Sprintf("list([%s])", strings.Join(items, ", "))
@@ -419,19 +477,20 @@ func (ls *List) Compare(other Object) (int, error) {
 	if typeComp != 0 {
 		return typeComp, nil
 	}
-	otherArr := other.(*List)
-	if len(ls.items) > len(otherArr.items) {
+	items := ls.Value()
+	otherItems := other.(*List).Value()
+	if len(items) > len(otherItems) {
 		return 1, nil
-	} else if len(ls.items) < len(otherArr.items) {
+	} else if len(items) < len(otherItems) {
 		return -1, nil
 	}
-	for i := 0; i < len(ls.items); i++ {
-		comparable, ok := ls.items[i].(Comparable)
+	for i := 0; i < len(items); i++ {
+		comparable, ok := items[i].(Comparable)
 		if !ok {
 			return 0, fmt.Errorf("type error: %s object is not comparable",
-				ls.items[i].Type())
+				items[i].Type())
 		}
-		comp, err := comparable.Compare(otherArr.items[i])
+		comp, err := comparable.Compare(otherItems[i])
 		if err != nil {
 			return 0, err
 		}
@@ -446,12 +505,13 @@ func (ls *List) Equals(other Object) Object {
 	if other.Type() != LIST {
 		return False
 	}
-	otherList := other.(*List)
-	if len(ls.items) != len(otherList.items) {
+	items := ls.Value()
+	otherItems := other.(*List).Value()
+	if len(items) != len(otherItems) {
 		return False
 	}
-	for i, v := range ls.items {
-		otherV := otherList.items[i]
+	for i, v := range items {
+		otherV := otherItems[i]
 		if !Equals(v, otherV) {
 			return False
 		}
@@ -460,21 +520,23 @@ func (ls *List) Equals(other Object) Object {
 }
 
 func (ls *List) IsTruthy() bool {
-	return len(ls.items) > 0
+	return ls.Size() > 0
 }
 
 func (ls *List) Reversed() *List {
-	result := &List{items: make([]Object, 0, len(ls.items))}
-	size := len(ls.items)
+	items := ls.Value()
+	result := &List{items: make([]Object, 0, len(items))}
+	size := len(items)
 	for i := 0; i < size; i++ {
-		result.items = append(result.items, ls.items[size-1-i])
+		result.items = append(result.items, items[size-1-i])
 	}
 	return result
 }
 
 func (ls *List) Keys() Object {
-	items := make([]Object, 0, len(ls.items))
-	for i := 0; i < len(ls.items); i++ {
+	size := ls.Size()
+	items := make([]Object, 0, size)
+	for i := 0; i < size; i++ {
 		items = append(items, NewInt(int64(i)))
 	}
 	return NewList(items)
@@ -485,6 +547,8 @@ func (ls *List) GetItem(key Object) (Object, *Error) {
 	if !ok {
 		return nil, Errorf("type error: list index must be an int (got %s)", key.Type())
 	}
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
 	idx, err := ResolveIndex(indexObj.value, int64(len(ls.items)))
 	if err != nil {
 		return nil, Errorf(err.Error())
@@ -492,24 +556,45 @@ func (ls *List) GetItem(key Object) (Object, *Error) {
 	return ls.items[idx], nil
 }
 
-// GetSlice implements the [start:stop] operator for a container type.
+// GetSlice implements the [start:stop:step] operator for a container type.
 func (ls *List) GetSlice(s Slice) (Object, *Error) {
-	start, stop, err := ResolveIntSlice(s, int64(len(ls.items)))
+	ls.mutex.RLock()
+	defer ls.mutex.RUnlock()
+	if s.Step == nil {
+		start, stop, err := ResolveIntSlice(s, int64(len(ls.items)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		items := ls.items[start:stop]
+		itemsCopy := make([]Object, len(items))
+		copy(itemsCopy, items)
+		return NewList(itemsCopy), nil
+	}
+	indices, err := SliceIndices(s, int64(len(ls.items)))
 	if err != nil {
 		return nil, Errorf(err.Error())
 	}
-	items := ls.items[start:stop]
-	itemsCopy := make([]Object, len(items))
-	copy(itemsCopy, items)
-	return NewList(itemsCopy), nil
+	items := make([]Object, 0, len(indices))
+	for _, idx := range indices {
+		items = append(items, ls.items[idx])
+	}
+	return NewList(items), nil
 }
 
-// SetItem implements the [key] = value operator for a container type.
+// SetItem implements the [key] = value operator for a container type. If
+// the key is a Slice, the value must be a list whose items replace those in
+// the slice. A slice with a step other than 1 must be replaced by the same
+// number of items.
 func (ls *List) SetItem(key, value Object) *Error {
+	if slice, ok := key.(Slice); ok {
+		return ls.setSlice(slice, value)
+	}
 	indexObj, ok := key.(*Int)
 	if !ok {
 		return Errorf("type error: list index must be an int (got %s)", key.Type())
 	}
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
 	idx, err := ResolveIndex(indexObj.value, int64(len(ls.items)))
 	if err != nil {
 		return Errorf(err.Error())
@@ -518,14 +603,75 @@ func (ls *List) SetItem(key, value Object) *Error {
 	return nil
 }
 
-// DelItem implements the del [key] operator for a container type.
+func (ls *List) setSlice(slice Slice, value Object) *Error {
+	other, ok := value.(*List)
+	if !ok {
+		return Errorf("type error: slice assignment expected a list (got %s)", value.Type())
+	}
+	// Copy the new items in case the list is assigned to a slice of itself
+	items := other.Value()
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	start, stop, step, err := ResolveSlice(slice, int64(len(ls.items)))
+	if err != nil {
+		return Errorf(err.Error())
+	}
+	if step == 1 {
+		result := make([]Object, 0, len(ls.items)-int(stop-start)+len(items))
+		result = append(result, ls.items[:start]...)
+		result = append(result, items...)
+		ls.items = append(result, ls.items[stop:]...)
+		return nil
+	}
+	indices, _ := SliceIndices(slice, int64(len(ls.items)))
+	if len(indices) != len(items) {
+		return Errorf("slice error: cannot assign %d items to a slice of %d items with step %d",
+			len(items), len(indices), step)
+	}
+	for i, idx := range indices {
+		ls.items[idx] = items[i]
+	}
+	return nil
+}
+
+// DelItem implements the del [key] operator for a container type. The key
+// may be an index or a Slice.
 func (ls *List) DelItem(key Object) *Error {
-	return Errorf("list does not support del operator")
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	var indices []int64
+	switch key := key.(type) {
+	case *Int:
+		idx, err := ResolveIndex(key.value, int64(len(ls.items)))
+		if err != nil {
+			return Errorf(err.Error())
+		}
+		indices = []int64{idx}
+	case Slice:
+		var err error
+		if indices, err = SliceIndices(key, int64(len(ls.items))); err != nil {
+			return Errorf(err.Error())
+		}
+	default:
+		return Errorf("type error: list index must be an int (got %s)", key.Type())
+	}
+	remove := make(map[int64]bool, len(indices))
+	for _, idx := range indices {
+		remove[idx] = true
+	}
+	items := make([]Object, 0, len(ls.items)-len(remove))
+	for i, item := range ls.items {
+		if !remove[int64(i)] {
+			items = append(items, item)
+		}
+	}
+	ls.items = items
+	return nil
 }
 
 // Contains returns true if the given item is found in this container.
 func (ls *List) Contains(item Object) *Bool {
-	for _, v := range ls.items {
+	for _, v := range ls.Value() {
 		if Equals(v, item) {
 			return True
 		}
@@ -535,7 +681,7 @@ func (ls *List) Contains(item Object) *Bool {
 
 // Len returns the number of items in this container.
 func (ls *List) Len() *Int {
-	return NewInt(int64(len(ls.items)))
+	return NewInt(int64(ls.Size()))
 }
 
 func (ls *List) Iter() Iterator {
@@ -60,15 +60,14 @@ func (iter *ListIter) GetAttr(name string) (Object, bool) {
 }
 
 func (iter *ListIter) IsTruthy() bool {
-	return iter.pos < int64(len(iter.l.items))
+	return iter.pos < int64(iter.l.Size())
 }
 
 func (iter *ListIter) Next() (IteratorEntry, bool) {
-	items := iter.l.items
-	if iter.pos >= int64(len(items)) {
+	r, ok := iter.l.At(int(iter.pos))
+	if !ok {
 		return nil, false
 	}
-	r := items[iter.pos]
 	entry := NewEntry(NewInt(iter.pos), r)
 	iter.pos++
 	return entry, true
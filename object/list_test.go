@@ -45,3 +45,63 @@ func TestListPop(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, "index error: index out of range: 1", err.Message().Value())
 }
+
+func TestListSliceStep(t *testing.T) {
+	ints := func(values ...int64) []Object {
+		items := make([]Object, len(values))
+		for i, v := range values {
+			items[i] = NewInt(v)
+		}
+		return items
+	}
+	tests := []struct {
+		slice    Slice
+		expected []Object
+	}{
+		{Slice{Step: NewInt(2)}, ints(0, 2, 4)},
+		{Slice{Step: NewInt(-1)}, ints(5, 4, 3, 2, 1, 0)},
+		{Slice{Start: NewInt(4), Stop: NewInt(1), Step: NewInt(-1)}, ints(4, 3, 2)},
+		{Slice{Start: NewInt(-2), Step: NewInt(-2)}, ints(4, 2, 0)},
+		{Slice{Start: NewInt(1), Stop: NewInt(5), Step: NewInt(3)}, ints(1, 4)},
+	}
+	list := NewList(ints(0, 1, 2, 3, 4, 5))
+	for _, tc := range tests {
+		result, err := list.GetSlice(tc.slice)
+		require.Nil(t, err, tc.slice.Inspect())
+		require.Equal(t, tc.expected, result.(*List).Value(), tc.slice.Inspect())
+	}
+	_, err := list.GetSlice(Slice{Step: NewInt(0)})
+	require.Equal(t, "slice error: slice step cannot be zero", err.Message().Value())
+	_, err = list.GetSlice(Slice{Start: NewInt(1), Stop: NewInt(3), Step: NewInt(-1)})
+	require.Equal(t, "slice error: start index is less than stop index", err.Message().Value())
+}
+
+func TestListSetSlice(t *testing.T) {
+	list := NewList([]Object{NewInt(0), NewInt(1), NewInt(2), NewInt(3)})
+	require.Nil(t, list.SetItem(Slice{Start: NewInt(1), Stop: NewInt(3)}, NewList([]Object{NewString("a")})))
+	require.Equal(t, "[0, \"a\", 3]", list.Inspect())
+
+	require.Nil(t, list.SetItem(Slice{Step: NewInt(-2)}, NewList([]Object{NewInt(7), NewInt(8)})))
+	require.Equal(t, "[8, \"a\", 7]", list.Inspect())
+
+	err := list.SetItem(Slice{Step: NewInt(2)}, NewList([]Object{NewInt(1)}))
+	require.Equal(t, "slice error: cannot assign 1 items to a slice of 2 items with step 2", err.Message().Value())
+
+	err = list.SetItem(Slice{}, NewInt(1))
+	require.Equal(t, "type error: slice assignment expected a list (got int)", err.Message().Value())
+}
+
+func TestListDelItem(t *testing.T) {
+	list := NewList([]Object{NewInt(0), NewInt(1), NewInt(2), NewInt(3), NewInt(4)})
+	require.Nil(t, list.DelItem(NewInt(-1)))
+	require.Equal(t, "[0, 1, 2, 3]", list.Inspect())
+
+	require.Nil(t, list.DelItem(Slice{Start: NewInt(1), Stop: NewInt(3)}))
+	require.Equal(t, "[0, 3]", list.Inspect())
+
+	require.Nil(t, list.DelItem(Slice{Step: NewInt(-1)}))
+	require.Equal(t, "[]", list.Inspect())
+
+	err := list.DelItem(NewString("x"))
+	require.Equal(t, "type error: list index must be an int (got string)", err.Message().Value())
+}
@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// Map is a mutable mapping of hashable keys to values. Entries are stored by
+// the HashKey of their key, and the original key objects are kept alongside
+// so that keys retain their type. Like a List, a map may be shared by
+// goroutines, so its entries are guarded by a mutex.
 type Map struct {
-	items map[string]Object
+	mutex sync.RWMutex
+	items map[HashKey]Object
+	keys  map[HashKey]Object
 
 	// Used to avoid the possibility of infinite recursion when inspecting.
 	// Similar to the usage of Py_ReprEnter in CPython.
-	inspectActive bool
+	inspectActive atomic.Bool
 }
 
 func (m *Map) Type() Type {
@@ -23,17 +31,17 @@ func (m *Map) Type() Type {
 func (m *Map) Inspect() string {
 	// A map can contain itself. Detect if we're already inspecting the map
 	// and return a placeholder if so.
-	if m.inspectActive {
+	if !m.inspectActive.CompareAndSwap(false, true) {
 		return "{...}"
 	}
-	m.inspectActive = true
-	defer func() { m.inspectActive = false }()
+	defer m.inspectActive.Store(false)
 
 	var out bytes.Buffer
 	pairs := make([]string, 0)
-	for _, k := range m.SortedKeys() {
-		v := m.items[k]
-		pairs = append(pairs, fmt.Sprintf("%q: %s", k, v.Inspect()))
+	keys, values := m.sortedEntries()
+	for i, k := range keys {
+		v := values[i]
+		pairs = append(pairs, fmt.Sprintf("%s: %s", k.Inspect(), v.Inspect()))
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -44,17 +52,17 @@ func (m *Map) Inspect() string {
 func (m *Map) String() string {
 	// A map can contain itself. Detect if we're already inspecting the map
 	// and return a placeholder if so.
-	if m.inspectActive {
+	if !m.inspectActive.CompareAndSwap(false, true) {
 		return "{...}"
 	}
-	m.inspectActive = true
-	defer func() { m.inspectActive = false }()
+	defer m.inspectActive.Store(false)
 
 	var out bytes.Buffer
 	pairs := make([]string, 0)
-	for _, k := range m.SortedKeys() {
-		v := m.items[k]
-		pairs = append(pairs, fmt.Sprintf("%q: %s", k, v))
+	keys, values := m.sortedEntries()
+	for i, k := range keys {
+		v := values[i]
+		pairs = append(pairs, fmt.Sprintf("%s: %s", k.Inspect(), v))
 	}
 	out.WriteString("map(")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -62,8 +70,30 @@ func (m *Map) String() string {
 	return out.String()
 }
 
+// Value returns the entries of the map that have string keys. Entries with
+// keys of other types are left out, so use Entries to access every item.
 func (m *Map) Value() map[string]Object {
-	return m.items
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	result := make(map[string]Object, len(m.items))
+	for k, v := range m.items {
+		if k.Type == STRING {
+			result[k.StrValue] = v
+		}
+	}
+	return result
+}
+
+// Entries returns a copy of the map values indexed by the HashKey of their
+// keys.
+func (m *Map) Entries() map[HashKey]Object {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	result := make(map[HashKey]Object, len(m.items))
+	for k, v := range m.items {
+		result[k] = v
+	}
+	return result
 }
 
 func (m *Map) GetAttr(name string) (Object, bool) {
@@ -95,11 +125,10 @@ func (m *Map) GetAttr(name string) (Object, bool) {
 				if len(args) < 1 || len(args) > 2 {
 					return NewArgsRangeError("map.get", 1, 2, len(args))
 				}
-				key, err := AsString(args[0])
+				value, found, err := m.Lookup(args[0])
 				if err != nil {
 					return err
 				}
-				value, found := m.items[key]
 				if !found {
 					if len(args) == 2 {
 						return args[1]
@@ -148,15 +177,11 @@ func (m *Map) GetAttr(name string) (Object, bool) {
 				if nArgs < 1 || nArgs > 2 {
 					return NewArgsRangeError("map.pop", 1, 2, len(args))
 				}
-				key, err := AsString(args[0])
-				if err != nil {
-					return err
-				}
 				var def Object
 				if nArgs == 2 {
 					def = args[1]
 				}
-				return m.Pop(key, def)
+				return m.Pop(args[0], def)
 			},
 		}, true
 	case "setdefault":
@@ -166,11 +191,7 @@ func (m *Map) GetAttr(name string) (Object, bool) {
 				if len(args) != 2 {
 					return NewArgsError("map.setdefault", 2, len(args))
 				}
-				key, err := AsString(args[0])
-				if err != nil {
-					return err
-				}
-				return m.SetDefault(key, args[1])
+				return m.SetDefault(args[0], args[1])
 			},
 		}, true
 	case "update":
@@ -193,29 +214,45 @@ func (m *Map) GetAttr(name string) (Object, bool) {
 }
 
 func (m *Map) ListItems() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, NewList([]Object{NewString(k), m.items[k]}))
+	keys, values := m.sortedEntries()
+	items := make([]Object, 0, len(keys))
+	for i, k := range keys {
+		items = append(items, NewList([]Object{k, values[i]}))
 	}
 	return NewList(items)
 }
 
 func (m *Map) Clear() {
-	m.items = map[string]Object{}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.items = map[HashKey]Object{}
+	m.keys = map[HashKey]Object{}
 }
 
 func (m *Map) Copy() *Map {
-	items := make(map[string]Object, len(m.items))
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	result := NewMapWithSize(len(m.items))
 	for k, v := range m.items {
-		items[k] = v
+		result.items[k] = v
+		result.keys[k] = m.keys[k]
 	}
-	return &Map{items: items}
+	return result
 }
 
-func (m *Map) Pop(key string, def Object) Object {
-	value, found := m.items[key]
+// Pop removes the given key from the map and returns its value. If the key
+// is not present, def is returned, or nil if def is nil.
+func (m *Map) Pop(key Object, def Object) Object {
+	hashKey, err := mapKey(key)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	value, found := m.items[hashKey]
 	if found {
-		delete(m.items, key)
+		delete(m.items, hashKey)
+		delete(m.keys, hashKey)
 		return value
 	}
 	if def != nil {
@@ -224,62 +261,146 @@ func (m *Map) Pop(key string, def Object) Object {
 	return Nil
 }
 
-func (m *Map) SetDefault(key string, value Object) Object {
-	if _, found := m.items[key]; !found {
-		m.items[key] = value
+// SetDefault sets the given key to value if the key is not already present.
+// The value now associated with the key is returned.
+func (m *Map) SetDefault(key Object, value Object) Object {
+	hashKey, err := mapKey(key)
+	if err != nil {
+		return err
 	}
-	return m.items[key]
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, found := m.items[hashKey]; !found {
+		m.items[hashKey] = value
+		m.keys[hashKey] = key
+	}
+	return m.items[hashKey]
 }
 
 func (m *Map) Update(other *Map) {
+	other.mutex.RLock()
+	items := make(map[HashKey]Object, len(other.items))
+	keys := make(map[HashKey]Object, len(other.keys))
 	for k, v := range other.items {
+		items[k] = v
+		keys[k] = other.keys[k]
+	}
+	other.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for k, v := range items {
 		m.items[k] = v
+		m.keys[k] = keys[k]
+	}
+}
+
+// SortedKeys returns the map keys in a stable order. Keys are grouped by
+// type and then ordered by value within each type.
+func (m *Map) SortedKeys() []Object {
+	keys, _ := m.sortedEntries()
+	return keys
+}
+
+// sortedEntries returns the map keys in the same order as SortedKeys,
+// along with the value for each key.
+func (m *Map) sortedEntries() ([]Object, []Object) {
+	m.mutex.RLock()
+	hashKeys := make([]HashKey, 0, len(m.keys))
+	for k := range m.keys {
+		hashKeys = append(hashKeys, k)
+	}
+	keys := make([]Object, len(hashKeys))
+	values := make([]Object, len(hashKeys))
+	for i, k := range hashKeys {
+		keys[i], values[i] = m.keys[k], m.items[k]
+	}
+	m.mutex.RUnlock()
+	sort.Sort(&entrySorter{keys: keys, values: values})
+	return keys, values
+}
+
+// entrySorter sorts map keys, keeping their values in the same positions.
+type entrySorter struct {
+	keys   []Object
+	values []Object
+}
+
+func (s *entrySorter) Len() int {
+	return len(s.keys)
+}
+
+func (s *entrySorter) Less(a, b int) bool {
+	keyA, keyB := s.keys[a], s.keys[b]
+	if typeComp := CompareTypes(keyA, keyB); typeComp != 0 {
+		return typeComp < 0
+	}
+	if comparable, ok := keyA.(Comparable); ok {
+		if result, err := comparable.Compare(keyB); err == nil {
+			return result < 0
+		}
 	}
+	return keyA.Inspect() < keyB.Inspect()
 }
 
-func (m *Map) SortedKeys() []string {
-	keys := make([]string, 0, len(m.items))
-	for k := range m.items {
-		keys = append(keys, k)
+func (s *entrySorter) Swap(a, b int) {
+	s.keys[a], s.keys[b] = s.keys[b], s.keys[a]
+	s.values[a], s.values[b] = s.values[b], s.values[a]
+}
+
+// StringKeys returns the string keys of the map in sorted order. Keys of
+// other types are skipped. This is useful for maps such as keyword arguments
+// that are known to only contain string keys.
+func (m *Map) StringKeys() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	keys := make([]string, 0, len(m.keys))
+	for k := range m.keys {
+		if k.Type == STRING {
+			keys = append(keys, k.StrValue)
+		}
 	}
 	sort.Strings(keys)
 	return keys
 }
 
 func (m *Map) Keys() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, NewString(k))
-	}
-	return &List{items: items}
+	return &List{items: m.SortedKeys()}
 }
 
 func (m *Map) Values() *List {
-	items := make([]Object, 0, len(m.items))
-	for _, k := range m.SortedKeys() {
-		items = append(items, m.items[k])
+	_, values := m.sortedEntries()
+	return &List{items: values}
+}
+
+// Lookup returns the value for the given key and whether it was found. An
+// error is returned if the key is not hashable.
+func (m *Map) Lookup(key Object) (Object, bool, *Error) {
+	hashKey, err := mapKey(key)
+	if err != nil {
+		return nil, false, err
 	}
-	return &List{items: items}
+	value, found := m.get(hashKey)
+	return value, found, nil
+}
+
+// get returns the value for the key with the given HashKey, if any.
+func (m *Map) get(key HashKey) (Object, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	value, found := m.items[key]
+	return value, found
 }
 
 func (m *Map) GetWithObject(key *String) Object {
-	value, found := m.items[key.value]
-	if !found {
-		return Nil
-	}
-	return value
+	return m.Get(key.value)
 }
 
 func (m *Map) Get(key string) Object {
-	value, found := m.items[key]
-	if !found {
-		return Nil
-	}
-	return value
+	return m.GetWithDefault(key, Nil)
 }
 
 func (m *Map) GetWithDefault(key string, defaultValue Object) Object {
-	value, found := m.items[key]
+	value, found := m.get(stringKey(key))
 	if !found {
 		return defaultValue
 	}
@@ -287,22 +408,50 @@ func (m *Map) GetWithDefault(key string, defaultValue Object) Object {
 }
 
 func (m *Map) Delete(key string) Object {
-	delete(m.items, key)
+	hashKey := stringKey(key)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.items, hashKey)
+	delete(m.keys, hashKey)
 	return Nil
 }
 
 func (m *Map) Set(key string, value Object) {
-	m.items[key] = value
+	hashKey := stringKey(key)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.items[hashKey] = value
+	m.keys[hashKey] = NewString(key)
 }
 
 func (m *Map) Size() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return len(m.items)
 }
 
+// Interface converts the map to a map[string]any when all of its keys are
+// strings. Otherwise a map[any]any is returned, keyed by the Go values of
+// the map keys.
 func (m *Map) Interface() interface{} {
-	result := make(map[string]any, len(m.items))
-	for k, v := range m.items {
-		result[k] = v.Interface()
+	keys, values := m.sortedEntries()
+	stringKeys := true
+	for _, k := range keys {
+		if k.Type() != STRING {
+			stringKeys = false
+			break
+		}
+	}
+	if stringKeys {
+		result := make(map[string]any, len(keys))
+		for i, k := range keys {
+			result[k.(*String).value] = values[i].Interface()
+		}
+		return result
+	}
+	result := make(map[any]any, len(keys))
+	for i, k := range keys {
+		result[k.Interface()] = values[i].Interface()
 	}
 	return result
 }
@@ -311,12 +460,13 @@ func (m *Map) Equals(other Object) Object {
 	if other.Type() != MAP {
 		return False
 	}
-	otherMap := other.(*Map)
-	if len(m.items) != len(otherMap.items) {
+	items := m.Entries()
+	otherItems := other.(*Map).Entries()
+	if len(items) != len(otherItems) {
 		return False
 	}
-	for k, v := range m.items {
-		otherValue, found := otherMap.items[k]
+	for k, v := range items {
+		otherValue, found := otherItems[k]
 		if !found {
 			return False
 		}
@@ -328,13 +478,12 @@ func (m *Map) Equals(other Object) Object {
 }
 
 func (m *Map) GetItem(key Object) (Object, *Error) {
-	strObj, ok := key.(*String)
-	if !ok {
-		return nil, Errorf("key error: map key must be a string (got %s)", key.Type())
+	value, found, err := m.Lookup(key)
+	if err != nil {
+		return nil, err
 	}
-	value, found := m.items[strObj.value]
 	if !found {
-		return nil, Errorf("key error: %q", strObj.Value())
+		return nil, Errorf("key error: %s", key.Inspect())
 	}
 	return value, nil
 }
@@ -346,62 +495,91 @@ func (m *Map) GetSlice(s Slice) (Object, *Error) {
 
 // SetItem assigns a value to the given key in the map.
 func (m *Map) SetItem(key, value Object) *Error {
-	strObj, ok := key.(*String)
-	if !ok {
-		return Errorf("key error: map key must be a string (got %s)", key.Type())
+	hashKey, err := mapKey(key)
+	if err != nil {
+		return err
 	}
-	m.items[strObj.value] = value
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.items[hashKey] = value
+	m.keys[hashKey] = key
 	return nil
 }
 
 // DelItem deletes the item with the given key from the map.
 func (m *Map) DelItem(key Object) *Error {
-	strObj, ok := key.(*String)
-	if !ok {
-		return Errorf("key error: map key must be a string (got %s)", key.Type())
+	hashKey, err := mapKey(key)
+	if err != nil {
+		return err
 	}
-	delete(m.items, strObj.value)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.items, hashKey)
+	delete(m.keys, hashKey)
 	return nil
 }
 
 // Contains returns true if the given item is found in this container.
 func (m *Map) Contains(key Object) *Bool {
-	strObj, ok := key.(*String)
-	if !ok {
+	_, found, err := m.Lookup(key)
+	if err != nil {
 		return False
 	}
-	_, found := m.items[strObj.value]
 	return NewBool(found)
 }
 
 func (m *Map) IsTruthy() bool {
-	return len(m.items) > 0
+	return m.Size() > 0
 }
 
 // Len returns the number of items in this container.
 func (m *Map) Len() *Int {
-	return NewInt(int64(len(m.items)))
+	return NewInt(int64(m.Size()))
 }
 
 func (m *Map) Iter() Iterator {
 	return NewMapIter(m)
 }
 
+// mapKey returns the HashKey for an object used as a map key.
+func mapKey(key Object) (HashKey, *Error) {
+	hashable, ok := key.(Hashable)
+	if !ok {
+		return HashKey{}, Errorf("type error: %s object is unhashable", key.Type())
+	}
+	return hashable.HashKey(), nil
+}
+
+func stringKey(key string) HashKey {
+	return HashKey{Type: STRING, StrValue: key}
+}
+
+// NewMap returns a map containing the given string-keyed items.
 func NewMap(m map[string]Object) *Map {
-	if m == nil {
-		m = map[string]Object{}
+	result := NewMapWithSize(len(m))
+	for k, v := range m {
+		result.Set(k, v)
+	}
+	return result
+}
+
+// NewMapWithSize returns an empty map with room for the given number of
+// items.
+func NewMapWithSize(size int) *Map {
+	return &Map{
+		items: make(map[HashKey]Object, size),
+		keys:  make(map[HashKey]Object, size),
 	}
-	return &Map{items: m}
 }
 
 func NewMapFromGo(m map[string]interface{}) *Map {
-	result := &Map{items: make(map[string]Object, len(m))}
+	result := NewMapWithSize(len(m))
 	for k, v := range m {
 		value := FromGoType(v)
 		if value == nil {
 			panic(fmt.Sprintf("type error: cannot convert %v to a tamarin object", v))
 		}
-		result.items[k] = value
+		result.Set(k, value)
 	}
 	return result
 }
@@ -7,7 +7,7 @@ import (
 
 type MapIter struct {
 	m    *Map
-	keys []string
+	keys []Object
 	pos  int64
 }
 
@@ -71,11 +71,11 @@ func (iter *MapIter) Next() (IteratorEntry, bool) {
 	}
 	key := keys[iter.pos]
 	iter.pos++
-	value, ok := iter.m.items[key]
+	value, ok := iter.m.get(key.(Hashable).HashKey())
 	if !ok {
 		return nil, false
 	}
-	return NewEntry(NewString(key), value), true
+	return NewEntry(key, value), true
 }
 
 func NewMapIter(m *Map) *MapIter {
@@ -0,0 +1,90 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapNonStringKeys(t *testing.T) {
+	m := NewMapWithSize(0)
+	require.Nil(t, m.SetItem(NewInt(1), NewString("a")))
+	require.Nil(t, m.SetItem(True, NewString("b")))
+	require.Nil(t, m.SetItem(NewString("1"), NewString("c")))
+	require.Equal(t, 3, m.Size())
+
+	value, err := m.GetItem(NewInt(1))
+	require.Nil(t, err)
+	require.Equal(t, NewString("a"), value)
+
+	value, err = m.GetItem(True)
+	require.Nil(t, err)
+	require.Equal(t, NewString("b"), value)
+
+	require.Equal(t, NewString("c"), m.Get("1"))
+	require.Equal(t, []Object{True, NewInt(1), NewString("1")}, m.SortedKeys())
+	require.Equal(t, `{true: "b", 1: "a", "1": "c"}`, m.Inspect())
+
+	require.Nil(t, m.DelItem(NewInt(1)))
+	require.Equal(t, False, m.Contains(NewInt(1)))
+	require.Equal(t, True, m.Contains(NewString("1")))
+}
+
+func TestMapValue(t *testing.T) {
+	m := NewMap(map[string]Object{"a": NewInt(1)})
+	require.Nil(t, m.SetItem(NewInt(2), NewInt(3)))
+	require.Equal(t, map[string]Object{"a": NewInt(1)}, m.Value())
+	require.Len(t, m.Entries(), 2)
+	require.Equal(t, NewInt(3), m.Entries()[NewInt(2).HashKey()])
+}
+
+func TestMapUnhashableKey(t *testing.T) {
+	m := NewMapWithSize(0)
+	err := m.SetItem(NewList(nil), NewInt(1))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: list object is unhashable", err.Message().Value())
+
+	_, err = m.GetItem(NewMap(nil))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: map object is unhashable", err.Message().Value())
+}
+
+func TestMapGetItemMissing(t *testing.T) {
+	m := NewMap(map[string]Object{"a": NewInt(1)})
+	_, err := m.GetItem(NewString("b"))
+	require.NotNil(t, err)
+	require.Equal(t, `key error: "b"`, err.Message().Value())
+
+	_, err = m.GetItem(NewInt(2))
+	require.NotNil(t, err)
+	require.Equal(t, "key error: 2", err.Message().Value())
+}
+
+func TestMapInterface(t *testing.T) {
+	m := NewMap(map[string]Object{"a": NewInt(1)})
+	require.Equal(t, map[string]any{"a": int64(1)}, m.Interface())
+
+	require.Nil(t, m.SetItem(NewInt(2), True))
+	require.Equal(t, map[any]any{"a": int64(1), int64(2): true}, m.Interface())
+}
+
+func TestMapEquals(t *testing.T) {
+	a := NewMapWithSize(0)
+	b := NewMapWithSize(0)
+	require.Nil(t, a.SetItem(NewInt(1), NewString("x")))
+	require.Nil(t, b.SetItem(NewString("1"), NewString("x")))
+	require.Equal(t, False, a.Equals(b))
+
+	require.Nil(t, b.DelItem(NewString("1")))
+	require.Nil(t, b.SetItem(NewInt(1), NewString("x")))
+	require.Equal(t, True, a.Equals(b))
+}
+
+func TestMapStringIfaceConverter(t *testing.T) {
+	conv := &MapStringIfaceConverter{}
+	m := NewMapWithSize(0)
+	require.Nil(t, m.SetItem(NewInt(1), True))
+	_, err := conv.To(m)
+	require.NotNil(t, err)
+	require.Equal(t, "type error: expected map keys to be strings (got int)", err.Error())
+}
@@ -16,36 +16,47 @@
 // name of the object type, such as "STRING" or "FLOAT".
 package object
 
+import "context"
+
 // Type defines the type of an object.
 type Type string
 
 // Type constants
 const (
-	INT           Type = "int"
-	FLOAT         Type = "float"
-	BOOL          Type = "bool"
-	NIL           Type = "nil"
-	ERROR         Type = "error"
-	FUNCTION      Type = "function"
-	STRING        Type = "string"
-	BUILTIN       Type = "builtin"
-	LIST          Type = "list"
-	MAP           Type = "map"
-	FILE          Type = "file"
-	REGEXP        Type = "regexp"
-	SET           Type = "set"
-	MODULE        Type = "module"
-	RESULT        Type = "result"
-	HTTP_RESPONSE Type = "http_response"
-	DB_CONNECTION Type = "db_connection"
-	TIME          Type = "time"
-	PROXY         Type = "proxy"
-	CONTROL       Type = "control"
-	STRING_ITER   Type = "string_iter"
-	LIST_ITER     Type = "list_iter"
-	MAP_ITER      Type = "map_iter"
-	SET_ITER      Type = "set_iter"
-	ITER_ENTRY    Type = "iter_entry"
+	INT               Type = "int"
+	FLOAT             Type = "float"
+	BIGINT            Type = "bigint"
+	DECIMAL           Type = "decimal"
+	BOOL              Type = "bool"
+	NIL               Type = "nil"
+	ERROR             Type = "error"
+	FUNCTION          Type = "function"
+	COMPILED_FUNCTION Type = "compiled_function"
+	STRING            Type = "string"
+	BYTES             Type = "bytes"
+	BUILTIN           Type = "builtin"
+	LIST              Type = "list"
+	MAP               Type = "map"
+	FILE              Type = "file"
+	REGEXP            Type = "regexp"
+	SET               Type = "set"
+	MODULE            Type = "module"
+	RESULT            Type = "result"
+	HTTP_RESPONSE     Type = "http_response"
+	DB_CONNECTION     Type = "db_connection"
+	TIME              Type = "time"
+	CHAN              Type = "chan"
+	STRUCT            Type = "struct"
+	PROXY             Type = "proxy"
+	CONTROL           Type = "control"
+	STRING_ITER       Type = "string_iter"
+	BYTES_ITER        Type = "bytes_iter"
+	LIST_ITER         Type = "list_iter"
+	MAP_ITER          Type = "map_iter"
+	SET_ITER          Type = "set_iter"
+	ITER_ENTRY        Type = "iter_entry"
+	SLICE             Type = "slice"
+	GENERATOR         Type = "generator"
 )
 
 var (
@@ -76,10 +87,13 @@ type Object interface {
 	IsTruthy() bool
 }
 
-// Slice is used to specify a range or slice of items in a container.
+// Slice is used to specify a range or slice of items in a container. Any
+// of the fields may be nil to use the default. A Slice is also an Object, so
+// that it may be passed to SetItem and DelItem.
 type Slice struct {
 	Start Object
 	Stop  Object
+	Step  Object
 }
 
 // IteratorEntry is a single item returned by an iterator.
@@ -104,7 +118,7 @@ type Container interface {
 	// GetItem implements the [key] operator for a container type.
 	GetItem(key Object) (Object, *Error)
 
-	// GetSlice implements the [start:stop] operator for a container type.
+	// GetSlice implements the [start:stop:step] operator for a container type.
 	GetSlice(s Slice) (Object, *Error)
 
 	// SetItem implements the [key] = value operator for a container type.
@@ -123,7 +137,45 @@ type Container interface {
 	Iter() Iterator
 }
 
-// Hashable types can be hashed and consequently used in a set.
+// AttrSetter is implemented by objects whose attributes may be assigned.
+type AttrSetter interface {
+
+	// SetAttr implements the obj.name = value operator.
+	SetAttr(name string, value Object) *Error
+}
+
+// BinaryOperator is implemented by objects that support binary operators
+// such as +, -, * and <. Compound assignment operators are given without the
+// trailing "=", so += is dispatched as +, and != is dispatched as == with the
+// result negated.
+type BinaryOperator interface {
+
+	// BinaryOp applies the operator with this object as the left operand. It
+	// returns nil if the operation is not supported for the given operand.
+	// The context is that of the calling program.
+	BinaryOp(ctx context.Context, operator string, right Object) Object
+}
+
+// ReflectedBinaryOperator is implemented by objects that support binary
+// operators when they are the right operand, e.g. 2 * vector. It is only
+// consulted if the left operand does not support the operation.
+type ReflectedBinaryOperator interface {
+
+	// ReflectedBinaryOp applies the operator with this object as the right
+	// operand. It returns nil if the operation is not supported.
+	ReflectedBinaryOp(ctx context.Context, operator string, left Object) Object
+}
+
+// UnaryOperator is implemented by objects that support the prefix
+// operators !, - and ^.
+type UnaryOperator interface {
+
+	// UnaryOp applies the operator to this object. It returns nil if the
+	// operation is not supported.
+	UnaryOp(ctx context.Context, operator string) Object
+}
+
+// Hashable types can be hashed and consequently used in a set or as map keys.
 type Hashable interface {
 
 	// Hash returns a hash key for the given object.
@@ -151,7 +203,7 @@ func CompareTypes(a, b Object) int {
 	return 0
 }
 
-// HashKey is used to identify unique values in a set.
+// HashKey is used to identify unique values in a set and keys in a map.
 type HashKey struct {
 	// Type of the object being referenced.
 	Type Type
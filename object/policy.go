@@ -0,0 +1,217 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Policy declares the capabilities a program is allowed to use, which is
+// useful when running untrusted code. Builtins and modules that reach outside
+// of the program, such as fetch or the rand module, consult the Policy before
+// doing so. Anything a Policy doesn't allow is denied. The methods of a nil
+// Policy allow everything.
+type Policy struct {
+	// AllowedHosts lists the hosts that fetch may connect to, including
+	// hosts it is redirected to. Patterns may use "*" to match any sequence
+	// of characters, so "*.example.com" matches any subdomain of example.com
+	// and "*" matches any host.
+	AllowedHosts []string
+
+	// AllowedDatabases lists patterns for the databases that may be
+	// connected to, in the form "postgres://user@host:port/database". Each
+	// part is matched against the parsed connection string and may use "*"
+	// to match any sequence of characters, while parts left out of the
+	// pattern match anything. For example, "postgres://*@db.internal/*"
+	// matches any user and database on db.internal.
+	AllowedDatabases []string
+
+	// FileRoots lists the directories under which files may be read, such as
+	// by imports.
+	FileRoots []string
+
+	// AllowExit allows the program to stop itself using exit.
+	AllowExit bool
+
+	// AllowClock allows the program to read the current time.
+	AllowClock bool
+
+	// AllowRandom allows the program to generate random numbers.
+	AllowRandom bool
+}
+
+// PermissionError is the error returned when a program uses a capability that
+// its Policy doesn't allow. Hosts may detect it using errors.As.
+type PermissionError struct {
+	capability string
+	message    string
+}
+
+// Capability returns the name of the Policy field that denied the operation.
+func (e *PermissionError) Capability() string {
+	return e.capability
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission error: %s", e.message)
+}
+
+// IsPermissionError returns true if the error was caused by an operation that
+// the Policy of the program denied.
+func IsPermissionError(err *Error) bool {
+	var permErr *PermissionError
+	return errors.As(err.err, &permErr)
+}
+
+func denied(capability, format string, args ...interface{}) *Error {
+	return NewError(&PermissionError{capability: capability, message: fmt.Sprintf(format, args...)})
+}
+
+// CheckHost returns an error if the program may not connect to the host.
+func (p *Policy) CheckHost(host string) *Error {
+	if p == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range p.AllowedHosts {
+		if matchPattern(strings.ToLower(pattern), host) {
+			return nil
+		}
+	}
+	return denied("AllowedHosts", "network access to %q is not allowed", host)
+}
+
+// Database identifies a database that a program connects to, as parsed
+// from its connection string.
+type Database struct {
+	Host     string
+	Port     uint16
+	Database string
+	User     string
+}
+
+// CheckDatabase returns an error if the program may not connect to the
+// database. A nil database stands for a connection string that couldn't be
+// parsed, which is always denied. The connection string isn't included in
+// the error since it may hold credentials.
+func (p *Policy) CheckDatabase(db *Database) *Error {
+	if p == nil {
+		return nil
+	}
+	if db != nil {
+		for _, pattern := range p.AllowedDatabases {
+			if matchDatabase(pattern, db) {
+				return nil
+			}
+		}
+	}
+	return denied("AllowedDatabases", "connecting to this database is not allowed")
+}
+
+// CheckFile returns an error if the file isn't under one of the FileRoots.
+// Symbolic links are followed when the path exists, so that a link can't be
+// used to reach a file outside of the roots.
+func (p *Policy) CheckFile(path string) *Error {
+	if p == nil {
+		return nil
+	}
+	resolved := resolvePath(path)
+	for _, root := range p.FileRoots {
+		rel, err := filepath.Rel(resolvePath(root), resolved)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return denied("FileRoots", "file access to %q is not allowed", path)
+}
+
+// CheckExit returns an error if the program may not stop itself using exit.
+func (p *Policy) CheckExit() *Error {
+	if p == nil || p.AllowExit {
+		return nil
+	}
+	return denied("AllowExit", "exit is not allowed")
+}
+
+// CheckClock returns an error if the program may not read the current time.
+func (p *Policy) CheckClock() *Error {
+	if p == nil || p.AllowClock {
+		return nil
+	}
+	return denied("AllowClock", "clock access is not allowed")
+}
+
+// CheckRandom returns an error if the program may not generate random
+// numbers.
+func (p *Policy) CheckRandom() *Error {
+	if p == nil || p.AllowRandom {
+		return nil
+	}
+	return denied("AllowRandom", "random number generation is not allowed")
+}
+
+// matchPattern reports whether the value matches the pattern, in which "*"
+// matches any sequence of characters and everything else matches literally.
+func matchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, _ := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", value)
+	return matched
+}
+
+// matchDatabase reports whether the database matches a pattern of the form
+// "postgres://user@host:port/database", in which each part is optional.
+func matchDatabase(pattern string, db *Database) bool {
+	pattern = strings.TrimPrefix(pattern, "postgresql://")
+	pattern = strings.TrimPrefix(pattern, "postgres://")
+	user, database := "*", "*"
+	if i := strings.LastIndex(pattern, "@"); i >= 0 {
+		user, pattern = pattern[:i], pattern[i+1:]
+	}
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		pattern, database = pattern[:i], pattern[i+1:]
+	}
+	host, port := pattern, "*"
+	if i := strings.LastIndex(pattern, ":"); i >= 0 && !strings.HasSuffix(pattern, "]") {
+		host, port = pattern[:i], pattern[i+1:]
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return matchPattern(strings.ToLower(host), strings.ToLower(db.Host)) &&
+		matchPattern(port, strconv.Itoa(int(db.Port))) &&
+		matchPattern(user, db.User) &&
+		matchPattern(database, db.Database)
+}
+
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
+
+const policyKey = contextKey("policy")
+
+// WithPolicy adds a Policy to the context, which is consulted by builtins
+// and modules before they use a capability.
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, policyKey, p)
+}
+
+// GetPolicy returns the Policy from the context. A nil Policy, which allows
+// everything, is returned if there isn't one.
+func GetPolicy(ctx context.Context) *Policy {
+	p, _ := ctx.Value(policyKey).(*Policy)
+	return p
+}
@@ -0,0 +1,88 @@
+package object
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+	require.Nil(t, p.CheckHost("example.com"))
+	require.Nil(t, p.CheckDatabase(&Database{Host: "localhost", Port: 5432, Database: "db"}))
+	require.Nil(t, p.CheckFile("/etc/passwd"))
+	require.Nil(t, p.CheckExit())
+	require.Nil(t, p.CheckClock())
+	require.Nil(t, p.CheckRandom())
+}
+
+func TestPolicyHosts(t *testing.T) {
+	p := &Policy{AllowedHosts: []string{"api.example.com", "*.cdn.example.com"}}
+	require.Nil(t, p.CheckHost("api.example.com"))
+	require.Nil(t, p.CheckHost("API.Example.com"))
+	require.Nil(t, p.CheckHost("img.cdn.example.com"))
+	require.NotNil(t, p.CheckHost("cdn.example.com"))
+	require.NotNil(t, p.CheckHost("api.example.com.evil.com"))
+
+	err := p.CheckHost("evil.com")
+	require.NotNil(t, err)
+	require.True(t, IsPermissionError(err))
+	require.Equal(t, `permission error: network access to "evil.com" is not allowed`, err.Message().Value())
+	var permErr *PermissionError
+	require.True(t, errors.As(err.Value(), &permErr))
+	require.Equal(t, "AllowedHosts", permErr.Capability())
+
+	require.Nil(t, (&Policy{AllowedHosts: []string{"*"}}).CheckHost("anything.com"))
+}
+
+func TestPolicyDatabases(t *testing.T) {
+	p := &Policy{AllowedDatabases: []string{"postgres://*@db.internal:5432/*"}}
+	require.Nil(t, p.CheckDatabase(&Database{Host: "db.internal", Port: 5432, Database: "app", User: "app"}))
+	require.Nil(t, p.CheckDatabase(&Database{Host: "DB.Internal", Port: 5432, Database: "app", User: "app"}))
+	require.NotNil(t, p.CheckDatabase(&Database{Host: "db.internal", Port: 5433, Database: "app", User: "app"}))
+	require.NotNil(t, p.CheckDatabase(&Database{Host: "db.internal.evil.com", Port: 5432, Database: "app"}))
+	require.NotNil(t, p.CheckDatabase(nil))
+
+	// The host of a connection string that mentions an allowed host in its
+	// query is still checked on its own
+	err := p.CheckDatabase(&Database{Host: "evil.com", Port: 5432, Database: "db", User: "u"})
+	require.NotNil(t, err)
+	require.True(t, IsPermissionError(err))
+	require.Equal(t, "permission error: connecting to this database is not allowed", err.Message().Value())
+
+	// Parts left out of a pattern match anything
+	p = &Policy{AllowedDatabases: []string{"db.internal", "app@[::1]:6543/reports"}}
+	require.Nil(t, p.CheckDatabase(&Database{Host: "db.internal", Port: 6000, Database: "x", User: "y"}))
+	require.Nil(t, p.CheckDatabase(&Database{Host: "::1", Port: 6543, Database: "reports", User: "app"}))
+	require.NotNil(t, p.CheckDatabase(&Database{Host: "::1", Port: 6543, Database: "reports", User: "admin"}))
+	require.NotNil(t, p.CheckDatabase(&Database{Host: "::1", Port: 6543, Database: "other", User: "app"}))
+}
+
+func TestPolicyFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(outside, "secret.tm"), []byte("1"), 0o600))
+	require.Nil(t, os.Symlink(filepath.Join(outside, "secret.tm"), filepath.Join(root, "link.tm")))
+
+	p := &Policy{FileRoots: []string{root}}
+	require.Nil(t, p.CheckFile(filepath.Join(root, "lib.tm")))
+	require.Nil(t, p.CheckFile(filepath.Join(root, "sub", "lib.tm")))
+	require.NotNil(t, p.CheckFile(filepath.Join(root, "..", "lib.tm")))
+	require.NotNil(t, p.CheckFile(filepath.Join(outside, "secret.tm")))
+	require.NotNil(t, p.CheckFile(filepath.Join(root, "link.tm")))
+}
+
+func TestPolicyFlags(t *testing.T) {
+	p := &Policy{}
+	require.Equal(t, "permission error: exit is not allowed", p.CheckExit().Message().Value())
+	require.Equal(t, "permission error: clock access is not allowed", p.CheckClock().Message().Value())
+	require.Equal(t, "permission error: random number generation is not allowed", p.CheckRandom().Message().Value())
+
+	p = &Policy{AllowExit: true, AllowClock: true, AllowRandom: true}
+	require.Nil(t, p.CheckExit())
+	require.Nil(t, p.CheckClock())
+	require.Nil(t, p.CheckRandom())
+}
@@ -197,6 +197,7 @@ func NewTypeRegistry(opts ...TypeRegistryOpts) (*DefaultTypeRegistry, error) {
 			&Float64Converter{},
 			&TimeConverter{},
 			&StringConverter{},
+			&BytesConverter{},
 			&BooleanConverter{},
 			&MapStringIfaceConverter{},
 		}
@@ -417,6 +418,13 @@ func (p *Proxy) call(ctx context.Context, m *GoMethod, args ...Object) Object {
 		if argIndex >= len(args) {
 			break
 		}
+		// Proxied Go values are passed through as-is when the method accepts
+		// their type, e.g. the other operand of an Add method
+		if proxy, ok := args[argIndex].(*Proxy); ok && reflect.TypeOf(proxy.obj).AssignableTo(m.method.Type.In(i)) {
+			inputs = append(inputs, reflect.ValueOf(proxy.obj))
+			argIndex++
+			continue
+		}
 		input, err := m.inputConverters[i-1].To(args[argIndex])
 		if err != nil {
 			return Errorf("type error: failed to convert argument %d in %s() call: %s", i, methodName, err)
@@ -439,7 +447,7 @@ func (p *Proxy) call(ctx context.Context, m *GoMethod, args ...Object) Object {
 			}
 			return NewOkResult(Nil)
 		}
-		obj, err := m.outputConverters[0].From(outputs[0].Interface())
+		obj, err := p.convertOutput(m.outputConverters[0], outputs[0])
 		if err != nil {
 			return Errorf("call error: failed to convert output from %s() call: %s", methodName, err)
 		}
@@ -448,11 +456,11 @@ func (p *Proxy) call(ctx context.Context, m *GoMethod, args ...Object) Object {
 		if !m.outputHasErr {
 			return Errorf("call error: too many outputs from %s() call", methodName)
 		}
-		obj0, err := m.outputConverters[0].From(outputs[0].Interface())
+		obj0, err := p.convertOutput(m.outputConverters[0], outputs[0])
 		if err != nil {
 			return Errorf("call error: failed to convert output from %s() call: %s", methodName, err)
 		}
-		obj1, err := m.outputConverters[1].From(outputs[1].Interface())
+		obj1, err := p.convertOutput(m.outputConverters[1], outputs[1])
 		if err != nil {
 			return Errorf("call error: failed to convert output from %s() call: %s", methodName, err)
 		}
@@ -472,6 +480,119 @@ func (p *Proxy) call(ctx context.Context, m *GoMethod, args ...Object) Object {
 	return Errorf("call error: method %s has too many outputs", methodName)
 }
 
+// convertOutput converts a value returned by a proxied method. Values of the
+// same Go type as the proxied object are wrapped in a new proxy, so that
+// methods such as Add can return further proxied values.
+func (p *Proxy) convertOutput(converter TypeConverter, value reflect.Value) (Object, error) {
+	if value.Type() == p.typ.rType {
+		return &Proxy{reg: p.reg, typ: p.typ, obj: value.Interface()}, nil
+	}
+	return converter.From(value.Interface())
+}
+
+// proxyOperatorMethods maps operators to the methods on proxied Go types that
+// implement them. Each method takes the other operand as its only argument.
+var proxyOperatorMethods = map[string]string{
+	"+":  "Add",
+	"-":  "Sub",
+	"*":  "Mul",
+	"/":  "Div",
+	"%":  "Mod",
+	"==": "Equal",
+	"<":  "Less",
+}
+
+// BinaryOp implements the BinaryOperator interface by calling the method on
+// the Go type that corresponds to the operator, e.g. Add for + and Less for <.
+// The >, <= and >= operators are derived from Less.
+func (p *Proxy) BinaryOp(ctx context.Context, operator string, right Object) Object {
+	switch operator {
+	case ">":
+		if other, ok := right.(*Proxy); ok {
+			return other.callOperator(ctx, "Less", p)
+		}
+		return nil
+	case "<=":
+		return notOperator(p.BinaryOp(ctx, ">", right))
+	case ">=":
+		return notOperator(p.callOperator(ctx, "Less", right))
+	}
+	name, ok := proxyOperatorMethods[operator]
+	if !ok {
+		return nil
+	}
+	return p.callOperator(ctx, name, right)
+}
+
+// UnaryOp implements the UnaryOperator interface. The - operator calls a Neg
+// method on the Go type if it has one.
+func (p *Proxy) UnaryOp(ctx context.Context, operator string) Object {
+	if operator != "-" {
+		return nil
+	}
+	return p.callOperator(ctx, "Neg")
+}
+
+// callOperator calls the named method if the Go type has it and the method
+// accepts the given arguments. Nil is returned otherwise, so that operands
+// the method doesn't accept, such as nil for an Equal method, get the default
+// behavior of the operator. Methods that return an error have the error
+// returned directly.
+func (p *Proxy) callOperator(ctx context.Context, name string, args ...Object) Object {
+	attr, found := p.reg.GetAttr(p.obj, name)
+	if !found {
+		return nil
+	}
+	method, ok := attr.(*GoMethod)
+	if !ok || !method.accepts(args) {
+		return nil
+	}
+	result := p.call(ctx, method, args...)
+	if res, ok := result.(*Result); ok {
+		if res.IsErr() {
+			return res.UnwrapErr()
+		}
+		return res.Unwrap()
+	}
+	return result
+}
+
+// accepts returns true if the method takes exactly the given arguments,
+// besides a context.Context, and each of them converts to the type of its
+// parameter.
+func (m *GoMethod) accepts(args []Object) bool {
+	if m.method.Type.IsVariadic() {
+		return false
+	}
+	var argIndex int
+	for i, converter := range m.inputConverters {
+		if _, ok := converter.(*ContextConverter); ok {
+			continue
+		}
+		if argIndex >= len(args) {
+			return false
+		}
+		arg := args[argIndex]
+		argIndex++
+		if proxy, ok := arg.(*Proxy); ok && reflect.TypeOf(proxy.obj).AssignableTo(m.method.Type.In(i+1)) {
+			continue
+		}
+		if _, err := converter.To(arg); err != nil {
+			return false
+		}
+	}
+	return argIndex == len(args)
+}
+
+// notOperator negates the boolean result of an operator method, passing
+// through nil and errors.
+func notOperator(result Object) Object {
+	if b, ok := result.(*Bool); ok {
+		return Not(b)
+	}
+	return result
+}
+
 // NewProxy returns a new Tamarin proxy object that wraps the given Go object.
 // The Go type is registered with the type registry, which has no effect if the
 // type is already registered. This operation may fail if the Go type has
@@ -115,3 +115,126 @@ func TestProxy(t *testing.T) {
 	require.Equal(t, "strconv.Atoi: parsing \"not-an-int\": invalid syntax",
 		result.UnwrapErr().Message().Value())
 }
+
+// Money is proxied to test mapping operators onto Go methods.
+type Money struct {
+	Cents int64
+}
+
+func (m *Money) Add(other *Money) *Money {
+	return &Money{Cents: m.Cents + other.Cents}
+}
+
+func (m *Money) Sub(other *Money) *Money {
+	return &Money{Cents: m.Cents - other.Cents}
+}
+
+func (m *Money) Mul(factor int64) *Money {
+	return &Money{Cents: m.Cents * factor}
+}
+
+func (m *Money) Mod(ctx context.Context, divisor int64) (*Money, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Money{Cents: m.Cents % divisor}, nil
+}
+
+func (m *Money) Div(divisor int64) (*Money, error) {
+	if divisor == 0 {
+		return nil, fmt.Errorf("cannot divide money by zero")
+	}
+	return &Money{Cents: m.Cents / divisor}, nil
+}
+
+func (m *Money) Less(other *Money) bool {
+	return m.Cents < other.Cents
+}
+
+func (m *Money) Equal(other *Money) bool {
+	return m.Cents == other.Cents
+}
+
+func (m *Money) Neg() *Money {
+	return &Money{Cents: -m.Cents}
+}
+
+func (m *Money) String() string {
+	return fmt.Sprintf("$%.2f", float64(m.Cents)/100)
+}
+
+func TestProxyOperators(t *testing.T) {
+	reg, err := object.NewTypeRegistry()
+	require.Nil(t, err)
+
+	newMoney := func(cents int64) *object.Proxy {
+		p, err := object.NewProxy(reg, &Money{Cents: cents})
+		require.Nil(t, err)
+		return p
+	}
+	a := newMoney(150)
+	b := newMoney(275)
+
+	ctx := context.Background()
+	sum := a.BinaryOp(ctx, "+", b)
+	require.IsType(t, &object.Proxy{}, sum)
+	require.Equal(t, "$4.25", sum.Inspect())
+	require.Equal(t, "$1.25", b.BinaryOp(ctx, "-", a).Inspect())
+	require.Equal(t, "$4.50", a.BinaryOp(ctx, "*", object.NewInt(3)).Inspect())
+	require.Equal(t, "$0.75", a.BinaryOp(ctx, "/", object.NewInt(2)).Inspect())
+	require.Equal(t, "$-1.50", a.UnaryOp(ctx, "-").Inspect())
+
+	require.Equal(t, object.True, a.BinaryOp(ctx, "<", b))
+	require.Equal(t, object.False, a.BinaryOp(ctx, ">", b))
+	require.Equal(t, object.True, a.BinaryOp(ctx, "<=", b))
+	require.Equal(t, object.False, a.BinaryOp(ctx, ">=", b))
+	require.Equal(t, object.True, a.BinaryOp(ctx, "==", newMoney(150)))
+
+	divErr, ok := a.BinaryOp(ctx, "/", object.NewInt(0)).(*object.Error)
+	require.True(t, ok)
+	require.Equal(t, "cannot divide money by zero", divErr.Message().Value())
+
+	// Operators without a corresponding method are not supported
+	require.Nil(t, a.BinaryOp(ctx, "**", b))
+	require.Nil(t, a.UnaryOp(ctx, "!"))
+
+	// Operands that the method doesn't accept get the default behavior
+	require.Nil(t, a.BinaryOp(ctx, "==", object.Nil))
+	require.Nil(t, a.BinaryOp(ctx, "==", object.NewInt(1)))
+	require.Nil(t, a.BinaryOp(ctx, "+", object.NewInt(1)))
+
+	// Methods that accept a context receive the context of the caller
+	require.Equal(t, "$0.50", a.BinaryOp(ctx, "%", object.NewInt(100)).Inspect())
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	modErr, ok := a.BinaryOp(canceled, "%", object.NewInt(100)).(*object.Error)
+	require.True(t, ok)
+	require.Equal(t, "context canceled", modErr.Message().Value())
+}
+
+// Codec is proxied to test passing byte slices to and from Go methods.
+type Codec struct{}
+
+func (c *Codec) Reverse(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i, v := range b {
+		result[len(b)-1-i] = v
+	}
+	return result
+}
+
+func TestProxyBytes(t *testing.T) {
+	reg, err := object.NewTypeRegistry()
+	require.Nil(t, err)
+	proxy, err := object.NewProxy(reg, &Codec{})
+	require.Nil(t, err)
+
+	method, ok := proxy.GetAttr("Reverse")
+	require.True(t, ok)
+	res := method.(*object.Builtin).Call(context.Background(), object.NewBytes([]byte{1, 2, 3}))
+	require.Equal(t, []byte{3, 2, 1}, res.(*object.Bytes).Value())
+
+	res = method.(*object.Builtin).Call(context.Background(), object.NewString("abc"))
+	require.Equal(t, "type error: failed to convert argument 1 in Codec.Reverse() call: type error: expected bytes (got string)",
+		res.(*object.Error).Message().Value())
+}
@@ -0,0 +1,167 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cloudcmds/tamarin/token"
+)
+
+// StackFrame describes a function call that was active when a runtime error
+// occurred.
+type StackFrame struct {
+	// Name of the function, or "main" for the top-level program
+	Name string
+	// Position of the code being executed in the function
+	Position token.Position
+	// Omitted is set on a frame that stands in for the given number of
+	// frames left out of a long stack trace, in which case Name and
+	// Position are empty
+	Omitted int
+}
+
+// MaxStackFrames is the maximum number of frames kept in the stack trace of a
+// RuntimeError. Deeper stacks, such as those of runaway recursion, keep their
+// outermost and innermost frames with a marker frame in between.
+const MaxStackFrames = 20
+
+// RuntimeErrorOpts holds the data used to create a RuntimeError. Only
+// Cause is required.
+type RuntimeErrorOpts struct {
+	Cause         error
+	StartPosition token.Position
+	EndPosition   token.Position
+	SourceCode    string
+	Stack         []StackFrame
+}
+
+// RuntimeError is the Go error returned when execution of a Tamarin program
+// fails. It wraps the original error along with the position in the source
+// code where the error occurred and the call stack at that point.
+type RuntimeError struct {
+	// The wrapped error
+	cause error
+	// Start position of the code that failed
+	startPosition token.Position
+	// End position of the code that failed
+	endPosition token.Position
+	// Relevant line of source code text
+	sourceCode string
+	// Active function calls, outermost first
+	stack []StackFrame
+}
+
+// NewRuntimeError returns a new RuntimeError populated with the given data.
+func NewRuntimeError(opts RuntimeErrorOpts) *RuntimeError {
+	return &RuntimeError{
+		cause:         opts.Cause,
+		startPosition: opts.StartPosition,
+		endPosition:   opts.EndPosition,
+		sourceCode:    opts.SourceCode,
+		stack:         truncateStack(opts.Stack),
+	}
+}
+
+func truncateStack(frames []StackFrame) []StackFrame {
+	if len(frames) <= MaxStackFrames {
+		return frames
+	}
+	keep := MaxStackFrames / 2
+	omitted := len(frames) - 2*keep
+	result := make([]StackFrame, 0, 2*keep+1)
+	result = append(result, frames[:keep]...)
+	result = append(result, StackFrame{Omitted: omitted})
+	return append(result, frames[len(frames)-keep:]...)
+}
+
+func (e *RuntimeError) Error() string {
+	return e.cause.Error()
+}
+
+// FriendlyMessage returns the error message along with its location, the
+// offending line of source code when known, and a stack trace.
+func (e *RuntimeError) FriendlyMessage() string {
+	var msg bytes.Buffer
+	msg.WriteString(e.Error())
+	msg.WriteString("\n\n")
+
+	start := e.startPosition
+	lineNum := start.LineNumber()
+	colStart := start.ColumnNumber()
+	friendlyLoc := fmt.Sprintf("line %d, column %d", lineNum, colStart)
+	if start.File != "" {
+		msg.WriteString(fmt.Sprintf("location: %s:%d:%d (%s)\n", start.File, lineNum, colStart, friendlyLoc))
+	} else {
+		msg.WriteString(fmt.Sprintf("location: %s\n", friendlyLoc))
+	}
+
+	if e.sourceCode != "" {
+		colEnd := colStart
+		if e.endPosition.Line == start.Line && e.endPosition.Column > start.Column {
+			colEnd = e.endPosition.ColumnNumber()
+		}
+		msg.WriteString("\n" + e.sourceCode + "\n")
+		msg.WriteString(strings.Repeat(" ", colStart-1))
+		msg.WriteString(strings.Repeat("^", colEnd-colStart+1))
+		msg.WriteString("\n")
+	}
+
+	if len(e.stack) > 0 {
+		msg.WriteString("\nstack trace (most recent call last):\n")
+		for _, frame := range e.stack {
+			if frame.Omitted > 0 {
+				msg.WriteString(fmt.Sprintf("  ... %d more frames\n", frame.Omitted))
+				continue
+			}
+			loc := fmt.Sprintf("line %d", frame.Position.LineNumber())
+			if frame.Position.File != "" {
+				loc = fmt.Sprintf("%s:%d", frame.Position.File, frame.Position.LineNumber())
+			}
+			msg.WriteString(fmt.Sprintf("  %s - in %s\n", loc, frame.Name))
+		}
+	}
+	return strings.TrimSuffix(msg.String(), "\n")
+}
+
+// Cause returns the wrapped error.
+func (e *RuntimeError) Cause() error {
+	return e.cause
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.cause
+}
+
+// File returns the name of the file where the error occurred, if known.
+func (e *RuntimeError) File() string {
+	return e.startPosition.File
+}
+
+func (e *RuntimeError) StartPosition() token.Position {
+	return e.startPosition
+}
+
+func (e *RuntimeError) EndPosition() token.Position {
+	return e.endPosition
+}
+
+// SourceCode returns the line of source code where the error occurred, if
+// it is known.
+func (e *RuntimeError) SourceCode() string {
+	return e.sourceCode
+}
+
+// Stack returns the function calls that were active when the error occurred,
+// with the outermost call first.
+func (e *RuntimeError) Stack() []StackFrame {
+	return e.stack
+}
+
+// WithSourceCode returns a copy of the error that includes the given line
+// of source code.
+func (e *RuntimeError) WithSourceCode(sourceCode string) *RuntimeError {
+	copied := *e
+	copied.sourceCode = sourceCode
+	return &copied
+}
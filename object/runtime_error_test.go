@@ -0,0 +1,79 @@
+package object
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cloudcmds/tamarin/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeError(t *testing.T) {
+	cause := errors.New("type error: oops")
+	err := NewRuntimeError(RuntimeErrorOpts{
+		Cause:         cause,
+		StartPosition: token.Position{File: "main.tm", Line: 1, Column: 4},
+		EndPosition:   token.Position{File: "main.tm", Line: 1, Column: 6},
+		Stack: []StackFrame{
+			{Name: "main", Position: token.Position{File: "main.tm", Line: 4}},
+			{Name: "f", Position: token.Position{File: "main.tm", Line: 1}},
+		},
+	})
+	require.Equal(t, "type error: oops", err.Error())
+	require.True(t, errors.Is(err, cause))
+	require.Equal(t, "main.tm", err.File())
+	require.Len(t, err.Stack(), 2)
+	require.Equal(t, `type error: oops
+
+location: main.tm:2:5 (line 2, column 5)
+
+stack trace (most recent call last):
+  main.tm:5 - in main
+  main.tm:2 - in f`, err.FriendlyMessage())
+
+	withSource := err.WithSourceCode("  x + y")
+	require.Equal(t, "", err.SourceCode())
+	require.Equal(t, "  x + y", withSource.SourceCode())
+	require.Equal(t, `type error: oops
+
+location: main.tm:2:5 (line 2, column 5)
+
+  x + y
+    ^^^
+
+stack trace (most recent call last):
+  main.tm:5 - in main
+  main.tm:2 - in f`, withSource.FriendlyMessage())
+}
+
+func TestErrorWithRuntimeError(t *testing.T) {
+	err := Errorf("eval error: bad")
+	_, ok := err.RuntimeError()
+	require.False(t, ok)
+
+	annotated := err.WithRuntimeError(RuntimeErrorOpts{
+		StartPosition: token.Position{Line: 2},
+	})
+	rtErr, ok := annotated.RuntimeError()
+	require.True(t, ok)
+	require.Equal(t, err.Value(), rtErr.Cause())
+	require.Equal(t, 3, rtErr.StartPosition().LineNumber())
+	require.Equal(t, err.Message(), annotated.Message())
+}
+
+func TestRuntimeErrorTruncatedStack(t *testing.T) {
+	frames := []StackFrame{{Name: "main", Position: token.Position{Line: 1}}}
+	for i := 0; i < 100; i++ {
+		frames = append(frames, StackFrame{Name: "f", Position: token.Position{Line: 0}})
+	}
+	err := NewRuntimeError(RuntimeErrorOpts{
+		Cause: errors.New("recursion error: too deep"),
+		Stack: frames,
+	})
+	stack := err.Stack()
+	require.Len(t, stack, MaxStackFrames+1)
+	require.Equal(t, "main", stack[0].Name)
+	require.Equal(t, 81, stack[MaxStackFrames/2].Omitted)
+	require.Equal(t, "f", stack[len(stack)-1].Name)
+	require.Contains(t, err.FriendlyMessage(), "  line 1 - in f\n  ... 81 more frames\n  line 1 - in f\n")
+}
@@ -5,9 +5,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 )
 
+// Set is an unordered collection of hashable items. Like a List, a set may
+// be shared by goroutines, so its items are guarded by a mutex.
 type Set struct {
+	mutex sync.RWMutex
 	items map[HashKey]Object
 }
 
@@ -15,13 +19,31 @@ func (s *Set) Type() Type {
 	return SET
 }
 
+// Value returns a copy of the items in the set, indexed by their HashKey.
 func (s *Set) Value() map[HashKey]Object {
-	return s.items
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	items := make(map[HashKey]Object, len(s.items))
+	for k, v := range s.items {
+		items[k] = v
+	}
+	return items
+}
+
+// values returns the items in the set in no particular order.
+func (s *Set) values() []Object {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	items := make([]Object, 0, len(s.items))
+	for _, v := range s.items {
+		items = append(items, v)
+	}
+	return items
 }
 
 func (s *Set) Inspect() string {
 	var out bytes.Buffer
-	items := make([]string, 0, len(s.items))
+	items := make([]string, 0)
 	for _, item := range s.SortedItems() {
 		items = append(items, item.Inspect())
 	}
@@ -33,7 +55,7 @@ func (s *Set) Inspect() string {
 
 func (s *Set) String() string {
 	var out bytes.Buffer
-	items := make([]string, 0, len(s.items))
+	items := make([]string, 0)
 	for _, item := range s.SortedItems() {
 		items = append(items, fmt.Sprintf("%s", item))
 	}
@@ -109,7 +131,7 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 }
 
 func (s *Set) Interface() interface{} {
-	items := make([]interface{}, 0, len(s.items))
+	items := make([]interface{}, 0)
 	for _, item := range s.SortedItems() {
 		items = append(items, item.Interface())
 	}
@@ -117,19 +139,20 @@ func (s *Set) Interface() interface{} {
 }
 
 func (s *Set) Size() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return len(s.items)
 }
 
 func (s *Set) SortedItems() []Object {
-	items := make([]Object, 0, len(s.items))
-	for _, v := range s.items {
-		items = append(items, v)
-	}
+	items := s.values()
 	Sort(items)
 	return items
 }
 
 func (s *Set) Add(items ...Object) Object {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	for _, item := range items {
 		hashable, ok := item.(Hashable)
 		if !ok {
@@ -141,6 +164,8 @@ func (s *Set) Add(items ...Object) Object {
 }
 
 func (s *Set) Remove(items ...Object) Object {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	for _, item := range items {
 		hashable, ok := item.(Hashable)
 		if !ok {
@@ -152,16 +177,15 @@ func (s *Set) Remove(items ...Object) Object {
 }
 
 func (s *Set) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.items = map[HashKey]Object{}
 }
 
 // Union returns a new set that is the union of the two sets.
 func (s *Set) Union(other *Set) *Set {
-	union := &Set{items: map[HashKey]Object{}}
-	for k, v := range s.items {
-		union.items[k] = v
-	}
-	for k, v := range other.items {
+	union := &Set{items: s.Value()}
+	for k, v := range other.Value() {
 		union.items[k] = v
 	}
 	return union
@@ -170,8 +194,9 @@ func (s *Set) Union(other *Set) *Set {
 // Intersection returns a new set that is the intersection of the two sets.
 func (s *Set) Intersection(other *Set) *Set {
 	intersection := &Set{items: map[HashKey]Object{}}
-	for k, v := range s.items {
-		if _, ok := other.items[k]; ok {
+	otherItems := other.Value()
+	for k, v := range s.Value() {
+		if _, ok := otherItems[k]; ok {
 			intersection.items[k] = v
 		}
 	}
@@ -181,8 +206,9 @@ func (s *Set) Intersection(other *Set) *Set {
 // Difference returns a new set that is the difference of the two sets.
 func (s *Set) Difference(other *Set) *Set {
 	difference := &Set{items: map[HashKey]Object{}}
-	for k, v := range s.items {
-		if _, ok := other.items[k]; !ok {
+	otherItems := other.Value()
+	for k, v := range s.Value() {
+		if _, ok := otherItems[k]; !ok {
 			difference.items[k] = v
 		}
 	}
@@ -190,35 +216,40 @@ func (s *Set) Difference(other *Set) *Set {
 }
 
 func (s *Set) List() *List {
-	l := &List{items: make([]Object, 0, len(s.items))}
-	for _, item := range s.items {
-		l.items = append(l.items, item)
-	}
-	return l
+	return &List{items: s.values()}
 }
 
 func (s *Set) Equals(other Object) Object {
 	if other.Type() != SET {
 		return False
 	}
-	otherSet := other.(*Set)
-	if len(s.items) != len(otherSet.items) {
+	items := s.Value()
+	otherItems := other.(*Set).Value()
+	if len(items) != len(otherItems) {
 		return False
 	}
-	for k, v := range s.items {
-		if otherV, ok := otherSet.items[k]; !ok || !v.Equals(otherV).(*Bool).value {
+	for k, v := range items {
+		if otherV, ok := otherItems[k]; !ok || !v.Equals(otherV).(*Bool).value {
 			return False
 		}
 	}
 	return True
 }
 
+// get returns the item with the given HashKey, if the set contains one.
+func (s *Set) get(key HashKey) (Object, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	item, ok := s.items[key]
+	return item, ok
+}
+
 func (s *Set) GetItem(key Object) (Object, *Error) {
 	hashable, ok := key.(Hashable)
 	if !ok {
 		return nil, Errorf("type error: %s object is unhashable", key.Type())
 	}
-	if _, ok := s.items[hashable.HashKey()]; ok {
+	if _, ok := s.get(hashable.HashKey()); ok {
 		return True, nil
 	}
 	return False, nil
@@ -240,6 +271,8 @@ func (s *Set) DelItem(key Object) *Error {
 	if !ok {
 		return Errorf("type error: %s object is unhashable", key.Type())
 	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	delete(s.items, hashable.HashKey())
 	return nil
 }
@@ -250,17 +283,17 @@ func (s *Set) Contains(key Object) *Bool {
 	if !ok {
 		return False
 	}
-	_, ok = s.items[hashable.HashKey()]
+	_, ok = s.get(hashable.HashKey())
 	return NewBool(ok)
 }
 
 func (s *Set) IsTruthy() bool {
-	return len(s.items) > 0
+	return s.Size() > 0
 }
 
 // Len returns the number of items in this container.
 func (s *Set) Len() *Int {
-	return NewInt(int64(len(s.items)))
+	return NewInt(int64(s.Size()))
 }
 
 func (s *Set) Iter() Iterator {
@@ -71,7 +71,7 @@ func (iter *SetIter) Next() (IteratorEntry, bool) {
 	}
 	key := hashKeys[iter.pos]
 	iter.pos++
-	value, ok := iter.set.items[key]
+	value, ok := iter.set.get(key)
 	if !ok {
 		return nil, false
 	}
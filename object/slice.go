@@ -0,0 +1,140 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (s Slice) Type() Type {
+	return SLICE
+}
+
+func (s Slice) Inspect() string {
+	parts := []string{"", ""}
+	if s.Start != nil {
+		parts[0] = s.Start.Inspect()
+	}
+	if s.Stop != nil {
+		parts[1] = s.Stop.Inspect()
+	}
+	if s.Step != nil {
+		parts = append(parts, s.Step.Inspect())
+	}
+	return strings.Join(parts, ":")
+}
+
+func (s Slice) Interface() interface{} {
+	return s.Inspect()
+}
+
+func (s Slice) Equals(other Object) Object {
+	o, ok := other.(Slice)
+	if !ok {
+		return False
+	}
+	for _, pair := range [][2]Object{{s.Start, o.Start}, {s.Stop, o.Stop}, {s.Step, o.Step}} {
+		if (pair[0] == nil) != (pair[1] == nil) || (pair[0] != nil && !Equals(pair[0], pair[1])) {
+			return False
+		}
+	}
+	return True
+}
+
+func (s Slice) GetAttr(name string) (Object, bool) {
+	var value Object
+	switch name {
+	case "start":
+		value = s.Start
+	case "stop":
+		value = s.Stop
+	case "step":
+		value = s.Step
+	default:
+		return nil, false
+	}
+	if value == nil {
+		return Nil, true
+	}
+	return value, true
+}
+
+func (s Slice) IsTruthy() bool {
+	return true
+}
+
+// ResolveSlice resolves the indices of a slice like ResolveIntSlice, and
+// also returns its step. The step defaults to 1. When it is negative, the
+// slice runs backwards from start, which defaults to the last index, down
+// to but excluding stop, which defaults to just before the first index.
+// A stop of -1 is returned in that case.
+func ResolveSlice(slice Slice, size int64) (start, stop, step int64, err error) {
+	step = 1
+	if slice.Step != nil {
+		stepObj, ok := slice.Step.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice step must be an int (got %s)", slice.Step.Type())
+			return
+		}
+		step = stepObj.value
+		if step == 0 {
+			err = fmt.Errorf("slice error: slice step cannot be zero")
+			return
+		}
+	}
+	if step > 0 {
+		start, stop, err = ResolveIntSlice(slice, size)
+		return
+	}
+	start, stop = size-1, -1
+	if slice.Start != nil {
+		startObj, ok := slice.Start.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice start index must be an int (got %s)", slice.Start.Type())
+			return
+		}
+		if start, err = resolveSliceIndex(startObj.value, size); err != nil {
+			err = fmt.Errorf("slice error: start index is out of range")
+			return
+		}
+	}
+	if slice.Stop != nil {
+		stopObj, ok := slice.Stop.(*Int)
+		if !ok {
+			err = fmt.Errorf("type error: slice stop index must be an int (got %s)", slice.Stop.Type())
+			return
+		}
+		if stop, err = resolveSliceIndex(stopObj.value, size); err != nil {
+			err = fmt.Errorf("slice error: stop index is out of range")
+			return
+		}
+	}
+	if start < stop {
+		err = fmt.Errorf("slice error: start index is less than stop index")
+		return
+	}
+	return start, stop, step, nil
+}
+
+func resolveSliceIndex(idx, size int64) (int64, error) {
+	if idx < 0 {
+		idx += size
+	}
+	if idx < 0 || idx >= size {
+		return 0, fmt.Errorf("index out of range")
+	}
+	return idx, nil
+}
+
+// SliceIndices returns the indices of the items selected by a slice, in
+// order, for a container of the given size.
+func SliceIndices(slice Slice, size int64) ([]int64, error) {
+	start, stop, step, err := ResolveSlice(slice, size)
+	if err != nil {
+		return nil, err
+	}
+	var indices []int64
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		indices = append(indices, i)
+	}
+	return indices, nil
+}
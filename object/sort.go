@@ -32,3 +32,42 @@ func Sort(items []Object) *Error {
 	}
 	return nil
 }
+
+// SortBy sorts a list in place, ordering the items by the key at the same
+// index in keys. If reverse is true, the order is descending. Items with
+// equal keys keep their original order. If a key is not comparable, an
+// error is returned.
+func SortBy(items, keys []Object, reverse bool) *Error {
+	indices := make([]int, len(items))
+	for i := range indices {
+		indices[i] = i
+	}
+	var comparableErr string
+	sort.SliceStable(indices, func(a, b int) bool {
+		keyA := keys[indices[a]]
+		keyB := keys[indices[b]]
+		compA, ok := keyA.(Comparable)
+		if !ok {
+			comparableErr = fmt.Sprintf(
+				"type error: sorted() encountered a non-comparable item (%s)", keyA.Type())
+			return false
+		}
+		result, err := compA.Compare(keyB)
+		if err != nil {
+			comparableErr = err.Error()
+		}
+		if reverse {
+			return result == 1
+		}
+		return result == -1
+	})
+	if comparableErr != "" {
+		return Errorf(comparableErr)
+	}
+	sorted := make([]Object, len(items))
+	for i, index := range indices {
+		sorted[i] = items[index]
+	}
+	copy(items, sorted)
+	return nil
+}
@@ -87,7 +87,7 @@ func (s *String) GetAttr(name string) (Object, bool) {
 				if len(args) != 1 {
 					return NewArgsError("string.join", 1, len(args))
 				}
-				return s.Join(args[0])
+				return s.Join(ctx, args[0])
 			},
 		}, true
 	case "split":
@@ -257,11 +257,22 @@ func (s *String) GetItem(key Object) (Object, *Error) {
 
 func (s *String) GetSlice(slice Slice) (Object, *Error) {
 	runes := []rune(s.value)
-	start, stop, err := ResolveIntSlice(slice, int64(len(runes)))
+	if slice.Step == nil {
+		start, stop, err := ResolveIntSlice(slice, int64(len(runes)))
+		if err != nil {
+			return nil, Errorf(err.Error())
+		}
+		resultRunes := runes[start:stop]
+		return NewString(string(resultRunes)), nil
+	}
+	indices, err := SliceIndices(slice, int64(len(runes)))
 	if err != nil {
 		return nil, Errorf(err.Error())
 	}
-	resultRunes := runes[start:stop]
+	resultRunes := make([]rune, 0, len(indices))
+	for _, idx := range indices {
+		resultRunes = append(resultRunes, runes[idx])
+	}
 	return NewString(string(resultRunes)), nil
 }
 
@@ -305,19 +316,30 @@ func (s *String) Count(obj Object) Object {
 	return NewInt(int64(strings.Count(s.value, substr)))
 }
 
-func (s *String) Join(obj Object) Object {
+// Join joins the strings in a list, separated by this string. The length of
+// the result is checked against the Limiter in the context before it is
+// allocated.
+func (s *String) Join(ctx context.Context, obj Object) Object {
 	ls, err := AsList(obj)
 	if err != nil {
 		return err
 	}
 	var strs []string
+	var size int64
 	for _, item := range ls.Value() {
 		itemStr, err := AsString(item)
 		if err != nil {
 			return err
 		}
+		if len(strs) > 0 {
+			size += int64(len(s.value))
+		}
+		size += int64(len(itemStr))
 		strs = append(strs, itemStr)
 	}
+	if err := GetLimiter(ctx).CheckSize(STRING, size); err != nil {
+		return err
+	}
 	return NewString(strings.Join(strs, s.value))
 }
 
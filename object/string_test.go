@@ -84,3 +84,23 @@ func TestStringGetItem(t *testing.T) {
 		}
 	}
 }
+
+func TestStringSliceStep(t *testing.T) {
+	tests := []struct {
+		slice    Slice
+		expected string
+	}{
+		{Slice{Step: NewInt(-1)}, "fedcba"},
+		{Slice{Start: NewInt(1), Step: NewInt(2)}, "bdf"},
+		{Slice{Start: NewInt(-2), Stop: NewInt(0), Step: NewInt(-2)}, "ec"},
+		{Slice{Stop: NewInt(3), Step: NewInt(1)}, "abc"},
+	}
+	for _, tc := range tests {
+		result, err := NewString("abcdef").GetSlice(tc.slice)
+		require.Nil(t, err, tc.slice.Inspect())
+		require.Equal(t, tc.expected, result.(*String).Value(), tc.slice.Inspect())
+	}
+	result, err := NewString("héllo").GetSlice(Slice{Step: NewInt(-1)})
+	require.Nil(t, err)
+	require.Equal(t, "olléh", result.(*String).Value())
+}
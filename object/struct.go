@@ -0,0 +1,228 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// StructOpts configures a new Struct.
+type StructOpts struct {
+	// Name of the struct type.
+	Name string
+
+	// Fields lists the names of the fields in declaration order.
+	Fields []string
+
+	// Defaults holds the initial value of fields that declare one. Other
+	// fields are initialized to nil.
+	Defaults map[string]Object
+
+	// Methods holds the methods of the struct by name. Each method receives
+	// the instance it is called on as its first argument.
+	Methods map[string]Object
+}
+
+// Struct is a user-defined type declared with a struct statement. Calling
+// a Struct constructs a new Instance of it.
+type Struct struct {
+	name     string
+	fields   []string
+	defaults map[string]Object
+	methods  map[string]Object
+}
+
+func (s *Struct) Type() Type {
+	return STRUCT
+}
+
+func (s *Struct) Name() string {
+	return s.name
+}
+
+func (s *Struct) Fields() []string {
+	return s.fields
+}
+
+func (s *Struct) Inspect() string {
+	return fmt.Sprintf("struct(%s)", s.name)
+}
+
+// GetAttr returns the named method of the struct. The method is not bound
+// to an instance, so the instance must be passed as the first argument.
+func (s *Struct) GetAttr(name string) (Object, bool) {
+	method, ok := s.methods[name]
+	return method, ok
+}
+
+func (s *Struct) Interface() interface{} {
+	return s.name
+}
+
+func (s *Struct) Equals(other Object) Object {
+	if o, ok := other.(*Struct); ok && s == o {
+		return True
+	}
+	return False
+}
+
+func (s *Struct) IsTruthy() bool {
+	return true
+}
+
+// Call constructs a new instance of the struct. If the struct has an init
+// method, it is called with the new instance followed by the given
+// arguments. Otherwise the arguments are assigned to the fields in order.
+func (s *Struct) Call(ctx context.Context, args ...Object) Object {
+	return s.CallWithKwargs(ctx, NewMap(nil), args...)
+}
+
+// CallWithKwargs constructs a new instance of the struct like Call. The
+// keyword arguments are passed on to the init method if there is one, and
+// otherwise are assigned to the fields of the same name.
+func (s *Struct) CallWithKwargs(ctx context.Context, kwargs *Map, args ...Object) Object {
+	instance := &Instance{typ: s, fields: make(map[string]Object, len(s.fields))}
+	for _, field := range s.fields {
+		if value, ok := s.defaults[field]; ok {
+			instance.fields[field] = value
+		} else {
+			instance.fields[field] = Nil
+		}
+	}
+	if init, ok := s.methods["init"]; ok {
+		result := callFunction(ctx, s.name, init, append([]Object{instance}, args...), kwargs)
+		if IsError(result) {
+			return result
+		}
+		return instance
+	}
+	if len(args) > len(s.fields) {
+		return Errorf("type error: %s() takes at most %d arguments (%d given)",
+			s.name, len(s.fields), len(args))
+	}
+	values, err := BindArguments(s.name, s.fields, args, kwargs)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		if value != nil {
+			instance.fields[s.fields[i]] = value
+		}
+	}
+	return instance
+}
+
+func NewStruct(opts StructOpts) *Struct {
+	s := &Struct{
+		name:     opts.Name,
+		fields:   opts.Fields,
+		defaults: opts.Defaults,
+		methods:  opts.Methods,
+	}
+	if s.methods == nil {
+		s.methods = map[string]Object{}
+	}
+	return s
+}
+
+// Instance is a value of a user-defined Struct type. Its type name is the
+// name of the struct. An instance may be shared by goroutines, so its fields
+// are guarded by a mutex.
+type Instance struct {
+	mutex  sync.RWMutex
+	typ    *Struct
+	fields map[string]Object
+}
+
+func (i *Instance) Type() Type {
+	return Type(i.typ.name)
+}
+
+func (i *Instance) Struct() *Struct {
+	return i.typ
+}
+
+// values returns a copy of the field values of the instance.
+func (i *Instance) values() map[string]Object {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	values := make(map[string]Object, len(i.fields))
+	for name, value := range i.fields {
+		values[name] = value
+	}
+	return values
+}
+
+func (i *Instance) Inspect() string {
+	var out bytes.Buffer
+	values := i.values()
+	fields := make([]string, 0, len(i.typ.fields))
+	for _, name := range i.typ.fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", name, values[name].Inspect()))
+	}
+	out.WriteString(i.typ.name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// GetAttr returns the value of the named field, or the named method bound
+// to this instance.
+func (i *Instance) GetAttr(name string) (Object, bool) {
+	i.mutex.RLock()
+	value, ok := i.fields[name]
+	i.mutex.RUnlock()
+	if ok {
+		return value, true
+	}
+	method, ok := i.typ.methods[name]
+	if !ok {
+		return nil, false
+	}
+	key := fmt.Sprintf("%s.%s", i.typ.name, name)
+	return NewKwargsBuiltin(key, func(ctx context.Context, kwargs *Map, args ...Object) Object {
+		return callFunction(ctx, key, method, append([]Object{i}, args...), kwargs)
+	}), true
+}
+
+// SetAttr sets the value of the named field. Only fields declared by the
+// struct may be set.
+func (i *Instance) SetAttr(name string, value Object) *Error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if _, ok := i.fields[name]; !ok {
+		return Errorf("attribute error: %s has no field \"%s\"", i.typ.name, name)
+	}
+	i.fields[name] = value
+	return nil
+}
+
+func (i *Instance) Interface() interface{} {
+	values := i.values()
+	result := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		result[name] = value.Interface()
+	}
+	return result
+}
+
+func (i *Instance) Equals(other Object) Object {
+	o, ok := other.(*Instance)
+	if !ok || i.typ != o.typ {
+		return False
+	}
+	otherValues := o.values()
+	for name, value := range i.values() {
+		if value.Equals(otherValues[name]) != True {
+			return False
+		}
+	}
+	return True
+}
+
+func (i *Instance) IsTruthy() bool {
+	return true
+}
@@ -0,0 +1,72 @@
+package object
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructConstruct(t *testing.T) {
+	ctx := context.Background()
+	point := NewStruct(StructOpts{
+		Name:     "Point",
+		Fields:   []string{"x", "y"},
+		Defaults: map[string]Object{"y": NewInt(0)},
+	})
+	require.Equal(t, STRUCT, point.Type())
+	require.Equal(t, "struct(Point)", point.Inspect())
+
+	p, ok := point.Call(ctx, NewInt(1)).(*Instance)
+	require.True(t, ok)
+	require.Equal(t, Type("Point"), p.Type())
+	require.Equal(t, "Point{x: 1, y: 0}", p.Inspect())
+	require.Equal(t, True, p.Equals(point.Call(ctx, NewInt(1), NewInt(0))))
+	require.Equal(t, False, p.Equals(point.Call(ctx, NewInt(2))))
+
+	require.Nil(t, p.SetAttr("y", NewInt(5)))
+	value, found := p.GetAttr("y")
+	require.True(t, found)
+	require.Equal(t, NewInt(5), value)
+	require.Equal(t, Errorf("attribute error: Point has no field \"z\""), p.SetAttr("z", Nil))
+
+	require.Equal(t, Errorf("type error: Point() takes at most 2 arguments (3 given)"),
+		point.Call(ctx, Nil, Nil, Nil))
+}
+
+func TestStructKwargs(t *testing.T) {
+	ctx := context.Background()
+	point := NewStruct(StructOpts{
+		Name:     "Point",
+		Fields:   []string{"x", "y"},
+		Defaults: map[string]Object{"y": NewInt(0)},
+	})
+	kwargs := NewMap(map[string]Object{"x": NewInt(2)})
+	require.Equal(t, "Point{x: 2, y: 0}", point.CallWithKwargs(ctx, kwargs).Inspect())
+	kwargs = NewMap(map[string]Object{"y": NewInt(3)})
+	require.Equal(t, "Point{x: 1, y: 3}", point.CallWithKwargs(ctx, kwargs, NewInt(1)).Inspect())
+	require.Equal(t, Errorf("type error: Point() got multiple values for argument \"x\""),
+		point.CallWithKwargs(ctx, NewMap(map[string]Object{"x": Nil}), NewInt(1)))
+	require.Equal(t, Errorf("type error: Point() got an unexpected keyword argument \"z\""),
+		point.CallWithKwargs(ctx, NewMap(map[string]Object{"z": Nil})))
+}
+
+func TestStructMethod(t *testing.T) {
+	var receiver Object
+	method := NewBuiltin("get", func(ctx context.Context, args ...Object) Object {
+		receiver = args[0]
+		return args[1]
+	})
+	point := NewStruct(StructOpts{
+		Name:    "Point",
+		Methods: map[string]Object{"get": method},
+	})
+	ctx := WithCallFunc(context.Background(), func(ctx context.Context, s interface{}, fn Object, args []Object) Object {
+		return fn.(*Builtin).Call(ctx, args...)
+	})
+	p := point.Call(ctx).(*Instance)
+	bound, found := p.GetAttr("get")
+	require.True(t, found)
+	require.Equal(t, NewInt(3), bound.(*Builtin).Call(ctx, NewInt(3)))
+	require.Equal(t, p, receiver)
+}
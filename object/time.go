@@ -18,6 +18,10 @@ func (t *Time) Value() time.Time {
 	return t.value
 }
 
+func (t *Time) HashKey() HashKey {
+	return HashKey{Type: t.Type(), IntValue: t.value.UnixNano(), StrValue: t.value.Location().String()}
+}
+
 func (t *Time) Inspect() string {
 	return t.value.Format(time.RFC3339)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"time"
 
@@ -23,6 +24,14 @@ func AsString(obj Object) (result string, err *Error) {
 	return s.value, nil
 }
 
+func AsBytes(obj Object) ([]byte, *Error) {
+	b, ok := obj.(*Bytes)
+	if !ok {
+		return nil, Errorf("type error: expected bytes (got %v)", obj.Type())
+	}
+	return b.value, nil
+}
+
 func AsInt(obj Object) (int64, *Error) {
 	i, ok := obj.(*Int)
 	if !ok {
@@ -35,6 +44,9 @@ func AsFloat(obj Object) (float64, *Error) {
 	switch obj := obj.(type) {
 	case *Int:
 		return float64(obj.value), nil
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.value).Float64()
+		return f, nil
 	case *Float:
 		return obj.value, nil
 	default:
@@ -88,12 +100,21 @@ func FromGoType(obj interface{}) Object {
 		return NewInt(int64(obj))
 	case int64:
 		return NewInt(obj)
+	case *big.Int:
+		return NewBigInt(new(big.Int).Set(obj))
+	case json.Number:
+		if d, ok := ParseDecimal(string(obj)); ok {
+			return d
+		}
+		return Errorf("value error: invalid number: %s", obj)
 	case float32:
 		return NewFloat(float64(obj))
 	case float64:
 		return NewFloat(obj)
 	case string:
 		return NewString(obj)
+	case []byte:
+		return NewBytes(obj)
 	case bool:
 		if obj {
 			return True
@@ -152,6 +173,7 @@ var (
 	intType         = reflect.TypeOf(int(0))
 	int64Type       = reflect.TypeOf(int64(0))
 	stringType      = reflect.TypeOf("")
+	bytesType       = reflect.TypeOf([]byte(nil))
 	float32Type     = reflect.TypeOf(float32(0))
 	float64Type     = reflect.TypeOf(float64(0))
 	booleanType     = reflect.TypeOf(false)
@@ -218,6 +240,25 @@ func (c *StringConverter) Type() reflect.Type {
 	return stringType
 }
 
+// BytesConverter converts between []byte and Bytes.
+type BytesConverter struct{}
+
+func (c *BytesConverter) To(obj Object) (interface{}, error) {
+	b, ok := obj.(*Bytes)
+	if !ok {
+		return nil, fmt.Errorf("type error: expected bytes (got %v)", obj.Type())
+	}
+	return append([]byte(nil), b.value...), nil
+}
+
+func (c *BytesConverter) From(obj interface{}) (Object, error) {
+	return NewBytes(obj.([]byte)), nil
+}
+
+func (c *BytesConverter) Type() reflect.Type {
+	return bytesType
+}
+
 // Float64Converter converts between float64 and Float.
 type Float64Converter struct{}
 
@@ -302,16 +343,21 @@ func (c *MapStringIfaceConverter) To(obj Object) (interface{}, error) {
 	if !ok {
 		return nil, fmt.Errorf("type error: expected a map (got %v)", obj.Type())
 	}
+	for _, key := range mapObj.SortedKeys() {
+		if key.Type() != STRING {
+			return nil, fmt.Errorf("type error: expected map keys to be strings (got %v)", key.Type())
+		}
+	}
 	return mapObj.Interface(), nil
 }
 
 func (c *MapStringIfaceConverter) From(obj interface{}) (Object, error) {
 	m := obj.(map[string]interface{})
-	objMap := make(map[string]Object, len(m))
+	mapObj := NewMapWithSize(len(m))
 	for k, v := range m {
-		objMap[k] = FromGoType(v)
+		mapObj.Set(k, FromGoType(v))
 	}
-	return NewMap(objMap), nil
+	return mapObj, nil
 }
 
 func (c *MapStringIfaceConverter) Type() reflect.Type {
@@ -325,11 +371,11 @@ type StructConverter struct {
 }
 
 func (c *StructConverter) To(obj Object) (interface{}, error) {
-	m, ok := obj.(*Map)
-	if !ok {
-		return nil, fmt.Errorf("type error: expected a map (got %v)", obj.Type())
+	m, err := (&MapStringIfaceConverter{}).To(obj)
+	if err != nil {
+		return nil, err
 	}
-	jsonBytes, err := json.Marshal(m.Interface())
+	jsonBytes, err := json.Marshal(m)
 	if err != nil {
 		return nil, err
 	}
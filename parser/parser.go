@@ -7,7 +7,9 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -81,24 +83,28 @@ type Parser struct {
 	//
 	// Nested ternary expressions are illegal :)
 	tern bool
+
+	// generators has an entry for each function being parsed, which is set
+	// to true once a yield statement is found in its body.
+	generators []bool
 }
 
 // New returns a Parser for the program provided by the lexer.
 func New(l *lexer.Lexer) *Parser {
 
-	// Create the parser and prime the token pump
+	// Create the parser. The token pump is primed once the parse functions
+	// are registered, since they are used to look ahead after a "?"
 	p := &Parser{
 		l:               l,
 		prefixParseFns:  map[token.Type]prefixParseFn{},
 		infixParseFns:   map[token.Type]infixParseFn{},
 		postfixParseFns: map[token.Type]postfixParseFn{},
 	}
-	p.nextToken() // makes curToken=<empty>, peekToken=token[0]
-	p.nextToken() // makes curToken=token[0], peekToken=token[1]
 
 	// Register prefix-functions
 	p.registerPrefix(token.BACKTICK, p.parseString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpr)
+	p.registerPrefix(token.CARET, p.parsePrefixExpr)
 	p.registerPrefix(token.EOF, p.illegalToken)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.FLOAT, p.parseFloat)
@@ -113,27 +119,39 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACE, p.parseMapOrSet)
 	p.registerPrefix(token.LBRACKET, p.parseList)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpr)
+	p.registerPrefix(token.MATCH, p.parseMatch)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpr)
 	p.registerPrefix(token.NEWLINE, p.parseNewline)
 	p.registerPrefix(token.NIL, p.parseNil)
 	p.registerPrefix(token.PIPE, p.parsePrefixExpr)
 	p.registerPrefix(token.RANGE, p.parseRange)
+	p.registerPrefix(token.SELECT, p.parseSelect)
 	p.registerPrefix(token.STRING, p.parseString)
 	p.registerPrefix(token.SWITCH, p.parseSwitch)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 
 	// Register infix functions
+	p.registerInfix(token.AMPERSAND_EQUALS, p.parseAssign)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpr)
+	p.registerInfix(token.AND_NOT_EQUALS, p.parseAssign)
+	p.registerInfix(token.AND_NOT, p.parseInfixExpr)
 	p.registerInfix(token.AND, p.parseInfixExpr)
 	p.registerInfix(token.ASSIGN, p.parseAssign)
 	p.registerInfix(token.ASTERISK_EQUALS, p.parseAssign)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpr)
+	p.registerInfix(token.CARET_EQUALS, p.parseAssign)
+	p.registerInfix(token.CARET, p.parseInfixExpr)
 	p.registerInfix(token.EQ, p.parseInfixExpr)
 	p.registerInfix(token.GT_EQUALS, p.parseInfixExpr)
 	p.registerInfix(token.GT, p.parseInfixExpr)
+	p.registerInfix(token.GT_GT_EQUALS, p.parseAssign)
+	p.registerInfix(token.GT_GT, p.parseInfixExpr)
 	p.registerInfix(token.LBRACKET, p.parseIndex)
 	p.registerInfix(token.LPAREN, p.parseCall)
 	p.registerInfix(token.LT_EQUALS, p.parseInfixExpr)
 	p.registerInfix(token.LT, p.parseInfixExpr)
+	p.registerInfix(token.LT_LT_EQUALS, p.parseAssign)
+	p.registerInfix(token.LT_LT, p.parseInfixExpr)
 	p.registerInfix(token.MINUS_EQUALS, p.parseAssign)
 	p.registerInfix(token.MINUS, p.parseInfixExpr)
 	p.registerInfix(token.MOD, p.parseInfixExpr)
@@ -141,9 +159,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.OR, p.parseInfixExpr)
 	p.registerInfix(token.PERIOD, p.parseGetAttr)
 	p.registerInfix(token.PIPE, p.parsePipe)
+	p.registerInfix(token.PIPE_EQUALS, p.parseAssign)
 	p.registerInfix(token.PLUS_EQUALS, p.parseAssign)
 	p.registerInfix(token.PLUS, p.parseInfixExpr)
 	p.registerInfix(token.POW, p.parseInfixExpr)
+	p.registerInfix(token.PROPAGATE, p.parsePropagate)
 	p.registerInfix(token.QUESTION, p.parseTernary)
 	p.registerInfix(token.SLASH_EQUALS, p.parseAssign)
 	p.registerInfix(token.SLASH, p.parseInfixExpr)
@@ -152,6 +172,9 @@ func New(l *lexer.Lexer) *Parser {
 	// Register postfix functions
 	p.registerPostfix(token.MINUS_MINUS, p.parsePostfixExpr)
 	p.registerPostfix(token.PLUS_PLUS, p.parsePostfixExpr)
+
+	p.nextToken() // makes curToken=<empty>, peekToken=token[0]
+	p.nextToken() // makes curToken=token[0], peekToken=token[1]
 	return p
 }
 
@@ -162,6 +185,105 @@ func (p *Parser) nextToken() {
 	p.nextTokenWithError()
 }
 
+// parserState is a snapshot of the parser and lexer positions, used to
+// backtrack after looking ahead.
+type parserState struct {
+	lexer      lexer.Lexer
+	prevToken  token.Token
+	curToken   token.Token
+	peekToken  token.Token
+	err        ParserError
+	tern       bool
+	generators []bool
+}
+
+func (p *Parser) save() parserState {
+	return parserState{
+		lexer:      *p.l,
+		prevToken:  p.prevToken,
+		curToken:   p.curToken,
+		peekToken:  p.peekToken,
+		err:        p.err,
+		tern:       p.tern,
+		generators: append([]bool(nil), p.generators...),
+	}
+}
+
+func (p *Parser) restore(state parserState) {
+	*p.l = state.lexer
+	p.prevToken = state.prevToken
+	p.curToken = state.curToken
+	p.peekToken = state.peekToken
+	p.err = state.err
+	p.tern = state.tern
+	p.generators = state.generators
+}
+
+// isTernary returns true if the "?" that is the peek token starts a ternary
+// expression rather than being the postfix error propagation operator, as
+// in "x? - 1". A "?" followed by a token that can only start an operand, as
+// in "x ? 1 : 2" or "x ?if", is a ternary, while one followed by a token that
+// can't start an expression, such as another "?", is not. Otherwise it is a
+// ternary when a ":" follows at the same nesting level before the end of the
+// expression, unless a later "?" starts the ternary that the ":" belongs to.
+// Since ternary expressions can't be nested, a "?" within one is always the
+// propagation operator.
+func (p *Parser) isTernary() bool {
+	if p.tern {
+		return false
+	}
+	l := *p.l // look ahead using a copy of the lexer
+	tok, err := l.NextToken()
+	if err != nil || !p.startsExpression(tok.Type) {
+		return false
+	}
+	if p.infixParseFns[tok.Type] == nil && tok.Type != token.LBRACE {
+		return true
+	}
+	depth := 0
+	for {
+		switch tok.Type {
+		case token.COLON:
+			if depth == 0 {
+				return true
+			}
+		case token.QUESTION:
+			if depth == 0 {
+				next := l
+				if tok, err := next.NextToken(); err == nil && p.startsExpression(tok.Type) {
+					return false
+				}
+			}
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.NEWLINE, token.SEMICOLON, token.COMMA:
+			if depth == 0 {
+				return false
+			}
+		case token.EOF:
+			return false
+		}
+		if tok, err = l.NextToken(); err != nil {
+			return false
+		}
+	}
+}
+
+// startsExpression returns true if an expression may begin with a token of
+// the given type.
+func (p *Parser) startsExpression(t token.Type) bool {
+	switch t {
+	case token.EOF, token.NEWLINE, token.ILLEGAL:
+		return false
+	}
+	return p.prefixParseFns[t] != nil
+}
+
 // nextToken moves to the next token from the lexer, updating all of
 // prevToken, curToken, and peekToken.
 func (p *Parser) nextTokenWithError() error {
@@ -174,6 +296,9 @@ func (p *Parser) nextTokenWithError() error {
 	p.curToken = p.peekToken
 	p.peekToken, err = p.l.NextToken()
 	if err == nil {
+		if p.peekToken.Type == token.QUESTION && !p.isTernary() {
+			p.peekToken.Type = token.PROPAGATE
+		}
 		return nil // success
 	}
 	// The lexer encountered an error. We consider all lexer errors
@@ -282,13 +407,28 @@ func (p *Parser) parseStatement() ast.Node {
 		return p.parseBreak()
 	case token.CONTINUE:
 		return p.parseContinue()
+	case token.GO:
+		return p.parseGo()
+	case token.DEFER:
+		return p.parseDefer()
+	case token.YIELD:
+		return p.parseYield()
+	case token.STRUCT:
+		return p.parseStruct()
 	case token.NEWLINE:
 		return nil
 	case token.IDENT:
 		if p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.COMMA) {
 			return p.parseDeclaration()
 		}
+		if p.peekTokenIs(token.COLON) {
+			return p.parseAnnotatedDeclaration()
+		}
 		// intentional fallthrough!
+	case token.LBRACKET, token.LBRACE:
+		if stmt := p.parseBracketDestructure(); stmt != nil {
+			return stmt
+		}
 	}
 	return p.parseExpressionStatement()
 }
@@ -306,6 +446,13 @@ func (p *Parser) parseVar() ast.Node {
 		}
 		idents = append(idents, ast.NewIdent(p.curToken))
 	}
+	var annotation *ast.Ident
+	if len(idents) == 1 && p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if annotation = p.parseTypeAnnotation("var statement"); annotation == nil {
+			return nil
+		}
+	}
 	if !p.expectPeek("var statement", token.ASSIGN) {
 		return nil
 	}
@@ -317,18 +464,18 @@ func (p *Parser) parseVar() ast.Node {
 	if len(idents) > 1 {
 		return ast.NewMultiVar(tok, idents, value, false)
 	}
-	return ast.NewVar(tok, idents[0], value)
+	return ast.NewVar(tok, idents[0], value, annotation)
 }
 
-func (p *Parser) parseDeclaration() ast.Node {
+// parseAnnotatedDeclaration parses a declaration with a type annotation,
+// as in "x: int := 1".
+func (p *Parser) parseAnnotatedDeclaration() ast.Node {
 	tok := p.curToken
-	idents := []*ast.Ident{ast.NewIdent(p.curToken)}
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		if !p.expectPeek("declaration statement", token.IDENT) {
-			return nil
-		}
-		idents = append(idents, ast.NewIdent(p.curToken))
+	ident := ast.NewIdent(p.curToken)
+	p.nextToken() // move to the ":"
+	annotation := p.parseTypeAnnotation("declaration statement")
+	if annotation == nil {
+		return nil
 	}
 	if !p.expectPeek("declaration statement", token.DECLARE) {
 		return nil
@@ -338,10 +485,193 @@ func (p *Parser) parseDeclaration() ast.Node {
 	if value == nil {
 		return nil
 	}
-	if len(idents) > 1 {
-		return ast.NewMultiVar(tok, idents, value, true)
+	return ast.NewDeclaration(tok, ident, value, annotation)
+}
+
+// parseTypeAnnotation parses the type name that follows the current ":" or
+// "->" token. Type names are identifiers, or nil.
+func (p *Parser) parseTypeAnnotation(context string) *ast.Ident {
+	if p.peekTokenIs(token.NIL) {
+		p.nextToken()
+	} else if !p.expectPeek(context, token.IDENT) {
+		return nil
+	}
+	return ast.NewIdent(p.curToken)
+}
+
+func (p *Parser) parseDeclaration() ast.Node {
+	tok := p.curToken
+	targets, rest := p.parseTargetList()
+	if targets == nil {
+		return nil
 	}
-	return ast.NewDeclaration(tok, idents[0], value)
+	return p.parseDestructure(tok, targets, rest)
+}
+
+// parseDestructure parses the ":=" or "=" and the value that follow the
+// targets of an assignment. Declarations of plain variable names result in
+// a Var or MultiVar node, while anything else is a Destructure.
+func (p *Parser) parseDestructure(tok token.Token, targets []ast.Pattern, rest ast.Pattern) ast.Node {
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+	} else if !p.expectPeek("declaration statement", token.DECLARE) {
+		return nil
+	}
+	isWalrus := p.curTokenIs(token.DECLARE)
+	p.nextToken()
+	value := p.parseAssignmentValue()
+	if value == nil {
+		return nil
+	}
+	if idents, ok := targetNames(targets); ok && rest == nil && isWalrus {
+		if len(idents) > 1 {
+			return ast.NewMultiVar(tok, idents, value, true)
+		}
+		return ast.NewDeclaration(tok, idents[0], value, nil)
+	}
+	node := ast.NewDestructure(tok, targets, rest, value, isWalrus)
+	if !p.checkBindings(tok, []ast.Pattern{node.Target()}) {
+		return nil
+	}
+	return node
+}
+
+// targetNames returns the variable names if all the targets are plain
+// names, including "_".
+func targetNames(targets []ast.Pattern) ([]*ast.Ident, bool) {
+	idents := make([]*ast.Ident, 0, len(targets))
+	for _, target := range targets {
+		switch target := target.(type) {
+		case *ast.BindingPattern:
+			idents = append(idents, target.Name())
+		case *ast.WildcardPattern:
+			idents = append(idents, ast.NewIdent(target.Token()))
+		default:
+			return nil, false
+		}
+	}
+	return idents, true
+}
+
+// parseBracketDestructure parses a statement that starts with "[" or "{" as
+// a destructuring assignment, such as "[a, b] := x". If the statement turns
+// out to be something else, like a list literal, the parser backtracks and
+// nil is returned.
+func (p *Parser) parseBracketDestructure() ast.Node {
+	state := p.save()
+	tok := p.curToken
+	targets, rest := p.parseTargetList()
+	if targets == nil || p.err != nil || !(p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.ASSIGN)) {
+		p.restore(state)
+		return nil
+	}
+	return p.parseDestructure(tok, targets, rest)
+}
+
+// parseTargetList parses comma separated assignment targets, optionally
+// ending with a "...rest" target.
+func (p *Parser) parseTargetList() ([]ast.Pattern, ast.Pattern) {
+	var targets []ast.Pattern
+	for {
+		target := p.parseTarget()
+		if target == nil {
+			return nil, nil
+		}
+		targets = append(targets, target)
+		if !p.peekTokenIs(token.COMMA) {
+			return targets, nil
+		}
+		p.nextToken() // move to the comma
+		p.nextToken() // move to the following target
+		if p.curTokenIs(token.ELLIPSIS) {
+			rest := p.parseRestPattern()
+			if rest == nil {
+				return nil, nil
+			}
+			return targets, rest
+		}
+	}
+}
+
+// parseTarget parses the target of an assignment, which is a variable name
+// or a list or map of targets that the value is destructured into.
+func (p *Parser) parseTarget() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return ast.NewWildcardPattern(p.curToken)
+		}
+		return ast.NewBindingPattern(p.curToken, ast.NewIdent(p.curToken))
+	case token.LBRACKET:
+		listToken := p.curToken
+		var elements []ast.Pattern
+		rest, ok := p.parsePatternItems(token.RBRACKET, func() bool {
+			element := p.parseTarget()
+			if element == nil {
+				return false
+			}
+			elements = append(elements, element)
+			return true
+		})
+		if !ok {
+			return nil
+		}
+		return ast.NewListPattern(listToken, elements, rest)
+	case token.LBRACE:
+		return p.parseMapTarget()
+	}
+	p.setTokenError(p.curToken, "invalid assignment target (got %s)", p.curToken.Literal)
+	return nil
+}
+
+// parseMapTarget parses a map of targets like "{name, age: years = 0}". A key
+// given without a target is assigned to a variable of the same name.
+func (p *Parser) parseMapTarget() ast.Pattern {
+	mapToken := p.curToken
+	var keys []ast.Expression
+	var values []ast.Pattern
+	rest, ok := p.parsePatternItems(token.RBRACE, func() bool {
+		keyToken := p.curToken
+		var value ast.Pattern
+		if keyToken.Type == token.IDENT {
+			value = ast.NewBindingPattern(keyToken, ast.NewIdent(keyToken))
+		}
+		key := p.parseMapPatternKey("map target")
+		if key == nil {
+			return false
+		}
+		if value == nil || p.peekTokenIs(token.COLON) {
+			if !p.expectPeek("map target", token.COLON) {
+				return false
+			}
+			p.nextToken() // move to the target
+			p.eatNewlines()
+			if value = p.parseTarget(); value == nil {
+				return false
+			}
+		}
+		if p.peekTokenIs(token.ASSIGN) {
+			binding, ok := value.(*ast.BindingPattern)
+			if !ok {
+				p.setTokenError(p.peekToken, "default values may only be given for variables")
+				return false
+			}
+			p.nextToken() // move to the "="
+			p.nextToken() // move to the default value
+			defaultValue := p.parseExpression(LOWEST)
+			if defaultValue == nil {
+				return false
+			}
+			value = ast.NewDefaultBindingPattern(binding.Token(), binding.Name(), defaultValue)
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	if !ok {
+		return nil
+	}
+	return ast.NewMapPattern(mapToken, keys, values, rest)
 }
 
 func (p *Parser) parseConst() *ast.Const {
@@ -350,6 +680,13 @@ func (p *Parser) parseConst() *ast.Const {
 		return nil
 	}
 	ident := ast.NewIdent(p.curToken)
+	var annotation *ast.Ident
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if annotation = p.parseTypeAnnotation("const statement"); annotation == nil {
+			return nil
+		}
+	}
 	if !p.expectPeek("const statement", token.ASSIGN) {
 		return nil
 	}
@@ -358,7 +695,7 @@ func (p *Parser) parseConst() *ast.Const {
 	if value == nil {
 		return nil
 	}
-	return ast.NewConst(tok, ident, value)
+	return ast.NewConst(tok, ident, value, annotation)
 }
 
 // Parses the right hand side of an assignment statement.
@@ -515,14 +852,12 @@ func (p *Parser) parseIdent() ast.Expression {
 
 func (p *Parser) parseInt() ast.Expression {
 	tok, lit := p.curToken, p.curToken.Literal
-	var value int64
-	var err error
-	if strings.HasPrefix(lit, "0b") {
-		value, err = strconv.ParseInt(lit[2:], 2, 64)
-	} else if strings.HasPrefix(lit, "0x") {
-		value, err = strconv.ParseInt(lit[2:], 16, 64)
-	} else {
-		value, err = strconv.ParseInt(lit, 10, 64)
+	value, err := parseIntLiteral(lit)
+	if errors.Is(err, strconv.ErrRange) {
+		// Literals too large for an int64 become bigints
+		if value, ok := parseBigIntLiteral(lit); ok {
+			return ast.NewBigInt(tok, value)
+		}
 	}
 	if err != nil {
 		p.setError(NewParserError(ErrorOpts{
@@ -538,6 +873,28 @@ func (p *Parser) parseInt() ast.Expression {
 	return ast.NewInt(tok, value)
 }
 
+// parseIntLiteral converts an integer literal to its value. Literals with a
+// 0x, 0o or 0b prefix use that base, while all others are decimal, including
+// those with leading zeros. Underscores may separate digits.
+func parseIntLiteral(lit string) (int64, error) {
+	if len(lit) > 1 && lit[0] == '0' && strings.ContainsRune("xXoObB", rune(lit[1])) {
+		return strconv.ParseInt(lit, 0, 64)
+	}
+	if strings.HasSuffix(lit, "_") || strings.Contains(lit, "__") {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(strings.ReplaceAll(lit, "_", ""), 10, 64)
+}
+
+// parseBigIntLiteral converts an integer literal that is out of range for an
+// int64, following the same rules as parseIntLiteral.
+func parseBigIntLiteral(lit string) (*big.Int, bool) {
+	if len(lit) > 1 && lit[0] == '0' && strings.ContainsRune("xXoObB", rune(lit[1])) {
+		return new(big.Int).SetString(lit, 0)
+	}
+	return new(big.Int).SetString(strings.ReplaceAll(lit, "_", ""), 10)
+}
+
 func (p *Parser) parseFloat() ast.Expression {
 	tok, lit := p.curToken, p.curToken.Literal
 	value, err := strconv.ParseFloat(lit, 64)
@@ -567,68 +924,517 @@ func (p *Parser) parseSwitch() ast.Expression {
 	}
 	p.nextToken()
 	p.eatNewlines()
-	// Process the switch case statements
-	var cases []*ast.Case
+	// Process the switch case statements
+	var cases []*ast.Case
+	var defaultCaseCount int
+	// Each time through this loop we process one case statement
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.setTokenError(p.prevToken, "unterminated switch statement")
+			return nil
+		}
+		if p.curToken.Literal != "case" && p.curToken.Literal != "default" {
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		caseToken := p.curToken
+		var isDefaultCase bool
+		var caseExprs []ast.Expression
+		if p.curTokenIs(token.DEFAULT) {
+			isDefaultCase = true
+		} else if p.curTokenIs(token.CASE) {
+			p.nextToken() // move to the token following "case"
+			caseExprs = append(caseExprs, p.parseExpression(LOWEST))
+			for p.peekTokenIs(token.COMMA) {
+				p.nextToken() // move to the comma
+				p.nextToken() // move to the following expression
+				caseExprs = append(caseExprs, p.parseExpression(LOWEST))
+			}
+		} else {
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek("switch statement", token.COLON) {
+			return nil
+		}
+		// Now we are at the block of code to be executed for this case
+		block := p.parseCaseBlock()
+		if block == nil {
+			return nil
+		}
+		if isDefaultCase {
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+				return nil
+			}
+			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewCase(caseToken, caseExprs, block))
+		}
+	}
+	return ast.NewSwitch(switchToken, switchValue, cases)
+}
+
+// parseCaseBlock parses the statements following the colon of a case or
+// default label, stopping at the next label or the closing brace.
+func (p *Parser) parseCaseBlock() *ast.Block {
+	p.nextToken()
+	p.eatNewlines()
+	blockFirstToken := p.curToken
+	var blockStatements []ast.Node
+	for {
+		stmt := p.parseStatement()
+		if stmt == nil {
+			return nil
+		}
+		blockStatements = append(blockStatements, stmt)
+		// Move past the end of the statement, which may be followed by the
+		// next label or the closing brace on the same line
+		if !p.curTokenIs(token.NEWLINE) && !p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
+			break
+		}
+	}
+	return ast.NewBlock(blockFirstToken, blockStatements)
+}
+
+func (p *Parser) parseMatch() ast.Expression {
+	matchToken := p.curToken
+	p.nextToken()
+	matchValue := p.parseExpression(LOWEST)
+	if matchValue == nil {
+		return nil
+	}
+	if !p.expectPeek("match expression", token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	p.eatNewlines()
+	var cases []*ast.MatchCase
+	var defaultCaseCount int
+	// Each time through this loop we process one case
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.setTokenError(p.prevToken, "unterminated match expression")
+			return nil
+		}
+		caseToken := p.curToken
+		var patterns []ast.Pattern
+		var guard ast.Expression
+		switch caseToken.Type {
+		case token.DEFAULT:
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "match expression has multiple default blocks")
+				return nil
+			}
+		case token.CASE:
+			p.nextToken() // move to the token following "case"
+			for {
+				pattern := p.parsePattern()
+				if pattern == nil {
+					return nil
+				}
+				patterns = append(patterns, pattern)
+				if !p.peekTokenIs(token.COMMA) {
+					break
+				}
+				p.nextToken() // move to the comma
+				p.nextToken() // move to the following pattern
+			}
+			if !p.checkBindings(caseToken, patterns) {
+				return nil
+			}
+			if p.peekTokenIs(token.IF) {
+				p.nextToken() // move to the "if"
+				p.nextToken() // move to the guard condition
+				if guard = p.parseExpression(LOWEST); guard == nil {
+					return nil
+				}
+			}
+		default:
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek("match expression", token.COLON) {
+			return nil
+		}
+		// Now we are at the block of code to be executed for this case
+		block := p.parseCaseBlock()
+		if block == nil {
+			return nil
+		}
+		if caseToken.Type == token.DEFAULT {
+			cases = append(cases, ast.NewDefaultMatchCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewMatchCase(caseToken, patterns, guard, block))
+		}
+	}
+	return ast.NewMatch(matchToken, matchValue, cases)
+}
+
+// checkBindings validates the variables bound by the patterns of a match
+// case. A variable may only be bound once, and alternative patterns may not
+// bind variables at all since only one of them will match.
+func (p *Parser) checkBindings(caseToken token.Token, patterns []ast.Pattern) bool {
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		names := pattern.Bindings()
+		if len(names) > 0 && len(patterns) > 1 {
+			p.setTokenError(caseToken, "match case with multiple patterns cannot bind variables")
+			return false
+		}
+		for _, name := range names {
+			if seen[name] {
+				p.setTokenError(caseToken, "variable %q is bound more than once in pattern", name)
+				return false
+			}
+			seen[name] = true
+		}
+	}
+	return true
+}
+
+// parsePattern parses a pattern in a match case, starting at the current
+// token. Patterns resemble the literals for the values they match.
+func (p *Parser) parsePattern() ast.Pattern {
+	patternToken := p.curToken
+	switch patternToken.Type {
+	case token.IDENT:
+		if p.peekTokenIs(token.LPAREN) {
+			return p.parseTypePattern()
+		}
+		if patternToken.Literal == "_" {
+			return ast.NewWildcardPattern(patternToken)
+		}
+		return ast.NewBindingPattern(patternToken, ast.NewIdent(patternToken))
+	case token.INT, token.FLOAT, token.STRING, token.BACKTICK, token.TRUE, token.FALSE, token.NIL, token.MINUS:
+		value := p.parseExpression(PREFIX)
+		if value == nil {
+			return nil
+		}
+		if !isLiteral(value) {
+			p.setTokenError(patternToken, "invalid pattern (got %s)", value.String())
+			return nil
+		}
+		return ast.NewValuePattern(patternToken, value)
+	case token.LBRACKET:
+		return p.parseListPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	}
+	p.setTokenError(patternToken, "invalid pattern (got %s)", patternToken.Literal)
+	return nil
+}
+
+// isLiteral returns true if the expression is a literal that may be used as a
+// pattern. Negative numbers are accepted too.
+func isLiteral(expr ast.Expression) bool {
+	switch expr := expr.(type) {
+	case *ast.Int, *ast.BigInt, *ast.Float, *ast.String, *ast.Bool, *ast.Nil:
+		return true
+	case *ast.Prefix:
+		switch expr.Right().(type) {
+		case *ast.Int, *ast.BigInt, *ast.Float:
+			return expr.Operator() == "-"
+		}
+	}
+	return false
+}
+
+// parseTypePattern parses a pattern like "int(n)", "ok(value)" or "err(msg)".
+func (p *Parser) parseTypePattern() ast.Pattern {
+	nameToken := p.curToken
+	p.nextToken() // move to the "("
+	p.nextToken() // move to the inner pattern
+	p.eatNewlines()
+	value := p.parsePattern()
+	if value == nil {
+		return nil
+	}
+	for p.peekTokenIs(token.NEWLINE) {
+		p.nextToken()
+	}
+	if !p.expectPeek("pattern", token.RPAREN) {
+		return nil
+	}
+	return ast.NewTypePattern(nameToken, nameToken.Literal, value)
+}
+
+// parseRestPattern parses the name following "..." at the end of a list or
+// map pattern.
+func (p *Parser) parseRestPattern() ast.Pattern {
+	if !p.expectPeek("pattern", token.IDENT) {
+		return nil
+	}
+	if p.curToken.Literal == "_" {
+		return ast.NewWildcardPattern(p.curToken)
+	}
+	return ast.NewBindingPattern(p.curToken, ast.NewIdent(p.curToken))
+}
+
+// parsePatternItems calls parseItem for each comma separated item up to the
+// closing token, which is left as the current token. A rest pattern may
+// appear as the final item.
+func (p *Parser) parsePatternItems(closing token.Type, parseItem func() bool) (ast.Pattern, bool) {
+	var rest ast.Pattern
+	for {
+		for p.peekTokenIs(token.NEWLINE) {
+			p.nextToken()
+		}
+		if p.peekTokenIs(closing) {
+			break
+		}
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if rest = p.parseRestPattern(); rest == nil {
+				return nil, false
+			}
+		} else if !parseItem() {
+			return nil, false
+		}
+		for p.peekTokenIs(token.NEWLINE) {
+			p.nextToken()
+		}
+		if rest != nil || !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // move to the comma
+	}
+	if !p.expectPeek("pattern", closing) {
+		return nil, false
+	}
+	return rest, true
+}
+
+func (p *Parser) parseListPattern() ast.Pattern {
+	listToken := p.curToken
+	var elements []ast.Pattern
+	rest, ok := p.parsePatternItems(token.RBRACKET, func() bool {
+		element := p.parsePattern()
+		if element == nil {
+			return false
+		}
+		elements = append(elements, element)
+		return true
+	})
+	if !ok {
+		return nil
+	}
+	return ast.NewListPattern(listToken, elements, rest)
+}
+
+// parseMapPatternKey parses the key of an item in a map pattern or target.
+// A name is shorthand for a string key, and any other key must be a literal.
+func (p *Parser) parseMapPatternKey(context string) ast.Expression {
+	keyToken := p.curToken
+	switch keyToken.Type {
+	case token.IDENT, token.STRING, token.BACKTICK:
+		return ast.NewString(keyToken)
+	case token.INT, token.FLOAT, token.TRUE, token.FALSE, token.MINUS:
+		key := p.parseExpression(PREFIX)
+		if key == nil {
+			return nil
+		}
+		if isLiteral(key) {
+			return key
+		}
+	}
+	p.setTokenError(keyToken, "expected a literal key in %s (got %s)", context, keyToken.Literal)
+	return nil
+}
+
+func (p *Parser) parseMapPattern() ast.Pattern {
+	mapToken := p.curToken
+	var keys []ast.Expression
+	var values []ast.Pattern
+	rest, ok := p.parsePatternItems(token.RBRACE, func() bool {
+		key := p.parseMapPatternKey("map pattern")
+		if key == nil {
+			return false
+		}
+		if !p.expectPeek("map pattern", token.COLON) {
+			return false
+		}
+		p.nextToken() // move to the value pattern
+		p.eatNewlines()
+		value := p.parsePattern()
+		if value == nil {
+			return false
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	if !ok {
+		return nil
+	}
+	return ast.NewMapPattern(mapToken, keys, values, rest)
+}
+
+func (p *Parser) parseSelect() ast.Expression {
+	selectToken := p.curToken
+	if !p.expectPeek("select statement", token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	p.eatNewlines()
+	// Process the select case statements
+	var cases []*ast.SelectCase
 	var defaultCaseCount int
 	// Each time through this loop we process one case statement
 	for !p.curTokenIs(token.RBRACE) {
 		if p.curTokenIs(token.EOF) {
-			p.setTokenError(p.prevToken, "unterminated switch statement")
-			return nil
-		}
-		if p.curToken.Literal != "case" && p.curToken.Literal != "default" {
-			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			p.setTokenError(p.prevToken, "unterminated select statement")
 			return nil
 		}
 		caseToken := p.curToken
-		var isDefaultCase bool
-		var caseExprs []ast.Expression
-		if p.curTokenIs(token.DEFAULT) {
-			isDefaultCase = true
-		} else if p.curTokenIs(token.CASE) {
+		var names []*ast.Ident
+		var comm *ast.ObjectCall
+		if p.curTokenIs(token.CASE) {
 			p.nextToken() // move to the token following "case"
-			caseExprs = append(caseExprs, p.parseExpression(LOWEST))
-			for p.peekTokenIs(token.COMMA) {
-				p.nextToken() // move to the comma
-				p.nextToken() // move to the following expression
-				caseExprs = append(caseExprs, p.parseExpression(LOWEST))
+			if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.COMMA)) {
+				names = append(names, ast.NewIdent(p.curToken))
+				for p.peekTokenIs(token.COMMA) {
+					p.nextToken()
+					if !p.expectPeek("select statement", token.IDENT) {
+						return nil
+					}
+					names = append(names, ast.NewIdent(p.curToken))
+				}
+				if !p.expectPeek("select statement", token.DECLARE) {
+					return nil
+				}
+				p.nextToken()
 			}
-		} else {
+			commToken := p.curToken
+			comm = p.parseSelectComm(p.parseExpression(LOWEST), names)
+			if comm == nil {
+				p.setTokenError(commToken, "select case must be a channel send or recv call")
+				return nil
+			}
+		} else if !p.curTokenIs(token.DEFAULT) {
 			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
 			return nil
 		}
-		if !p.expectPeek("switch statement", token.COLON) {
+		if !p.expectPeek("select statement", token.COLON) {
 			return nil
 		}
 		// Now we are at the block of code to be executed for this case
-		p.nextToken()
-		p.eatNewlines()
-		blockFirstToken := p.curToken
-		var blockStatements []ast.Node
-		for {
-			stmt := p.parseStatement()
-			if stmt == nil {
-				return nil
-			}
-			blockStatements = append(blockStatements, stmt)
-			p.eatNewlines()
-			if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
-				break
-			}
+		block := p.parseCaseBlock()
+		if block == nil {
+			return nil
 		}
-		block := ast.NewBlock(blockFirstToken, blockStatements)
-		if isDefaultCase {
+		if comm == nil {
 			defaultCaseCount++
 			if defaultCaseCount > 1 {
-				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+				p.setTokenError(caseToken, "select statement has multiple default blocks")
 				return nil
 			}
-			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+			cases = append(cases, ast.NewDefaultSelectCase(caseToken, block))
 		} else {
-			cases = append(cases, ast.NewCase(caseToken, caseExprs, block))
+			cases = append(cases, ast.NewSelectCase(caseToken, names, comm, block))
 		}
 	}
-	return ast.NewSwitch(switchToken, switchValue, cases)
+	return ast.NewSelect(selectToken, cases)
+}
+
+// parseSelectComm checks that the expression in a select case is either
+// ch.send(value) or ch.recv(), with the latter optionally assigned to one
+// or two names. Returns nil if the expression is not valid.
+func (p *Parser) parseSelectComm(expr ast.Expression, names []*ast.Ident) *ast.ObjectCall {
+	comm, ok := expr.(*ast.ObjectCall)
+	if !ok {
+		return nil
+	}
+	call, ok := comm.Call().(*ast.Call)
+	if !ok {
+		return nil
+	}
+	switch call.Function().String() {
+	case "send":
+		if len(call.Arguments()) != 1 || len(names) > 0 {
+			return nil
+		}
+	case "recv":
+		if len(call.Arguments()) != 0 || len(names) > 2 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return comm
+}
+
+func (p *Parser) parseGo() ast.Node {
+	goToken := p.curToken
+	p.nextToken()
+	call := p.parseExpressionStatement()
+	if call == nil {
+		return nil
+	}
+	switch call.(type) {
+	case *ast.Call, *ast.ObjectCall:
+		return ast.NewGo(goToken, call)
+	}
+	p.setTokenError(goToken, "go statement requires a function call")
+	return nil
+}
+
+func (p *Parser) parseYield() ast.Node {
+	yieldToken := p.curToken
+	if len(p.generators) == 0 {
+		p.setTokenError(yieldToken, "yield outside function")
+		return nil
+	}
+	p.generators[len(p.generators)-1] = true
+	switch p.peekToken.Type {
+	case token.SEMICOLON, token.NEWLINE, token.RBRACE, token.EOF:
+		// A bare yield produces nil
+		for p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
+			if err := p.nextTokenWithError(); err != nil {
+				return nil
+			}
+		}
+		return ast.NewYield(yieldToken, nil)
+	}
+	p.nextToken()
+	value := p.parseExpressionStatement()
+	if value == nil {
+		return nil
+	}
+	return ast.NewYield(yieldToken, value)
+}
+
+// parseFuncBody parses the body of a function or method, reporting whether
+// it contains a yield statement.
+func (p *Parser) parseFuncBody() (*ast.Block, bool) {
+	p.generators = append(p.generators, false)
+	body := p.parseBlock()
+	generator := p.generators[len(p.generators)-1]
+	p.generators = p.generators[:len(p.generators)-1]
+	return body, generator
+}
+
+func (p *Parser) parseDefer() ast.Node {
+	deferToken := p.curToken
+	p.nextToken()
+	call := p.parseExpressionStatement()
+	if call == nil {
+		return nil
+	}
+	switch call.(type) {
+	case *ast.Call, *ast.ObjectCall:
+		return ast.NewDefer(deferToken, call)
+	}
+	p.setTokenError(deferToken, "defer statement requires a function call")
+	return nil
 }
 
 func (p *Parser) parseImport() ast.Expression {
@@ -679,6 +1485,10 @@ func (p *Parser) parseInfixExpr(left ast.Expression) ast.Expression {
 	return ast.NewInfix(firstToken, left, firstToken.Literal, right)
 }
 
+func (p *Parser) parsePropagate(value ast.Expression) ast.Expression {
+	return ast.NewPropagate(p.curToken, value)
+}
+
 func (p *Parser) parseTernary(condition ast.Expression) ast.Expression {
 	if p.tern {
 		p.setTokenError(p.curToken, "nested ternary expression detected")
@@ -847,42 +1657,220 @@ func (p *Parser) parseFunc() ast.Expression {
 	if !p.expectPeek("function", token.LPAREN) { // Move to the "("
 		return nil
 	}
-	defaults, params := p.parseFuncParams()
+	defaults, params, variadic, paramTypes := p.parseFuncParams()
+	if defaults == nil {
+		return nil
+	}
+	types, ok := p.parseFuncTypes("function", paramTypes)
+	if !ok {
+		return nil
+	}
 	if !p.expectPeek("function", token.LBRACE) { // move to the "{"
 		return nil
 	}
-	return ast.NewFunc(funcToken, ident, params, defaults, p.parseBlock())
+	body, generator := p.parseFuncBody()
+	return ast.NewFuncWithOpts(ast.FuncOpts{
+		Token:      funcToken,
+		Name:       ident,
+		Parameters: params,
+		Defaults:   defaults,
+		Body:       body,
+		Variadic:   variadic,
+		Generator:  generator,
+		Types:      types,
+	})
+}
+
+func (p *Parser) parseStruct() ast.Node {
+	structToken := p.curToken
+	if !p.expectPeek("struct", token.IDENT) { // move to the struct name
+		return nil
+	}
+	name := ast.NewIdent(p.curToken)
+	if !p.expectPeek("struct", token.LBRACE) { // move to the "{"
+		return nil
+	}
+	var fields []*ast.Ident
+	var methods []*ast.Func
+	defaults := map[string]ast.Expression{}
+	declared := map[string]bool{}
+	p.nextToken() // move past the "{"
+	for !p.curTokenIs(token.RBRACE) {
+		switch p.curToken.Type {
+		case token.EOF:
+			p.setTokenError(structToken, "unterminated struct declaration")
+			return nil
+		case token.NEWLINE, token.SEMICOLON, token.COMMA:
+			// Fields may be separated by newlines or commas
+		case token.IDENT:
+			field := ast.NewIdent(p.curToken)
+			if declared[field.String()] {
+				p.setTokenError(p.curToken, "duplicate field or method %s in struct %s", field, name)
+				return nil
+			}
+			declared[field.String()] = true
+			// If there is "=expr" after the name then expr is a default value
+			if p.peekTokenIs(token.ASSIGN) {
+				p.nextToken()
+				p.nextToken()
+				expr := p.parseExpression(LOWEST)
+				if expr == nil {
+					return nil
+				}
+				defaults[field.String()] = expr
+			}
+			fields = append(fields, field)
+		case token.FUNC:
+			method := p.parseMethod()
+			if method == nil {
+				return nil
+			}
+			if declared[method.Name().String()] {
+				p.setTokenError(method.Name().Token(), "duplicate field or method %s in struct %s", method.Name(), name)
+				return nil
+			}
+			declared[method.Name().String()] = true
+			methods = append(methods, method)
+		default:
+			p.setTokenError(p.curToken, "unexpected %s in struct declaration", tokenDescription(p.curToken))
+			return nil
+		}
+		if err := p.nextTokenWithError(); err != nil {
+			return nil
+		}
+	}
+	// Skip an optional semicolon following the closing brace
+	for p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
+		if err := p.nextTokenWithError(); err != nil {
+			return nil
+		}
+	}
+	return ast.NewStruct(structToken, name, fields, defaults, methods)
+}
+
+// parseMethod parses a method declared within a struct, which has the form
+// func (receiver) name(params) { ... }. The receiver becomes the first
+// parameter of the returned function.
+func (p *Parser) parseMethod() *ast.Func {
+	funcToken := p.curToken
+	if !p.expectPeek("method", token.LPAREN) { // move to the "("
+		return nil
+	}
+	if !p.expectPeek("method", token.IDENT) { // move to the receiver
+		return nil
+	}
+	receiver := ast.NewIdent(p.curToken)
+	if !p.expectPeek("method", token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek("method", token.IDENT) { // move to the method name
+		return nil
+	}
+	name := ast.NewIdent(p.curToken)
+	if !p.expectPeek("method", token.LPAREN) {
+		return nil
+	}
+	defaults, params, variadic, paramTypes := p.parseFuncParams()
+	if defaults == nil {
+		return nil
+	}
+	types, ok := p.parseFuncTypes("method", paramTypes)
+	if !ok {
+		return nil
+	}
+	if !p.expectPeek("method", token.LBRACE) { // move to the "{"
+		return nil
+	}
+	body, generator := p.parseFuncBody()
+	if body == nil {
+		return nil
+	}
+	params = append([]*ast.Ident{receiver}, params...)
+	return ast.NewFuncWithOpts(ast.FuncOpts{
+		Token:      funcToken,
+		Name:       name,
+		Parameters: params,
+		Defaults:   defaults,
+		Body:       body,
+		Variadic:   variadic,
+		Generator:  generator,
+		Types:      types,
+	})
+}
+
+// parseFuncTypes parses the optional "-> type" annotation that follows the
+// parameters of a function. The result is nil if neither the parameters nor
+// the result are annotated. False is returned if the annotation is invalid.
+func (p *Parser) parseFuncTypes(context string, params map[string]*ast.Ident) (*ast.FuncTypes, bool) {
+	var result *ast.Ident
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		if result = p.parseTypeAnnotation(context); result == nil {
+			return nil, false
+		}
+	}
+	if len(params) == 0 && result == nil {
+		return nil, true
+	}
+	return ast.NewFuncTypes(params, result), true
 }
 
-func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident) {
+// parseFuncParams parses the parameters of a function up to the closing
+// ")". The returned bool is true if the last parameter is variadic, which is
+// written as "...name". Parameters may be annotated with a type, as in
+// "x: int", and these are returned keyed by parameter name.
+func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident, bool, map[string]*ast.Ident) {
 	// If the next parameter is ")", then there are no parameters
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return map[string]ast.Expression{}, nil
+		return map[string]ast.Expression{}, nil, false, nil
 	}
 	defaults := map[string]ast.Expression{}
 	params := make([]*ast.Ident, 0)
+	types := map[string]*ast.Ident{}
+	variadic := false
 	p.nextToken()
 	for !p.curTokenIs(token.RPAREN) { // Keep going until we find a ")"
 		if p.curTokenIs(token.EOF) {
 			p.setTokenError(p.prevToken, "unterminated function parameters")
-			return nil, nil
+			return nil, nil, false, nil
+		}
+		if variadic {
+			p.setTokenError(p.curToken, "variadic parameter must be the last parameter")
+			return nil, nil, false, nil
+		}
+		if p.curTokenIs(token.ELLIPSIS) {
+			variadic = true
+			p.nextToken()
 		}
 		if !p.curTokenIs(token.IDENT) {
 			p.setTokenError(p.curToken, "expected an identifier (got %s)", p.curToken.Literal)
-			return nil, nil
+			return nil, nil, false, nil
 		}
 		ident := ast.NewIdent(p.curToken)
 		params = append(params, ident)
+		// If there is ": type" after the name then it is a type annotation
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			typ := p.parseTypeAnnotation("function parameters")
+			if typ == nil {
+				return nil, nil, false, nil
+			}
+			types[ident.String()] = typ
+		}
 		if err := p.nextTokenWithError(); err != nil {
-			return nil, nil
+			return nil, nil, false, nil
 		}
 		// If there is "=expr" after the name then expr is a default value
 		if p.curTokenIs(token.ASSIGN) {
+			if variadic {
+				p.setTokenError(p.curToken, "variadic parameter cannot have a default value")
+				return nil, nil, false, nil
+			}
 			p.nextToken()
 			expr := p.parseExpression(LOWEST)
 			if expr == nil {
-				return nil, nil
+				return nil, nil, false, nil
 			}
 			defaults[ident.String()] = expr
 			p.nextToken()
@@ -891,7 +1879,7 @@ func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident) {
 			p.nextToken()
 		}
 	}
-	return defaults, params
+	return defaults, params, variadic, types
 }
 
 func (p *Parser) parseString() ast.Expression {
@@ -939,11 +1927,131 @@ func (p *Parser) parseString() ast.Expression {
 
 func (p *Parser) parseList() ast.Expression {
 	bracket := p.curToken
-	items := p.parseExprList(token.RBRACKET)
+	var clauses []*ast.ComprehensionClause
+	items := p.parseItemList(token.RBRACKET, func() ast.Expression {
+		item := p.parseExpression(LOWEST)
+		if item != nil && p.peekTokenIs(token.FOR) {
+			if clauses != nil {
+				p.setTokenError(p.peekToken, "unexpected for in list expression")
+				return nil
+			}
+			if clauses = p.parseComprehensionClauses(); clauses == nil {
+				return nil
+			}
+		}
+		return item
+	})
+	if items == nil {
+		return nil
+	}
+	if clauses != nil {
+		if len(items) > 1 {
+			p.setTokenError(bracket, "list comprehension must have a single item")
+			return nil
+		}
+		return ast.NewListComprehension(bracket, items[0], clauses)
+	}
 	return ast.NewList(bracket, items)
 }
 
+// parseComprehensionClauses parses the "for" clauses of a comprehension,
+// starting with the peek token. Each clause may be followed by an "if"
+// condition that filters the items.
+func (p *Parser) parseComprehensionClauses() []*ast.ComprehensionClause {
+	var clauses []*ast.ComprehensionClause
+	for p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		forToken := p.curToken
+		p.nextToken()
+		targets, rest := p.parseTargetList()
+		if targets == nil {
+			return nil
+		}
+		if rest != nil || len(targets) > 2 {
+			p.setTokenError(forToken, "comprehension expected one or two variables")
+			return nil
+		}
+		if !p.checkBindings(forToken, []ast.Pattern{ast.NewListPattern(forToken, targets, nil)}) {
+			return nil
+		}
+		if !p.peekTokenIs(token.IN) {
+			p.setTokenError(p.peekToken, "expected in after comprehension variables (got %s)", p.peekToken.Literal)
+			return nil
+		}
+		p.nextToken()
+		p.nextToken()
+		iterable := p.parseExpression(LOWEST)
+		if iterable == nil {
+			return nil
+		}
+		var condition ast.Expression
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			if condition = p.parseExpression(LOWEST); condition == nil {
+				return nil
+			}
+		}
+		clauses = append(clauses, ast.NewComprehensionClause(forToken, targets, iterable, condition))
+	}
+	return clauses
+}
+
 func (p *Parser) parseExprList(end token.Type) []ast.Expression {
+	return p.parseItemList(end, func() ast.Expression {
+		return p.parseExpression(LOWEST)
+	})
+}
+
+// parseArgumentList parses the arguments of a call, which may include
+// spread arguments written as "...list".
+func (p *Parser) parseArgumentList() []ast.Expression {
+	return p.parseItemList(token.RPAREN, func() ast.Expression {
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+			nameToken := p.curToken
+			p.nextToken()
+			p.nextToken()
+			value := p.parseExpression(LOWEST)
+			if value == nil {
+				return nil
+			}
+			return ast.NewKeywordArg(nameToken, value)
+		}
+		if !p.curTokenIs(token.ELLIPSIS) {
+			return p.parsePositionalArgument()
+		}
+		spreadToken := p.curToken
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		return ast.NewSpread(spreadToken, value)
+	})
+}
+
+// parsePositionalArgument parses an argument of a call that isn't a keyword
+// or spread argument. This may be a slice written as start:stop:step.
+func (p *Parser) parsePositionalArgument() ast.Expression {
+	argToken := p.curToken
+	var start ast.Expression
+	if !p.curTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+		if start == nil || !p.peekTokenIs(token.COLON) {
+			return start
+		}
+		p.nextToken() // move to the ":"
+	}
+	stop, step, ok := p.parseSliceBounds(token.COMMA, token.RPAREN, token.NEWLINE)
+	if !ok {
+		return nil
+	}
+	return ast.NewSliceLiteral(argToken, start, stop, step)
+}
+
+// parseItemList parses a comma separated list of items up to the given end
+// token, using parseItem to parse each one.
+func (p *Parser) parseItemList(end token.Type, parseItem func() ast.Expression) []ast.Expression {
 	list := make([]ast.Expression, 0)
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -955,7 +2063,7 @@ func (p *Parser) parseExprList(end token.Type) []ast.Expression {
 		}
 	}
 	p.nextToken()
-	expr := p.parseExpression(LOWEST)
+	expr := parseItem()
 	if expr == nil {
 		p.setTokenError(p.curToken, "invalid syntax in list expression")
 		return nil
@@ -980,7 +2088,7 @@ func (p *Parser) parseExprList(end token.Type) []ast.Expression {
 		if err := p.nextTokenWithError(); err != nil {
 			return nil
 		}
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, parseItem())
 	}
 	if !p.expectPeek("an expression list", end) {
 		return nil
@@ -990,7 +2098,7 @@ func (p *Parser) parseExprList(end token.Type) []ast.Expression {
 
 func (p *Parser) parseIndex(left ast.Expression) ast.Expression {
 	indexToken := p.curToken
-	var firstIndex, secondIndex ast.Expression
+	var firstIndex ast.Expression
 	if !p.peekTokenIs(token.COLON) {
 		p.nextToken() // move to the first index
 		firstIndex = p.parseExpression(LOWEST)
@@ -999,37 +2107,79 @@ func (p *Parser) parseIndex(left ast.Expression) ast.Expression {
 			return ast.NewIndex(indexToken, left, firstIndex)
 		}
 	}
-	if p.peekTokenIs(token.COLON) {
-		p.nextToken() // move to the ":"
-		if p.peekTokenIs(token.RBRACKET) {
-			p.nextToken() // move to the "]"
-			return ast.NewSlice(indexToken, left, firstIndex, nil)
-		}
-		p.nextToken() // move to the second index
-		secondIndex = p.parseExpression(LOWEST)
+	if !p.peekTokenIs(token.COLON) {
+		p.expectPeek("an index expression", token.RBRACKET)
+		return nil
+	}
+	p.nextToken() // move to the ":"
+	secondIndex, step, ok := p.parseSliceBounds(token.RBRACKET)
+	if !ok {
+		return nil
 	}
 	if !p.expectPeek("an index expression", token.RBRACKET) {
 		return nil
 	}
-	return ast.NewSlice(indexToken, left, firstIndex, secondIndex)
+	return ast.NewSlice(indexToken, left, firstIndex, secondIndex, step)
+}
+
+// parseSliceBounds parses the stop index and step of a slice, starting on
+// the colon that follows the start index. Both are optional, and parsing
+// stops before any of the given end tokens.
+func (p *Parser) parseSliceBounds(ends ...token.Type) (stop, step ast.Expression, ok bool) {
+	atEnd := func() bool {
+		for _, end := range ends {
+			if p.peekTokenIs(end) {
+				return true
+			}
+		}
+		return false
+	}
+	if !atEnd() && !p.peekTokenIs(token.COLON) {
+		p.nextToken() // move to the stop index
+		if stop = p.parseExpression(LOWEST); stop == nil {
+			return nil, nil, false
+		}
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // move to the second ":"
+		if !atEnd() {
+			p.nextToken() // move to the step
+			if step = p.parseExpression(LOWEST); step == nil {
+				return nil, nil, false
+			}
+		}
+	}
+	return stop, step, true
 }
 
 func (p *Parser) parseAssign(name ast.Expression) ast.Expression {
 	operator := p.curToken
 	var ident *ast.Ident
 	var index *ast.Index
+	var slice *ast.Slice
+	var attr *ast.GetAttr
 	switch node := name.(type) {
 	case *ast.Ident:
 		ident = node
 	case *ast.Index:
 		index = node
+	case *ast.Slice:
+		slice = node
+		if operator.Type != token.ASSIGN {
+			p.setTokenError(operator, "unsupported operator for slice assignment: %s", operator.Literal)
+			return nil
+		}
+	case *ast.GetAttr:
+		attr = node
 	default:
 		p.setTokenError(operator, "unexpected token for assignment: %s", name.Literal())
 		return nil
 	}
 	switch operator.Type {
 	case token.PLUS_EQUALS, token.MINUS_EQUALS, token.SLASH_EQUALS,
-		token.ASTERISK_EQUALS, token.DECLARE, token.ASSIGN:
+		token.ASTERISK_EQUALS, token.AMPERSAND_EQUALS, token.PIPE_EQUALS,
+		token.CARET_EQUALS, token.AND_NOT_EQUALS, token.LT_LT_EQUALS,
+		token.GT_GT_EQUALS, token.DECLARE, token.ASSIGN:
 		// this is a valid operator
 	default:
 		p.setTokenError(operator, "unsupported operator for assignment: %s", operator.Literal)
@@ -1044,15 +2194,39 @@ func (p *Parser) parseAssign(name ast.Expression) ast.Expression {
 	if index != nil {
 		return ast.NewAssignIndex(operator, index, right)
 	}
+	if slice != nil {
+		return ast.NewAssignSlice(operator, slice, right)
+	}
+	if attr != nil {
+		return ast.NewAssignAttr(operator, attr, right)
+	}
 	return ast.NewAssign(operator, ident, right)
 }
 
 func (p *Parser) parseCall(function ast.Expression) ast.Expression {
 	callToken := p.curToken
-	arguments := p.parseExprList(token.RPAREN)
+	arguments := p.parseArgumentList()
 	if arguments == nil {
 		return nil
 	}
+	// Keyword arguments must follow any positional arguments and each name
+	// may only be given once
+	names := map[string]bool{}
+	for _, arg := range arguments {
+		keyword, ok := arg.(*ast.KeywordArg)
+		if !ok {
+			if len(names) > 0 {
+				p.setTokenError(callToken, "positional argument follows keyword argument")
+				return nil
+			}
+			continue
+		}
+		if names[keyword.Name()] {
+			p.setTokenError(keyword.Token(), "duplicate keyword argument %s", keyword.Name())
+			return nil
+		}
+		names[keyword.Name()] = true
+	}
 	return ast.NewCall(callToken, function, arguments)
 }
 
@@ -1108,6 +2282,14 @@ func (p *Parser) parseRange() ast.Expression {
 		p.setTokenError(p.curToken, "invalid range expression")
 		return nil
 	}
+	// A call binds to the container, so that "range count(10)" iterates over
+	// the result of the call
+	for p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if container = p.parseCall(container); container == nil {
+			return nil
+		}
+	}
 	return ast.NewRange(rangeToken, container)
 }
 
@@ -1129,6 +2311,13 @@ func (p *Parser) parseMapOrSet() ast.Expression {
 		p.nextToken() // move to the ":"
 		p.nextToken() // move to the first value
 		firstValue := p.parseExpression(LOWEST)
+		if p.peekTokenIs(token.FOR) {
+			clauses := p.parseComprehensionClauses()
+			if clauses == nil || !p.expectPeek("map comprehension", token.RBRACE) {
+				return nil
+			}
+			return ast.NewMapComprehension(firstToken, firstKey, firstValue, clauses)
+		}
 		pairs := map[ast.Expression]ast.Expression{firstKey: firstValue}
 		for !p.peekTokenIs(token.RBRACE) {
 			if !p.expectPeek("map", token.COMMA) {
@@ -1160,6 +2349,13 @@ func (p *Parser) parseMapOrSet() ast.Expression {
 		return ast.NewMap(firstToken, pairs)
 	} else { // This is a set
 		items := []ast.Expression{firstKey}
+		if p.peekTokenIs(token.FOR) {
+			clauses := p.parseComprehensionClauses()
+			if clauses == nil || !p.expectPeek("set comprehension", token.RBRACE) {
+				return nil
+			}
+			return ast.NewSetComprehension(firstToken, firstKey, clauses)
+		}
 		if p.peekTokenIs(token.COMMA) {
 			p.nextToken()
 		} else if p.peekTokenIs(token.RBRACE) {
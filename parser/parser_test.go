@@ -149,6 +149,56 @@ func TestInt(t *testing.T) {
 	}
 }
 
+func TestIntBases(t *testing.T) {
+	tests := []struct {
+		input string
+		value int64
+	}{
+		{"0xff", 255},
+		{"0XFF", 255},
+		{"0o17", 15},
+		{"0O17", 15},
+		{"0b1010", 10},
+		{"0B11", 3},
+		{"0755", 755},
+		{"1_000_000", 1000000},
+		{"0x_dead_beef", 0xdeadbeef},
+		{"0b1111_0000", 240},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		integer, ok := program.First().(*ast.Int)
+		require.True(t, ok, "got %T", program.First())
+		require.Equal(t, tt.value, integer.Value(), tt.input)
+		require.Equal(t, tt.input, integer.Literal())
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"0099999999999999999999", "99999999999999999999"},
+		{"1_000_000_000_000_000_000_000", "1000000000000000000000"},
+		{"0xffff_ffff_ffff_ffff_ff", "4722366482869645213695"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		integer, ok := program.First().(*ast.BigInt)
+		require.True(t, ok, "got %T", program.First())
+		require.Equal(t, tt.value, integer.Value().String(), tt.input)
+		require.Equal(t, tt.input, integer.Literal())
+	}
+	_, err := Parse("1_000_000_000_000_000_000_")
+	require.NotNil(t, err)
+}
+
 func TestBool(t *testing.T) {
 	tests := []struct {
 		input     string
@@ -250,6 +300,13 @@ func TestOperatorPrecedence(t *testing.T) {
 		{"a + add(b*c)+d", "((a + add((b * c))) + d)"},
 		{"a*[1,2,3,4][b*c]*d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a*b[2], b[1], 2 * [1,2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a + b & c", "(a + (b & c))"},
+		{"a ^ b & c", "(a ^ (b & c))"},
+		{"a << 2 + b >> 1", "((a << 2) + (b >> 1))"},
+		{"a &^ b * c", "((a &^ b) * c)"},
+		{"^a & b", "((^a) & b)"},
+		{"-a ^ b", "((-a) ^ b)"},
 	}
 	for _, tt := range tests {
 		program, err := Parse(tt.input)
@@ -259,6 +316,46 @@ func TestOperatorPrecedence(t *testing.T) {
 	}
 }
 
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"r?", "(r?)"},
+		{"x := f(a)?.b", "x := (f(a)?).b"},
+		{"c ? r? : 2", "(c ? (r?) : 2)"},
+		{"a + r?", "(a + (r?))"},
+		{"-r?", "(-(r?))"},
+		{"r? == 1", "((r?) == 1)"},
+		{"c ? 1 : 2", "(c ? 1 : 2)"},
+		{"c ? -1 : 2", "(c ? (-1) : 2)"},
+		{"f(r?, s?)", "f((r?), (s?))"},
+		{"r?.x?", "((r?).x?)"},
+		{"[r?]", "[(r?)]"},
+		{"r? // comment", "(r?)"},
+		{"r? # comment", "(r?)"},
+		{"r? != 1", "((r?) != 1)"},
+		{"c ? !d : 2", "(c ? (!d) : 2)"},
+		{"x? - 1", "((x?) - 1)"},
+		{"x?[0]", "((x?)[0])"},
+		{"x? ^ y", "((x?) ^ y)"},
+		{"x? * -y", "((x?) * (-y))"},
+		{"c ? x? - 1 : 2", "(c ? ((x?) - 1) : 2)"},
+		{"c ? f(x) : [1, 2]", "(c ? f(x) : [1, 2])"},
+		{"[r? - 1, c ? 1 : 2]", "[((r?) - 1), (c ? 1 : 2)]"},
+		{"l[r?:2]", "(l[(r?):2])"},
+		{`r? ? "yes" : "no"`, `((r?) ? "yes" : "no")`},
+		{`x? - y ? 1 : 2`, `(((x?) - y) ? 1 : 2)`},
+		{`c ? -x? : 2`, `(c ? (-(x?)) : 2)`},
+		{"if r? { 1 }", "if (r?) 1"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err, tt.input)
+		require.Equal(t, tt.expected, program.String(), tt.input)
+	}
+}
+
 func TestIf(t *testing.T) {
 	program, err := Parse("if x < y { x }")
 	require.Nil(t, err)
@@ -314,6 +411,35 @@ func TestFuncParams(t *testing.T) {
 	}
 }
 
+func TestVariadicFunc(t *testing.T) {
+	program, err := Parse("func f(x, ...rest) { rest }")
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	function, ok := program.First().(*ast.Func)
+	require.True(t, ok)
+	require.True(t, function.Variadic())
+	params := function.Parameters()
+	require.Len(t, params, 2)
+	testLiteralExpression(t, params[0], "x")
+	testLiteralExpression(t, params[1], "rest")
+	require.Equal(t, "func f(x, ...rest) rest", function.String())
+}
+
+func TestSpread(t *testing.T) {
+	program, err := Parse("f(1, ...items, g(...more))")
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	expr, ok := program.First().(*ast.Call)
+	require.True(t, ok)
+	args := expr.Arguments()
+	require.Len(t, args, 3)
+	testLiteralExpression(t, args[0], 1)
+	spread, ok := args[1].(*ast.Spread)
+	require.True(t, ok)
+	testIdentifier(t, spread.Value(), "items")
+	require.Equal(t, "f(1, ...items, g(...more))", expr.String())
+}
+
 func TestCall(t *testing.T) {
 	program, err := Parse("add(1, 2*3, 4+5)")
 	require.Nil(t, err)
@@ -422,7 +548,7 @@ func TestParsingMapLiteralWithExpression(t *testing.T) {
 	}
 }
 
-// Test operators: +=, -=, /=, and *=.
+// Test operators: +=, -=, /=, *= and the bitwise assignments.
 func TestMutators(t *testing.T) {
 	inputs := []string{
 		"var w = 5; w *= 3;",
@@ -432,6 +558,7 @@ func TestMutators(t *testing.T) {
 		"var z = 1; z++;",
 		"var z = 1; z--;",
 		"var z = 10; var a = 3; y = a;",
+		"var b = 6; b &= 3; b |= 8; b ^= 1; b &^= 2; b <<= 1; b >>= 1;",
 	}
 	for _, input := range inputs {
 		_, err := Parse(input)
@@ -519,6 +646,173 @@ default:
 	require.Equal(t, 8, parserErr.EndPosition().Line)
 }
 
+func TestMatch(t *testing.T) {
+	input := `match msg {
+case {"type": "user", id: int(id), ...rest} if id > 0:
+    x
+case [first, ..._]:
+    y
+case ok(_), err(_):
+    z
+case 1, -2.5, "s", nil, true, _:
+    w
+default: v
+}`
+	program, err := Parse(input)
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	matchExpr, ok := program.First().(*ast.Match)
+	require.True(t, ok)
+	require.Equal(t, "msg", matchExpr.Value().String())
+	cases := matchExpr.Cases()
+	require.Len(t, cases, 5)
+
+	mapPattern, ok := cases[0].Patterns()[0].(*ast.MapPattern)
+	require.True(t, ok)
+	require.Len(t, mapPattern.Keys(), 2)
+	require.Equal(t, `"type"`, mapPattern.Keys()[0].String())
+	require.Equal(t, []string{"id", "rest"}, mapPattern.Bindings())
+	require.Equal(t, `{"type": "user", "id": int(id), ...rest}`, mapPattern.String())
+	require.Equal(t, "(id > 0)", cases[0].Guard().String())
+
+	listPattern, ok := cases[1].Patterns()[0].(*ast.ListPattern)
+	require.True(t, ok)
+	require.Equal(t, []string{"first"}, listPattern.Bindings())
+	require.Equal(t, "[first, ..._]", listPattern.String())
+	require.Nil(t, cases[1].Guard())
+
+	require.Len(t, cases[2].Patterns(), 2)
+	require.Equal(t, "err(_)", cases[2].Patterns()[1].String())
+
+	var values []string
+	for _, pattern := range cases[3].Patterns() {
+		values = append(values, pattern.String())
+	}
+	require.Equal(t, []string{"1", "(-2.5)", `"s"`, "nil", "true", "_"}, values)
+	require.True(t, cases[4].IsDefault())
+}
+
+func TestGo(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`go work(1, 2)`, "go work(1, 2)"},
+		{`go wg.done()`, "go wg.done()"},
+		{`go func() { x }()`, "go func() x()"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		stmt, ok := program.First().(*ast.Go)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, stmt.String())
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`defer conn.close()`, "defer conn.close()"},
+		{`defer print("done", x)`, `defer print("done", x)`},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		stmt, ok := program.First().(*ast.Defer)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, stmt.String())
+	}
+}
+
+func TestSelect(t *testing.T) {
+	input := `select {
+case msg := jobs.recv():
+    print(msg)
+case value, ok := results.recv():
+    print(value, ok)
+case done.send(true):
+    x
+default:
+    y
+}`
+	program, err := Parse(input)
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	selectExpr, ok := program.First().(*ast.Select)
+	require.True(t, ok)
+	cases := selectExpr.Cases()
+	require.Len(t, cases, 4)
+	require.Len(t, cases[0].Names(), 1)
+	require.Equal(t, "msg", cases[0].Names()[0].String())
+	require.Equal(t, "jobs", cases[0].Channel().String())
+	require.False(t, cases[0].IsSend())
+	require.Len(t, cases[1].Names(), 2)
+	require.True(t, cases[2].IsSend())
+	require.Equal(t, "true", cases[2].Value().String())
+	require.True(t, cases[3].IsDefault())
+}
+
+func TestStruct(t *testing.T) {
+	input := `struct Point {
+    x, y
+    z = 0
+
+    func (p) add(other, scale=1) {
+        return Point(p.x + other.x, p.y + other.y)
+    }
+}`
+	program, err := Parse(input)
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	stmt, ok := program.First().(*ast.Struct)
+	require.True(t, ok)
+	require.Equal(t, "Point", stmt.Name().String())
+	require.Len(t, stmt.Fields(), 3)
+	require.Equal(t, "z", stmt.Fields()[2].String())
+	require.Equal(t, "0", stmt.Defaults()["z"].String())
+	require.Len(t, stmt.Methods(), 1)
+	method := stmt.Methods()[0]
+	require.Equal(t, "add", method.Name().String())
+	require.Len(t, method.Parameters(), 3)
+	require.Equal(t, "p", method.Parameters()[0].String())
+	require.Contains(t, method.Defaults(), "scale")
+}
+
+func TestStructSemicolon(t *testing.T) {
+	program, err := Parse("struct P { x }; P(1)")
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 2)
+	_, ok := program.First().(*ast.Struct)
+	require.True(t, ok)
+	require.Equal(t, "P(1)", program.Statements()[1].String())
+}
+
+func TestSetAttr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`p.x = 1`, "p.x = 1"},
+		{`p.x += 2`, "p.x += 2"},
+		{`p.x <<= 2`, "p.x <<= 2"},
+		{`a.b.c = 3`, "a.b.c = 3"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		assign, ok := program.First().(*ast.Assign)
+		require.True(t, ok)
+		require.NotNil(t, assign.Attr())
+		require.Equal(t, tt.expected, assign.String())
+	}
+}
+
 func TestPipe(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -605,10 +899,30 @@ func TestCallExpression(t *testing.T) {
 	require.Equal(t, "foo", call.Function().String())
 	args := call.Arguments()
 	require.Len(t, args, 2)
-	arg0 := args[0].(*ast.Assign)
-	require.Equal(t, "a = 1", arg0.String())
-	arg1 := args[1].(*ast.Assign)
-	require.Equal(t, "b = 2", arg1.String())
+	arg0 := args[0].(*ast.KeywordArg)
+	require.Equal(t, "a=1", arg0.String())
+	arg1 := args[1].(*ast.KeywordArg)
+	require.Equal(t, "b=2", arg1.String())
+}
+
+func TestKeywordArgs(t *testing.T) {
+	program, err := Parse("sorted(items, key=len, reverse=x > 1)")
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	call, ok := program.First().(*ast.Call)
+	require.True(t, ok)
+	args := call.Arguments()
+	require.Len(t, args, 3)
+	testIdentifier(t, args[0], "items")
+	key, ok := args[1].(*ast.KeywordArg)
+	require.True(t, ok)
+	require.Equal(t, "key", key.Name())
+	testIdentifier(t, key.Value(), "len")
+	reverse, ok := args[2].(*ast.KeywordArg)
+	require.True(t, ok)
+	require.Equal(t, "reverse", reverse.Name())
+	require.Equal(t, "(x > 1)", reverse.Value().String())
+	require.Equal(t, "sorted(items, key=len, reverse=(x > 1))", call.String())
 }
 
 func TestGetAttr(t *testing.T) {
@@ -690,6 +1004,190 @@ func TestMultiVar(t *testing.T) {
 	require.Equal(t, "[1, 2]", expr.String())
 }
 
+func TestDestructure(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		walrus   bool
+		bindings []string
+	}{
+		{"[a, [b, c]] := x", "[a, [b, c]] := x", true, []string{"a", "b", "c"}},
+		{"first, ...rest := items", "[first, ...rest] := items", true, []string{"first", "rest"}},
+		{"{name, age = 0} := person", `{"name": name, "age": age = 0} := person`, true, []string{"name", "age"}},
+		{`{"full name": n, tags: [t, ..._], ...other} = p`, `{"full name": n, "tags": [t, ..._], ...other} = p`, false, []string{"n", "t", "other"}},
+		{"[a, b] = [b, a]", "[a, b] = [b, a]", false, []string{"a", "b"}},
+		{"x, _ = pair", "[x, _] = pair", false, []string{"x"}},
+		{"{1: one, true: yes} := m", "{1: one, true: yes} := m", true, []string{"one", "yes"}},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err, tt.input)
+		require.Len(t, program.Statements(), 1)
+		stmt, ok := program.First().(*ast.Destructure)
+		require.True(t, ok, tt.input)
+		require.Equal(t, tt.expected, stmt.String())
+		require.Equal(t, tt.walrus, stmt.IsWalrus())
+		require.Equal(t, tt.bindings, stmt.Target().Bindings())
+	}
+}
+
+func TestDestructureForLoop(t *testing.T) {
+	program, err := Parse("for i, {name} := range people { name }")
+	require.Nil(t, err)
+	loop, ok := program.First().(*ast.For)
+	require.True(t, ok)
+	require.True(t, loop.IsIteratorLoop())
+	cond, ok := loop.Condition().(*ast.Destructure)
+	require.True(t, ok)
+	require.Equal(t, `[i, {"name": name}] := range people`, cond.String())
+}
+
+func TestComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in items if x > 0]", "[(x * 2) for x in items if (x > 0)]"},
+		{"{k: v for k, v in m}", "{k: v for k, v in m}"},
+		{"{x % 3 for x in nums}", "{(x % 3) for x in nums}"},
+		{"[[a, b] for a in xs for [b, _] in ys if a < b]", "[[a, b] for a in xs for [b, _] in ys if (a < b)]"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err, tt.input)
+		require.Len(t, program.Statements(), 1)
+		require.Equal(t, tt.expected, program.First().String())
+	}
+	program, err := Parse("[k for k, {name} in people if name]")
+	require.Nil(t, err)
+	comp, ok := program.First().(*ast.ListComprehension)
+	require.True(t, ok)
+	require.Len(t, comp.Clauses(), 1)
+	clause := comp.Clauses()[0]
+	require.Equal(t, []string{"k", "name"}, clause.Bindings())
+	require.Equal(t, "people", clause.Iterable().String())
+	require.Equal(t, "name", clause.Condition().String())
+}
+
+func TestSlice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x[1:2]", "(x[1:2])"},
+		{"x[1:]", "(x[1:])"},
+		{"x[:2]", "(x[:2])"},
+		{"x[::-1]", "(x[::(-1)])"},
+		{"x[a:b:c]", "(x[a:b:c])"},
+		{"x[1::2]", "(x[1::2])"},
+		{"x[1:3] = y", "(x[1:3]) = y"},
+		{"delete(x, 1:3)", "delete(x, 1:3)"},
+		{"f(:2, ::-1)", "f(:2, ::(-1))"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err, tt.input)
+		require.Len(t, program.Statements(), 1)
+		require.Equal(t, tt.expected, program.First().String())
+	}
+	program, err := Parse("x[1:2:3]")
+	require.Nil(t, err)
+	slice, ok := program.First().(*ast.Slice)
+	require.True(t, ok)
+	require.Equal(t, "1", slice.FromIndex().String())
+	require.Equal(t, "2", slice.ToIndex().String())
+	require.Equal(t, "3", slice.Step().String())
+}
+
+func TestYield(t *testing.T) {
+	program, err := Parse("func f() { yield 1\nyield }")
+	require.Nil(t, err)
+	fn, ok := program.First().(*ast.Func)
+	require.True(t, ok)
+	require.True(t, fn.Generator())
+	statements := fn.Body().Statements()
+	require.Len(t, statements, 2)
+	first, ok := statements[0].(*ast.Yield)
+	require.True(t, ok)
+	require.Equal(t, "1", first.Value().String())
+	second, ok := statements[1].(*ast.Yield)
+	require.True(t, ok)
+	require.Nil(t, second.Value())
+	require.Equal(t, "yield", second.String())
+
+	// A yield belongs to the innermost function
+	program, err = Parse("func f() { func() { yield 1 } }")
+	require.Nil(t, err)
+	fn, ok = program.First().(*ast.Func)
+	require.True(t, ok)
+	require.False(t, fn.Generator())
+
+	// A call binds to the range container
+	program, err = Parse("range gen(3)")
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Range)
+	require.True(t, ok)
+	require.Equal(t, "gen(3)", node.Container().String())
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	program, err := Parse("func f(x: int, y, z: string = \"a\", ...rest: any) -> list { [x] }")
+	require.Nil(t, err)
+	fn, ok := program.First().(*ast.Func)
+	require.True(t, ok)
+	types := fn.Types()
+	require.NotNil(t, types)
+	require.Equal(t, "int", types.Param("x").String())
+	require.Nil(t, types.Param("y"))
+	require.Equal(t, "string", types.Param("z").String())
+	require.Equal(t, "any", types.Param("rest").String())
+	require.Equal(t, "list", types.Result().String())
+	require.Equal(t, "\"a\"", fn.Defaults()["z"].String())
+	require.Equal(t, "func f(x: int, y, z: string, ...rest: any) -> list [x]", fn.String())
+
+	// Functions without annotations have no types
+	program, err = Parse("func(x) { x }")
+	require.Nil(t, err)
+	fn, ok = program.First().(*ast.Func)
+	require.True(t, ok)
+	require.Nil(t, fn.Types())
+	require.Nil(t, fn.Types().Result())
+
+	program, err = Parse("struct P { func (p) m(n: int) -> nil { nil } }")
+	require.Nil(t, err)
+	method := program.First().(*ast.Struct).Methods()[0]
+	require.Equal(t, "int", method.Types().Param("n").String())
+	require.Equal(t, "nil", method.Types().Result().String())
+
+	tests := []struct {
+		input      string
+		annotation string
+		str        string
+	}{
+		{"var x: int = 1", "int", "var x: int = 1"},
+		{"x: float := 1.5", "float", "x: float := 1.5"},
+		{"x := 1", "", "x := 1"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(tt.input)
+		require.Nil(t, err)
+		node, ok := program.First().(*ast.Var)
+		require.True(t, ok)
+		if tt.annotation == "" {
+			require.Nil(t, node.TypeAnnotation())
+		} else {
+			require.Equal(t, tt.annotation, node.TypeAnnotation().String())
+		}
+		require.Equal(t, tt.str, node.String())
+	}
+	program, err = Parse("const c: string = \"c\"")
+	require.Nil(t, err)
+	node, ok := program.First().(*ast.Const)
+	require.True(t, ok)
+	require.Equal(t, "string", node.TypeAnnotation().String())
+	require.Equal(t, "const c: string = \"c\"", node.String())
+}
+
 func TestIn(t *testing.T) {
 	program, err := Parse("x in [1, 2]")
 	require.Nil(t, err)
@@ -834,6 +1332,49 @@ func TestBadInputs(t *testing.T) {
 		{"range", `parse error: invalid range expression`},
 		{"in", `parse error: invalid syntax (unexpected "in")`},
 		{"x in", `parse error: invalid in expression`},
+		{"go x", `parse error: go statement requires a function call`},
+		{"defer x", `parse error: defer statement requires a function call`},
+		{"select {\ncase x:\n  y\n}", `parse error: select case must be a channel send or recv call`},
+		{"select {\ncase x := ch.send(1):\n  y\n}", `parse error: select case must be a channel send or recv call`},
+		{"struct {}", `parse error: unexpected { while parsing struct (expected identifier)`},
+		{"struct P { x, x }", `parse error: duplicate field or method x in struct P`},
+		{"struct P { 1 }", `parse error: unexpected 1 in struct declaration`},
+		{"struct P { func f() {} }", `parse error: unexpected f while parsing method (expected ()`},
+		{"struct P {", `parse error: unterminated struct declaration`},
+		{"p.x := 1", `parse error: invalid syntax (unexpected ":=")`},
+		{"func(...a, b) {}", `parse error: variadic parameter must be the last parameter`},
+		{"func(...a = 1) {}", `parse error: variadic parameter cannot have a default value`},
+		{"f(...)", `parse error: invalid syntax (unexpected ")")`},
+		{"f(a=1, 2)", `parse error: positional argument follows keyword argument`},
+		{"f(a=1, a=2)", `parse error: duplicate keyword argument a`},
+		{"0b102", `parse error: invalid integer: 0b102`},
+		{"0o8", `parse error: invalid integer: 0o8`},
+		{"0x", `parse error: invalid integer: 0x`},
+		{"1__0", `parse error: invalid integer: 1__0`},
+		{"1_", `parse error: invalid integer: 1_`},
+		{"0x1__0", `parse error: invalid integer: 0x1__0`},
+		{"match x {\ncase [a, a]:\n  1\n}", `parse error: variable "a" is bound more than once in pattern`},
+		{"match x {\ncase a, 2:\n  1\n}", `parse error: match case with multiple patterns cannot bind variables`},
+		{"match x {\ncase f():\n  1\n}", `parse error: invalid pattern (got ))`},
+		{"match x {\ncase -a:\n  1\n}", `parse error: invalid pattern (got (-a))`},
+		{"match x {\ncase {[1]: a}:\n  1\n}", `parse error: expected a literal key in map pattern (got [)`},
+		{"match x {\ncase {-a: b}:\n  1\n}", `parse error: expected a literal key in map pattern (got -)`},
+		{"match x {\ncase [...a, b]:\n  1\n}", `parse error: unexpected , while parsing pattern (expected ])`},
+		{"match x {\ndefault:\n  1\ndefault:\n  2\n}", `parse error: match expression has multiple default blocks`},
+		{"[a, [b, a]] := x", `parse error: variable "a" is bound more than once in pattern`},
+		{"a, ...b, c := x", `parse error: unexpected , while parsing declaration statement (expected :=)`},
+		{"[x for x, y, z in q]", `parse error: comprehension expected one or two variables`},
+		{"x[1:2] += y", `parse error: unsupported operator for slice assignment: +=`},
+		{"x[1:2:3:4]", `parse error: unexpected : while parsing an index expression (expected ])`},
+		{"[x for a, a in q]", `parse error: variable "a" is bound more than once in pattern`},
+		{"[1, x for x in q]", `parse error: list comprehension must have a single item`},
+		{"[x for x of q]", `parse error: expected in after comprehension variables (got of)`},
+		{"{x for x in q", `parse error: unexpected end of file while parsing set comprehension (expected })`},
+		{"yield 1", `parse error: yield outside function`},
+		{"func f(x:) {}", `parse error: unexpected ) while parsing function parameters (expected identifier)`},
+		{"func f() -> {}", `parse error: unexpected { while parsing function (expected identifier)`},
+		{"x: int = 1", `parse error: unexpected = while parsing declaration statement (expected :=)`},
+		{"var a, b: int = [1, 2]", `parse error: unexpected : while parsing var statement (expected =)`},
 	}
 	for _, tt := range tests {
 		program, err := Parse(tt.input)
@@ -13,8 +13,8 @@ const (
 	TERNARY     // ? :
 	EQUALS      // == or !=
 	LESSGREATER // > or <
-	SUM         // + or -
-	PRODUCT     // * or /
+	SUM         // + or - or ^
+	PRODUCT     // * or / or & or << or >>
 	POWER       // **
 	MOD         // %
 	PREFIX      // -X or !X
@@ -27,31 +27,43 @@ const (
 
 // Precedences for each token type
 var precedences = map[token.Type]int{
-	token.QUESTION:        TERNARY,
-	token.ASSIGN:          ASSIGN,
-	token.DECLARE:         DECLARE,
-	token.EQ:              EQUALS,
-	token.NOT_EQ:          EQUALS,
-	token.LT:              LESSGREATER,
-	token.LT_EQUALS:       LESSGREATER,
-	token.GT:              LESSGREATER,
-	token.GT_EQUALS:       LESSGREATER,
-	token.PLUS:            SUM,
-	token.PLUS_EQUALS:     SUM,
-	token.MINUS:           SUM,
-	token.MINUS_EQUALS:    SUM,
-	token.SLASH:           PRODUCT,
-	token.SLASH_EQUALS:    PRODUCT,
-	token.ASTERISK:        PRODUCT,
-	token.ASTERISK_EQUALS: PRODUCT,
-	token.POW:             POWER,
-	token.MOD:             MOD,
-	token.AND:             COND,
-	token.OR:              COND,
-	token.PIPE:            PIPE,
-	token.LPAREN:          CALL,
-	token.PERIOD:          CALL,
-	token.LBRACKET:        INDEX,
-	token.IN:              IN,
-	token.RANGE:           RANGE,
+	token.QUESTION:         TERNARY,
+	token.ASSIGN:           ASSIGN,
+	token.DECLARE:          DECLARE,
+	token.EQ:               EQUALS,
+	token.NOT_EQ:           EQUALS,
+	token.LT:               LESSGREATER,
+	token.LT_EQUALS:        LESSGREATER,
+	token.GT:               LESSGREATER,
+	token.GT_EQUALS:        LESSGREATER,
+	token.PLUS:             SUM,
+	token.PLUS_EQUALS:      SUM,
+	token.MINUS:            SUM,
+	token.MINUS_EQUALS:     SUM,
+	token.SLASH:            PRODUCT,
+	token.SLASH_EQUALS:     PRODUCT,
+	token.ASTERISK:         PRODUCT,
+	token.ASTERISK_EQUALS:  PRODUCT,
+	token.CARET:            SUM,
+	token.CARET_EQUALS:     SUM,
+	token.AMPERSAND:        PRODUCT,
+	token.AMPERSAND_EQUALS: PRODUCT,
+	token.AND_NOT:          PRODUCT,
+	token.AND_NOT_EQUALS:   PRODUCT,
+	token.LT_LT:            PRODUCT,
+	token.LT_LT_EQUALS:     PRODUCT,
+	token.GT_GT:            PRODUCT,
+	token.GT_GT_EQUALS:     PRODUCT,
+	token.PIPE_EQUALS:      SUM,
+	token.POW:              POWER,
+	token.MOD:              MOD,
+	token.AND:              COND,
+	token.OR:               COND,
+	token.PIPE:             PIPE,
+	token.LPAREN:           CALL,
+	token.PROPAGATE:        CALL,
+	token.PERIOD:           CALL,
+	token.LBRACKET:         INDEX,
+	token.IN:               IN,
+	token.RANGE:            RANGE,
 }
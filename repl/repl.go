@@ -2,6 +2,7 @@ package repl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -60,12 +61,18 @@ func Run(ctx context.Context, sc *scope.Scope) error {
 		return clearLine + ">>> " + accumulate
 	}
 
+	// Set when a program calls exit, which ends the session once the
+	// terminal is restored
+	var exitErr *object.ExitError
+
 	// This could certainly use a refactor! But it works for now.
-	return keyboard.Listen(func(key keys.Key) (stop bool, err error) {
+	err = keyboard.Listen(func(key keys.Key) (stop bool, err error) {
 		switch key.Code {
 		case keys.Enter:
 			fmt.Printf("\n")
-			execute(ctx, accumulate, sc)
+			if _, err := execute(ctx, accumulate, sc); errors.As(err, &exitErr) {
+				return true, nil
+			}
 			appendToHistory(accumulate)
 			history = append(history, accumulate)
 			historyIndex = len(history)
@@ -158,6 +165,13 @@ func Run(ctx context.Context, sc *scope.Scope) error {
 		}
 		return false, nil
 	})
+	if err != nil {
+		return err
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return nil
 }
 
 func execute(ctx context.Context, code string, sc *scope.Scope) (object.Object, error) {
@@ -168,7 +182,10 @@ func execute(ctx context.Context, code string, sc *scope.Scope) (object.Object,
 		Importer:          &evaluator.SimpleImporter{},
 	})
 	if err != nil {
-		color.Red(err.Error())
+		var exitErr *object.ExitError
+		if !errors.As(err, &exitErr) {
+			color.Red(err.Error())
+		}
 		return nil, err
 	}
 	switch result.(type) {
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/token"
+)
+
+// Diagnostic is a problem found by the resolver. It carries the position of
+// the offending code and implements parser.ParserError, so it can be
+// reported the same way as a syntax error.
+type Diagnostic struct {
+	*parser.BaseParserError
+	warning bool
+}
+
+// IsWarning returns true if the diagnostic doesn't prevent the program from
+// running, as is the case for shadowed variables.
+func (d *Diagnostic) IsWarning() bool {
+	return d.warning
+}
+
+// DiagnosticOpts describes a diagnostic created with NewDiagnostic.
+type DiagnosticOpts struct {
+	// ErrType is the kind of problem, such as "name error".
+	ErrType string
+
+	// Message describes the problem.
+	Message string
+
+	// Token is the offending code.
+	Token token.Token
+
+	// File is the name of the file the program was parsed from (optional).
+	File string
+
+	// Input is the source code of the program (optional). If set, the
+	// offending line is included in the diagnostic.
+	Input string
+
+	// Warning is true if the problem doesn't prevent the program from running.
+	Warning bool
+}
+
+// NewDiagnostic returns a diagnostic for a problem found in a program. It
+// is used by other packages that check programs before they run.
+func NewDiagnostic(opts DiagnosticOpts) *Diagnostic {
+	return &Diagnostic{
+		BaseParserError: parser.NewParserError(parser.ErrorOpts{
+			ErrType:       opts.ErrType,
+			Message:       opts.Message,
+			File:          opts.File,
+			StartPosition: opts.Token.StartPosition,
+			EndPosition:   opts.Token.EndPosition,
+			SourceCode:    sourceLine(opts.Input, opts.Token.StartPosition),
+		}),
+		warning: opts.Warning,
+	}
+}
+
+func (r *resolver) fail(tok token.Token, errType, format string, args ...interface{}) {
+	r.report(tok, errType, fmt.Sprintf(format, args...), false)
+}
+
+func (r *resolver) warn(tok token.Token, format string, args ...interface{}) {
+	r.report(tok, "warning", fmt.Sprintf(format, args...), true)
+}
+
+func (r *resolver) report(tok token.Token, errType, message string, warning bool) {
+	// A statement like "x++" is parsed as the identifier followed by the
+	// postfix operator, which would otherwise report the same problem twice
+	if n := len(r.diagnostics); n > 0 {
+		last := r.diagnostics[n-1]
+		if last.Message() == message && last.StartPosition() == tok.StartPosition {
+			return
+		}
+	}
+	r.diagnostics = append(r.diagnostics, NewDiagnostic(DiagnosticOpts{
+		ErrType: errType,
+		Message: message,
+		Token:   tok,
+		File:    r.opts.File,
+		Input:   r.opts.Input,
+		Warning: warning,
+	}))
+}
+
+// sourceLine returns the line of the input at the given position.
+func sourceLine(input string, pos token.Position) string {
+	if input == "" {
+		return ""
+	}
+	// Positions are measured in runes, the same as in the lexer
+	runes := []rune(input)
+	if pos.LineStart < 0 || pos.LineStart > len(runes) {
+		return ""
+	}
+	line := string(runes[pos.LineStart:])
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return line
+}
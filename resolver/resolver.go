@@ -0,0 +1,550 @@
+// Package resolver checks the names used by a Tamarin program before it
+// runs. It reports undefined names, assignments to constants and variables
+// that shadow others, and records where each variable is stored so that the
+// evaluator can find it without looking it up by name.
+package resolver
+
+import (
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/token"
+)
+
+// Opts configures the resolver.
+type Opts struct {
+	// Scope is the scope the program will be evaluated in. Its variables,
+	// such as the modules added by exec.AutoImport, are treated as defined.
+	Scope *scope.Scope
+
+	// If set to true, the default builtins are not treated as defined.
+	DisableDefaultBuiltins bool
+
+	// Supplies extra builtins that are treated as defined.
+	Builtins []*object.Builtin
+
+	// File is the name of the file the program was parsed from (optional).
+	File string
+
+	// Input is the source code of the program (optional). If set, the
+	// offending line is included in each diagnostic.
+	Input string
+
+	// Slots receives the location of each variable referred to (optional),
+	// which lets the evaluator find it without looking it up by name when
+	// passed as evaluator.Opts.Slots.
+	Slots evaluator.Slots
+}
+
+// variable is a variable declared by the program.
+type variable struct {
+	name     string
+	slot     int
+	readOnly bool
+}
+
+// block mirrors one scope.Scope created while evaluating the program: the
+// program's own scope, a function scope, or the scopes created by for loops,
+// match cases and comprehensions. Variables are numbered in the order they
+// are declared in the source, which is normally the order the evaluator
+// declares them at runtime.
+type block struct {
+	parent    *block
+	variables map[string]*variable
+	count     int
+}
+
+func newBlock(parent *block, count int) *block {
+	return &block{parent: parent, variables: map[string]*variable{}, count: count}
+}
+
+type resolver struct {
+	opts        Opts
+	builtins    map[string]bool
+	block       *block
+	diagnostics []*Diagnostic
+}
+
+// Resolve checks the names used by the program and records the slot of
+// each variable referred to in opts.Slots. The program isn't modified, so it
+// may be resolved while it is being evaluated. The diagnostics are returned
+// in the order they were found, with warnings mixed in with errors.
+//
+// Names are visible throughout the scope that declares them, so that a
+// function may call one declared after it.
+func Resolve(program *ast.Program, opts Opts) []*Diagnostic {
+	r := &resolver{opts: opts, builtins: map[string]bool{}}
+	if !opts.DisableDefaultBuiltins {
+		for _, b := range evaluator.GlobalBuiltins() {
+			r.builtins[b.Key()] = true
+		}
+	}
+	for _, b := range opts.Builtins {
+		r.builtins[b.Key()] = true
+	}
+	// Variables declared by the program follow those already in the scope
+	count := 0
+	if opts.Scope != nil {
+		count = len(opts.Scope.Keys())
+	}
+	r.block = newBlock(nil, count)
+	r.hoist(program.Statements())
+	for _, statement := range program.Statements() {
+		r.resolve(statement)
+	}
+	return r.diagnostics
+}
+
+// Errors returns the diagnostics that are not warnings.
+func Errors(diagnostics []*Diagnostic) []*Diagnostic {
+	var errs []*Diagnostic
+	for _, d := range diagnostics {
+		if !d.IsWarning() {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// push enters a new block, returning a function that restores the current one.
+func (r *resolver) push() func() {
+	outer := r.block
+	r.block = newBlock(outer, 0)
+	return func() { r.block = outer }
+}
+
+// declare adds a variable to the current block, warning if it shadows one
+// from an outer block. Names that are already declared in the block are left
+// as they are.
+func (r *resolver) declare(name string, readOnly bool, tok token.Token) {
+	if _, ok := r.block.variables[name]; ok {
+		return
+	}
+	// The blank variable "_" is expected to be reused
+	if r.block.parent != nil && name != "_" {
+		if _, _, ok := r.lookupFrom(r.block.parent, name); ok {
+			r.warn(tok, "%q shadows a variable declared in an outer scope", name)
+		}
+	}
+	r.block.variables[name] = &variable{name: name, slot: r.block.count, readOnly: readOnly}
+	r.block.count++
+}
+
+// lookupFrom finds a variable declared by the program, starting at the given
+// block. The depth is the number of blocks between it and the declaration.
+// Variables of the host scope are found with a nil variable.
+func (r *resolver) lookupFrom(b *block, name string) (*variable, int, bool) {
+	for depth := 0; b != nil; depth++ {
+		if v, ok := b.variables[name]; ok {
+			return v, depth, true
+		}
+		b = b.parent
+	}
+	if r.opts.Scope != nil {
+		if _, ok := r.opts.Scope.Get(name); ok {
+			return nil, 0, true
+		}
+	}
+	return nil, 0, false
+}
+
+// assign checks that the named variable exists and is not read-only.
+func (r *resolver) assign(name string, tok token.Token) {
+	v, _, ok := r.lookupFrom(r.block, name)
+	switch {
+	case !ok:
+		r.fail(tok, "name error", "%q is not defined", name)
+	case v != nil && v.readOnly:
+		r.fail(tok, "assignment error", "%q is read-only", name)
+	case v == nil && r.opts.Scope.IsReadOnly(name):
+		r.fail(tok, "assignment error", "%q is read-only", name)
+	}
+}
+
+// hoist declares the variables declared directly within the given
+// statements. This includes declarations nested in if and switch blocks,
+// since those share the scope of the enclosing block, but excludes those in
+// for loops, match and select cases and functions which get scopes of their
+// own.
+func (r *resolver) hoist(statements []ast.Node) {
+	for _, statement := range statements {
+		switch node := statement.(type) {
+		case *ast.Var:
+			name, _ := node.Value()
+			r.declare(name, false, node.Token())
+		case *ast.Const:
+			name, _ := node.Value()
+			r.declare(name, true, node.Token())
+		case *ast.MultiVar:
+			names, _ := node.Value()
+			for _, name := range names {
+				r.declare(name, false, node.Token())
+			}
+		case *ast.Destructure:
+			if node.IsWalrus() {
+				for _, binding := range ast.BindingPatterns(node.Target()) {
+					r.declare(binding.Name().String(), false, binding.Name().Token())
+				}
+			}
+		case *ast.Func:
+			if node.Name() != nil {
+				r.declare(node.Name().String(), true, node.Name().Token())
+			}
+		case *ast.Struct:
+			r.declare(node.Name().String(), true, node.Name().Token())
+		case *ast.Import:
+			r.declare(node.Module().String(), true, node.Module().Token())
+		case *ast.Assign:
+			if node.Operator() == ":=" && node.Index() == nil && node.Slice() == nil && node.Attr() == nil {
+				r.declare(node.Name(), false, node.Token())
+			}
+		case *ast.If:
+			r.hoist(node.Consequence().Statements())
+			if node.Alternative() != nil {
+				r.hoist(node.Alternative().Statements())
+			}
+		case *ast.Switch:
+			for _, choice := range node.Choices() {
+				r.hoist(choice.Block().Statements())
+			}
+		case *ast.Block:
+			r.hoist(node.Statements())
+		}
+	}
+}
+
+// resolve checks the names used within a node, which may be nil.
+func (r *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+	case *ast.Ident:
+		r.resolveIdent(node)
+	case *ast.Block:
+		for _, statement := range node.Statements() {
+			r.resolve(statement)
+		}
+
+	// Operator expressions
+	case *ast.Prefix:
+		r.resolve(node.Right())
+	case *ast.Postfix:
+		r.assign(node.Literal(), node.Token())
+	case *ast.Infix:
+		r.resolve(node.Left())
+		r.resolve(node.Right())
+	case *ast.Ternary:
+		r.resolve(node.Condition())
+		r.resolve(node.IfTrue())
+		r.resolve(node.IfFalse())
+	case *ast.Propagate:
+		r.resolve(node.Value())
+	case *ast.In:
+		r.resolve(node.Left())
+		r.resolve(node.Right())
+	case *ast.Index:
+		r.resolve(node.Left())
+		r.resolve(node.Index())
+	case *ast.Slice:
+		r.resolve(node.Left())
+		r.resolve(node.FromIndex())
+		r.resolve(node.ToIndex())
+		r.resolve(node.Step())
+	case *ast.SliceLiteral:
+		r.resolve(node.FromIndex())
+		r.resolve(node.ToIndex())
+		r.resolve(node.Step())
+
+	// Assignment
+	case *ast.Var:
+		name, value := node.Value()
+		r.resolve(value)
+		r.declare(name, false, node.Token())
+	case *ast.Const:
+		name, value := node.Value()
+		r.resolve(value)
+		r.declare(name, true, node.Token())
+	case *ast.MultiVar:
+		names, value := node.Value()
+		r.resolve(value)
+		for _, name := range names {
+			r.declare(name, false, node.Token())
+		}
+	case *ast.Assign:
+		r.resolveAssign(node)
+	case *ast.Destructure:
+		r.resolve(node.Value())
+		r.resolvePattern(node.Target())
+		for _, binding := range ast.BindingPatterns(node.Target()) {
+			name := binding.Name()
+			if node.IsWalrus() {
+				r.declare(name.String(), false, name.Token())
+			} else {
+				r.assign(name.String(), name.Token())
+			}
+		}
+	case *ast.Import:
+		r.declare(node.Module().String(), true, node.Module().Token())
+
+	// Functions
+	case *ast.Func:
+		if node.Name() != nil {
+			r.declare(node.Name().String(), true, node.Name().Token())
+		}
+		r.resolveFunc(node)
+	case *ast.Struct:
+		for _, field := range node.Fields() {
+			r.resolve(node.Defaults()[field.String()])
+		}
+		for _, method := range node.Methods() {
+			r.resolveFunc(method)
+		}
+		r.declare(node.Name().String(), true, node.Name().Token())
+
+	// Calls
+	case *ast.Call:
+		r.resolve(node.Function())
+		r.resolveExpressions(node.Arguments())
+	case *ast.ObjectCall:
+		// The name of the method is not a variable
+		r.resolve(node.Object())
+		if call, ok := node.Call().(*ast.Call); ok {
+			r.resolveExpressions(call.Arguments())
+		}
+	case *ast.GetAttr:
+		r.resolve(node.Object())
+	case *ast.Spread:
+		r.resolve(node.Value())
+	case *ast.KeywordArg:
+		r.resolve(node.Value())
+
+	// Control
+	case *ast.If:
+		r.resolve(node.Condition())
+		r.resolve(node.Consequence())
+		if node.Alternative() != nil {
+			r.resolve(node.Alternative())
+		}
+	case *ast.For:
+		r.resolveFor(node)
+	case *ast.Switch:
+		r.resolve(node.Value())
+		for _, choice := range node.Choices() {
+			r.resolveExpressions(choice.Expressions())
+			r.resolve(choice.Block())
+		}
+	case *ast.Select:
+		for _, choice := range node.Cases() {
+			if !choice.IsDefault() {
+				r.resolve(choice.Channel())
+				r.resolve(choice.Value())
+			}
+			pop := r.push()
+			for _, name := range choice.Names() {
+				r.declare(name.String(), false, name.Token())
+			}
+			r.hoist(choice.Block().Statements())
+			r.resolve(choice.Block())
+			pop()
+		}
+	case *ast.Match:
+		r.resolveMatch(node)
+	case *ast.Yield:
+		r.resolve(node.Value())
+	case *ast.Go:
+		r.resolve(node.Call())
+	case *ast.Defer:
+		r.resolve(node.Call())
+	case *ast.Pipe:
+		r.resolveExpressions(node.Expressions())
+	case *ast.Control:
+		r.resolve(node.Value())
+	case *ast.Range:
+		r.resolve(node.Container())
+
+	// Literals
+	case *ast.String:
+		r.resolveExpressions(node.TemplateExpressions())
+	case *ast.List:
+		r.resolveExpressions(node.Items())
+	case *ast.Set:
+		r.resolveExpressions(node.Items())
+	case *ast.Map:
+		for key, value := range node.Items() {
+			// A key that is an identifier is used as a string
+			if _, ok := key.(*ast.Ident); !ok {
+				r.resolve(key)
+			}
+			r.resolve(value)
+		}
+	case *ast.ListComprehension:
+		r.resolveClauses(node.Clauses(), func() { r.resolve(node.Element()) })
+	case *ast.SetComprehension:
+		r.resolveClauses(node.Clauses(), func() { r.resolve(node.Element()) })
+	case *ast.MapComprehension:
+		r.resolveClauses(node.Clauses(), func() {
+			r.resolve(node.Key())
+			r.resolve(node.Value())
+		})
+	}
+}
+
+func (r *resolver) resolveExpressions(exprs []ast.Expression) {
+	for _, expr := range exprs {
+		r.resolve(expr)
+	}
+}
+
+func (r *resolver) resolveIdent(node *ast.Ident) {
+	name := node.String()
+	v, depth, ok := r.lookupFrom(r.block, name)
+	if v != nil {
+		if r.opts.Slots != nil {
+			r.opts.Slots[node] = evaluator.Slot{Depth: depth, Index: v.slot}
+		}
+		return
+	}
+	if ok || r.builtins[name] {
+		return
+	}
+	r.fail(node.Token(), "name error", "%q is not defined", name)
+}
+
+func (r *resolver) resolveAssign(node *ast.Assign) {
+	switch {
+	case node.Attr() != nil:
+		r.resolve(node.Attr().Object())
+	case node.Index() != nil:
+		r.resolve(node.Index())
+	case node.Slice() != nil:
+		r.resolve(node.Slice())
+	}
+	r.resolve(node.Value())
+	if node.Attr() != nil || node.Index() != nil || node.Slice() != nil {
+		return
+	}
+	if node.Operator() == ":=" {
+		r.declare(node.Name(), false, node.Token())
+		return
+	}
+	r.assign(node.Name(), node.Token())
+}
+
+// resolvePattern resolves the default values of a destructuring pattern.
+func (r *resolver) resolvePattern(pattern ast.Pattern) {
+	for _, binding := range ast.BindingPatterns(pattern) {
+		r.resolve(binding.Default())
+	}
+}
+
+// resolveFunc resolves a function literal or method. Default values are
+// evaluated in the scope the function was defined in, while its parameters
+// and body get a scope of their own. Parameters with defaults are declared
+// first, followed by a variadic parameter and then the rest, which is the
+// order they are declared by the evaluator.
+func (r *resolver) resolveFunc(node *ast.Func) {
+	defaults := node.Defaults()
+	params := node.Parameters()
+	for _, param := range params {
+		if value, ok := defaults[param.String()]; ok {
+			r.resolve(value)
+		}
+	}
+	defer r.push()()
+	for _, param := range params {
+		if _, ok := defaults[param.String()]; ok {
+			r.declare(param.String(), false, param.Token())
+		}
+	}
+	if node.Variadic() && len(params) > 0 {
+		param := params[len(params)-1]
+		r.declare(param.String(), false, param.Token())
+	}
+	for _, param := range params {
+		r.declare(param.String(), false, param.Token())
+	}
+	r.hoist(node.Body().Statements())
+	r.resolve(node.Body())
+}
+
+// resolveFor resolves a for loop. Like the evaluator, the loop gets two
+// scopes: one for the init, condition and post statements, and one for the
+// loop body. The container of an iterator loop is evaluated in the scope of
+// the loop body, before its variables are declared.
+func (r *resolver) resolveFor(node *ast.For) {
+	defer r.push()()
+	if init := node.Init(); init != nil {
+		r.hoist([]ast.Node{init})
+		r.resolve(init)
+	}
+	if !node.IsSimpleLoop() && !node.IsIteratorLoop() {
+		r.resolve(node.Condition())
+		if post := node.Post(); post != nil {
+			r.resolve(post)
+		}
+	}
+	defer r.push()()
+	if node.IsIteratorLoop() {
+		switch cond := node.Condition().(type) {
+		case *ast.Var:
+			name, value := cond.Value()
+			r.resolve(value)
+			r.declare(name, false, cond.Token())
+		case *ast.MultiVar:
+			names, value := cond.Value()
+			r.resolve(value)
+			for _, name := range names {
+				r.declare(name, false, cond.Token())
+			}
+		case *ast.Destructure:
+			r.resolve(cond.Value())
+			for _, target := range evaluator.LoopTargets(cond) {
+				r.resolvePattern(target)
+				for _, binding := range ast.BindingPatterns(target) {
+					r.declare(binding.Name().String(), false, binding.Name().Token())
+				}
+			}
+		}
+	}
+	r.hoist(node.Consequence().Statements())
+	r.resolve(node.Consequence())
+}
+
+// resolveMatch resolves a match expression. Each case gets a scope holding
+// the variables bound by its pattern.
+func (r *resolver) resolveMatch(node *ast.Match) {
+	r.resolve(node.Value())
+	for _, choice := range node.Cases() {
+		pop := r.push()
+		if patterns := choice.Patterns(); len(patterns) > 0 {
+			for _, binding := range ast.BindingPatterns(patterns[0]) {
+				r.declare(binding.Name().String(), false, binding.Name().Token())
+			}
+		}
+		r.resolve(choice.Guard())
+		r.hoist(choice.Block().Statements())
+		r.resolve(choice.Block())
+		pop()
+	}
+}
+
+// resolveClauses resolves the clauses of a comprehension. Each clause gets a
+// scope holding its variables, nested within the scope of the clause before
+// it, and the body is resolved in the innermost scope.
+func (r *resolver) resolveClauses(clauses []*ast.ComprehensionClause, body func()) {
+	if len(clauses) == 0 {
+		body()
+		return
+	}
+	clause := clauses[0]
+	r.resolve(clause.Iterable())
+	defer r.push()()
+	for _, target := range clause.Targets() {
+		r.resolvePattern(target)
+		for _, binding := range ast.BindingPatterns(target) {
+			r.declare(binding.Name().String(), false, binding.Name().Token())
+		}
+	}
+	r.resolve(clause.Condition())
+	r.resolveClauses(clauses[1:], body)
+}
@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/stretchr/testify/require"
+)
+
+func resolve(t *testing.T, input string, s *scope.Scope) (*ast.Program, []string) {
+	t.Helper()
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	var messages []string
+	for _, d := range Resolve(program, Opts{Scope: s}) {
+		messages = append(messages, d.Error())
+	}
+	return program, messages
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x := 1; x + len([])", nil},
+		{"y + 1", []string{`name error: "y" is not defined`}},
+		{"func f() { g() }; func g() { 1 }", nil},
+		{"const a = 1; a = 2", []string{`assignment error: "a" is read-only`}},
+		{"func f() {}; f += 1", []string{`assignment error: "f" is read-only`}},
+		{"b = 2", []string{`name error: "b" is not defined`}},
+		{"c++", []string{`name error: "c" is not defined`}},
+		{"len = 1", []string{`name error: "len" is not defined`}},
+		{"x := 1; func f(x) { x }", []string{`warning: "x" shadows a variable declared in an outer scope`}},
+		{"x := 1; [x for x in [2]]", []string{`warning: "x" shadows a variable declared in an outer scope`}},
+		{"for _, v := range [1] { w := v }; w", []string{`name error: "w" is not defined`}},
+		{"for _, x := range x { 1 }", []string{`name error: "x" is not defined`}},
+		{"if true { z := 1 }; z", nil},
+		{`{foo: 1, "bar": foo}`, []string{`name error: "foo" is not defined`}},
+		{`x := "a"; x.upper(); f(key=1)`, []string{`name error: "f" is not defined`}},
+		{"struct P { a = q; func (p) m() { p.a } }", []string{`name error: "q" is not defined`}},
+		{"match 1 { case [a, b]: a + b; default: a }", []string{`name error: "a" is not defined`}},
+		{"[a, b] := [1, 2]; [b, a] = [a, b]", nil},
+		{"const a = 1; [a] = [2]", []string{`assignment error: "a" is read-only`}},
+		{"func f(a, b=a) { b }", []string{`name error: "a" is not defined`}},
+		{"math.abs(-1)", []string{`name error: "math" is not defined`}},
+		{"'{missing}'", []string{`name error: "missing" is not defined`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, messages := resolve(t, tt.input, nil)
+			require.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+func TestHostScope(t *testing.T) {
+	s := scope.New(scope.Opts{})
+	require.Nil(t, s.Declare("math", object.NewString("module"), true))
+	require.Nil(t, s.Declare("input", object.NewInt(1), false))
+	_, messages := resolve(t, "input = math; math = 1", s)
+	require.Equal(t, []string{`assignment error: "math" is read-only`}, messages)
+}
+
+func TestBuiltins(t *testing.T) {
+	program, err := parser.Parse("len(custom())")
+	require.Nil(t, err)
+	custom := object.NewBuiltin("custom", func(ctx context.Context, args ...object.Object) object.Object {
+		return object.NewList(nil)
+	}, nil)
+	require.Len(t, Resolve(program, Opts{Builtins: []*object.Builtin{custom}}), 0)
+	diagnostics := Resolve(program, Opts{DisableDefaultBuiltins: true})
+	require.Len(t, diagnostics, 2)
+	require.Equal(t, `name error: "len" is not defined`, diagnostics[0].Error())
+}
+
+func TestPosition(t *testing.T) {
+	input := "x := 1\nfunc f() {\n  return x + y\n}"
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	diagnostics := Resolve(program, Opts{File: "main.tm", Input: input})
+	require.Len(t, diagnostics, 1)
+	d := diagnostics[0]
+	require.False(t, d.IsWarning())
+	require.Equal(t, "main.tm", d.File())
+	require.Equal(t, 3, d.StartPosition().LineNumber())
+	require.Equal(t, 14, d.StartPosition().ColumnNumber())
+	require.Equal(t, "  return x + y", d.SourceCode())
+	var parserErr parser.ParserError = d
+	require.NotNil(t, parserErr)
+}
+
+func TestSlots(t *testing.T) {
+	s := scope.New(scope.Opts{})
+	require.Nil(t, s.Declare("input", object.NewInt(10), false))
+	program, err := parser.Parse("a := 1\nfunc f(b, c=2) { [a, b, c, input] }")
+	require.Nil(t, err)
+	found := evaluator.Slots{}
+	require.Len(t, Resolve(program, Opts{Scope: s, Slots: found}), 0)
+	fn := program.Statements()[1].(*ast.Func)
+	list := fn.Body().Statements()[0].(*ast.List)
+	var slots [][2]int
+	for _, item := range list.Items() {
+		if slot, ok := found[item.(*ast.Ident)]; ok {
+			slots = append(slots, [2]int{slot.Depth, slot.Index})
+		}
+	}
+	// Variables of the host scope are looked up by name. Defaults are
+	// declared before the other parameters.
+	require.Equal(t, [][2]int{{1, 1}, {0, 1}, {0, 0}}, slots)
+}
+
+func TestEvaluate(t *testing.T) {
+	input := `
+	total := 0
+	func add(n) {
+		total += n
+		return total
+	}
+	func counter() {
+		count := 0
+		return func() {
+			count++
+			return count
+		}
+	}
+	next := counter()
+	for i := 0; i < 3; i++ {
+		add(i)
+		next()
+	}
+	squares := {k: v * v for k, v in {"a": 2, "b": 3}}
+	[total, next(), squares, match [1, 2] { case [x, y]: x + y }]
+	`
+	program, err := parser.Parse(input)
+	require.Nil(t, err)
+	s := scope.New(scope.Opts{})
+	slots := evaluator.Slots{}
+	require.Len(t, Errors(Resolve(program, Opts{Scope: s, Slots: slots})), 0)
+	result := evaluator.New(evaluator.Opts{Slots: slots}).Evaluate(context.Background(), program, s)
+	require.Equal(t, `[3, 4, {"a": 4, "b": 9}, 3]`, result.Inspect())
+}
@@ -3,21 +3,33 @@ package scope
 import (
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/cloudcmds/tamarin/object"
 )
 
-// Scope stores our functions, variables, constants, etc.
+// Scope stores our functions, variables, constants, etc. A Scope is safe
+// for use by multiple goroutines.
 type Scope struct {
+	// guards slots, names, values, readOnly and types
+	mutex sync.RWMutex
+
 	// name of the scope
 	name string
 
-	// holds variables, including functions
-	store map[string]object.Object
+	// maps the name of each variable, including functions, to its slot
+	slots map[string]int
+
+	// the names and values of the variables, in the order they were declared
+	names  []string
+	values []object.Object
 
 	// marks named variables as read-only
 	readOnly map[string]bool
 
+	// the type annotations of variables declared with one
+	types map[string]string
+
 	// optional parent environment
 	parent *Scope
 }
@@ -32,8 +44,9 @@ func New(opts Opts) *Scope {
 	return &Scope{
 		name:     opts.Name,
 		parent:   opts.Parent,
-		store:    map[string]object.Object{},
+		slots:    map[string]int{},
 		readOnly: map[string]bool{},
+		types:    map[string]string{},
 	}
 }
 
@@ -42,11 +55,20 @@ func (s *Scope) Name() string {
 }
 
 func (s *Scope) IsReadOnly(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return s.readOnly[name]
 }
 
 func (s *Scope) Get(name string) (object.Object, bool) {
-	if obj, ok := s.store[name]; ok {
+	s.mutex.RLock()
+	slot, ok := s.slots[name]
+	var obj object.Object
+	if ok {
+		obj = s.values[slot]
+	}
+	s.mutex.RUnlock()
+	if ok {
 		return obj, true
 	}
 	if s.parent != nil {
@@ -55,25 +77,79 @@ func (s *Scope) Get(name string) (object.Object, bool) {
 	return nil, false
 }
 
+// GetAt returns the variable in the given slot of the scope that is depth
+// levels above this one, as found by the resolver package. Slots are
+// numbered in the order variables are declared. If the slot doesn't hold the
+// named variable, for example because declarations ran in a different order
+// than they appear in the source, false is returned and the caller should
+// fall back to Get.
+func (s *Scope) GetAt(depth, slot int, name string) (object.Object, bool) {
+	target := s
+	for ; depth > 0 && target != nil; depth-- {
+		target = target.parent
+	}
+	if target == nil {
+		return nil, false
+	}
+	target.mutex.RLock()
+	defer target.mutex.RUnlock()
+	if slot >= len(target.names) || target.names[slot] != name {
+		return nil, false
+	}
+	return target.values[slot], true
+}
+
 func (s *Scope) Declare(name string, obj object.Object, readOnly bool) error {
-	if _, exists := s.store[name]; exists {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.slots[name]; exists {
 		return fmt.Errorf("assignment error: %q is already set", name)
 	}
-	s.store[name] = obj
+	s.slots[name] = len(s.names)
+	s.names = append(s.names, name)
+	s.values = append(s.values, obj)
 	if readOnly {
 		s.readOnly[name] = true
 	}
 	return nil
 }
 
+// Annotate records the type annotation of a variable declared in this scope.
+// The scope doesn't check values itself; callers check each new value of the
+// variable against the annotation returned by Annotation.
+func (s *Scope) Annotate(name, typ string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.types[name] = typ
+}
+
+// Annotation returns the type annotation of the named variable, found in the
+// same way as Get. An empty string is returned if the variable has none.
+func (s *Scope) Annotation(name string) string {
+	s.mutex.RLock()
+	_, ok := s.slots[name]
+	typ := s.types[name]
+	s.mutex.RUnlock()
+	if ok {
+		return typ
+	}
+	if s.parent != nil {
+		return s.parent.Annotation(name)
+	}
+	return ""
+}
+
 func (s *Scope) Update(name string, obj object.Object) error {
-	if _, ok := s.store[name]; ok {
-		if s.IsReadOnly(name) {
+	s.mutex.Lock()
+	if slot, ok := s.slots[name]; ok {
+		defer s.mutex.Unlock()
+		if s.readOnly[name] {
 			return fmt.Errorf("assignment error: %q is read-only", name)
 		}
-		s.store[name] = obj
+		s.values[slot] = obj
 		return nil
 	}
+	s.mutex.Unlock()
 	if s.parent != nil {
 		return s.parent.Update(name, obj)
 	}
@@ -81,18 +157,26 @@ func (s *Scope) Update(name string, obj object.Object) error {
 }
 
 func (s *Scope) Contents() map[string]object.Object {
-	contents := make(map[string]object.Object, len(s.store))
-	for k, v := range s.store {
-		contents[k] = v
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	contents := make(map[string]object.Object, len(s.names))
+	for i, name := range s.names {
+		contents[name] = s.values[i]
 	}
 	return contents
 }
 
 func (s *Scope) Clear() {
-	for k := range s.store {
-		delete(s.store, k)
-		delete(s.readOnly, k)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, name := range s.names {
+		delete(s.slots, name)
+		delete(s.readOnly, name)
+		delete(s.types, name)
+		s.values[i] = nil
 	}
+	s.names = s.names[:0]
+	s.values = s.values[:0]
 }
 
 func (s *Scope) NewChild(opts Opts) *Scope {
@@ -102,10 +186,10 @@ func (s *Scope) NewChild(opts Opts) *Scope {
 }
 
 func (s *Scope) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	var keys []string
-	for k := range s.store {
-		keys = append(keys, k)
-	}
+	keys = append(keys, s.names...)
 	sort.Strings(keys)
 	return keys
 }
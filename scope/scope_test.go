@@ -1 +1,65 @@
 package scope
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentAccess(t *testing.T) {
+	s := New(Opts{Name: "global"})
+	require.Nil(t, s.Declare("counter", object.NewInt(0), false))
+	child := s.NewChild(Opts{Name: "function"})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("x%d", i)
+			require.Nil(t, child.Declare(name, object.NewInt(int64(i)), false))
+			for j := 0; j < 100; j++ {
+				_, ok := child.Get("counter")
+				require.True(t, ok)
+				require.Nil(t, child.Update("counter", object.NewInt(int64(j))))
+				child.Keys()
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, child.Keys(), 10)
+}
+
+func TestGetAt(t *testing.T) {
+	s := New(Opts{Name: "global"})
+	require.Nil(t, s.Declare("a", object.NewInt(1), false))
+	require.Nil(t, s.Declare("b", object.NewInt(2), false))
+	child := s.NewChild(Opts{Name: "function"})
+	require.Nil(t, child.Declare("c", object.NewInt(3), false))
+
+	value, ok := child.GetAt(1, 1, "b")
+	require.True(t, ok)
+	require.Equal(t, object.NewInt(2), value)
+	value, ok = child.GetAt(0, 0, "c")
+	require.True(t, ok)
+	require.Equal(t, object.NewInt(3), value)
+
+	// The slot must hold the named variable
+	_, ok = child.GetAt(1, 0, "b")
+	require.False(t, ok)
+	_, ok = child.GetAt(0, 1, "c")
+	require.False(t, ok)
+	_, ok = child.GetAt(2, 0, "a")
+	require.False(t, ok)
+
+	// Clearing a scope frees its slots
+	child.Clear()
+	_, ok = child.GetAt(0, 0, "c")
+	require.False(t, ok)
+	require.Nil(t, child.Declare("d", object.NewInt(4), false))
+	value, ok = child.GetAt(0, 0, "d")
+	require.True(t, ok)
+	require.Equal(t, object.NewInt(4), value)
+}
@@ -5,13 +5,23 @@ import (
 	"strings"
 
 	"github.com/cloudcmds/tamarin/ast"
+	"github.com/cloudcmds/tamarin/object"
 	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/token"
 )
 
+// DeferredCall is a function call scheduled by a defer statement.
+type DeferredCall struct {
+	Function  object.Object
+	Arguments []object.Object
+	Kwargs    *object.Map
+}
+
 type Frame struct {
 	name      string
 	statement ast.Statement
 	scope     *scope.Scope
+	deferred  []DeferredCall
 }
 
 type FrameOpts struct {
@@ -40,15 +50,56 @@ func (f *Frame) Name() string {
 	return f.name
 }
 
+// Defer schedules a call to run when the function of this frame returns.
+func (f *Frame) Defer(call DeferredCall) {
+	f.deferred = append(f.deferred, call)
+}
+
+// PopDeferred removes and returns the most recently deferred call. If there
+// are no deferred calls, false is returned.
+func (f *Frame) PopDeferred() (DeferredCall, bool) {
+	size := len(f.deferred)
+	if size == 0 {
+		return DeferredCall{}, false
+	}
+	call := f.deferred[size-1]
+	f.deferred = f.deferred[:size-1]
+	return call, true
+}
+
+// DefaultMaxDepth is the maximum number of frames on a Stack when no other
+// maximum is given. Deeper recursion would risk overflowing the Go stack,
+// which crashes the process instead of returning an error.
+const DefaultMaxDepth = 10000
+
+// Opts configures a Stack.
+type Opts struct {
+	// MaxDepth is the maximum number of frames on the stack. If zero,
+	// DefaultMaxDepth is used.
+	MaxDepth int
+}
+
+// NewDepthError returns the error for a call that would exceed the given
+// maximum depth.
+func NewDepthError(maxDepth int) *object.Error {
+	return object.Errorf("recursion error: maximum call depth exceeded (%d)", maxDepth)
+}
+
 // Stack represents the call stack of a Tamarin program. Push and Pop are called
 // to add and remove frames from the stack, respectively.
 type Stack struct {
-	frames []*Frame
+	frames   []*Frame
+	maxDepth int
 }
 
-// Push adds a new frame to the stack.
-func (s *Stack) Push(f *Frame) {
+// Push adds a new frame to the stack. An error is returned instead if the
+// stack is already at its maximum depth.
+func (s *Stack) Push(f *Frame) *object.Error {
+	if len(s.frames) >= s.maxDepth {
+		return NewDepthError(s.maxDepth)
+	}
 	s.frames = append(s.frames, f)
+	return nil
 }
 
 // Pop removes the top frame from the stack and returns it.
@@ -71,6 +122,11 @@ func (s *Stack) Top() *Frame {
 	return s.frames[size-1]
 }
 
+// MaxDepth returns the maximum number of frames on the stack.
+func (s *Stack) MaxDepth() int {
+	return s.maxDepth
+}
+
 // Size returns the number of frames on the stack.
 func (s *Stack) Size() int {
 	return len(s.frames)
@@ -92,6 +148,26 @@ func (s *Stack) TrackStatement(statement ast.Statement, sc *scope.Scope) *Frame
 	return f
 }
 
+// Trace returns a snapshot of the frames on the stack, outermost first, for
+// use in runtime errors. The position of the top frame is given by the caller
+// since it is more precise than the statement tracked for that frame. Frames
+// that have not executed any statements, like those of builtins, are omitted.
+func (s *Stack) Trace(top token.Position) []object.StackFrame {
+	var frames []object.StackFrame
+	for i, frame := range s.frames {
+		var pos token.Position
+		if i == len(s.frames)-1 {
+			pos = top
+		} else if frame.statement != nil {
+			pos = frame.statement.Token().StartPosition
+		} else {
+			continue
+		}
+		frames = append(frames, object.StackFrame{Name: frame.name, Position: pos})
+	}
+	return frames
+}
+
 func (s *Stack) String() string {
 	var frames []string
 	for i, frame := range s.frames {
@@ -113,7 +189,16 @@ func (s *Stack) String() string {
 	return strings.Join(frames, "\n")
 }
 
-// New returns a new Stack.
+// New returns a new Stack with the default maximum depth.
 func New() *Stack {
-	return &Stack{}
+	return NewWithOpts(Opts{})
+}
+
+// NewWithOpts returns a new Stack configured with the given options.
+func NewWithOpts(opts Opts) *Stack {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	return &Stack{maxDepth: maxDepth}
 }
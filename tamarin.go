@@ -15,6 +15,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -68,6 +69,10 @@ func main() {
 	} else if nArgs == 0 && len(code) == 0 {
 		// Run REPL
 		if err := repl.Run(ctx, globalScope); err != nil {
+			var exitErr *object.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.Code())
+			}
 			fmt.Fprintf(os.Stderr, "%s\n", red(err.Error()))
 			os.Exit(1)
 		}
@@ -109,9 +114,16 @@ func main() {
 		Breakpoints:       breaks,
 	})
 	if err != nil {
-		parserErr, ok := err.(parser.ParserError)
-		if ok {
+		// A script that calls exit ends the process with the given code
+		var exitErr *object.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code())
+		}
+		var runtimeErr *object.RuntimeError
+		if parserErr, ok := err.(parser.ParserError); ok {
 			fmt.Fprintf(os.Stderr, "%s\n", red(parserErr.FriendlyMessage()))
+		} else if errors.As(err, &runtimeErr) {
+			fmt.Fprintf(os.Stderr, "%s\n", red(runtimeErr.FriendlyMessage()))
 		} else {
 			fmt.Fprintf(os.Stderr, "%s\n", red(err.Error()))
 		}
@@ -79,10 +79,11 @@ func getTestCase(name string) (TestCase, error) {
 	return testCase, err
 }
 
-func execute(ctx context.Context, input string) (object.Object, error) {
+func execute(ctx context.Context, input string, backend exec.Backend) (object.Object, error) {
 	result, err := exec.Execute(ctx, exec.Opts{
 		Input:    string(input),
 		Importer: &evaluator.SimpleImporter{},
+		Backend:  backend,
 	})
 	if err != nil {
 		return nil, err
@@ -104,7 +105,18 @@ func listTestFiles() []string {
 	return testFiles
 }
 
+// Every test file is run against each execution backend
+var backends = []exec.Backend{exec.BackendEvaluator, exec.BackendVM}
+
 func TestFiles(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(string(backend), func(t *testing.T) {
+			testFiles(t, backend)
+		})
+	}
+}
+
+func testFiles(t *testing.T, backend exec.Backend) {
 	only := "" // test-2022-12-03-08-12
 	for _, name := range listTestFiles() {
 		if !strings.HasSuffix(name, ".tm") {
@@ -117,7 +129,7 @@ func TestFiles(t *testing.T) {
 			tc, err := getTestCase(name)
 			require.Nil(t, err)
 			ctx := context.Background()
-			result, err := execute(ctx, tc.Text)
+			result, err := execute(ctx, tc.Text, backend)
 			expectedType := object.Type(tc.ExpectedType)
 
 			if tc.ExpectedValue != "" {
@@ -0,0 +1,36 @@
+// go statements, channels, select and wait groups
+// expected value: [55, "done"]
+// expected type: list
+
+results := chan(10)
+wg := sync.wait_group()
+
+func square(n) {
+    results.send(n * n)
+    wg.done()
+}
+
+for i := 1; i <= 5; i++ {
+    wg.add()
+    go square(i)
+}
+wg.wait()
+results.close()
+
+total := 0
+for {
+    select {
+    case value, ok := results.recv():
+        if !ok {
+            break
+        }
+        total += value
+    }
+}
+
+status := chan()
+go func() {
+    status.send("done")
+}()
+
+[total, status.recv()]
@@ -0,0 +1,35 @@
+// structs with fields, methods and constructors
+// expected value: ["Rect", 12, Rect{width: 3, height: 4, label: "box"}, true, "Square", 25]
+// expected type: list
+
+struct Rect {
+    width
+    height
+    label = "rect"
+
+    func (r) area() {
+        return r.width * r.height
+    }
+
+    func (r) rename(label) {
+        r.label = label
+        return r
+    }
+}
+
+struct Square {
+    side, name
+
+    func (s) init(side) {
+        s.side = side
+        s.name = "square"
+    }
+
+    func (s) area() {
+        s.side * s.side
+    }
+}
+
+r := Rect(3, 4)
+sq := Square(5)
+[type(r), r.area(), r.rename("box"), r == Rect(3, 4, "box"), type(sq), sq.area()]
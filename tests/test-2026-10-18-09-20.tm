@@ -0,0 +1,30 @@
+// deferred calls run in LIFO order when the function returns or fails
+// expected value: [["fetch", "release", "close"], ["fetch", "release", "close"], "failed"]
+// expected type: list
+
+steps := []
+
+struct Conn {
+    open = true
+
+    func (c) close() {
+        c.open = false
+        steps.append("close")
+    }
+}
+
+func query(conn, fail) {
+    defer conn.close()
+    defer steps.append("release")
+    steps.append("fetch")
+    if fail {
+        return 1 / 0
+    }
+    return "rows"
+}
+
+query(Conn(), false)
+ok := steps
+steps = []
+result := try(query(Conn(), true), "failed")
+[ok, steps, result]
@@ -0,0 +1,27 @@
+// variadic functions collect extra arguments and lists spread into calls
+// expected value: [6, 16, ["info", []], "warn: disk 90%", [1, [2, 3]]]
+// expected type: list
+
+func sum(...numbers) {
+    total := 0
+    for _, n := range numbers {
+        total += n
+    }
+    return total
+}
+
+func log(level, ...args) {
+    if len(args) == 0 {
+        return [level, args]
+    }
+    return sprintf("%s: " + args[0], level, ...args[1:])
+}
+
+struct Pair {
+    func (p) split(first, ...rest) {
+        [first, rest]
+    }
+}
+
+numbers := [1, 2, 3]
+[sum(...numbers), sum(0, ...numbers, 10), log("info"), log("warn", "disk %d%%", 90), Pair().split(...numbers)]
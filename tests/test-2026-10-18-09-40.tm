@@ -0,0 +1,24 @@
+// keyword arguments bind to parameters, struct fields and builtin options
+// expected value: ["Hello, Ada?", "Hi, Bob!", ["banana", "pear", "fig"], Point{x: 1, y: 4}, 7]
+// expected type: list
+
+func greet(name, greeting = "Hello", punctuation = "!") {
+    return sprintf("%s, %s%s", greeting, name, punctuation)
+}
+
+struct Point {
+    x = 0
+    y = 0
+}
+
+func add(a, b) {
+    a + b
+}
+
+[
+    greet("Ada", punctuation="?"),
+    greet(greeting="Hi", name="Bob"),
+    sorted(["pear", "fig", "banana"], key=len, reverse=true),
+    Point(y=4, x=1),
+    call(add, b=3, a=4),
+]
@@ -0,0 +1,35 @@
+// bitwise operators, shifts and hex/octal/binary literals
+// expected value: [255, 493, 165, 1000000, 8, 15, 7, 4, 1024, 16, -1, 13, 10]
+// expected type: list
+
+const READ = 0b100
+const WRITE = 0b010
+const EXEC = 0b001
+
+func set_flags(mode, flags) {
+    mode |= flags
+    return mode
+}
+
+perms := 0
+perms = set_flags(perms, READ | WRITE)
+perms &^= WRITE
+perms ^= EXEC
+perms <<= 1
+perms |= 0b1000
+
+[
+    0xFF,
+    0o755,
+    0b1010_0101,
+    1_000_000,
+    0xC & 0xA,
+    0b1100 ^ 0b0011,
+    (READ | WRITE) | EXEC,
+    READ & ^WRITE,
+    1 << 10,
+    256 >> 4,
+    ^0,
+    perms + 3,
+    perms,
+]
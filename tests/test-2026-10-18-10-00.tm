@@ -0,0 +1,36 @@
+// ? propagates err results to the caller and unwraps ok results
+// expected value: [ok(42), "invalid digit: x", ok(6), "invalid digit: y", 0, 5, ["21", "x", "1", "2", "1", "y"]]
+// expected type: list
+
+seen := []
+
+func atoi(s) {
+    if s == "x" || s == "y" {
+        return err("invalid digit: " + s)
+    }
+    return ok(int(s))
+}
+
+func parse(s) {
+    defer seen.append(s)
+    n := atoi(s)?
+    return ok(n * 2)
+}
+
+func sum(a, b) {
+    return ok(parse(a)? + parse(b)?)
+}
+
+func guarded(s) {
+    return try(parse(s)?, 0)
+}
+
+[
+    parse("21"),
+    parse("x").err_msg(),
+    sum("1", "2"),
+    sum("1", "y").err_msg(),
+    guarded("x").is_err() ? 0 : 1,
+    ok(5)?,
+    seen[:6],
+]
@@ -0,0 +1,41 @@
+// match routes decoded JSON payloads by their structure
+// expected value: ["user 7 (ada)", "order 3 x 2 = 6", "2 events, first click", "empty batch", "unknown payload: ping", "invalid: [1, 2]", "error"]
+// expected type: list
+
+func handle(payload) {
+    return match payload {
+    case {"kind": "user", "id": id, "profile": {"name": string(name)}}:
+        'user {id} ({name})'
+    case {"kind": "order", "qty": qty, "price": price} if qty > 0:
+        'order {price} x {qty} = {price * qty}'
+    case {"kind": "batch", "events": [{"type": first}, ...rest]}:
+        '{len(rest) + 1} events, first {first}'
+    case {"kind": "batch", "events": []}:
+        "empty batch"
+    case {"kind": string(kind), ..._}:
+        "unknown payload: " + kind
+    case list(items):
+        'invalid: {items}'
+    default:
+        "error"
+    }
+}
+
+messages := [
+    `{"kind": "user", "id": 7, "profile": {"name": "ada"}}`,
+    `{"kind": "order", "qty": 2, "price": 3}`,
+    `{"kind": "batch", "events": [{"type": "click"}, {"type": "scroll"}]}`,
+    `{"kind": "batch", "events": []}`,
+    `{"kind": "ping"}`,
+    `[1, 2]`,
+    `not json`,
+]
+
+messages.map(func(text) {
+    match json.unmarshal(text) {
+    case ok(payload):
+        handle(payload)
+    case err(_):
+        "error"
+    }
+})
@@ -0,0 +1,22 @@
+// destructuring assignments unpack nested lists and maps
+// expected value: ["ada: admin, staff (36)", "bob: guest (0)", [2, 1], ["x", ["y", "z"]]]
+// expected type: list
+
+users := [
+    {"name": "ada", "roles": ["admin", "staff"], "age": 36},
+    {"name": "bob", "roles": ["guest"]},
+]
+
+summaries := []
+for _, {name, roles: [role, ...others], age = 0} := range users {
+    all := [role]
+    all.extend(others)
+    summaries.append('{name}: {", ".join(all)} ({age})')
+}
+
+a, b := [1, 2]
+[a, b] = [b, a]
+
+[head, [first, ...tail]] := ["ignored", ["x", "y", "z"]]
+
+[summaries[0], summaries[1], [a, b], [first, tail]]
@@ -0,0 +1,16 @@
+// comprehensions build lists, maps and sets from other containers
+// expected value: [["ada", "cy"], {"ada": 36, "bob": 17, "cy": 52}, {"eng", "ops"}, [["ada", "go"], ["ada", "sql"], ["cy", "go"]]]
+// expected type: list
+
+people := [
+    {"name": "ada", "age": 36, "team": "eng", "skills": ["go", "sql"]},
+    {"name": "bob", "age": 17, "team": "ops", "skills": []},
+    {"name": "cy", "age": 52, "team": "eng", "skills": ["go"]},
+]
+
+adults := [p["name"] for p in people if p["age"] >= 18]
+ages := {name: age for {name, age} in people}
+teams := {p["team"] for p in people}
+skills := [[p["name"], s] for p in people for s in p["skills"]]
+
+[adults, ages, teams, skills]
@@ -0,0 +1,18 @@
+// slices with steps, slice assignment and slice deletion
+// expected value: [[9, 7, 5, 3, 1], "dlrow", [1, "two", "three", 4, 5, 6, 7, 8, 9], [1, 4, 5, 6, 7, 8, 9], [0, 5, 0, 7, 0, 9]]
+// expected type: list
+
+nums := [1, 2, 3, 4, 5, 6, 7, 8, 9]
+odds := nums[::-2]
+word := "hello world"[:5:-1]
+
+nums[1:3] = ["two", "three"]
+named := nums.copy()
+
+delete(nums, 1:3)
+deleted := nums.copy()
+
+delete(nums, 0)
+nums[::2] = [0, 0, 0]
+
+[odds, word, named, deleted, nums]
@@ -0,0 +1,11 @@
+// maps with non-string keys keep the type of each key
+// expected value: [["bool", "int", "string"], "one", "yes", "int one", {1: 10, 2: 20}, false]
+// expected type: list
+
+m := {1: "one", true: "yes", "1": "string one"}
+m["1"] = "int one"
+
+key_types := m.keys().map(func(k) { type(k) })
+doubled := map([[1, 10], [2, 20]])
+
+[key_types, m[1], m[true], m["1"], doubled, 3 in doubled]
@@ -0,0 +1,27 @@
+// generators produce pages lazily, stopping once enough items are taken
+// expected value: [["a", "b", "c", "d", "e"], 3]
+// expected type: list
+
+pages := [["a", "b"], ["c", "d"], ["e", "f"], ["g"]]
+fetched := 0
+
+func fetch_all() {
+	for _, page := range pages {
+		fetched++
+		for _, item := range page {
+			yield item
+		}
+	}
+}
+
+func take(items, n) {
+	for _, item := range items {
+		if n == 0 {
+			return nil
+		}
+		yield item
+		n--
+	}
+}
+
+[list(take(fetch_all(), 5)), fetched]
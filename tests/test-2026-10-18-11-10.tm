@@ -0,0 +1,16 @@
+// expected error: name error: "totl" is not defined
+// expected error line: 12
+// expected error column: 12
+
+// a misspelled name is reported before any of the program runs
+
+func average(items) {
+    total := 0
+    for _, item := range items {
+        total += item
+    }
+    return totl / len(items)
+}
+
+print("this line never runs")
+average([1, 2, 3])
@@ -0,0 +1,19 @@
+// type annotations are checked before the program runs when the value is
+// known, and when the function is called otherwise
+// expected value: ["3 items", "type error: describe() argument \"count\" must be int (got string)"]
+// expected type: list
+
+func describe(count: int, unit: string = "item") -> string {
+    if count == 1 {
+        return '1 {unit}'
+    }
+    return '{count} {unit}s'
+}
+
+func parse(raw) {
+    return raw
+}
+
+summary: string := describe(3)
+failed := try(describe(parse("4")), func(msg) { msg })
+[summary, failed]
@@ -0,0 +1,16 @@
+// int arithmetic that overflows int64 is promoted to a bigint
+// expected value: ["bigint", "1267650600228229401496703205376", 9223372036854775808, true, 42, "int"]
+// expected type: list
+
+max := 9223372036854775807
+total := max
+total++
+big := 2 ** 100
+[
+    type(big),
+    string(big),
+    total,
+    total - 1 == max,
+    int(bigint("42")),
+    type(int(big / big * 42)),
+]
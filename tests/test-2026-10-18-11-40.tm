@@ -0,0 +1,18 @@
+// decimals avoid the rounding errors of floats in money arithmetic
+// expected value: ["0.30", true, "1.67", "20.29", "decimal"]
+// expected type: list
+
+prices := [decimal("0.10"), decimal("0.20"), decimal("19.99")]
+subtotal := prices[0] + prices[1]
+total := decimal(0)
+for _, price := range prices {
+    total += price
+}
+tax := (total * decimal("0.0825")).round(2, "half_up")
+[
+    string(subtotal),
+    subtotal == decimal("0.3"),
+    string(tax),
+    string(total),
+    type(total),
+]
@@ -0,0 +1,15 @@
+// bytes hold binary data and convert to and from strings with encodings
+// expected value: [8, 137, "PNG", "89504e47", "iVBORw0KGgo=", true, "bytes"]
+// expected type: list
+
+signature := bytes([137, 80, 78, 71, 13, 10, 26, 10])
+encoded := string(signature, "base64")
+[
+    len(signature),
+    signature[0],
+    string(signature[1:4], "ascii"),
+    signature[:4].hex(),
+    encoded,
+    bytes(encoded, "base64") == signature,
+    type(signature),
+]
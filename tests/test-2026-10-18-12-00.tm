@@ -0,0 +1,16 @@
+// runaway recursion fails with an error that try can handle
+// expected value: "recursion error: maximum call depth exceeded (10000) 55"
+// expected type: string
+
+func forever(n) {
+    return forever(n + 1)
+}
+
+func fib(n) {
+    if n < 2 {
+        return n
+    }
+    return fib(n - 1) + fib(n - 2)
+}
+
+try(forever(0), func(e) { string(e) }) + " " + string(fib(10))
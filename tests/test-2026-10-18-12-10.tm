@@ -0,0 +1,26 @@
+// closures created in a loop keep the variables of their own iteration
+// expected value: [[1, 2, 3], [[3, 0], [3, 20]], [101, 102]]
+// expected type: list
+
+byRange := []
+for i, v := range [1, 2, 3] {
+    byRange.append(func() { v })
+}
+
+byCounter := []
+for i := 0; i < 3; i++ {
+    x := i * 10
+    byCounter.append(func() { [i, x] })
+}
+
+byBody := []
+for _, v := range [1, 2] {
+    y := v + 100
+    byBody.append(func() { y })
+}
+
+[
+    [byRange[0](), byRange[1](), byRange[2]()],
+    [byCounter[0](), byCounter[2]()],
+    [byBody[0](), byBody[1]()],
+]
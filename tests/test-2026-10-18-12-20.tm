@@ -0,0 +1,18 @@
+// map patterns match keys of any literal type by their hash key
+// expected value: ["int", "neg", "bool", "str", "none", "one", {"1": "s"}]
+// expected type: list
+
+func kind(m) {
+    match m {
+    case {1: _}: "int"
+    case {-1: _}: "neg"
+    case {true: _}: "bool"
+    case {x: _}: "str"
+    default: "none"
+    }
+}
+
+m := {1: "one", "1": "s"}
+{1: one, ...rest} := m
+
+[kind({1: 0}), kind({-1: 0}), kind({true: 0}), kind({"x": 0}), kind({"1": 0}), one, rest]
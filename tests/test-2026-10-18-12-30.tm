@@ -0,0 +1,24 @@
+// expected value: "a1b2c3|b2c3|0:1"
+// expected type: string
+
+// A single pattern in a range loop is applied to each element
+pairs := [["a", 1], ["b", 2], ["c", 3]]
+out := ""
+for [name, n] := range pairs {
+    out = out + name + string(n)
+}
+
+rest := ""
+for [_, ...tail] := range [pairs] {
+    for [name, n] := range tail {
+        rest = rest + name + string(n)
+    }
+}
+
+// Two targets still receive the index and the element
+indexed := ""
+for i, [_, n] := range pairs[:1] {
+    indexed = string(i) + ":" + string(n)
+}
+
+out + "|" + rest + "|" + indexed
@@ -0,0 +1,51 @@
+// expected value: [0, 1, "closed", "closed", 0, 0, "closed", "caught", "closed", 5, "kept"]
+// expected type: list
+
+// Leaving a loop over a generator early closes the generator, which runs
+// its deferred calls
+log := []
+
+func counter() {
+    defer func() { log.append("closed") }()
+    i := 0
+    for {
+        yield i
+        i++
+    }
+}
+
+for n := range counter() {
+    if n == 2 {
+        break
+    }
+    log.append(n)
+}
+
+func first() {
+    for n := range counter() {
+        return n
+    }
+}
+log.append(first())
+
+func failing() {
+    for n := range counter() {
+        log.append(n)
+        n.missing()
+    }
+}
+log.append(try(failing(), "caught"))
+
+g := counter()
+g.next()
+g.close()
+
+// A generator that finishes isn't affected
+func five() {
+    defer func() { log.append("kept") }()
+    yield 5
+}
+for _, n := range five() {
+    log.append(n)
+}
+log
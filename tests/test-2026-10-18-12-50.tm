@@ -0,0 +1,30 @@
+// select cases bind their variables in scopes of their own
+// expected value: [1, 2, true, "ok", "default"]
+// expected type: list
+
+c := chan(2)
+c.send(1)
+c.send(2)
+
+got := []
+select {
+case v, ok := c.recv():
+    got.append(v)
+}
+select {
+case v, ok := c.recv():
+    got.append(v)
+    got.append(ok)
+}
+
+// The builtin ok isn't hidden by the names bound above
+got.append(ok(1).is_ok() ? "ok" : "hidden")
+
+select {
+case v := c.recv():
+    got.append(v)
+default:
+    v := "default"
+    got.append(v)
+}
+got
@@ -0,0 +1,24 @@
+// A single name in a range loop receives the index, like in Go, while a
+// single pattern is matched against each element
+// expected value: [[0, 1], [10, 20], [1, 2]]
+// expected type: list
+
+items := [[10], [20]]
+
+indexes := []
+for i := range items {
+    indexes.append(i)
+}
+
+values := []
+for [v] := range items {
+    values.append(v)
+}
+
+pairs := []
+for [a, b] := range [[1, 2]] {
+    pairs.append(a)
+    pairs.append(b)
+}
+
+[indexes, values, pairs]
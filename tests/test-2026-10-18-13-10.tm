@@ -0,0 +1,17 @@
+// The math functions accept bigints, and math.abs promotes the one int
+// whose absolute value doesn't fit in an int
+// expected value: [9223372036854775808, 123456789012345678901234567890, 123456789012345678901234567890, -123456789012345678901234567890, 123456789012345678901234567891, 400, 2000, true]
+// expected type: list
+
+big := 123456789012345678901234567890
+
+[
+    math.abs(-9223372036854775807 - 1),
+    math.abs(-big),
+    math.max([1, big, 2.5]),
+    math.min([1, -big, 2.5]),
+    math.sum([1, big]),
+    math.log10(bigint(10) ** 400),
+    math.log2(bigint(2) ** 2000),
+    math.sqrt(big) == math.sqrt(float(big)),
+]
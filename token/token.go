@@ -35,68 +35,88 @@ type Token struct {
 
 // pre-defined Type
 const (
-	AND             = "&&"
-	ASSIGN          = "="
-	ASTERISK        = "*"
-	ASTERISK_EQUALS = "*="
-	BACKTICK        = "`"
-	FSTRING         = "'"
-	BANG            = "!"
-	CASE            = "case"
-	COLON           = ":"
-	COMMA           = ","
-	CONST           = "CONST"
-	DECLARE         = ":="
-	DEFAULT         = "DEFAULT"
-	FUNC            = "FUNC"
-	ELSE            = "ELSE"
-	EOF             = "EOF"
-	EQ              = "=="
-	FALSE           = "FALSE"
-	FLOAT           = "FLOAT"
-	FOR             = "FOR"
-	GT              = ">"
-	GT_EQUALS       = ">="
-	IDENT           = "IDENT"
-	IF              = "IF"
-	ILLEGAL         = "ILLEGAL"
-	INT             = "INT"
-	LBRACE          = "{"
-	LBRACKET        = "["
-	LPAREN          = "("
-	LT              = "<"
-	LT_EQUALS       = "<="
-	MINUS           = "-"
-	MINUS_EQUALS    = "-="
-	MINUS_MINUS     = "--"
-	MOD             = "%"
-	NOT_EQ          = "!="
-	NIL             = "nil"
-	PIPE            = "|"
-	OR              = "||"
-	PERIOD          = "."
-	PLUS            = "+"
-	PLUS_EQUALS     = "+="
-	PLUS_PLUS       = "++"
-	POW             = "**"
-	QUESTION        = "?"
-	RBRACE          = "}"
-	RBRACKET        = "]"
-	RETURN          = "RETURN"
-	RPAREN          = ")"
-	SEMICOLON       = ";"
-	SLASH           = "/"
-	SLASH_EQUALS    = "/="
-	STRING          = "STRING"
-	SWITCH          = "switch"
-	TRUE            = "TRUE"
-	NEWLINE         = "EOL"
-	IMPORT          = "IMPORT"
-	BREAK           = "BREAK"
-	CONTINUE        = "CONTINUE"
-	VAR             = "VAR"
-	IN              = "IN"
-	RANGE           = "RANGE"
+	AMPERSAND        = "&"
+	AMPERSAND_EQUALS = "&="
+	AND              = "&&"
+	AND_NOT          = "&^"
+	AND_NOT_EQUALS   = "&^="
+	ARROW            = "->"
+	ASSIGN           = "="
+	ASTERISK         = "*"
+	ASTERISK_EQUALS  = "*="
+	BACKTICK         = "`"
+	FSTRING          = "'"
+	BANG             = "!"
+	CARET            = "^"
+	CARET_EQUALS     = "^="
+	CASE             = "case"
+	COLON            = ":"
+	COMMA            = ","
+	CONST            = "CONST"
+	DECLARE          = ":="
+	DEFAULT          = "DEFAULT"
+	DEFER            = "DEFER"
+	FUNC             = "FUNC"
+	ELLIPSIS         = "..."
+	ELSE             = "ELSE"
+	EOF              = "EOF"
+	EQ               = "=="
+	FALSE            = "FALSE"
+	FLOAT            = "FLOAT"
+	FOR              = "FOR"
+	GO               = "GO"
+	GT               = ">"
+	GT_EQUALS        = ">="
+	GT_GT            = ">>"
+	GT_GT_EQUALS     = ">>="
+	IDENT            = "IDENT"
+	IF               = "IF"
+	ILLEGAL          = "ILLEGAL"
+	INT              = "INT"
+	LBRACE           = "{"
+	LBRACKET         = "["
+	LPAREN           = "("
+	LT               = "<"
+	LT_EQUALS        = "<="
+	LT_LT            = "<<"
+	LT_LT_EQUALS     = "<<="
+	MATCH            = "MATCH"
+	MINUS            = "-"
+	MINUS_EQUALS     = "-="
+	MINUS_MINUS      = "--"
+	MOD              = "%"
+	NOT_EQ           = "!="
+	NIL              = "nil"
+	PIPE             = "|"
+	PIPE_EQUALS      = "|="
+	OR               = "||"
+	PERIOD           = "."
+	PLUS             = "+"
+	PLUS_EQUALS      = "+="
+	PLUS_PLUS        = "++"
+	PROPAGATE        = "PROPAGATE"
+	POW              = "**"
+	QUESTION         = "?"
+	RBRACE           = "}"
+	RBRACKET         = "]"
+	RETURN           = "RETURN"
+	RPAREN           = ")"
+	SELECT           = "SELECT"
+	SEMICOLON        = ";"
+	SLASH            = "/"
+	SLASH_EQUALS     = "/="
+	STRING           = "STRING"
+	STRUCT           = "STRUCT"
+	SWITCH           = "switch"
+	TRUE             = "TRUE"
+	NEWLINE          = "EOL"
+	IMPORT           = "IMPORT"
+	BREAK            = "BREAK"
+	CONTINUE         = "CONTINUE"
+	VAR              = "VAR"
+	IN               = "IN"
+	RANGE            = "RANGE"
+	YIELD            = "YIELD"
 )
 
 // reserved keywords
@@ -104,14 +124,19 @@ var keywords = map[string]Type{
 	"case":     CASE,
 	"const":    CONST,
 	"default":  DEFAULT,
+	"defer":    DEFER,
 	"else":     ELSE,
 	"false":    FALSE,
 	"for":      FOR,
 	"func":     FUNC,
+	"go":       GO,
 	"if":       IF,
+	"match":    MATCH,
 	"var":      VAR,
 	"nil":      NIL,
 	"return":   RETURN,
+	"select":   SELECT,
+	"struct":   STRUCT,
 	"switch":   SWITCH,
 	"true":     TRUE,
 	"import":   IMPORT,
@@ -119,6 +144,7 @@ var keywords = map[string]Type{
 	"continue": CONTINUE,
 	"in":       IN,
 	"range":    RANGE,
+	"yield":    YIELD,
 }
 
 // LookupIdentifier used to determinate whether identifier is keyword nor not
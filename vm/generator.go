@@ -0,0 +1,43 @@
+package vm
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// newGenerator is used when a generator function is called. The frame that
+// was just entered for the call, with its arguments already bound, is
+// removed and a generator is returned that runs the frame in a fork of the
+// VM as values are requested. The fork pauses at each yield instruction.
+// The arguments are type checked when the call is made, like any other
+// function call.
+func (v *VM) newGenerator() (*object.Generator, *object.Error) {
+	f := v.frames[len(v.frames)-1]
+	err := v.checkParams(&f)
+	locals := make([]object.Object, v.sp-f.bp)
+	copy(locals, v.stack[f.bp:v.sp])
+	v.frames = v.frames[:len(v.frames)-1]
+	v.sp = f.bp - 1
+	if err != nil {
+		return nil, err
+	}
+	return object.NewGenerator(v.ctx, f.fn.Name(), func(ctx context.Context, yield object.YieldFunc) object.Object {
+		child := v.fork()
+		child.yield = yield
+		child.ctx = object.WithCallFunc(ctx, child.callFunc)
+		child.ctx = object.WithKwargsCallFunc(child.ctx, child.kwargsCallFunc)
+		child.done = ctx.Done()
+		child.push(f.closure)
+		for _, local := range locals {
+			child.push(local)
+		}
+		child.frames = append(child.frames, frame{
+			closure:      f.closure,
+			fn:           f.fn,
+			instructions: f.instructions,
+			bp:           1,
+		})
+		return child.run(0)
+	}), nil
+}
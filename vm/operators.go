@@ -0,0 +1,50 @@
+package vm
+
+import (
+	"context"
+
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+)
+
+// binary applies a binary operator. Common integer operations are handled
+// directly and everything else, including operations that overflow, is
+// delegated to the evaluator so that both backends share the same semantics.
+func binary(ctx context.Context, operator string, left, right object.Object, overflow evaluator.IntOverflow) object.Object {
+	if l, ok := left.(*object.Int); ok {
+		if r, ok := right.(*object.Int); ok {
+			a, b := l.Value(), r.Value()
+			switch operator {
+			case "+", "+=":
+				if c := a + b; (c > a) == (b > 0) {
+					return object.NewInt(c)
+				}
+			case "-", "-=":
+				if c := a - b; (c < a) == (b > 0) {
+					return object.NewInt(c)
+				}
+			case "==":
+				return object.NewBool(a == b)
+			case "!=":
+				return object.NewBool(a != b)
+			case "<":
+				return object.NewBool(a < b)
+			case "<=":
+				return object.NewBool(a <= b)
+			case ">":
+				return object.NewBool(a > b)
+			case ">=":
+				return object.NewBool(a >= b)
+			case "&", "&=":
+				return object.NewInt(a & b)
+			case "|", "|=":
+				return object.NewInt(a | b)
+			case "^", "^=":
+				return object.NewInt(a ^ b)
+			case "&^", "&^=":
+				return object.NewInt(a &^ b)
+			}
+		}
+	}
+	return evaluator.Infix(ctx, operator, left, right, overflow)
+}
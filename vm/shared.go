@@ -0,0 +1,30 @@
+package vm
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// shared holds the state of a program that is shared by all goroutines
+// started by its go statements. Each goroutine runs in its own VM.
+type shared struct {
+	// mutex guards global variables and cells once there is more than one
+	// goroutine. Until then locking is skipped.
+	mutex      sync.Mutex
+	concurrent atomic.Bool
+}
+
+// lock acquires the shared mutex if the program has started goroutines.
+// Since only a goroutine can start another, the flag cannot change between
+// a call to lock and the matching call to unlock.
+func (s *shared) lock() {
+	if s.concurrent.Load() {
+		s.mutex.Lock()
+	}
+}
+
+func (s *shared) unlock() {
+	if s.concurrent.Load() {
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,1692 @@
+// Package vm executes bytecode produced by the compiler package. It is an
+// alternative to the tree-walking evaluator that produces the same results
+// while avoiding per-node dispatch and scope lookups by name.
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudcmds/tamarin/compiler"
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/cloudcmds/tamarin/stack"
+)
+
+// Opts configures the virtual machine.
+type Opts struct {
+	// Scope is consulted for names the program does not declare itself,
+	// such as modules or host-provided variables. If nil, an empty scope
+	// is used.
+	Scope *scope.Scope
+
+	// Importer is used to import Tamarin code modules. If nil, module imports
+	// are not supported and an import will result in an error that stops code
+	// execution.
+	Importer evaluator.Importer
+
+	// If set to true, the default builtins will not be registered.
+	DisableDefaultBuiltins bool
+
+	// Supplies extra and/or override builtins for execution.
+	Builtins []*object.Builtin
+
+	// IntOverflow determines what happens when int arithmetic overflows.
+	// By default, the result is promoted to a bigint.
+	IntOverflow evaluator.IntOverflow
+
+	// Limiter restricts the resources used by the program, such as the
+	// number of instructions it runs and the size of the objects it
+	// creates. If nil, resources are not limited.
+	Limiter *object.Limiter
+
+	// MaxCallDepth is the maximum depth of nested function calls. If zero,
+	// stack.DefaultMaxDepth is used.
+	MaxCallDepth int
+
+	// Policy declares the capabilities that builtins and modules may use on
+	// behalf of the program. If nil, all capabilities are allowed.
+	Policy *object.Policy
+
+	// Goroutines runs the goroutines started by go statements. The first
+	// error returned by one of them is recorded there and stops the program.
+	// If nil, errors returned by goroutines are discarded.
+	Goroutines *object.Goroutines
+}
+
+// VM executes compiled Tamarin programs.
+type VM struct {
+	bytecode  *compiler.Bytecode
+	constants []object.Object
+	globals   []object.Object
+	names     []object.Object
+	scope     *scope.Scope
+	overrides map[string]*object.Builtin
+	opts      Opts
+	evaluator *evaluator.Evaluator
+	shared    *shared
+	stack     []object.Object
+	sp        int
+	frames    []frame
+	handlers  []handler
+	loops     []loop
+	ctx       context.Context
+	done      <-chan struct{}
+	// yield is set when running the body of a generator function
+	yield object.YieldFunc
+}
+
+// frame is the activation record of a running function. Its arguments and
+// local variables occupy the stack starting at bp, with the function object
+// itself just below.
+type frame struct {
+	closure      *object.Closure
+	fn           *object.CompiledFunction
+	instructions []byte
+	ip           int
+	bp           int
+	deferred     []deferredCall
+}
+
+// loop records a generator being iterated over by a running loop, along
+// with its position on the stack, so that it is closed if the loop is left
+// by a return or an error.
+type loop struct {
+	sp        int
+	generator *object.Generator
+}
+
+// deferredCall is a call scheduled by a defer statement, which runs when
+// the frame that deferred it returns.
+type deferredCall struct {
+	fn     object.Object
+	args   []object.Object
+	kwargs *object.Map
+}
+
+// handler marks a region of code where errors are caught and pushed onto
+// the stack instead of stopping execution. These are used for the arguments
+// of error handling builtins like try.
+type handler struct {
+	frame  int
+	sp     int
+	target int
+}
+
+// cellSlot is stored in a local variable slot once that variable has been
+// captured by a closure.
+type cellSlot struct {
+	*object.Cell
+}
+
+func (c cellSlot) Type() object.Type                        { return "cell" }
+func (c cellSlot) Inspect() string                          { return "cell" }
+func (c cellSlot) Interface() interface{}                   { return nil }
+func (c cellSlot) Equals(other object.Object) object.Object { return object.False }
+func (c cellSlot) GetAttr(name string) (object.Object, bool) {
+	return nil, false
+}
+func (c cellSlot) IsTruthy() bool { return true }
+
+const initialStackSize = 1024
+
+// New returns a VM that is ready to run the given bytecode.
+func New(bytecode *compiler.Bytecode, opts Opts) *VM {
+	s := opts.Scope
+	if s == nil {
+		s = scope.New(scope.Opts{Name: "global"})
+	}
+	if opts.MaxCallDepth <= 0 {
+		opts.MaxCallDepth = stack.DefaultMaxDepth
+	}
+	v := &VM{
+		bytecode:  bytecode,
+		constants: bytecode.Constants(),
+		globals:   make([]object.Object, len(bytecode.Globals())),
+		names:     make([]object.Object, len(bytecode.Names())),
+		scope:     s,
+		overrides: map[string]*object.Builtin{},
+		opts:      opts,
+		evaluator: newEvaluator(opts),
+		shared:    &shared{},
+		stack:     make([]object.Object, initialStackSize),
+	}
+	builtins := map[string]*object.Builtin{}
+	if !opts.DisableDefaultBuiltins {
+		for _, b := range evaluator.GlobalBuiltins() {
+			builtins[b.Key()] = b
+		}
+	}
+	for _, b := range opts.Builtins {
+		builtins[b.Key()] = b
+		v.overrides[b.Key()] = b
+	}
+	// Names not declared by the program resolve to the scope first and
+	// then to the builtins, just like identifiers in the evaluator
+	for i, name := range bytecode.Names() {
+		if obj, ok := s.Get(name); ok {
+			v.names[i] = obj
+		} else if b, ok := builtins[name]; ok {
+			v.names[i] = b
+		}
+	}
+	return v
+}
+
+// newEvaluator returns the evaluator used to import modules and to call
+// functions defined by those modules.
+func newEvaluator(opts Opts) *evaluator.Evaluator {
+	return evaluator.New(evaluator.Opts{
+		Importer:               opts.Importer,
+		DisableDefaultBuiltins: opts.DisableDefaultBuiltins,
+		Builtins:               opts.Builtins,
+		IntOverflow:            opts.IntOverflow,
+		Limiter:                opts.Limiter,
+		MaxCallDepth:           opts.MaxCallDepth,
+		Policy:                 opts.Policy,
+		Goroutines:             opts.Goroutines,
+	})
+}
+
+// fork returns a VM that shares the program state of this one but has its
+// own stack, so that it can run a goroutine.
+func (v *VM) fork() *VM {
+	v.shared.concurrent.Store(true)
+	child := &VM{
+		bytecode:  v.bytecode,
+		constants: v.constants,
+		globals:   v.globals,
+		names:     v.names,
+		scope:     v.scope,
+		overrides: v.overrides,
+		opts:      v.opts,
+		evaluator: newEvaluator(v.opts),
+		shared:    v.shared,
+		stack:     make([]object.Object, initialStackSize),
+		done:      v.done,
+	}
+	child.ctx = object.WithCallFunc(v.ctx, child.callFunc)
+	child.ctx = object.WithKwargsCallFunc(child.ctx, child.kwargsCallFunc)
+	return child
+}
+
+// Run executes the program. The context can be used to cancel execution.
+// If execution encounters an error, a Tamarin error object is returned.
+func (v *VM) Run(ctx context.Context) object.Object {
+	v.ctx = object.WithCallFunc(ctx, v.callFunc)
+	v.ctx = object.WithKwargsCallFunc(v.ctx, v.kwargsCallFunc)
+	if v.opts.Limiter != nil {
+		v.ctx = object.WithLimiter(v.ctx, v.opts.Limiter)
+	}
+	if v.opts.Policy != nil {
+		v.ctx = object.WithPolicy(v.ctx, v.opts.Policy)
+	}
+	if v.opts.IntOverflow == evaluator.ErrorOnOverflow {
+		v.ctx = object.WithErrorOnIntOverflow(v.ctx)
+	}
+	v.done = ctx.Done()
+	return v.invoke(object.NewClosure(v.bytecode.Main(), nil), nil)
+}
+
+// callFunc implements object.CallFunc so that builtins can call back into
+// Tamarin functions running on this VM.
+func (v *VM) callFunc(ctx context.Context, s interface{}, fn object.Object, args []object.Object) object.Object {
+	return v.invoke(fn, args)
+}
+
+// kwargsCallFunc implements object.KwargsCallFunc so that objects can call
+// back into Tamarin functions with keyword arguments.
+func (v *VM) kwargsCallFunc(ctx context.Context, fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
+	return v.invokeWithKwargs(fn, args, kwargs)
+}
+
+// invoke calls a function from Go and returns its result.
+func (v *VM) invoke(fn object.Object, args []object.Object) object.Object {
+	return v.invokeWithKwargs(fn, args, nil)
+}
+
+// invokeWithKwargs calls a function from Go with the given positional and
+// keyword arguments and returns its result. The keyword arguments may be
+// nil.
+func (v *VM) invokeWithKwargs(fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
+	closure, ok := fn.(*object.Closure)
+	if !ok {
+		return v.callObject(fn, args, kwargs)
+	}
+	base := len(v.frames)
+	v.push(closure)
+	for _, arg := range args {
+		v.push(arg)
+	}
+	if err := v.enterWithKwargs(closure, len(args), kwargs); err != nil {
+		v.sp -= len(args) + 1
+		return err
+	}
+	if closure.Function().Generator() {
+		gen, err := v.newGenerator()
+		if err != nil {
+			return err
+		}
+		return gen
+	}
+	return v.run(base)
+}
+
+// callObject calls any callable object other than a closure. The keyword
+// arguments are nil if there are none.
+func (v *VM) callObject(fn object.Object, args []object.Object, kwargs *object.Map) object.Object {
+	switch fn := fn.(type) {
+	case *object.Builtin:
+		if len(v.overrides) > 0 {
+			if priorityBuiltin, found := v.overrides[fn.Key()]; found {
+				// This is a priority builtin, possibly an override, so
+				// we should use this one
+				fn = priorityBuiltin
+			}
+		}
+		if kwargs != nil {
+			return fn.CallWithKwargs(v.ctx, kwargs, args...)
+		}
+		return fn.Call(v.ctx, args...)
+	case *object.Struct:
+		if kwargs != nil {
+			return fn.CallWithKwargs(v.ctx, kwargs, args...)
+		}
+		return fn.Call(v.ctx, args...)
+	case *object.Closure:
+		return v.invokeWithKwargs(fn, args, kwargs)
+	case *object.Function:
+		// Functions defined in modules imported by the evaluator
+		return v.evaluator.CallWithKwargs(v.ctx, fn, args, kwargs)
+	default:
+		return object.Errorf("type error: %s is not callable", fn.Type())
+	}
+}
+
+// enter pushes a frame for a closure whose arguments are on the stack.
+func (v *VM) enter(closure *object.Closure, nargs int) *object.Error {
+	if len(v.frames) >= v.opts.MaxCallDepth {
+		return stack.NewDepthError(v.opts.MaxCallDepth)
+	}
+	fn := closure.Function()
+	numParams := fn.NumParameters()
+	var rest *object.List
+	if fn.Variadic() {
+		// Gather the arguments beyond the fixed parameters into a list
+		// that is stored in the last parameter
+		fixed := numParams - 1
+		if nargs < fixed && !fn.HasDefaults() {
+			return object.Errorf("type error: function expected at least %d arguments (%d given)",
+				fixed, nargs)
+		}
+		rest = object.NewList(nil)
+		if nargs >= fixed {
+			items := make([]object.Object, nargs-fixed)
+			copy(items, v.stack[v.sp-len(items):v.sp])
+			v.sp -= len(items)
+			rest = object.NewList(items)
+			v.push(rest)
+			nargs = numParams
+		}
+	} else if nargs != numParams {
+		if !fn.HasDefaults() {
+			return object.Errorf("type error: function expected %d arguments (%d given)",
+				numParams, nargs)
+		}
+		if nargs > numParams {
+			v.sp -= nargs - numParams
+			nargs = numParams
+		}
+	}
+	bp := v.sp - nargs
+	top := bp + fn.NumLocals()
+	v.ensure(top - v.sp)
+	for i := v.sp; i < top; i++ {
+		v.stack[i] = nil
+	}
+	v.sp = top
+	if rest != nil {
+		v.stack[bp+numParams-1] = rest
+	}
+	for _, index := range fn.CellParameters() {
+		if value := v.stack[bp+index]; value != nil {
+			v.stack[bp+index] = cellSlot{&object.Cell{Value: value}}
+		}
+	}
+	v.frames = append(v.frames, frame{
+		closure:      closure,
+		fn:           fn,
+		instructions: fn.Instructions(),
+		bp:           bp,
+	})
+	return nil
+}
+
+// enterWithKwargs is like enter, but first assigns any keyword arguments to
+// the parameters of the same name. Parameters that are not given a value
+// are left empty so that their default value is used.
+func (v *VM) enterWithKwargs(closure *object.Closure, nargs int, kwargs *object.Map) *object.Error {
+	if kwargs == nil {
+		return v.enter(closure, nargs)
+	}
+	fn := closure.Function()
+	params := fn.Node().Parameters()
+	fixed := len(params)
+	if fn.Variadic() {
+		fixed--
+	}
+	positional := nargs
+	if positional > fixed && fn.Variadic() {
+		positional = fixed
+	}
+	names := make([]string, fixed)
+	for i := range names {
+		names[i] = params[i].String()
+	}
+	start := v.sp - nargs
+	values, err := object.BindArguments(fn.Name(), names, v.stack[start:start+positional], kwargs)
+	if err != nil {
+		return err
+	}
+	defaults := fn.Node().Defaults()
+	for i, value := range values {
+		if _, ok := defaults[names[i]]; value == nil && !ok {
+			return object.NewMissingArgError(fn.Name(), names[i])
+		}
+	}
+	// Extra arguments to a variadic function stay on the stack above the
+	// bound parameters, to be gathered into a list by enter
+	rest := make([]object.Object, nargs-positional)
+	copy(rest, v.stack[start+positional:v.sp])
+	v.sp = start
+	v.ensure(len(values) + len(rest))
+	for _, value := range values {
+		v.push(value)
+	}
+	for _, value := range rest {
+		v.push(value)
+	}
+	return v.enter(closure, len(values)+len(rest))
+}
+
+// call calls the function located below the given number of arguments on
+// the stack. Closures get a new frame, which the run loop continues with,
+// while other callables are run to completion and their result is pushed.
+func (v *VM) call(nargs int) *object.Error {
+	return v.callWithKwargs(nil, nargs, nil)
+}
+
+// callWithKwargs is like call, but also passes the given keyword arguments,
+// which may be nil. The receiver is the object whose method is being
+// called, if any, which is used to count the items the method adds to it
+// against the limits of the program.
+func (v *VM) callWithKwargs(receiver object.Object, nargs int, kwargs *object.Map) *object.Error {
+	fn := v.stack[v.sp-1-nargs]
+	if closure, ok := fn.(*object.Closure); ok {
+		if err := v.enterWithKwargs(closure, nargs, kwargs); err != nil {
+			return err
+		}
+		if closure.Function().Generator() {
+			gen, err := v.newGenerator()
+			if err != nil {
+				return err
+			}
+			v.push(gen)
+		}
+		return nil
+	}
+	args := make([]object.Object, nargs)
+	copy(args, v.stack[v.sp-nargs:v.sp])
+	v.sp -= nargs + 1
+	before, _ := object.Size(receiver)
+	result := v.callObject(fn, args, kwargs)
+	if err, ok := result.(*object.Error); ok {
+		return err
+	}
+	if result == nil {
+		result = object.Nil
+	}
+	if err := v.trackCall(receiver, before, args, result); err != nil {
+		return err
+	}
+	v.push(result)
+	return nil
+}
+
+// trackCall counts the result of a call to a builtin, and the items it
+// added to the receiver of the method, against the limits of the program.
+func (v *VM) trackCall(receiver object.Object, before int64, args []object.Object, result object.Object) *object.Error {
+	limiter := v.opts.Limiter
+	if limiter == nil {
+		return nil
+	}
+	if receiver == nil {
+		return limiter.TrackResult(result, args...)
+	}
+	if err := limiter.Grow(receiver, before); err != nil {
+		return err
+	}
+	if result == receiver {
+		return nil
+	}
+	return limiter.TrackResult(result, args...)
+}
+
+// callMethod replaces the object below the arguments on the stack with its
+// named method and calls it.
+func (v *VM) callMethod(name string, nargs int) *object.Error {
+	receiver := v.stack[v.sp-1-nargs]
+	if err := v.method(name, nargs); err != nil {
+		return err
+	}
+	return v.callWithKwargs(receiver, nargs, nil)
+}
+
+// method replaces the object below the arguments on the stack with its
+// named method.
+func (v *VM) method(name string, nargs int) *object.Error {
+	obj := v.stack[v.sp-1-nargs]
+	attr, found := obj.GetAttr(name)
+	if !found {
+		return object.Errorf("attribute error: %s has no attribute \"%s\"", obj.Type(), name)
+	}
+	if err, ok := attr.(*object.Error); ok {
+		return err
+	}
+	v.stack[v.sp-1-nargs] = attr
+	return nil
+}
+
+// spawn calls the function below the given number of arguments on the
+// stack in a new goroutine, replacing them with nil. The result of the call
+// is discarded unless it is an error, which stops the program. The keyword
+// arguments may be nil.
+func (v *VM) spawn(nargs int, kwargs *object.Map) *object.Error {
+	fn, args, err := v.popCall(nargs)
+	if err != nil {
+		return err
+	}
+	child := v.fork()
+	v.opts.Goroutines.Go(func() object.Object {
+		return child.invokeWithKwargs(fn, args, kwargs)
+	})
+	v.push(object.Nil)
+	return nil
+}
+
+// deferCall schedules the function below the given number of arguments on
+// the stack to be called when the current frame returns, replacing them
+// with nil. The keyword arguments may be nil.
+func (v *VM) deferCall(nargs int, kwargs *object.Map) *object.Error {
+	fn, args, err := v.popCall(nargs)
+	if err != nil {
+		return err
+	}
+	f := &v.frames[len(v.frames)-1]
+	f.deferred = append(f.deferred, deferredCall{fn: fn, args: args, kwargs: kwargs})
+	v.push(object.Nil)
+	return nil
+}
+
+// callExpanded calls the function on top of the stack with the given
+// arguments, which were gathered by OpSpread, and keyword arguments, which
+// may be nil. The kind is one of the compiler.Spread* call kinds.
+func (v *VM) callExpanded(kind byte, args []object.Object, kwargs *object.Map) *object.Error {
+	if kind == compiler.SpreadPipe {
+		// The output of the previous stage becomes the first argument
+		v.stack[v.sp-2], v.stack[v.sp-1] = v.stack[v.sp-1], v.stack[v.sp-2]
+	}
+	v.ensure(len(args))
+	for _, arg := range args {
+		v.push(arg)
+	}
+	nargs := len(args)
+	switch kind {
+	case compiler.SpreadGo:
+		return v.spawn(nargs, kwargs)
+	case compiler.SpreadDefer:
+		return v.deferCall(nargs, kwargs)
+	case compiler.SpreadPipe:
+		return v.callWithKwargs(nil, nargs+1, kwargs)
+	}
+	if err := v.callWithKwargs(nil, nargs, kwargs); err != nil {
+		return err
+	}
+	return v.checkDone()
+}
+
+// popCall pops a function and the given number of arguments from the stack
+// so that they can be called later.
+func (v *VM) popCall(nargs int) (object.Object, []object.Object, *object.Error) {
+	fn := v.stack[v.sp-1-nargs]
+	args := make([]object.Object, nargs)
+	copy(args, v.stack[v.sp-nargs:v.sp])
+	v.sp -= nargs + 1
+	switch fn.(type) {
+	case *object.Closure, *object.Function, *object.Builtin, *object.Struct:
+	default:
+		return nil, nil, object.Errorf("type error: %s is not callable", fn.Type())
+	}
+	return fn, args, nil
+}
+
+// runDeferred runs the calls deferred by the current frame, most recent
+// first. If a deferred call fails, the first such error is returned.
+func (v *VM) runDeferred() *object.Error {
+	var err *object.Error
+	top := len(v.frames) - 1
+	for {
+		deferred := v.frames[top].deferred
+		if len(deferred) == 0 {
+			return err
+		}
+		call := deferred[len(deferred)-1]
+		v.frames[top].deferred = deferred[:len(deferred)-1]
+		if e, ok := v.invokeWithKwargs(call.fn, call.args, call.kwargs).(*object.Error); ok && err == nil {
+			err = e
+		}
+	}
+}
+
+// unwind discards the frames at or above the given index after running the
+// calls they deferred.
+func (v *VM) unwind(index int) {
+	for len(v.frames) > index {
+		v.closeLoops(v.frames[len(v.frames)-1].bp)
+		v.runDeferred()
+		v.frames = v.frames[:len(v.frames)-1]
+	}
+}
+
+// closeLoops closes the generators of running loops whose iterators are
+// at or above the given stack position, which are being left early.
+func (v *VM) closeLoops(sp int) {
+	for n := len(v.loops); n > 0 && v.loops[n-1].sp >= sp; n-- {
+		v.loops[n-1].generator.Close()
+		v.loops = v.loops[:n-1]
+	}
+}
+
+// selectCases pops the operands of an OpSelect instruction, which are the
+// channel of each case followed by the value to send for send cases.
+func (v *VM) selectCases(ins []byte, table, count int) ([]object.SelectCase, *object.Error) {
+	size := 0
+	for i := 0; i < count; i++ {
+		size++
+		if ins[table+i*compiler.SelectCaseWidth] == 1 {
+			size++
+		}
+	}
+	start := v.sp - size
+	defer func() { v.sp = start }()
+	cases := make([]object.SelectCase, count)
+	pos := start
+	for i := range cases {
+		obj := v.stack[pos]
+		pos++
+		ch, ok := obj.(*object.Chan)
+		if !ok {
+			return nil, object.Errorf("type error: select case expected a chan (got %s)", obj.Type())
+		}
+		cases[i].Chan = ch
+		if ins[table+i*compiler.SelectCaseWidth] == 1 {
+			cases[i].Send = true
+			cases[i].Value = v.stack[pos]
+			pos++
+		}
+	}
+	return cases, nil
+}
+
+// annotate attaches the position of the failing instruction and the current
+// call stack to an error. Errors that are already annotated, like those that
+// were raised by a nested run loop, are returned unchanged.
+func (v *VM) annotate(err *object.Error) *object.Error {
+	if _, ok := err.RuntimeError(); ok {
+		return err
+	}
+	var opts object.RuntimeErrorOpts
+	for i := range v.frames {
+		f := &v.frames[i]
+		// The instruction pointer has already moved past the opcode of the
+		// instruction being executed by each frame
+		pos, ok := f.fn.Position(f.ip - 1)
+		if !ok {
+			continue
+		}
+		opts.Stack = append(opts.Stack, object.StackFrame{Name: f.fn.Name(), Position: pos.Start})
+		if i == len(v.frames)-1 {
+			opts.StartPosition = pos.Start
+			opts.EndPosition = pos.End
+		}
+	}
+	return err.WithRuntimeError(opts)
+}
+
+// returnValue returns from the current frame with the value on top of the
+// stack, after running any calls the frame deferred.
+func (v *VM) returnValue() (object.Object, *object.Error) {
+	f := &v.frames[len(v.frames)-1]
+	v.closeLoops(f.bp)
+	if len(f.deferred) > 0 {
+		if err := v.runDeferred(); err != nil {
+			return nil, err
+		}
+		f = &v.frames[len(v.frames)-1]
+	}
+	result := v.pop()
+	if node := f.fn.Node(); node != nil && !f.fn.Generator() {
+		if err := evaluator.CheckResult(f.fn.Name(), node.Types(), result); err != nil {
+			return nil, err
+		}
+	}
+	current := len(v.frames) - 1
+	for len(v.handlers) > 0 && v.handlers[len(v.handlers)-1].frame >= current {
+		v.handlers = v.handlers[:len(v.handlers)-1]
+	}
+	v.sp = f.bp - 1
+	v.frames = v.frames[:current]
+	return result, nil
+}
+
+// checkParams verifies the arguments of a frame against the type
+// annotations of its function's parameters. Parameters that have no value
+// yet, because their default has not been evaluated, are skipped.
+func (v *VM) checkParams(f *frame) *object.Error {
+	node := f.fn.Node()
+	if len(node.Types().Params()) == 0 {
+		return nil
+	}
+	params := node.Parameters()
+	values := make([]object.Object, len(params))
+	for i := range params {
+		values[i] = v.stack[f.bp+i]
+		if cell, ok := values[i].(cellSlot); ok {
+			values[i] = cell.Value
+		}
+	}
+	return evaluator.CheckParams(f.fn.Name(), node.Types(), params, node.Variadic(), values)
+}
+
+// raise handles a runtime error. If a handler is active within the current
+// run loop, execution resumes at its target with the error on the stack and
+// true is returned. Otherwise the frames of the run loop are unwound.
+func (v *VM) raise(err *object.Error, base int) bool {
+	// Exceeding a limit, calling exit or closing a generator unwinds
+	// everything, so it isn't handled
+	if n := len(v.handlers); n > 0 && !evaluator.IsUnhandled(err) {
+		h := v.handlers[n-1]
+		if h.frame >= base {
+			v.handlers = v.handlers[:n-1]
+			v.unwind(h.frame + 1)
+			v.closeLoops(h.sp)
+			v.sp = h.sp
+			v.push(err)
+			v.frames[h.frame].ip = h.target
+			return true
+		}
+	}
+	bp := v.frames[base].bp
+	v.unwind(base)
+	v.sp = bp - 1
+	return false
+}
+
+func (v *VM) push(obj object.Object) {
+	if v.sp == len(v.stack) {
+		v.ensure(1)
+	}
+	v.stack[v.sp] = obj
+	v.sp++
+}
+
+func (v *VM) pop() object.Object {
+	v.sp--
+	return v.stack[v.sp]
+}
+
+// popSlice pops the bounds of a slice pushed by the compiler, where bits 1,
+// 2 and 4 of flags indicate whether the start, stop and step are present.
+func (v *VM) popSlice(flags byte) object.Slice {
+	var slice object.Slice
+	if flags&4 != 0 {
+		slice.Step = v.pop()
+	}
+	if flags&2 != 0 {
+		slice.Stop = v.pop()
+	}
+	if flags&1 != 0 {
+		slice.Start = v.pop()
+	}
+	return slice
+}
+
+// ensure grows the stack so that n more values can be pushed.
+func (v *VM) ensure(n int) {
+	if v.sp+n <= len(v.stack) {
+		return
+	}
+	size := len(v.stack) * 2
+	for size < v.sp+n {
+		size *= 2
+	}
+	stack := make([]object.Object, size)
+	copy(stack, v.stack[:v.sp])
+	v.stack = stack
+}
+
+func (v *VM) constantString(index int) string {
+	return v.constants[index].(*object.String).Value()
+}
+
+// run executes instructions until the frame at index base returns.
+func (v *VM) run(base int) object.Object {
+	var err *object.Error
+	for {
+		if err != nil {
+			err = v.annotate(err)
+			if !v.raise(err, base) {
+				return err
+			}
+			err = nil
+		}
+		f := &v.frames[len(v.frames)-1]
+		ins := f.instructions
+		ip := f.ip
+		op := compiler.Opcode(ins[ip])
+		f.ip = ip + 1
+		if v.opts.Limiter != nil {
+			if err = v.opts.Limiter.Step(); err != nil {
+				continue
+			}
+		}
+
+		switch op {
+
+		case compiler.OpConstant:
+			v.push(v.constants[readUint16(ins, ip+1)])
+			f.ip += 2
+
+		case compiler.OpNil:
+			v.push(object.Nil)
+
+		case compiler.OpTrue:
+			v.push(object.True)
+
+		case compiler.OpFalse:
+			v.push(object.False)
+
+		case compiler.OpPop:
+			v.sp--
+
+		case compiler.OpDup:
+			v.push(v.stack[v.sp-1])
+
+		case compiler.OpJump:
+			target := readUint16(ins, ip+1)
+			if target <= ip {
+				// Check for cancellation on every backwards jump
+				select {
+				case <-v.done:
+					err = object.NewError(v.ctx.Err())
+					continue
+				default:
+				}
+			}
+			f.ip = target
+
+		case compiler.OpJumpIfFalse:
+			if v.pop().IsTruthy() {
+				f.ip += 2
+			} else {
+				f.ip = readUint16(ins, ip+1)
+			}
+
+		case compiler.OpJumpIfSet:
+			if v.stack[v.sp-1] != nil {
+				f.ip = readUint16(ins, ip+1)
+			} else {
+				v.pop()
+				f.ip += 2
+			}
+
+		case compiler.OpJumpIfFalseNoPop:
+			if v.stack[v.sp-1].IsTruthy() {
+				f.ip += 2
+			} else {
+				f.ip = readUint16(ins, ip+1)
+			}
+
+		case compiler.OpJumpIfTrueNoPop:
+			if v.stack[v.sp-1].IsTruthy() {
+				f.ip = readUint16(ins, ip+1)
+			} else {
+				f.ip += 2
+			}
+
+		case compiler.OpBinary:
+			f.ip++
+			right := v.pop()
+			left := v.stack[v.sp-1]
+			operator := compiler.BinaryOperators[ins[ip+1]]
+			var result object.Object
+			if v.opts.Limiter != nil {
+				if typ, size, ok := evaluator.InfixSize(operator, left, right); ok {
+					if e := v.opts.Limiter.CheckSize(typ, size); e != nil {
+						result = e
+					}
+				}
+			}
+			if result == nil {
+				result = binary(v.ctx, operator, left, right, v.opts.IntOverflow)
+				if e := v.opts.Limiter.TrackResult(result, left, right); e != nil {
+					result = e
+				}
+			}
+			if e, ok := result.(*object.Error); ok {
+				v.sp--
+				err = e
+				continue
+			}
+			v.stack[v.sp-1] = result
+
+		case compiler.OpPrefix:
+			f.ip++
+			result := evaluator.Prefix(v.ctx, compiler.PrefixOperators[ins[ip+1]], v.stack[v.sp-1], v.opts.IntOverflow)
+			if e, ok := result.(*object.Error); ok {
+				v.sp--
+				err = e
+				continue
+			}
+			v.stack[v.sp-1] = result
+
+		case compiler.OpIn:
+			right := v.pop()
+			left := v.pop()
+			container, ok := right.(object.Container)
+			if !ok {
+				err = object.Errorf("eval error: right hand side of 'in' operator must be a container")
+				continue
+			}
+			v.push(container.Contains(left))
+
+		case compiler.OpGetGlobal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			v.shared.lock()
+			value := v.globals[index]
+			v.shared.unlock()
+			if value == nil {
+				err = nameError(v.bytecode.Globals()[index])
+				continue
+			}
+			v.push(value)
+
+		case compiler.OpSetGlobal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			value := v.pop()
+			v.shared.lock()
+			defined := v.globals[index] != nil
+			if defined {
+				v.globals[index] = value
+			}
+			v.shared.unlock()
+			if !defined {
+				err = nameError(v.bytecode.Globals()[index])
+				continue
+			}
+
+		case compiler.OpDefineGlobal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			value := v.pop()
+			v.shared.lock()
+			defined := v.globals[index] != nil
+			if !defined {
+				v.globals[index] = value
+			}
+			v.shared.unlock()
+			if defined {
+				err = alreadySetError(v.bytecode.Globals()[index])
+				continue
+			}
+
+		case compiler.OpGetLocal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			value := v.stack[f.bp+index]
+			if value == nil {
+				err = nameError(f.fn.LocalName(index))
+				continue
+			}
+			v.push(value)
+
+		case compiler.OpSetLocal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			if v.stack[f.bp+index] == nil {
+				err = nameError(f.fn.LocalName(index))
+				continue
+			}
+			v.stack[f.bp+index] = v.pop()
+
+		case compiler.OpDefineLocal:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			if v.stack[f.bp+index] != nil {
+				err = alreadySetError(f.fn.LocalName(index))
+				continue
+			}
+			v.stack[f.bp+index] = v.pop()
+
+		case compiler.OpGetCell:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			slot, _ := v.stack[f.bp+index].(cellSlot)
+			var value object.Object
+			if slot.Cell != nil {
+				value = v.load(slot.Cell)
+			}
+			if value == nil {
+				err = nameError(f.fn.LocalName(index))
+				continue
+			}
+			v.push(value)
+
+		case compiler.OpSetCell:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			slot, _ := v.stack[f.bp+index].(cellSlot)
+			value := v.pop()
+			if slot.Cell == nil || !v.store(slot.Cell, value, true) {
+				err = nameError(f.fn.LocalName(index))
+				continue
+			}
+
+		case compiler.OpDefineCell:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			slot, _ := v.stack[f.bp+index].(cellSlot)
+			value := v.pop()
+			if slot.Cell == nil {
+				v.stack[f.bp+index] = cellSlot{&object.Cell{Value: value}}
+			} else if !v.store(slot.Cell, value, false) {
+				err = alreadySetError(f.fn.LocalName(index))
+				continue
+			}
+
+		case compiler.OpGetFree:
+			index := int(ins[ip+1])
+			f.ip++
+			value := v.load(f.closure.Free()[index])
+			if value == nil {
+				err = nameError(f.fn.FreeName(index))
+				continue
+			}
+			v.push(value)
+
+		case compiler.OpSetFree:
+			index := int(ins[ip+1])
+			f.ip++
+			if !v.store(f.closure.Free()[index], v.pop(), true) {
+				err = nameError(f.fn.FreeName(index))
+				continue
+			}
+
+		case compiler.OpGetName:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			v.shared.lock()
+			value := v.names[index]
+			v.shared.unlock()
+			if value == nil {
+				err = nameError(v.bytecode.Names()[index])
+				continue
+			}
+			v.push(value)
+
+		case compiler.OpSetName:
+			index := readUint16(ins, ip+1)
+			f.ip += 2
+			value := v.pop()
+			if e := v.scope.Update(v.bytecode.Names()[index], value); e != nil {
+				err = object.NewError(e)
+				continue
+			}
+			v.shared.lock()
+			v.names[index] = value
+			v.shared.unlock()
+
+		case compiler.OpClearLocals:
+			start := f.bp + readUint16(ins, ip+1)
+			count := readUint16(ins, ip+3)
+			f.ip += 4
+			for i := start; i < start+count; i++ {
+				v.stack[i] = nil
+			}
+
+		case compiler.OpJumpIfArg:
+			if v.stack[f.bp+int(ins[ip+1])] != nil {
+				f.ip = readUint16(ins, ip+2)
+			} else {
+				f.ip += 3
+			}
+
+		case compiler.OpList:
+			count := readUint16(ins, ip+1)
+			f.ip += 2
+			items := make([]object.Object, count)
+			copy(items, v.stack[v.sp-count:v.sp])
+			v.sp -= count
+			list := object.NewList(items)
+			if err = v.opts.Limiter.Track(list); err != nil {
+				continue
+			}
+			v.push(list)
+
+		case compiler.OpMap:
+			count := readUint16(ins, ip+1)
+			f.ip += 2
+			m := object.NewMapWithSize(count)
+			start := v.sp - count*2
+			for i := start; i < v.sp; i += 2 {
+				if e := m.SetItem(v.stack[i], v.stack[i+1]); e != nil {
+					err = e
+					break
+				}
+			}
+			v.sp = start
+			if err != nil {
+				continue
+			}
+			if err = v.opts.Limiter.Track(m); err != nil {
+				continue
+			}
+			v.push(m)
+
+		case compiler.OpSet:
+			count := readUint16(ins, ip+1)
+			f.ip += 2
+			set := object.NewSetWithSize(count)
+			result := set.Add(v.stack[v.sp-count : v.sp]...)
+			v.sp -= count
+			if e, ok := result.(*object.Error); ok {
+				err = e
+				continue
+			}
+			if err = v.opts.Limiter.Track(set); err != nil {
+				continue
+			}
+			v.push(set)
+
+		case compiler.OpCollect:
+			count := int(ins[ip+1])
+			f.ip++
+			values := make([]object.Object, count)
+			copy(values, v.stack[v.sp-count:v.sp])
+			v.sp -= count
+			collection := v.pop()
+			before, _ := object.Size(collection)
+			switch container := collection.(type) {
+			case *object.List:
+				container.Append(values[0])
+			case *object.Set:
+				if e, ok := container.Add(values[0]).(*object.Error); ok {
+					err = e
+				}
+			case *object.Map:
+				if e := container.SetItem(values[0], values[1]); e != nil {
+					err = e
+				}
+			}
+			if err == nil {
+				err = v.opts.Limiter.Grow(collection, before)
+			}
+
+		case compiler.OpIndex:
+			index := v.pop()
+			left := v.pop()
+			container, ok := left.(object.Container)
+			if !ok {
+				err = object.Errorf("type error: %s object is not scriptable", left.Type())
+				continue
+			}
+			item, e := container.GetItem(index)
+			if e != nil {
+				err = e
+				continue
+			}
+			v.push(item)
+
+		case compiler.OpSlice:
+			slice := v.popSlice(ins[ip+1])
+			f.ip++
+			left := v.pop()
+			container, ok := left.(object.Container)
+			if !ok {
+				err = object.Errorf("type error: %s object is not scriptable", left.Type())
+				continue
+			}
+			items, e := container.GetSlice(slice)
+			if e != nil {
+				err = e
+				continue
+			}
+			if err = v.opts.Limiter.Track(items); err != nil {
+				continue
+			}
+			v.push(items)
+
+		case compiler.OpNewSlice:
+			v.push(v.popSlice(ins[ip+1]))
+			f.ip++
+
+		case compiler.OpSetItem:
+			index := v.pop()
+			obj := v.pop()
+			value := v.pop()
+			container, ok := obj.(object.Container)
+			if !ok {
+				err = object.Errorf("type error: %s is not a container", obj.Type())
+				continue
+			}
+			before, _ := object.Size(obj)
+			if e := container.SetItem(index, value); e != nil {
+				err = e
+				continue
+			}
+			err = v.opts.Limiter.Grow(obj, before)
+
+		case compiler.OpGetAttr:
+			name := v.constantString(readUint16(ins, ip+1))
+			f.ip += 2
+			obj := v.stack[v.sp-1]
+			attr, found := obj.GetAttr(name)
+			if !found {
+				v.sp--
+				err = object.Errorf("attribute error: %s object has no attribute \"%s\"", obj.Type(), name)
+				continue
+			}
+			v.stack[v.sp-1] = attr
+
+		case compiler.OpSetAttr:
+			name := v.constantString(readUint16(ins, ip+1))
+			f.ip += 2
+			value := v.pop()
+			obj := v.pop()
+			setter, ok := obj.(object.AttrSetter)
+			if !ok {
+				err = object.Errorf("type error: cannot set attribute \"%s\" on %s", name, obj.Type())
+				continue
+			}
+			if e := setter.SetAttr(name, value); e != nil {
+				err = e
+				continue
+			}
+
+		case compiler.OpCall:
+			f.ip++
+			err = v.call(int(ins[ip+1]))
+			if err == nil {
+				err = v.checkDone()
+			}
+
+		case compiler.OpCallMethod:
+			name := v.constantString(readUint16(ins, ip+1))
+			f.ip += 3
+			err = v.callMethod(name, int(ins[ip+3]))
+
+		case compiler.OpCallPipe:
+			// Swap the function with the output of the previous stage so
+			// that the output becomes the first argument
+			f.ip++
+			nargs := int(ins[ip+1])
+			fnIndex := v.sp - 1 - nargs
+			v.stack[fnIndex-1], v.stack[fnIndex] = v.stack[fnIndex], v.stack[fnIndex-1]
+			err = v.call(nargs + 1)
+
+		case compiler.OpCallMethodPipe:
+			name := v.constantString(readUint16(ins, ip+1))
+			f.ip += 3
+			nargs := int(ins[ip+3])
+			objIndex := v.sp - 1 - nargs
+			v.stack[objIndex-1], v.stack[objIndex] = v.stack[objIndex], v.stack[objIndex-1]
+			err = v.callMethod(name, nargs+1)
+
+		case compiler.OpPipeValue:
+			first := ins[ip+1] == 1
+			f.ip++
+			obj := v.stack[v.sp-1]
+			switch obj.(type) {
+			case *object.Function, *object.Closure, *object.Builtin, *object.Struct:
+				v.stack[v.sp-2], v.stack[v.sp-1] = v.stack[v.sp-1], v.stack[v.sp-2]
+				err = v.call(1)
+			case *object.Int, *object.BigInt:
+				// Between two integers the pipe operator is a bitwise OR
+				switch left := v.stack[v.sp-2].(type) {
+				case *object.Int, *object.BigInt:
+					v.stack[v.sp-2] = binary(v.ctx, "|", left, obj, v.opts.IntOverflow)
+					v.sp--
+					continue
+				}
+				if !first {
+					v.sp -= 2
+					err = object.Errorf("type error: unexpected %s object in pipe expression", obj.Type())
+					continue
+				}
+				v.stack[v.sp-2] = obj
+				v.sp--
+			default:
+				if !first {
+					v.sp -= 2
+					err = object.Errorf("type error: unexpected %s object in pipe expression", obj.Type())
+					continue
+				}
+				v.stack[v.sp-2] = obj
+				v.sp--
+			}
+
+		case compiler.OpReturnValue:
+			var result object.Object
+			if result, err = v.returnValue(); err != nil {
+				continue
+			}
+			if len(v.frames) == base {
+				return result
+			}
+			v.push(result)
+
+		case compiler.OpPropagate:
+			// An err result is returned from the current function, while
+			// anything else is handled just like the evaluator does
+			value := v.stack[v.sp-1]
+			if r, ok := value.(*object.Result); ok && r.IsErr() {
+				var result object.Object
+				if result, err = v.returnValue(); err != nil {
+					continue
+				}
+				if len(v.frames) == base {
+					return result
+				}
+				v.push(result)
+				continue
+			}
+			result := evaluator.Propagate(value)
+			if e, ok := result.(*object.Error); ok {
+				v.sp--
+				err = e
+				continue
+			}
+			v.stack[v.sp-1] = result
+
+		case compiler.OpMatch:
+			pattern := v.bytecode.Patterns()[readUint16(ins, ip+1)]
+			f.ip += 2
+			bindings, ok := evaluator.MatchPattern(pattern, v.stack[v.sp-1])
+			for _, value := range bindings {
+				v.push(value)
+			}
+			v.push(object.NewBool(ok))
+
+		case compiler.OpDestructure:
+			pattern := v.bytecode.Patterns()[readUint16(ins, ip+1)]
+			f.ip += 2
+			values, destructureErr := evaluator.Destructure(pattern, v.pop())
+			if destructureErr != nil {
+				err = destructureErr
+				continue
+			}
+			for i := len(values) - 1; i >= 0; i-- {
+				v.push(values[i])
+			}
+
+		case compiler.OpClosure:
+			fn := v.constants[readUint16(ins, ip+1)].(*object.CompiledFunction)
+			numFree := int(ins[ip+3])
+			f.ip += 3
+			free := make([]*object.Cell, numFree)
+			for i := 0; i < numFree; i++ {
+				offset := f.ip + i*compiler.CaptureWidth
+				index := readUint16(ins, offset+1)
+				if ins[offset] == compiler.CaptureFree {
+					free[i] = f.closure.Free()[index]
+					continue
+				}
+				slot, ok := v.stack[f.bp+index].(cellSlot)
+				if !ok {
+					// The variable hasn't been declared yet, so create its
+					// cell now and let the declaration fill it in
+					slot = cellSlot{&object.Cell{}}
+					v.stack[f.bp+index] = slot
+				}
+				free[i] = slot.Cell
+			}
+			f.ip += numFree * compiler.CaptureWidth
+			v.push(object.NewClosure(fn, free))
+
+		case compiler.OpRange:
+			iterator, e := evaluator.Iterate(v.pop())
+			if e != nil {
+				err = e
+				continue
+			}
+			v.push(iterator)
+
+		case compiler.OpIterClose:
+			// The loop is over, and a generator it left paused is closed
+			iterator := v.pop()
+			if n := len(v.loops); n > 0 && v.loops[n-1].sp == v.sp {
+				v.loops = v.loops[:n-1]
+			}
+			if iterator, ok := iterator.(object.Iterator); ok {
+				object.CloseIterator(iterator)
+			}
+
+		case compiler.OpIterNext:
+			iterObj := v.stack[v.sp-1]
+			iterator, ok := iterObj.(object.Iterator)
+			if !ok {
+				err = object.Errorf("eval error: cannot iterate over %s", iterObj.Type())
+				continue
+			}
+			if g, ok := iterator.(*object.Generator); ok {
+				if n := len(v.loops); n == 0 || v.loops[n-1].sp != v.sp-1 {
+					v.loops = append(v.loops, loop{sp: v.sp - 1, generator: g})
+				}
+			}
+			entry, ok := iterator.Next()
+			if !ok {
+				if err = object.IteratorErr(iterator); err != nil {
+					continue
+				}
+				f.ip = readUint16(ins, ip+1)
+				continue
+			}
+			f.ip += 3
+			switch ins[ip+3] {
+			case 0:
+				// Comprehension clauses with one variable get the item alone
+				v.push(evaluator.ComprehensionItem(iterator, entry))
+			case 1:
+				v.push(entry.Key())
+			default:
+				v.push(entry.Key())
+				v.push(entry.Value())
+			}
+			if err = v.checkDone(); err != nil {
+				continue
+			}
+
+		case compiler.OpUnpack:
+			count := readUint16(ins, ip+1)
+			f.ip += 2
+			list, ok := v.pop().(*object.List)
+			if !ok {
+				err = object.Errorf("eval error: invalid multi variable assignment")
+				continue
+			}
+			items := list.Value()
+			if len(items) != count {
+				err = object.Errorf("eval error: invalid multi variable assignment (list size: %d; identifiers: %d)",
+					len(items), count)
+				continue
+			}
+			for _, item := range items {
+				v.push(item)
+			}
+
+		case compiler.OpPostfix:
+			operator := compiler.PostfixOperators[ins[ip+1]]
+			name := v.constantString(readUint16(ins, ip+2))
+			f.ip += 3
+			result := evaluator.Postfix(v.ctx, operator, name, v.stack[v.sp-1], v.opts.IntOverflow)
+			if e, ok := result.(*object.Error); ok {
+				v.sp -= 2
+				err = e
+				continue
+			}
+			v.stack[v.sp-1] = result
+
+		case compiler.OpImport:
+			name := v.constantString(readUint16(ins, ip+1))
+			f.ip += 2
+			module, e := v.importModule(name)
+			if e != nil {
+				err = e
+				continue
+			}
+			v.push(module)
+
+		case compiler.OpTemplate:
+			count := readUint16(ins, ip+1)
+			f.ip += 2
+			var b strings.Builder
+			for _, part := range v.stack[v.sp-count : v.sp] {
+				if s, ok := part.(*object.String); ok {
+					b.WriteString(s.Value())
+				} else {
+					b.WriteString(part.Inspect())
+				}
+			}
+			v.sp -= count
+			str := object.NewString(b.String())
+			if err = v.opts.Limiter.Track(str); err != nil {
+				continue
+			}
+			v.push(str)
+
+		case compiler.OpRaise:
+			err = object.NewError(errors.New(v.constantString(readUint16(ins, ip+1))))
+
+		case compiler.OpCatch:
+			f.ip += 2
+			v.handlers = append(v.handlers, handler{
+				frame:  len(v.frames) - 1,
+				sp:     v.sp,
+				target: readUint16(ins, ip+1),
+			})
+
+		case compiler.OpEndCatch:
+			v.handlers = v.handlers[:len(v.handlers)-1]
+
+		case compiler.OpYield:
+			if v.yield == nil {
+				err = object.Errorf("eval error: yield outside generator")
+				continue
+			}
+			if !v.yield(v.stack[v.sp-1]) {
+				// The generator was closed or its context was canceled
+				if e := v.ctx.Err(); e != nil {
+					err = object.NewError(e)
+				} else {
+					err = object.NewError(object.ErrGeneratorClosed)
+				}
+				continue
+			}
+			v.stack[v.sp-1] = object.Nil
+
+		case compiler.OpCheckParams:
+			err = v.checkParams(f)
+
+		case compiler.OpCheckType:
+			typ := v.constantString(readUint16(ins, ip+1))
+			name := v.constantString(readUint16(ins, ip+3))
+			f.ip += 4
+			if !evaluator.IsType(typ, v.stack[v.sp-1]) {
+				err = object.Errorf("type error: %q must be %s (got %s)", name, typ, v.stack[v.sp-1].Type())
+			}
+
+		case compiler.OpGo:
+			f.ip++
+			err = v.spawn(int(ins[ip+1]), nil)
+
+		case compiler.OpGoMethod:
+			name := v.constantString(readUint16(ins, ip+1))
+			nargs := int(ins[ip+3])
+			f.ip += 3
+			if err = v.method(name, nargs); err != nil {
+				v.sp -= nargs + 1
+				continue
+			}
+			err = v.spawn(nargs, nil)
+
+		case compiler.OpDefer:
+			f.ip++
+			err = v.deferCall(int(ins[ip+1]), nil)
+
+		case compiler.OpDeferMethod:
+			name := v.constantString(readUint16(ins, ip+1))
+			nargs := int(ins[ip+3])
+			f.ip += 3
+			if err = v.method(name, nargs); err != nil {
+				v.sp -= nargs + 1
+				continue
+			}
+			err = v.deferCall(nargs, nil)
+
+		case compiler.OpSelect:
+			count := int(ins[ip+1])
+			hasDefault := ins[ip+2] == 1
+			table := ip + 3
+			cases, e := v.selectCases(ins, table, count)
+			if e != nil {
+				err = e
+				continue
+			}
+			chosen, value, ok, e := object.Select(v.ctx, cases, !hasDefault)
+			if e != nil {
+				err = e
+				continue
+			}
+			if chosen < 0 {
+				f.ip = readUint16(ins, table+count*compiler.SelectCaseWidth)
+				continue
+			}
+			if value == nil {
+				value = object.Nil
+			}
+			v.push(value)
+			v.push(object.NewBool(ok))
+			f.ip = readUint16(ins, table+chosen*compiler.SelectCaseWidth+1)
+
+		case compiler.OpSpread:
+			nargs := int(ins[ip+1])
+			f.ip += 1 + nargs
+			start := v.sp - nargs
+			args := make([]object.Object, 0, nargs)
+			for i, arg := range v.stack[start:v.sp] {
+				if ins[ip+2+i] == 0 {
+					args = append(args, arg)
+					continue
+				}
+				list, ok := arg.(*object.List)
+				if !ok {
+					err = object.Errorf("type error: spread argument must be a list (%s given)", arg.Type())
+					break
+				}
+				args = append(args, list.Value()...)
+			}
+			if err != nil {
+				continue
+			}
+			v.sp = start
+			v.push(object.NewList(args))
+
+		case compiler.OpCallSpread:
+			f.ip++
+			args := v.pop().(*object.List).Value()
+			err = v.callExpanded(ins[ip+1], args, nil)
+
+		case compiler.OpCallKwargs:
+			f.ip++
+			kwargs := v.pop().(*object.Map)
+			args := v.pop().(*object.List).Value()
+			err = v.callExpanded(ins[ip+1], args, kwargs)
+
+		case compiler.OpStruct:
+			numFields := readUint16(ins, ip+1)
+			numMethods := readUint16(ins, ip+3)
+			f.ip += 4
+			start := v.sp - (numFields+numMethods)*2
+			opts := object.StructOpts{
+				Name:     v.stack[start-1].(*object.String).Value(),
+				Fields:   make([]string, 0, numFields),
+				Defaults: make(map[string]object.Object, numFields),
+				Methods:  make(map[string]object.Object, numMethods),
+			}
+			for i := 0; i < numFields; i++ {
+				name := v.stack[start+i*2].(*object.String).Value()
+				opts.Fields = append(opts.Fields, name)
+				opts.Defaults[name] = v.stack[start+i*2+1]
+			}
+			for i := numFields; i < numFields+numMethods; i++ {
+				name := v.stack[start+i*2].(*object.String).Value()
+				opts.Methods[name] = v.stack[start+i*2+1]
+			}
+			v.sp = start - 1
+			v.push(object.NewStruct(opts))
+
+		default:
+			return object.Errorf("eval error: unknown opcode %d", op)
+		}
+	}
+}
+
+// load returns the value of a cell, or nil if the variable it holds has
+// not been declared yet.
+func (v *VM) load(cell *object.Cell) object.Object {
+	v.shared.lock()
+	defer v.shared.unlock()
+	return cell.Value
+}
+
+// store sets the value of a cell. If update is true, the variable must
+// already be declared, otherwise it must not be. Returns false if this
+// condition is not met.
+func (v *VM) store(cell *object.Cell, value object.Object, update bool) bool {
+	v.shared.lock()
+	defer v.shared.unlock()
+	if (cell.Value != nil) != update {
+		return false
+	}
+	cell.Value = value
+	return true
+}
+
+// checkDone returns an error if the context was cancelled.
+func (v *VM) checkDone() *object.Error {
+	select {
+	case <-v.done:
+		return object.NewError(v.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+func (v *VM) importModule(name string) (*object.Module, *object.Error) {
+	importer := v.evaluator.Importer()
+	if importer == nil {
+		return nil, object.Errorf("import error: importing is disabled")
+	}
+	module, err := importer.Import(v.ctx, v.evaluator, fmt.Sprintf("%s.tm", name))
+	if err != nil {
+		return nil, object.NewError(err)
+	}
+	return module, nil
+}
+
+func readUint16(ins []byte, offset int) int {
+	return int(ins[offset])<<8 | int(ins[offset+1])
+}
+
+func nameError(name string) *object.Error {
+	return object.Errorf("name error: %q is not defined", name)
+}
+
+func alreadySetError(name string) *object.Error {
+	return object.Errorf("assignment error: %q is already set", name)
+}
@@ -0,0 +1,614 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudcmds/tamarin/compiler"
+	"github.com/cloudcmds/tamarin/evaluator"
+	"github.com/cloudcmds/tamarin/object"
+	"github.com/cloudcmds/tamarin/parser"
+	"github.com/cloudcmds/tamarin/scope"
+	"github.com/stretchr/testify/require"
+)
+
+func run(ctx context.Context, input string, s *scope.Scope) object.Object {
+	program, err := parser.Parse(input)
+	if err != nil {
+		panic(err)
+	}
+	bytecode, err := compiler.Compile(program, compiler.Opts{})
+	if err != nil {
+		panic(err)
+	}
+	return New(bytecode, Opts{Scope: s}).Run(ctx)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1 + 2 * 3`, `7`},
+		{`x := 5; x += 2; x`, `7`},
+		{`"a" + "b"`, `"ab"`},
+		{`[1, 2, 3][-1]`, `3`},
+		{`{"a": 1}["a"]`, `1`},
+		{`[1, 2, 3, 4][1:3]`, `[2, 3]`},
+		{`true && false || true`, `true`},
+		{`1 < 2 ? "yes" : "no"`, `"yes"`},
+		{`func add(a, b=10) { a + b }; add(1)`, `11`},
+		{`func fib(n) { if n < 2 { return n }; return fib(n-1) + fib(n-2) }; fib(15)`, `610`},
+		{`x := 0; for i := 0; i < 10; i++ { if i == 5 { break }; x += i }; x`, `10`},
+		{`x := 0; for _, v := range [1, 2, 3] { if v == 2 { continue }; x += v }; x`, `4`},
+		{"switch 2 {\ncase 1:\n\"one\"\ncase 2:\n\"two\"\ndefault:\n\"other\"\n}", `"two"`},
+		{`[1, 2, 3].map(func(x) { x * 2 })`, `[2, 4, 6]`},
+		{`[3, 1, 2] | sorted`, `[1, 2, 3]`},
+		{`try(error("kaboom"), "fallback")`, `"fallback"`},
+		{`try(error("kaboom"), func(msg) { msg })`, `"kaboom"`},
+		{`x := 3; 'x is {x}'`, `"x is 3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := run(context.Background(), tt.input, nil)
+			require.Equal(t, tt.expected, result.Inspect())
+		})
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+	func counter() {
+		count := 0
+		return func() { count++; return count }
+	}
+	c1 := counter()
+	c2 := counter()
+	c1(); c1(); c2()
+	[c1(), c2()]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, "[3, 2]", result.Inspect())
+}
+
+func TestLoopClosures(t *testing.T) {
+	input := `
+	funcs := []
+	for _, v := range [1, 2, 3] {
+		funcs.append(func() { v })
+	}
+	funcs.map(func(f) { f() })
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, "[1, 2, 3]", result.Inspect())
+}
+
+func TestGoAndSelect(t *testing.T) {
+	input := `
+	results := chan()
+	total := 0
+	func square(x) { results.send(x * x) }
+	for i := 1; i <= 3; i++ {
+		go square(i)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case value := results.recv():
+			total += value
+		}
+	}
+	done := chan(1)
+	go done.send(total)
+	select {
+	case result, ok := done.recv():
+		[result, ok]
+	}
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, "[14, true]", result.Inspect())
+}
+
+func TestSharedClosure(t *testing.T) {
+	input := `
+	func run() {
+		count := 0
+		done := chan()
+		for i := 0; i < 50; i++ {
+			go func() { count++; done.send(true) }()
+		}
+		for i := 0; i < 50; i++ { done.recv() }
+		return count
+	}
+	run()
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, "50", result.Inspect())
+}
+
+func TestStruct(t *testing.T) {
+	input := `
+	struct Counter {
+		name
+		count = 0
+
+		func (c) add(n=1) {
+			c.count += n
+			return c
+		}
+	}
+	func run() {
+		step := 2
+		struct Pair {
+			a, b
+			func (p) total() { p.a + p.b + step }
+		}
+		c := Counter("hits").add().add(5)
+		return [c, c.count, Pair(1, 2).total(), type(c), c == Counter("hits", 6)]
+	}
+	run()
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[Counter{name: "hits", count: 6}, 6, 5, "Counter", true]`, result.Inspect())
+}
+
+func TestDefer(t *testing.T) {
+	input := `
+	log := []
+	func inner() {
+		defer log.append("inner")
+		return 1 / 0
+	}
+	func outer() {
+		defer log.append("outer")
+		inner()
+	}
+	func safe() {
+		defer log.append("safe")
+		return try(outer(), "recovered")
+	}
+	defer log.append("main")
+	[safe(), log]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `["recovered", ["inner", "outer", "safe", "main"]]`, result.Inspect())
+}
+
+func TestVariadic(t *testing.T) {
+	input := `
+	log := []
+	func add(...items) { log.extend(items) }
+	func f(a, ...rest) {
+		defer add(...rest)
+		return [a, rest]
+	}
+	func concat(a, b) { a + b }
+	[f(...[1, 2, 3]), f(0), "x" | concat(...["y"]), log]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[[1, [2, 3]], [0, []], "xy", [2, 3]]`, result.Inspect())
+}
+
+func TestKeywordArgs(t *testing.T) {
+	input := `
+	log := []
+	func add(item, prefix = "") { log.append(prefix + item) }
+	func f(a, b = 2, ...rest) {
+		defer add("done", prefix="f: ")
+		return [a, b, rest]
+	}
+	struct P { x; y = 0 }
+	func join(a, b) { a + b }
+	[f(1, b=5), f(b=3, a=0), P(y=2), "x" | join(b="y"), sorted([3, 1, 2], reverse=true), log]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[[1, 5, []], [0, 3, []], P{x: nil, y: 2}, "xy", [3, 2, 1], ["f: done", "f: done"]]`,
+		result.Inspect())
+}
+
+func TestBitwise(t *testing.T) {
+	input := `
+	x := 0b1100
+	x &= 0b1010
+	x |= 1
+	x <<= 0o2
+	func double(n) { n * 2 }
+	[x, 0xF0 & 0x3C, 6 ^ 3, 6 &^ 3, 1_024 >> 3, ^5, 1 | 2 | 4, 3 | double | 1]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[36, 48, 5, 4, 128, -6, 7, 7]`, result.Inspect())
+}
+
+func TestMatch(t *testing.T) {
+	input := `
+	func describe(v) {
+		match v {
+		case {"kind": "point", "xy": [x, y]} if x == y:
+			return "diagonal"
+		case {"kind": "point", "xy": [x, y]}:
+			return x + y
+		case [head, ...tail]:
+			return [head, len(tail)]
+		case ok(int(_)), err(_):
+			return "result"
+		case string(s):
+			return s + "!"
+		}
+		return "other"
+	}
+	funcs := []
+	for _, v := range [[1, 2], [3]] {
+		match v {
+		case [a, ...rest]:
+			funcs.append(func() { a + len(rest) })
+		}
+	}
+	[
+		describe({"kind": "point", "xy": [2, 2]}),
+		describe({"kind": "point", "xy": [2, 3]}),
+		describe([1, 2, 3]),
+		describe(ok(1)),
+		describe(err("e")),
+		describe(ok("s")),
+		describe("hi"),
+		funcs.map(func(f) { f() }),
+	]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `["diagonal", 5, [1, 2], "result", "result", "other", "hi!", [2, 3]]`, result.Inspect())
+}
+
+func TestDestructure(t *testing.T) {
+	input := `
+	func split(p) {
+		{name, tags: [first, ...rest], age = 0} := p
+		return [name, first, rest, age]
+	}
+	a, b := [1, 2]
+	[a, b] = [b, a]
+	funcs := []
+	for i, [x, y] := range [[1, 2], [3, 4]] {
+		funcs.append(func() { i + x * y })
+	}
+	[
+		split({"name": "ada", "tags": ["x", "y", "z"]}),
+		split({"name": "bob", "tags": ["w"], "age": 5}),
+		[a, b],
+		funcs.map(func(f) { f() }),
+	]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[["ada", "x", ["y", "z"], 0], ["bob", "w", [], 5], [2, 1], [2, 13]]`, result.Inspect())
+}
+
+func TestComprehension(t *testing.T) {
+	input := `
+	func scale(items, factor) {
+		return [x * factor for x in items if x > 0]
+	}
+	counts := {"a": 1, "b": 2, "c": 3}
+	adders := [func(n) { n + i } for i in [1, 2]]
+	[
+		scale([1, -2, 3], 10),
+		{k: v * v for k, v in counts if v > 1},
+		{len(w) for w in ["ab", "cd", "e"]},
+		[[i, j] for i in [1, 2, 3] for j in [0, 1] if i % 2 == j],
+		[f(10) for f in adders],
+		[k for k in counts],
+	]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[[10, 30], {"b": 4, "c": 9}, {1, 2}, [[1, 1], [2, 0], [3, 1]], [11, 12], ["a", "b", "c"]]`, result.Inspect())
+}
+
+func TestGenerator(t *testing.T) {
+	input := `
+	func squares(n) {
+		for i := 0; i < n; i++ {
+			yield i * i
+		}
+	}
+	func naturals() {
+		i := 0
+		for {
+			yield i
+			i++
+		}
+	}
+	func take(items, n) {
+		for _, x := range items {
+			if n == 0 {
+				return nil
+			}
+			yield x
+			n--
+		}
+	}
+	g := naturals()
+	first := [g.next().value, g.next().value]
+	g.close()
+	[
+		list(squares(4)),
+		list(take(naturals(), 3)),
+		[x + 1 for _, x in squares(3)],
+		first,
+		g.next(),
+	]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[[0, 1, 4, 9], [0, 1, 2], [1, 2, 5], [0, 1], nil]`, result.Inspect())
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	input := `
+	struct P { x; func (p) add(n: int) -> int { p.x + n } }
+	func f(x: int, y: string = "a", ...rest: int) -> string {
+		return y + string(x)
+	}
+	func g(p: P) -> P { p }
+	func h(n: int) -> generator { yield n }
+	v: list := [f(2), f(1, "b", 3, 4), g(P(1)).add(1), list(h(5))]
+	var w: any = nil
+	[v, w]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[["a2", "b1", 2, [5]], nil]`, result.Inspect())
+
+	// Values assigned after the declaration are checked too
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var limit: int = 10; limit = "s"`, `type error: "limit" must be int (got string)`},
+		{`var limit: int = 10; limit += 0.5`, `type error: "limit" must be int (got float)`},
+		{`var n: int = 1; [n, _] = ["a", 2]`, `type error: "n" must be int (got string)`},
+		{`var n: int = 1; func f() { n = "a" }; f()`, `type error: "n" must be int (got string)`},
+		{`func f(x: int) { x = "a" }; f(1)`, `type error: "x" must be int (got string)`},
+		{`var limit: int = 10; limit += 5; limit++; func f(...xs: int) { xs = [limit] }; f()`, `[16]`},
+		{`var n: int = 1; func f() { n := "a"; n }; f()`, `"a"`},
+	}
+	for _, tt := range tests {
+		result := run(context.Background(), tt.input, nil)
+		if errObj, ok := result.(*object.Error); ok {
+			require.Equal(t, tt.expected, errObj.Message().Value(), tt.input)
+		} else {
+			require.Equal(t, tt.expected, result.Inspect(), tt.input)
+		}
+	}
+}
+
+func TestSliceStep(t *testing.T) {
+	input := `
+	x := [0, 1, 2, 3, 4, 5]
+	y := x.copy()
+	y[1:3] = ["a", "b", "c"]
+	z := x.copy()
+	z[::2] = [7, 8, 9]
+	delete(z, 1:3)
+	[x[::-1], x[4:1:-1], x[1::2], "hello"[::-1], y, z]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[[5, 4, 3, 2, 1, 0], [4, 3, 2], [1, 3, 5], "olleh", [0, "a", "b", "c", 3, 4, 5], [7, 3, 9, 5]]`, result.Inspect())
+}
+
+func TestMapKeys(t *testing.T) {
+	input := `
+	m := {1: "a", true: "b", "1": "c"}
+	m[2.5] = "d"
+	delete(m, "1")
+	[m, m[1], m[true], m.keys(), {x: x * 2 for x in [1, 2]}]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[{true: "b", 2.5: "d", 1: "a"}, "a", "b", [true, 2.5, 1], {1: 2, 2: 4}]`, result.Inspect())
+}
+
+// distance is proxied to test operator overloading in the VM
+type distance struct {
+	Meters int64
+}
+
+func (d *distance) Add(other *distance) *distance {
+	return &distance{Meters: d.Meters + other.Meters}
+}
+
+func (d *distance) Less(other *distance) bool {
+	return d.Meters < other.Meters
+}
+
+func (d *distance) Equal(other *distance) bool {
+	return d.Meters == other.Meters
+}
+
+func (d *distance) String() string {
+	return fmt.Sprintf("%dm", d.Meters)
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	reg, err := object.NewTypeRegistry()
+	require.Nil(t, err)
+	s := scope.New(scope.Opts{})
+	for name, meters := range map[string]int64{"a": 5, "b": 8, "c": 5} {
+		p, err := object.NewProxy(reg, &distance{Meters: meters})
+		require.Nil(t, err)
+		require.Nil(t, s.Declare(name, p, false))
+	}
+	input := `
+	total := a
+	total += b
+	[a + b, total, a < b, a > b, a <= c, b >= a, a == c, a != c, -a.Meters]
+	`
+	result := run(context.Background(), input, s)
+	require.Equal(t, "[13m, 13m, true, false, true, true, true, false, -5]", result.Inspect())
+
+	// Operands that the Go methods don't accept get the default behavior
+	result = run(context.Background(), `[a == nil, a != nil, a == 1, a != 1]`, s)
+	require.Equal(t, "[false, true, false, true]", result.Inspect())
+	result = run(context.Background(), `a + 1`, s)
+	require.Equal(t, "type error: unsupported operand types for +: proxy and int", result.(*object.Error).Message().Value())
+}
+
+func TestPropagate(t *testing.T) {
+	input := `
+	log := []
+	func atoi(s) { s == "x" ? err("invalid") : ok(int(s)) }
+	func parse(s) {
+		defer log.append(s)
+		return ok(atoi(s)? * 2)
+	}
+	func sum(a, b) { ok(parse(a)? + parse(b)?) }
+	func guarded(r) { try(r?, 0) }
+	[parse("21"), sum("1", "2"), sum("x", "2").is_err(), guarded(err("e")), ok(1)?, log]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[ok(42), ok(6), true, err("e"), 1, ["21", "1", "2", "x"]]`, result.Inspect())
+}
+
+func TestRuntimeErrorPosition(t *testing.T) {
+	input := `func add(a, b) {
+	return a + b
+}
+func run() {
+	add(1, "x")
+}
+run()`
+	result := run(context.Background(), input, nil)
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok, "got %T", result)
+	rtErr, ok := errObj.RuntimeError()
+	require.True(t, ok)
+	require.Equal(t, "type error: unsupported operand types for +: int and string", rtErr.Error())
+	require.Equal(t, 2, rtErr.StartPosition().LineNumber())
+	require.Equal(t, 11, rtErr.StartPosition().ColumnNumber())
+	var trace []string
+	for _, frame := range rtErr.Stack() {
+		trace = append(trace, fmt.Sprintf("%s:%d", frame.Name, frame.Position.LineNumber()))
+	}
+	require.Equal(t, []string{"main:7", "run:5", "add:2"}, trace)
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`bogus`, `name error: "bogus" is not defined`},
+		{`const x = 1; x = 2`, `assignment error: "x" is read-only`},
+		{`x := 1; x := 2`, `assignment error: "x" is already set`},
+		{`func f(a) { a }; f()`, `type error: function expected 1 arguments (0 given)`},
+		{`1 + "a"`, `type error: unsupported operand types for +: int and string`},
+		{`5()`, `type error: int is not callable`},
+		{`go 5()`, `type error: int is not callable`},
+		{`defer 5()`, `type error: int is not callable`},
+		{"func f() { defer func() { 1 / 0 }() }\nf()", `eval error: int divided by zero`},
+		{"struct P { x }\nP().y = 1", `attribute error: P has no field "y"`},
+		{"x := 1\nx.y = 1", `type error: cannot set attribute "y" on int`},
+		{"x := 1\nselect {\ncase x.recv():\n1\n}", `type error: select case expected a chan (got int)`},
+		{"func f(a, ...b) { b }\nf()", `type error: function expected at least 1 arguments (0 given)`},
+		{"func f(...b) { b }\nf(...1)", `type error: spread argument must be a list (int given)`},
+		{"func f(a) { a }\nf(b=1)", `type error: f() got an unexpected keyword argument "b"`},
+		{"func f(a, b) { a }\nf(b=1)", `type error: f() missing argument "a"`},
+		{"len(\"a\", x=1)", `type error: len() got an unexpected keyword argument "x"`},
+		{"x := 1\nx <<= -1", `eval error: negative shift count: -1`},
+		{"^\"a\"", `type error: expected int to follow ^ operator (got string)`},
+		{"x := 1\nx?", `type error: ? operator expected a result (got int)`},
+		{"[a, b] := [1]", `eval error: invalid destructuring assignment (list size: 1; targets: 2)`},
+		{"func f(x: int) { x }\nf(\"a\")", `type error: f() argument "x" must be int (got string)`},
+		{"func f(x: int = 1.5) { x }\nf()", `type error: f() argument "x" must be int (got float)`},
+		{"func f(x: int) { yield x }\nf(\"a\")", `type error: f() argument "x" must be int (got string)`},
+		{"func f() -> int { \"a\" }\nf()", `type error: f() must return int (got string)`},
+		{"var x: string = 1", `type error: "x" must be string (got int)`},
+		{"const x: int = nil", `type error: "x" must be int (got nil)`},
+		{"{a} := 1", `type error: cannot destructure int as a map`},
+		{"{a} := {\"b\": 1}", `key error: "a"`},
+		{"const a = 1\n[a] = [2]", `assignment error: "a" is read-only`},
+		{"[x for x in 5]", `type error: int is not a container`},
+		{"{x: 1 for x in [[1]]}", `type error: list object is unhashable`},
+		{"{[1]: 2}", `type error: list object is unhashable`},
+		{"{1: 2}[2]", `key error: 2`},
+		{"func f() { yield 1; 1 / 0 }\nlist(f())", `eval error: int divided by zero`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := run(context.Background(), tt.input, nil)
+			errObj, ok := result.(*object.Error)
+			require.True(t, ok, "got %s", result.Inspect())
+			require.Equal(t, tt.expected, errObj.Message().Value())
+		})
+	}
+}
+
+func TestHostScope(t *testing.T) {
+	s := scope.New(scope.Opts{})
+	s.Declare("x", object.NewInt(40), false)
+	result := run(context.Background(), `x + 2`, s)
+	require.Equal(t, object.NewInt(42), result)
+}
+
+func TestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result := run(ctx, "for i := 0; i < 999999999999; i++ { i }", nil)
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok)
+	require.Contains(t, errObj.Message().Value(), "deadline")
+}
+
+func TestBigInt(t *testing.T) {
+	input := `
+	x := 123456789012345678901234567890
+	y := 9223372036854775807
+	y++
+	z := bigint(6)
+	[x, type(x), y, type(y), 2 ** 70, z | 1, z & 3, -z, z > 5.5, {bigint(6): 1}[6], int(z)]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[123456789012345678901234567890, "bigint", 9223372036854775808, "bigint", 1180591620717411303424, 7, 2, -6, true, 1, 6]`, result.Inspect())
+}
+
+func TestIntOverflow(t *testing.T) {
+	tests := []string{
+		"9223372036854775807 + 1",
+		"x := 9223372036854775807; x += 1",
+		"x := 9223372036854775807; x++",
+		"4294967296 * 4294967296",
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			program, err := parser.Parse(input)
+			require.Nil(t, err)
+			bytecode, err := compiler.Compile(program, compiler.Opts{})
+			require.Nil(t, err)
+			result := New(bytecode, Opts{IntOverflow: evaluator.ErrorOnOverflow}).Run(context.Background())
+			errObj, ok := result.(*object.Error)
+			require.True(t, ok, "got %s", result.Inspect())
+			require.Contains(t, errObj.Message().Value(), "eval error: int overflow")
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	input := `
+	total := decimal("0")
+	for _, price := range ["0.10", "0.20", "19.99"] {
+		total += decimal(price)
+	}
+	tax := (total * decimal("0.0825")).round(2, "half_up")
+	[total, tax, total + tax, -tax, total > 20, total / 3]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[20.29, 1.67, 21.96, -1.67, true, 6.7633333333333333]`, result.Inspect())
+}
+
+func TestBytes(t *testing.T) {
+	input := `
+	data := bytes("GIF89a", "ascii") + bytes([1, 0, 255])
+	var header = []
+	for _, b := range data[:6] {
+		header.append(b)
+	}
+	[len(data), header, data[-1], data.hex(), string(data[:3])]
+	`
+	result := run(context.Background(), input, nil)
+	require.Equal(t, `[9, [71, 73, 70, 56, 57, 97], 255, "4749463839610100ff", "GIF"]`, result.Inspect())
+}